@@ -0,0 +1,135 @@
+package boxer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// AppleScriptString returns s as a quoted AppleScript string literal.
+// Backslashes & quotes are escaped and line breaks & tabs use their escape
+// sequences. Other control characters cannot be written in a literal so
+// they are replaced with spaces.
+func AppleScriptString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7F:
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// AppleScriptList returns a as an AppleScript list of string literals,
+// such as {"a", "b"}.
+func AppleScriptList(a []string) string {
+	items := make([]string, len(a))
+	for i, s := range a {
+		items[i] = AppleScriptString(s)
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// javaScriptString returns s as a quoted JavaScript string literal for
+// scripts run with "osascript -l JavaScript".
+func javaScriptString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// ScriptPath returns path as a clean, absolute path that is safe to
+// interpolate into a script. Returns an error if the path is blank or
+// contains a line break or NUL character.
+func ScriptPath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path required")
+	} else if strings.ContainsAny(path, "\x00\r\n") {
+		return "", fmt.Errorf("invalid path: %q", path)
+	}
+	return filepath.Abs(path)
+}
+
+// scriptFuncs are the functions available to script templates. Every value
+// interpolated into a script must be passed through one of them.
+var scriptFuncs = template.FuncMap{
+	"str":  AppleScriptString,
+	"list": AppleScriptList,
+	"js":   javaScriptString,
+}
+
+// newScriptTemplate parses an AppleScript or JavaScript template. Leading
+// & trailing whitespace is trimmed. Panics if src cannot be parsed.
+func newScriptTemplate(name, src string) *template.Template {
+	return template.Must(template.New(name).Funcs(scriptFuncs).Parse(strings.TrimSpace(src)))
+}
+
+// renderScript executes the script template with data.
+func renderScript(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render %s script: %s", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// AutomationError is returned when macOS denies permission to control an
+// app with Apple events.
+type AutomationError struct {
+	App string
+}
+
+// Error returns the error message along with how to grant the permission.
+func (e *AutomationError) Error() string {
+	return fmt.Sprintf("not authorized to control %s: allow it in System Settings > Privacy & Security > Automation", e.App)
+}
+
+// IsAutomationDenied returns true if osascript output reports that sending
+// Apple events is not permitted (-1743) or would require the user's consent
+// (-1744).
+func IsAutomationDenied(output []byte) bool {
+	s := string(output)
+	return strings.Contains(s, "-1743") || strings.Contains(s, "-1744") || strings.Contains(s, "Not authorized to send Apple events")
+}
+
+// RequestAutomation sends a harmless Apple event to app. The first time, macOS
+// shows the "wants to control" prompt and osascript blocks until it is
+// answered. Returns an *AutomationError if permission is denied.
+func RequestAutomation(exec CommandExecutor, app string) error {
+	src, err := renderScript(requestAutomationScript, app)
+	if err != nil {
+		return err
+	}
+
+	if b, err := exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
+		if IsAutomationDenied(b) {
+			return &AutomationError{App: app}
+		}
+		return fmt.Errorf("exec: %s", b)
+	}
+	return nil
+}
+
+var requestAutomationScript = newScriptTemplate("automation", `
+tell application {{str .}} to get name
+`)
@@ -0,0 +1,64 @@
+package boxer_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure strings are escaped for AppleScript string literals.
+func TestAppleScriptString(t *testing.T) {
+	if s := boxer.AppleScriptString("it's \"done\"\\\n\tnow\x00"); s != `"it's \"done\"\\\n\tnow "` {
+		t.Fatalf("unexpected string: %s", s)
+	}
+}
+
+// Ensure lists are written as AppleScript lists of string literals.
+func TestAppleScriptList(t *testing.T) {
+	if s := boxer.AppleScriptList([]string{"Work", `"Home"`}); s != `{"Work", "\"Home\""}` {
+		t.Fatalf("unexpected list: %s", s)
+	} else if s := boxer.AppleScriptList(nil); s != `{}` {
+		t.Fatalf("unexpected list: %s", s)
+	}
+}
+
+// Ensure script paths are made absolute & reject line breaks.
+func TestScriptPath(t *testing.T) {
+	if path, err := boxer.ScriptPath("/tmp/../tmp/it's here"); err != nil {
+		t.Fatal(err)
+	} else if path != "/tmp/it's here" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+
+	if _, err := boxer.ScriptPath("/tmp/foo\nbar"); err == nil || err.Error() != `invalid path: "/tmp/foo\nbar"` {
+		t.Fatal(err)
+	} else if _, err := boxer.ScriptPath(""); err == nil || err.Error() != `path required` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a denied automation permission returns an AutomationError.
+func TestRequestAutomation(t *testing.T) {
+	var src string
+	exec := boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		if src = string(b); src == `tell application "Finder" to get name` {
+			return []byte("Finder\n"), nil
+		}
+		return []byte("execution error: Not authorized to send Apple events to System Events. (-1743)"), errors.New("exit status 1")
+	})
+
+	if err := boxer.RequestAutomation(exec, "Finder"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := boxer.RequestAutomation(exec, "System Events")
+	if e, ok := err.(*boxer.AutomationError); !ok || e.App != "System Events" {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if src != `tell application "System Events" to get name` {
+		t.Fatalf("unexpected script: %s", src)
+	}
+}
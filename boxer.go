@@ -1,75 +1,1215 @@
 package boxer
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image/color"
 	"io"
 	"log"
 	"math"
+	"math/rand"
 	"os"
-	"os/exec"
 	"regexp"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/benbjohnson/boxer/clock"
+	"golang.org/x/image/colornames"
 )
 
 // Ticker represents an object that can check for new time intervals and perform actions.
-// The ticker is not safe to use in multiple goroutines.
+// Tick must not be called from multiple goroutines at once, however, every
+// other method may be called concurrently with Tick & Run.
 type Ticker struct {
-	prev time.Time // last tick time
+	mu      sync.Mutex
+	prev    time.Time             // last tick time
+	rule    *ScheduleRule         // schedule rule active at last tick
+	idle    bool                  // true if outside the schedule at last tick
+	paused  bool                  // true if paused by the user
+	label   string                // label set by the user
+	skipped time.Time             // time of the last skip
+	snoozed time.Time             // end of the snooze set by the user
+	anchor  time.Time             // start of intervals set by the user
+	errors  map[string]*stepError // last error by command name
+
+	failures    map[string]int       // consecutive failures by command name
+	quarantined map[string]time.Time // end of quarantine by command name
+
+	disabled map[string]bool     // names of commands disabled by the user
+	enabled  map[string]struct{} // names of commands re-enabled since the last tick
+
+	states      []*commandState         // state of each command, aligned with Commands
+	subscribers map[chan Event]struct{} // channels receiving events
+	wakeup      chan struct{}           // interrupts sleep in Run
 
-	// A list of commands to execute when steps occur.
+	// A list of commands to execute when steps occur. This must not be
+	// changed once the ticker is running. Use AddCommand, RemoveCommand, or
+	// ReplaceCommands instead.
 	Commands []Command
 
-	// The logger used for displaying debug information.
-	Logger *log.Logger
+	// If set, commands only execute while a rule in the schedule is active.
+	Schedule Schedule
+
+	// If set, commands only execute during these hours of the day.
+	ActiveHours *TimeRange
+
+	// If any suppressor is active then intrusive commands are skipped.
+	Suppressors []Suppressor
+
+	// If set, completed intervals are recorded to the journal.
+	Journal Journal
+
+	// If set, the interval start set by Start is saved to the store so it
+	// can be restored after a restart.
+	AnchorStore AnchorStore
+
+	// How an interval begun by Start ends. By default, the next interval
+	// begins at its boundary.
+	Overtime OvertimePolicy
+
+	// The location used to align steps & intervals to the wall clock. If
+	// nil, the location of the clock's time is used, which is normally the
+	// local time zone when the process started.
+	Location *time.Location
+
+	// The maximum time Run sleeps between ticks. Defaults to DefaultMaxSleep.
+	MaxSleep time.Duration
+
+	// If the time since the previous tick exceeds the threshold then the
+	// tick is treated as a wake from sleep. Interval handlers are skipped on
+	// wake unless the first step of the interval has just begun, or unless
+	// WakeIntervalHandlers is true. If zero, wake detection is disabled.
+	WakeThreshold        time.Duration
+	WakeIntervalHandlers bool
+
+	// If set, a command is disabled after its handlers fail this many times
+	// in a row. Commands returning a fatal error are always disabled.
+	MaxFailures int
+
+	// Handlers called once when a command is quarantined by its breaker.
+	QuarantineHandlers []QuarantineHandler
+
+	// Middleware applied to the handlers of every command. These wrap
+	// around each command's own middleware.
+	Middleware []Middleware
+
+	// The logger used for reporting handler execution.
+	Logger Logger
 
-	// A function used to return the current time.
-	// This is used for testing.
-	Now NowFunc
+	// If set, the reason each command does or does not execute is written
+	// to the debug logger on every tick.
+	Debug *log.Logger
+
+	// The clock used to determine the current time & to sleep between ticks.
+	// This is used for testing & simulation. Handler timeouts always use
+	// the system clock since they limit the real execution of handlers.
+	Clock clock.Clock
 }
 
+// DefaultMaxSleep is the default maximum time between ticks in Run.
+const DefaultMaxSleep = 1 * time.Minute
+
+// MinStep is the shortest step that ticks reliably. Shorter steps are
+// limited by timer precision & handler execution time.
+const MinStep = 100 * time.Millisecond
+
 // NewTicker returns a new instance of Ticker with default settings.
 func NewTicker() *Ticker {
 	return &Ticker{
-		Logger: log.New(os.Stderr, "", 0),
-		Now:    time.Now,
+		errors:   make(map[string]*stepError),
+		Logger:   NewTextLogger(log.New(os.Stderr, "", 0)),
+		Clock:    clock.New(),
+		MaxSleep: DefaultMaxSleep,
 	}
 }
 
 // Tick checks the current time to see if a new segment or interval has occurred.
+//
+// The handlers for each command are executed concurrently and Tick waits for
+// them to complete. If a command has a timeout then Tick stops waiting after
+// the timeout and the command is skipped on later ticks until its handler
+// returns. A handler that panics is logged as failed.
 func (t *Ticker) Tick() {
 	// Retrieve the current time.
-	now := t.Now()
+	now := t.now()
 
-	// Iterate over each command.
-	for _, cmd := range t.Commands {
-		// Initialize step to the interval if there is no step.
-		step, interval := cmd.Step, cmd.Interval
-		if step == 0 {
-			step = cmd.Interval
+	t.mu.Lock()
+	t.alignStart(now)
+	prev, paused, skipped, snoozed, anchor := t.prev, t.paused, t.skipped, t.snoozed, t.anchor
+	commands, states := t.Commands, t.commandStates()
+	if t.idle {
+		prev = time.Time{}
+	}
+	disabled, enabled := make(map[string]bool, len(t.disabled)), t.enabled
+	for name := range t.disabled {
+		disabled[name] = true
+	}
+	quarantined := make(map[string]time.Time, len(t.quarantined))
+	for name, until := range t.quarantined {
+		quarantined[name] = until
+	}
+	t.enabled = nil
+	t.mu.Unlock()
+
+	// Determine if the ticker is within the active hours & schedule.
+	rule, active := t.active(now)
+
+	// Record completed intervals to the journal.
+	t.track(now, rule, anchor, active, paused, skipped)
+
+	// Remain silent while paused or outside of the active hours & schedule.
+	// Clearing the previous time ensures all commands execute as soon as the
+	// ticker becomes active again.
+	if paused || !active {
+		if paused {
+			t.debugf("tick: paused")
+		} else {
+			t.debugf("tick: outside of active hours & schedule")
+		}
+		t.mu.Lock()
+		t.prev, t.rule, t.idle = now, nil, true
+		t.mu.Unlock()
+		return
+	}
+
+	// Report a change of the UTC offset, such as a DST transition.
+	if !prev.IsZero() && prev.Format("-07:00") != now.Format("-07:00") {
+		t.debugf("tick: utc offset changed from %s to %s", prev.Format("-07:00"), now.Format("-07:00"))
+		t.emit(&ZoneChangedEvent{Time: now, Prev: prev})
+	}
+
+	// Detect a wake from sleep by a large gap since the previous tick.
+	wake := t.WakeThreshold > 0 && !prev.IsZero() && now.Sub(prev) > t.WakeThreshold
+	if wake {
+		t.debugf("tick: woke after %s", now.Sub(prev))
+		t.emit(&WakeEvent{Time: now, Prev: prev, Missed: missed(commands, prev, now, rule, anchor)})
+	}
+
+	// Execute each command's handlers concurrently.
+	var wg sync.WaitGroup
+	var suppressed *bool
+	for _, index := range labelFirst(commands) {
+		cmd := resolve(commands[index], rule, anchor)
+		step, interval := cmd.step(), cmd.Interval
+
+		// Calculate the current step number & total steps.
+		_, i, n, overtime := t.position(&cmd, anchor, now)
+
+		// Ignore commands whose current interval has been skipped.
+		if !skipped.IsZero() && cmd.truncate(skipped, interval) == cmd.truncate(now, interval) {
+			t.debugf("%s: step %d/%d: interval skipped", cmd.Name, i+1, n)
+			continue
+		}
+
+		// Ignore disabled commands. Re-enabled commands execute their
+		// handlers for the current step as if the ticker had been resumed.
+		if disabled[cmd.Name] {
+			t.debugf("%s: step %d/%d: disabled", cmd.Name, i+1, n)
+			continue
+		}
+
+		// Skip quarantined commands until their next retry.
+		if until := quarantined[cmd.Name]; now.Before(until) {
+			t.debugf("%s: step %d/%d: quarantined until %s", cmd.Name, i+1, n, until.Format("15:04:05.999"))
+			continue
+		}
+
+		cmdPrev := prev
+		if _, ok := enabled[cmd.Name]; ok {
+			cmdPrev = time.Time{}
+		}
+
+		// Check if we've entered a new interval or step within the interval.
+		// Intervals held open in overtime don't begin or warn again.
+		newInterval := cmd.truncate(cmdPrev, interval) != cmd.truncate(now, interval) && cmd.IntervalHandler != nil && overtime == 0
+		newStep := cmd.truncate(cmdPrev, step) != cmd.truncate(now, step) && cmd.Handler != nil
+		newWarning := cmd.warning(cmdPrev, now) && overtime == 0
+		progress := cmd.ProgressHandler != nil
+
+		// After waking, only resync the current step unless the interval
+		// has just begun. This avoids firing interval handlers late.
+		if wake && i != 0 && !t.WakeIntervalHandlers {
+			newInterval = false
+		}
+
+		// Apply the catch-up policy if step boundaries were missed since
+		// the previous tick.
+		first := i
+		if missed := cmd.missedSteps(cmdPrev, now); missed > 0 && (newInterval || newStep) {
+			switch cmd.CatchUp {
+			case CatchUpAll:
+				if first = i - missed; first < 0 || overtime > 0 {
+					first = i
+				}
+			case CatchUpSkip:
+				t.debugf("%s: step %d/%d: %d missed step(s) not caught up", cmd.Name, i+1, n, missed)
+				newInterval, newStep = false, false
+			}
 		}
+		if overtime > 0 {
+			t.debugf("%s: step %d/%d: %d step(s) of overtime", cmd.Name, i+1, n, overtime)
+		}
+		if !newInterval && !newStep && !newWarning && !progress {
+			t.debugf("%s: step %d/%d: waiting until %s", cmd.Name, i+1, n, cmd.truncate(now, step).Add(step).Format("15:04:05.999"))
+			continue
+		}
+
+		// Skip intrusive commands while snoozed or suppressed. Suppressors
+		// are only checked once per tick.
+		if cmd.Intrusive {
+			if suppressed == nil {
+				v := now.Before(snoozed) || t.suppressed(now, cmd.Name, t.Suppressors)
+				suppressed = &v
+			}
+			if *suppressed {
+				t.debugf("%s: step %d/%d: intrusive command snoozed or suppressed", cmd.Name, i+1, n)
+				continue
+			}
+		}
+
+		// Skip the command while any of its own suppressors are active.
+		if t.suppressed(now, cmd.Name, cmd.Suppressors) {
+			t.debugf("%s: step %d/%d: suppressed", cmd.Name, i+1, n)
+			continue
+		}
+
+		// Skip the command if its previous execution is still running.
+		t.debugf("%s: step %d/%d: executing (interval=%v, step=%v, warning=%v, progress=%v)", cmd.Name, i+1, n, newInterval, newStep, newWarning, progress)
+		state := states[index]
+		if !t.begin(state) {
+			t.log(&LogEntry{Time: now, Command: cmd.Name, Step: i, Steps: n, Interval: newInterval, Err: ErrHandlerRunning})
+			continue
+		}
+
+		done := make(chan struct{})
+		go func(cmd Command) {
+			defer close(done)
+			defer t.end(state)
+
+			if newInterval {
+				t.exec(now, &cmd, &Invocation{Command: cmd.Name, Step: i, Steps: n, Interval: true})
+			}
+			if newStep {
+				for j := first; j <= i; j++ {
+					t.exec(now, &cmd, &Invocation{Command: cmd.Name, Step: j, Steps: n, Overtime: overtime})
+				}
+			}
+			if newWarning {
+				t.exec(now, &cmd, &Invocation{Command: cmd.Name, Step: i, Steps: n, Warning: true})
+			}
+			if progress {
+				t.exec(now, &cmd, &Invocation{Command: cmd.Name, Step: i, Steps: n, Overtime: overtime, Progress: true})
+			}
+		}(cmd)
+
+		// Wait for the command to finish or time out. Commands that set the
+		// label finish before the other commands start so they see it.
+		wg.Add(1)
+		wait := func(cmd Command) {
+			defer wg.Done()
 
-		// Check if we've entered a new step within the interval.
-		if t.prev.Truncate(step) != now.Truncate(step) && cmd.Handler != nil {
-			// Calculate the current step number & total steps.
-			var i, n int
-			if step == 0 {
-				i, n = 0, 1
-			} else {
-				i = int(now.Truncate(step).Sub(now.Truncate(interval)) / step)
-				n = int(interval / step)
+			if cmd.Timeout <= 0 {
+				<-done
+				return
 			}
 
-			// Execute the command's handler.
-			if err := cmd.Handler(i, n); err != nil {
-				t.Logger.Printf("%s: %s", cmd.Name, err.Error())
+			select {
+			case <-done:
+			case <-time.After(cmd.Timeout):
+				t.logResult(&LogEntry{Time: now, Command: cmd.Name, Step: i, Steps: n, Interval: newInterval, Duration: cmd.Timeout, Err: ErrHandlerTimeout})
+				t.emit(&HandlerFailedEvent{Time: now, Command: cmd.Name, Step: i, Steps: n, Interval: newInterval, Err: ErrHandlerTimeout})
 			}
 		}
+		if cmd.SetsLabel {
+			wait(cmd)
+		} else {
+			go wait(cmd)
+		}
 	}
+	wg.Wait()
 
 	// Set the previous tick time for the next run.
-	t.prev = now
+	t.mu.Lock()
+	t.prev, t.rule, t.idle = now, rule, false
+	t.mu.Unlock()
+}
+
+// Pause stops the ticker from executing handlers until Resume is called.
+func (t *Ticker) Pause() {
+	t.mu.Lock()
+	t.paused = true
+	t.signal()
+	t.mu.Unlock()
+
+	t.emit(&PauseEvent{Time: t.now(), Paused: true})
+}
+
+// Resume resumes a paused ticker. The handlers for the current step of every
+// command execute on the next tick.
+func (t *Ticker) Resume() {
+	t.mu.Lock()
+	t.paused = false
+	t.signal()
+	t.mu.Unlock()
+
+	t.emit(&PauseEvent{Time: t.now(), Paused: false})
+}
+
+// Snooze skips intrusive commands, such as announcements, for d. Other
+// commands continue to execute. A duration of zero or less ends the snooze.
+func (t *Ticker) Snooze(d time.Duration) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if d <= 0 {
+		t.snoozed = time.Time{}
+		return
+	}
+	t.snoozed = now.Add(d)
+}
+
+// SetLabel sets a label, such as the name of a task, for the current work.
+// The label remains until it is changed. A blank label clears it.
+func (t *Ticker) SetLabel(label string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.label = label
+}
+
+// Label returns the current label.
+func (t *Ticker) Label() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.label
+}
+
+// Disable stops executing the handlers of the named command until Enable
+// is called. Returns ErrCommandNotFound if no command has the name.
+func (t *Ticker) Disable(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.hasCommand(name) {
+		return ErrCommandNotFound
+	}
+	if t.disabled == nil {
+		t.disabled = make(map[string]bool)
+	}
+	t.disabled[name] = true
+	delete(t.enabled, name)
+	return nil
+}
+
+// Enable re-enables a command disabled by Disable. The handlers for the
+// current step of the command execute on the next tick.
+// Returns ErrCommandNotFound if no command has the name.
+func (t *Ticker) Enable(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.hasCommand(name) {
+		return ErrCommandNotFound
+	} else if !t.disabled[name] {
+		return nil
+	}
+	delete(t.disabled, name)
+	delete(t.failures, name)
+	delete(t.quarantined, name)
+	if t.enabled == nil {
+		t.enabled = make(map[string]struct{})
+	}
+	t.enabled[name] = struct{}{}
+	t.signal()
+	return nil
+}
+
+// Start begins a new interval for every command now instead of at the next
+// boundary of the wall clock. Later intervals are aligned to this time.
+func (t *Ticker) Start() error {
+	return t.SetAnchor(t.now())
+}
+
+// SetAnchor aligns the intervals of every command to anchor, overriding the
+// alignment of each command. A zero anchor realigns them. The anchor is saved
+// to the anchor store, if set.
+func (t *Ticker) SetAnchor(anchor time.Time) error {
+	t.mu.Lock()
+	t.anchor = anchor
+	t.signal()
+	t.mu.Unlock()
+
+	if t.AnchorStore != nil {
+		if err := t.AnchorStore.SaveAnchor(anchor); err != nil {
+			return fmt.Errorf("save anchor: %s", err)
+		}
+	}
+	return nil
+}
+
+// Anchor returns the time set by Start or SetAnchor.
+func (t *Ticker) Anchor() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.anchor
+}
+
+// RestoreAnchor loads the anchor from the anchor store, if set.
+func (t *Ticker) RestoreAnchor() error {
+	if t.AnchorStore == nil {
+		return nil
+	}
+
+	anchor, err := t.AnchorStore.LoadAnchor()
+	if err != nil {
+		return fmt.Errorf("load anchor: %s", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.anchor = anchor
+	return nil
+}
+
+// AddCommand adds cmd to the ticker. Its handlers for the current step
+// execute on the next tick. Returns ErrCommandExists if a command already
+// has the same name or ErrInvalidInterval if it has no interval.
+func (t *Ticker) AddCommand(cmd Command) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cmd.Interval <= 0 {
+		return ErrInvalidInterval
+	} else if t.hasCommand(cmd.Name) {
+		return ErrCommandExists
+	}
+
+	// Copy the lists so ticks in progress are unaffected.
+	states := t.commandStates()
+	t.Commands = append(t.Commands[:len(t.Commands):len(t.Commands)], cmd)
+	t.states = append(states[:len(states):len(states)], &commandState{})
+	t.added(cmd.Name)
+	return nil
+}
+
+// RemoveCommand removes the named command from the ticker. Handlers that are
+// already executing are allowed to complete.
+// Returns ErrCommandNotFound if no command has the name.
+func (t *Ticker) RemoveCommand(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.hasCommand(name) {
+		return ErrCommandNotFound
+	}
+
+	var commands []Command
+	var states []*commandState
+	for i, state := range t.commandStates() {
+		if t.Commands[i].Name != name {
+			commands, states = append(commands, t.Commands[i]), append(states, state)
+		}
+	}
+	t.Commands, t.states = commands, states
+	t.removed(name)
+	return nil
+}
+
+// ReplaceCommands replaces every command in the ticker, such as when the
+// configuration is reloaded. The handlers for the current step of each
+// command execute on the next tick. Commands with the same name as a
+// replaced command keep its state, such as whether it is disabled.
+func (t *Ticker) ReplaceCommands(a []Command) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Reuse the state of existing commands with the same name.
+	prev := make(map[string]*commandState, len(t.Commands))
+	for i, state := range t.commandStates() {
+		prev[t.Commands[i].Name] = state
+	}
+
+	t.Commands, t.states = make([]Command, len(a)), make([]*commandState, len(a))
+	copy(t.Commands, a)
+	for i, cmd := range a {
+		if t.states[i] = prev[cmd.Name]; t.states[i] == nil {
+			t.states[i] = &commandState{}
+		}
+		delete(prev, cmd.Name)
+		t.added(cmd.Name)
+	}
+	for name := range prev {
+		t.removed(name)
+	}
+}
+
+// added marks the named command to execute on the next tick. Must be called
+// with the lock held.
+func (t *Ticker) added(name string) {
+	if t.enabled == nil {
+		t.enabled = make(map[string]struct{})
+	}
+	t.enabled[name] = struct{}{}
+	t.signal()
+}
+
+// removed clears the state of the named command that is tracked by name. The
+// disabled state is kept in case the command is added again. Must be called
+// with the lock held.
+func (t *Ticker) removed(name string) {
+	delete(t.enabled, name)
+	delete(t.errors, name)
+	delete(t.failures, name)
+	delete(t.quarantined, name)
+}
+
+// command returns the command with the given name, or nil if none exists.
+// Must be called with the lock held.
+func (t *Ticker) command(name string) *Command {
+	for i := range t.Commands {
+		if t.Commands[i].Name == name {
+			return &t.Commands[i]
+		}
+	}
+	return nil
+}
+
+// hasCommand returns true if a command exists with the given name.
+func (t *Ticker) hasCommand(name string) bool {
+	return t.command(name) != nil
+}
+
+// Skip stops executing handlers for the remainder of each command's current
+// interval. Handlers resume when the next interval begins.
+func (t *Ticker) Skip() {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.skipped = now
+	t.signal()
+}
+
+// Run ticks until ctx is cancelled. Instead of polling, Run sleeps until the
+// next step of any command begins so handlers execute promptly at the step
+// boundary. Sleeps are limited to MaxSleep so changes to the wall clock, such
+// as waking from sleep, are noticed. Pause, Resume & Skip interrupt the sleep.
+//
+// Cancellation is only checked between ticks so in-flight handlers finish or
+// time out before Run returns.
+func (t *Ticker) Run(ctx context.Context) error {
+	return Run(ctx, t, RunOptions{Align: true})
+}
+
+// RunOptions represents the options for ticking a ticker with Run.
+type RunOptions struct {
+	// If true, the ticker wakes at the next step boundary of any command.
+	// Otherwise the ticker wakes every MaxSleep.
+	Align bool
+
+	// If set, each wakeup is delayed by a random duration up to Jitter so
+	// several tickers don't execute at the same instant.
+	Jitter time.Duration
+}
+
+// Run ticks t until ctx is cancelled, sleeping between ticks as set by opts.
+// Pause, Resume & Skip interrupt the sleep. Handlers that panic are recovered
+// and logged as failed so a single handler cannot stop the ticker.
+//
+// Cancellation is only checked between ticks so in-flight handlers finish or
+// time out before Run returns.
+func Run(ctx context.Context, t *Ticker, opts RunOptions) error {
+	t.mu.Lock()
+	if t.wakeup == nil {
+		t.wakeup = make(chan struct{}, 1)
+	}
+	wakeup := t.wakeup
+	t.mu.Unlock()
+
+	for {
+		t.Tick()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		now := t.now()
+		next := now.Add(t.maxSleep())
+		if opts.Align {
+			next = t.next(now)
+		}
+		if opts.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(opts.Jitter))))
+		}
+
+		timer := t.Clock.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-wakeup:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// now returns the current time in the ticker's location.
+func (t *Ticker) now() time.Time {
+	now := t.Clock.Now()
+	if t.Location != nil {
+		now = now.In(t.Location)
+	}
+	return now
+}
+
+// maxSleep returns MaxSleep, or DefaultMaxSleep if unset.
+func (t *Ticker) maxSleep() time.Duration {
+	if t.MaxSleep <= 0 {
+		return DefaultMaxSleep
+	}
+	return t.MaxSleep
+}
+
+// next returns the time of the next tick after now. This is the earliest
+// step boundary or warning of any command, limited by MaxSleep. If a schedule or active
+// hours are set then ticks also occur every minute to detect their boundaries.
+func (t *Ticker) next(now time.Time) time.Time {
+	next := now.Add(t.maxSleep())
+
+	var rule *ScheduleRule
+	if len(t.Schedule) > 0 {
+		rule = t.Schedule.Rule(now)
+	}
+	if len(t.Schedule) > 0 || t.ActiveHours != nil {
+		if edge := now.Truncate(time.Minute).Add(time.Minute); edge.Before(next) {
+			next = edge
+		}
+	}
+
+	t.mu.Lock()
+	commands, anchor := t.Commands, t.anchor
+	t.mu.Unlock()
+
+	for _, cmd := range commands {
+		cmd = resolve(cmd, rule, anchor)
+		step := cmd.step()
+		if edge := cmd.boundary(now, step); edge.Before(next) {
+			next = edge
+		}
+		if cmd.warns() {
+			if edge := cmd.truncate(now.Add(cmd.Warning), cmd.Interval).Add(cmd.Interval - cmd.Warning); edge.Before(next) {
+				next = edge
+			}
+		}
+		if cmd.ProgressHandler != nil && cmd.ProgressInterval > 0 {
+			if edge := now.Add(cmd.ProgressInterval); edge.Before(next) {
+				next = edge
+			}
+		}
+	}
+	return next
+}
+
+// signal interrupts the sleep of a running ticker. Must be called with the
+// lock held.
+func (t *Ticker) signal() {
+	select {
+	case t.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// track records each command's interval to the journal once it completes.
+// New intervals are only tracked while the ticker is active.
+func (t *Ticker) track(now time.Time, rule *ScheduleRule, anchor time.Time, active, paused bool, skipped time.Time) {
+	if t.Journal == nil {
+		return
+	}
+
+	var completed []*IntervalRecord
+	t.mu.Lock()
+	states := t.commandStates()
+	for index, cmd := range t.Commands {
+		state := states[index]
+		cmd = resolve(cmd, rule, anchor)
+		start, _, _, _ := t.position(&cmd, anchor, now)
+
+		// Complete the previous interval once a new one begins.
+		r := state.interval
+		if r != nil {
+			r.Skipped = r.Skipped || r.contains(skipped)
+			if t.label != "" {
+				r.Label = t.label
+			}
+			if !r.Start.Equal(start) {
+				completed = append(completed, r)
+				r = nil
+				state.interval = nil
+			}
+		}
+
+		// Begin tracking a new interval.
+		if r == nil {
+			if !active {
+				continue
+			}
+			r = &IntervalRecord{Command: cmd.Name, Start: start, End: start.Add(cmd.Interval)}
+			state.interval = r
+		}
+
+		r.Paused = r.Paused || paused
+		if t.label != "" {
+			r.Label = t.label
+		}
+		r.Skipped = r.Skipped || r.contains(skipped)
+	}
+	t.mu.Unlock()
+
+	for _, r := range completed {
+		if err := t.Journal.Record(r); err != nil {
+			t.log(&LogEntry{Time: now, Command: r.Command, Err: fmt.Errorf("journal: %s", err)})
+		}
+	}
+}
+
+// active returns the schedule rule active at now, if any, and true if now is
+// within the active hours & schedule.
+func (t *Ticker) active(now time.Time) (*ScheduleRule, bool) {
+	var rule *ScheduleRule
+	if len(t.Schedule) > 0 {
+		rule = t.Schedule.Rule(now)
+	}
+	return rule, (len(t.Schedule) == 0 || rule != nil) && (t.ActiveHours == nil || t.ActiveHours.Contains(now))
+}
+
+// Plan returns the intervals each command is scheduled to run between from
+// and to, ordered by command & then by start time. Intervals are clipped to
+// the active hours & schedule, which are checked every minute, and a new
+// interval begins whenever the active schedule rule changes.
+func (t *Ticker) Plan(from, to time.Time) []*IntervalRecord {
+	t.mu.Lock()
+	commands, anchor := t.Commands, t.anchor
+	t.mu.Unlock()
+
+	var a []*IntervalRecord
+	for _, cmd := range commands {
+		for now := from; now.Before(to); {
+			// Move to the next minute if the ticker is inactive.
+			rule, active := t.active(now)
+			if !active {
+				now = now.Truncate(time.Minute).Add(time.Minute)
+				continue
+			}
+
+			// Find the end of the interval or the first minute within it
+			// where the schedule changes.
+			c := resolve(cmd, rule, anchor)
+			end := c.boundary(now, c.Interval)
+			if end.After(to) {
+				end = to
+			}
+			for m := now.Truncate(time.Minute).Add(time.Minute); m.Before(end); m = m.Add(time.Minute) {
+				if r, ok := t.active(m); !ok || r != rule {
+					end = m
+					break
+				}
+			}
+
+			a = append(a, &IntervalRecord{Command: c.Name, Start: now, End: end})
+			now = end
+		}
+	}
+	return a
+}
+
+// missed returns the number of whole intervals missed by each command
+// between prev and now.
+func missed(commands []Command, prev, now time.Time, rule *ScheduleRule, anchor time.Time) map[string]int {
+	m := make(map[string]int)
+	for _, cmd := range commands {
+		if cmd = resolve(cmd, rule, anchor); cmd.Interval <= 0 {
+			continue
+		}
+		if n := int(cmd.truncate(now, cmd.Interval).Sub(cmd.truncate(prev, cmd.Interval))/cmd.Interval) - 1; n > 0 {
+			m[cmd.Name] = n
+		}
+	}
+	return m
+}
+
+// suppressed returns true if any suppressor in a is active. Errors are
+// logged under name and the failing suppressor is treated as inactive.
+func (t *Ticker) suppressed(now time.Time, name string, a []Suppressor) bool {
+	for _, s := range a {
+		if v, err := s.Suppress(now); err != nil {
+			t.log(&LogEntry{Time: now, Command: name, Err: fmt.Errorf("suppressor: %s", err)})
+		} else if v {
+			return true
+		}
+	}
+	return false
+}
+
+// begin marks the command with state as running.
+// Returns false if it is already running.
+func (t *Ticker) begin(state *commandState) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state.running {
+		return false
+	}
+	state.running = true
+	return true
+}
+
+// end marks the command with state as no longer running.
+func (t *Ticker) end(state *commandState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state.running = false
+}
+
+// commandState tracks a command across ticks.
+type commandState struct {
+	running  bool            // true while handlers are in-flight
+	interval *IntervalRecord // current interval tracked for the journal
+}
+
+// commandStates returns the state of each command, aligned with Commands.
+// The states are reset if Commands was set directly. Must be called with the
+// lock held.
+func (t *Ticker) commandStates() []*commandState {
+	if len(t.states) != len(t.Commands) {
+		t.states = make([]*commandState, len(t.Commands))
+		for i := range t.states {
+			t.states[i] = &commandState{}
+		}
+	}
+	return t.states
+}
+
+// alignStart anchors commands that are aligned to the start to now. The list
+// is copied so ticks in progress are unaffected. Must be called with the lock
+// held.
+func (t *Ticker) alignStart(now time.Time) {
+	var commands []Command
+	for i, cmd := range t.Commands {
+		if !cmd.AlignStart || !cmd.Anchor.IsZero() {
+			continue
+		} else if commands == nil {
+			commands = append([]Command(nil), t.Commands...)
+		}
+		commands[i].Anchor = now
+	}
+	if commands != nil {
+		t.Commands = commands
+	}
+}
+
+// labelFirst returns the indexes of commands with the commands that set the
+// label ahead of the others.
+func labelFirst(commands []Command) []int {
+	a := make([]int, 0, len(commands))
+	for i := range commands {
+		if commands[i].SetsLabel {
+			a = append(a, i)
+		}
+	}
+	for i := range commands {
+		if !commands[i].SetsLabel {
+			a = append(a, i)
+		}
+	}
+	return a
+}
+
+// exec executes one of cmd's handlers through the ticker & command middleware
+// and reports the result to the logger & subscribers.
+func (t *Ticker) exec(now time.Time, cmd *Command, inv *Invocation) {
+	// Intervals held open in overtime are past their end.
+	intervalStart, _, _ := cmd.position(now)
+	intervalIndex := cmd.intervalIndex(now)
+	if inv.Overtime > 0 {
+		intervalStart, intervalIndex = cmd.Anchor, cmd.intervalIndex(cmd.Anchor)
+	}
+	remaining := intervalStart.Add(cmd.Interval).Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	label := t.Label()
+
+	fn := func(inv *Invocation) error {
+		ctx := &Context{
+			Step:          inv.Step,
+			Total:         inv.Steps,
+			IntervalStart: intervalStart,
+			IntervalIndex: intervalIndex,
+			Remaining:     remaining,
+			Overtime:      inv.Overtime,
+			Now:           now,
+			Label:         label,
+		}
+		switch {
+		case inv.Interval:
+			return cmd.IntervalHandler(ctx)
+		case inv.Warning:
+			return cmd.WarningHandler(remaining)
+		case inv.Progress:
+			return cmd.ProgressHandler(math.Min(1, float64(now.Sub(intervalStart))/float64(cmd.Interval)))
+		}
+		return cmd.Handler(ctx)
+	}
+	// Retry failures until the end of the current step.
+	if cmd.Retry.Attempts > 0 {
+		step := cmd.step()
+		fn = cmd.Retry.middleware(cmd.boundary(now, step))(fn)
+	}
+	fn = Chain(t.Middleware...)(Chain(cmd.Middleware...)(fn))
+
+	switch {
+	case inv.Interval:
+		t.emit(&IntervalStartedEvent{Time: now, Command: inv.Command, Steps: inv.Steps})
+	case inv.Warning:
+		t.emit(&WarningEvent{Time: now, Command: inv.Command, Remaining: remaining})
+	case inv.Progress:
+		// Progress is reported every tick so no event is emitted.
+	default:
+		t.emit(&StepStartedEvent{Time: now, Command: inv.Command, Step: inv.Step, Steps: inv.Steps, Overtime: inv.Overtime})
+	}
+
+	start := time.Now()
+	err := t.call(fn, inv)
+
+	e := &LogEntry{
+		Time:     now,
+		Command:  inv.Command,
+		Step:     inv.Step,
+		Steps:    inv.Steps,
+		Interval: inv.Interval,
+		Warning:  inv.Warning,
+		Progress: inv.Progress,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+
+	// Progress runs on every tick so its results are not counted as failures
+	// or successes. Otherwise it would reset the count of a failing step.
+	if inv.Progress {
+		t.log(e)
+	} else {
+		t.logResult(e)
+	}
+	if err != nil {
+		t.emit(&HandlerFailedEvent{Time: now, Command: inv.Command, Step: inv.Step, Steps: inv.Steps, Interval: inv.Interval, Warning: inv.Warning, Err: err})
+	}
+}
+
+// call executes fn & returns a panic in fn as an error so a failing handler
+// cannot stop the ticker.
+func (t *Ticker) call(fn HandlerFunc, inv *Invocation) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			t.debugf("%s: %s\n%s", inv.Command, err, debug.Stack())
+		}
+	}()
+	return fn(inv)
+}
+
+// debugf writes to the debug logger, if set.
+func (t *Ticker) debugf(format string, v ...interface{}) {
+	if t.Debug != nil {
+		t.Debug.Printf(format, v...)
+	}
+}
+
+// log tracks the entry's error and writes the entry to the logger. Errors
+// that are not handler results, such as suppressor errors or skips while the
+// previous execution is running, are not counted as failures of the command.
+func (t *Ticker) log(e *LogEntry) {
+	t.mu.Lock()
+	t.trackError(e)
+	t.mu.Unlock()
+
+	t.Logger.Log(e)
+}
+
+// logResult logs the result of a handler execution. The command is
+// quarantined or disabled if it has failed too many times.
+func (t *Ticker) logResult(e *LogEntry) {
+	t.mu.Lock()
+	t.trackError(e)
+	f := t.fail(e.Time, e.Command, e.Err)
+	t.mu.Unlock()
+
+	t.Logger.Log(e)
+
+	switch {
+	case f.disabled:
+		t.debugf("%s: disabled after %d failure(s)", e.Command, f.n)
+		t.emit(&CommandDisabledEvent{Time: e.Time, Command: e.Command, Failures: f.n, Err: e.Err})
+	case f.quarantined:
+		t.debugf("%s: quarantined after %d failure(s) until %s", e.Command, f.n, f.until.Format("15:04:05.999"))
+		t.quarantine(&CommandQuarantinedEvent{Time: e.Time, Command: e.Command, Failures: f.n, Until: f.until, Err: e.Err})
+	}
+}
+
+// trackError records the entry's error so it can be reported by Status().
+// Must be called with the lock held.
+func (t *Ticker) trackError(e *LogEntry) {
+	if e.Err == nil {
+		return
+	}
+	if t.errors == nil {
+		t.errors = make(map[string]*stepError)
+	}
+	t.errors[e.Command] = &stepError{time: e.Time, err: e.Err}
+}
+
+// quarantine emits e & passes it to the quarantine handlers. Handler errors
+// are logged against the command.
+func (t *Ticker) quarantine(e *CommandQuarantinedEvent) {
+	t.emit(e)
+	for _, h := range t.QuarantineHandlers {
+		if err := h(e); err != nil {
+			t.Logger.Log(&LogEntry{Time: e.Time, Command: e.Command, Err: fmt.Errorf("quarantine handler: %s", err)})
+		}
+	}
+}
+
+// failure represents the result of counting a command's failure.
+type failure struct {
+	n           int       // consecutive failures
+	disabled    bool      // true if the command was disabled
+	quarantined bool      // true if the command was newly quarantined
+	until       time.Time // end of the quarantine
+}
+
+// fail counts the consecutive failures of the named command. The command is
+// disabled if err is fatal or the count reaches MaxFailures, otherwise it is
+// quarantined once the count reaches the failures of its breaker. A nil err
+// resets the count & closes the breaker. Must be called with the lock held.
+func (t *Ticker) fail(now time.Time, name string, err error) failure {
+	if err == nil {
+		if _, ok := t.quarantined[name]; ok {
+			t.debugf("%s: recovered from quarantine", name)
+		}
+		delete(t.failures, name)
+		delete(t.quarantined, name)
+		return failure{}
+	}
+
+	if t.failures == nil {
+		t.failures = make(map[string]int)
+	}
+	t.failures[name]++
+	f := failure{n: t.failures[name]}
+
+	cmd := t.command(name)
+	if cmd == nil || t.disabled[name] {
+		return f
+	}
+
+	// Disable commands that cannot succeed or have failed too many times.
+	if IsFatal(err) || (t.MaxFailures > 0 && f.n >= t.MaxFailures) {
+		delete(t.failures, name)
+		delete(t.quarantined, name)
+		if t.disabled == nil {
+			t.disabled = make(map[string]bool)
+		}
+		t.disabled[name] = true
+		delete(t.enabled, name)
+		f.disabled = true
+		return f
+	}
+
+	// Quarantine the command, or extend the quarantine after a failed retry.
+	if cmd.Breaker.Failures > 0 && f.n >= cmd.Breaker.Failures {
+		_, ok := t.quarantined[name]
+		if t.quarantined == nil {
+			t.quarantined = make(map[string]time.Time)
+		}
+		f.until = now.Add(cmd.Breaker.interval())
+		t.quarantined[name] = f.until
+		f.quarantined = !ok
+	}
+	return f
+}
+
+// Status returns the position of each command as of the last tick.
+func (t *Ticker) Status() *Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &Status{Time: t.prev, Active: !t.idle, Paused: t.paused, Label: t.label}
+	if t.snoozed.After(t.now()) {
+		snoozed := t.snoozed
+		s.SnoozedUntil = &snoozed
+	}
+	if !t.anchor.IsZero() {
+		anchor := t.anchor
+		s.Anchor = &anchor
+	}
+	for _, cmd := range t.Commands {
+		cmd = resolve(cmd, t.rule, t.anchor)
+		start, i, n, overtime := t.position(&cmd, t.anchor, t.prev)
+		cs := CommandStatus{
+			Name:          cmd.Name,
+			IntervalStart: start,
+			Elapsed:       t.prev.Sub(start).Seconds(),
+			Remaining:     math.Max(0, start.Add(cmd.Interval).Sub(t.prev).Seconds()),
+			Step:          i,
+			Steps:         n,
+			Overtime:      overtime,
+			Disabled:      t.disabled[cmd.Name],
+		}
+		if until, ok := t.quarantined[cmd.Name]; ok {
+			cs.QuarantinedUntil = &until
+		}
+		if e := t.errors[cmd.Name]; e != nil {
+			cs.LastError = e.err.Error()
+			cs.LastErrorTime = &e.time
+		}
+		s.Commands = append(s.Commands, cs)
+	}
+	return s
+}
+
+// stepError records an error returned by a handler.
+type stepError struct {
+	time time.Time
+	err  error
+}
+
+// Status represents a snapshot of the ticker's progress.
+type Status struct {
+	Time         time.Time       `json:"time"`
+	Active       bool            `json:"active"`
+	Paused       bool            `json:"paused"`
+	Label        string          `json:"label,omitempty"`
+	SnoozedUntil *time.Time      `json:"snoozed_until,omitempty"`
+	Anchor       *time.Time      `json:"anchor,omitempty"`
+	Commands     []CommandStatus `json:"commands"`
+}
+
+// CommandStatus represents the progress of a single command.
+type CommandStatus struct {
+	Name          string     `json:"name"`
+	IntervalStart time.Time  `json:"interval_start"`
+	Elapsed       float64    `json:"elapsed"`
+	Remaining     float64    `json:"remaining"`
+	Step          int        `json:"step"`
+	Steps         int        `json:"steps"`
+	Overtime      int        `json:"overtime,omitempty"`
+	Disabled      bool       `json:"disabled,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+
+	QuarantinedUntil *time.Time `json:"quarantined_until,omitempty"`
 }
 
 // Command represents an action that is executed every step or interval.
@@ -83,25 +1223,479 @@ type Command struct {
 
 	// The function to execute when a step is made in the interval.
 	Handler Handler
+
+	// The function to execute when a new interval begins.
+	IntervalHandler IntervalHandler
+
+	// The function to execute on every tick with the progress through the
+	// interval. While set, ticks occur at least every ProgressInterval.
+	ProgressHandler  ProgressHandler
+	ProgressInterval time.Duration
+
+	// The function to execute once the time remaining in the interval
+	// drops to Warning. Ignored if Warning is not less than the interval.
+	WarningHandler WarningHandler
+	Warning        time.Duration
+
+	// The maximum time to wait for the command's handlers to complete.
+	// If zero, the ticker waits indefinitely.
+	Timeout time.Duration
+
+	// Middleware applied to the command's handlers.
+	Middleware []Middleware
+
+	// The policy for retrying failed handlers. Retries only occur until the
+	// end of the current step.
+	Retry RetryPolicy
+
+	// The policy for quarantining the command after consecutive failures.
+	Breaker BreakerPolicy
+
+	// If true, the command is skipped while a suppressor is active.
+	// This is used for commands that interrupt the user, such as
+	// flashing the menu bar.
+	Intrusive bool
+
+	// Suppressors that skip the command while active, in addition to the
+	// ticker's suppressors if the command is intrusive.
+	Suppressors []Suppressor
+
+	// If true, the command's handlers set the label. They complete before
+	// the other commands execute on the same tick so those see the new
+	// label, such as time entries described by the current issue.
+	SetsLabel bool
+
+	// How steps missed between ticks are handled, such as when the process
+	// is stopped or the system is under heavy load.
+	CatchUp CatchUpPolicy
+
+	// If set, steps & intervals are aligned to the anchor instead of the
+	// wall clock, such as to the time boxer started.
+	Anchor time.Time
+
+	// If true & Anchor is not set, the anchor is set to the ticker's time on
+	// the first tick so steps & intervals are aligned to when it started.
+	AlignStart bool
+
+	// If non-zero, steps & intervals are aligned to this time of day instead
+	// of the wall clock. They realign every day so the last interval before
+	// the time of day may be cut short. Ignored if Anchor is set.
+	Offset time.Duration
+}
+
+// CatchUpPolicy represents how a command handles multiple step boundaries
+// passing between ticks.
+type CatchUpPolicy int
+
+const (
+	// CatchUpLatest executes only the latest step.
+	CatchUpLatest CatchUpPolicy = iota
+
+	// CatchUpAll executes every missed step of the current interval in order.
+	CatchUpAll
+
+	// CatchUpSkip executes nothing until the next step boundary.
+	CatchUpSkip
+)
+
+// ParseCatchUpPolicy parses the name of a catch-up policy.
+func ParseCatchUpPolicy(s string) (CatchUpPolicy, error) {
+	switch s {
+	case "", "latest":
+		return CatchUpLatest, nil
+	case "all":
+		return CatchUpAll, nil
+	case "skip":
+		return CatchUpSkip, nil
+	default:
+		return 0, fmt.Errorf("invalid catch-up policy: %q", s)
+	}
+}
+
+// OvertimePolicy represents how an interval begun by Ticker.Start ends.
+type OvertimePolicy int
+
+const (
+	// OvertimeNone begins the next interval at the boundary.
+	OvertimeNone OvertimePolicy = iota
+
+	// OvertimeHold holds the interval open past its end until Start is
+	// called again. Steps continue on the last step of the interval with
+	// the number of steps past its end as the overtime.
+	OvertimeHold
+)
+
+// ParseOvertimePolicy parses the name of an overtime policy.
+func ParseOvertimePolicy(s string) (OvertimePolicy, error) {
+	switch s {
+	case "", "none":
+		return OvertimeNone, nil
+	case "hold":
+		return OvertimeHold, nil
+	default:
+		return 0, fmt.Errorf("invalid overtime policy: %q", s)
+	}
+}
+
+// position returns the position of cmd at now like Command.position. If the
+// overtime policy holds the interval begun at anchor open past its end, the
+// interval's start & last step are returned along with the number of steps
+// begun since its end.
+func (t *Ticker) position(cmd *Command, anchor, now time.Time) (start time.Time, i, n, overtime int) {
+	start, i, n = cmd.position(now)
+	if t.Overtime != OvertimeHold || anchor.IsZero() || cmd.Interval <= 0 {
+		return start, i, n, 0
+	}
+
+	end := cmd.Anchor.Add(cmd.Interval)
+	if now.Before(end) {
+		return start, i, n, 0
+	}
+	return cmd.Anchor, n - 1, n, int(now.Sub(end)/cmd.step()) + 1
+}
+
+// step returns the step duration. Defaults to the interval if there is no step.
+func (c *Command) step() time.Duration {
+	if c.Step == 0 {
+		return c.Interval
+	}
+	return c.Step
+}
+
+// resolve returns a copy of cmd with the overrides of the schedule rule, if
+// any, and aligned to anchor, if set.
+func resolve(cmd Command, rule *ScheduleRule, anchor time.Time) Command {
+	if rule != nil {
+		cmd = rule.apply(cmd)
+	}
+	if !anchor.IsZero() {
+		cmd.Anchor = anchor
+	}
+	return cmd
 }
 
-// StepHandler is called whenever a new step occurs.
-// It is passed the current step index and the total number of steps per interval.
-type Handler func(i, n int) error
+// origin returns the time that steps & intervals containing t are aligned to.
+// Returns a zero time if the command is aligned to the wall clock.
+func (c *Command) origin(t time.Time) time.Time {
+	if !c.Anchor.IsZero() {
+		return c.Anchor
+	} else if c.Offset == 0 {
+		return time.Time{}
+	}
+
+	// Use the previous day's time of day if today's has not yet occurred.
+	// The offset is applied to the wall clock so a DST transition earlier
+	// in the day doesn't move it.
+	y, m, d := t.Date()
+	sec, nsec := int(c.Offset/time.Second), int(c.Offset%time.Second)
+	o := time.Date(y, m, d, 0, 0, sec, nsec, t.Location())
+	if o.After(t) {
+		o = time.Date(y, m, d-1, 0, 0, sec, nsec, t.Location())
+	}
+	return o
+}
+
+// truncate returns t rounded down to a multiple of d since the command's
+// origin, or on the wall clock of t's location if there is no origin. Zero
+// times are returned unchanged.
+func (c *Command) truncate(t time.Time, d time.Duration) time.Time {
+	if t.IsZero() || d <= 0 {
+		return t.Truncate(d)
+	}
+
+	// Use the UTC offset at the start of t's day so wall clock boundaries
+	// don't move during a DST transition.
+	o := c.origin(t)
+	if o.IsZero() {
+		y, m, day := t.Date()
+		_, sec := time.Date(y, m, day, 0, 0, 0, 0, t.Location()).Zone()
+		off := time.Duration(sec) * time.Second
+		return t.Add(off).Truncate(d).Add(-off)
+	}
+
+	// Round down for times before the origin.
+	n := t.Sub(o) / d
+	if t.Sub(o)%d < 0 {
+		n--
+	}
+	return o.Add(n * d)
+}
+
+// position returns the start of the interval containing now as well as
+// the current step index and the total number of steps per interval.
+func (c *Command) position(now time.Time) (start time.Time, i, n int) {
+	step, interval := c.step(), c.Interval
+	start = c.truncate(now, interval)
+	if step == 0 {
+		return start, 0, 1
+	}
+	return start, int(c.truncate(now, step).Sub(start) / step), int(interval / step)
+}
+
+// boundary returns the start of the step or interval of duration d after now.
+// Daily alignments begin a new step at the time of day.
+func (c *Command) boundary(now time.Time, d time.Duration) time.Time {
+	edge := c.truncate(now, d).Add(d)
+	if c.Anchor.IsZero() && c.Offset != 0 {
+		if o := c.origin(now).AddDate(0, 0, 1); o.Before(edge) {
+			edge = o
+		}
+	}
+	return edge
+}
+
+// missedSteps returns the number of step boundaries between prev & now,
+// excluding the latest. Returns zero if prev or the step is not set.
+func (c *Command) missedSteps(prev, now time.Time) int {
+	step := c.step()
+	if prev.IsZero() || step == 0 {
+		return 0
+	}
+	if n := int(c.truncate(now, step).Sub(c.truncate(prev, step))/step) - 1; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// intervalIndex returns the index within now's day of the command's interval
+// containing now. Intervals are counted from the one containing midnight.
+// Returns zero if the interval is not set.
+func (c *Command) intervalIndex(now time.Time) int {
+	if c.Interval <= 0 {
+		return 0
+	}
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	return int(c.truncate(now, c.Interval).Sub(c.truncate(midnight, c.Interval)) / c.Interval)
+}
+
+// intervalIndex returns the index within t's day of the interval containing t
+// when aligned to the wall clock.
+func intervalIndex(t time.Time, interval time.Duration) int {
+	return (&Command{Interval: interval}).intervalIndex(t)
+}
+
+// warns returns true if cmd has a warning handler that fits in its interval.
+func (c *Command) warns() bool {
+	return c.WarningHandler != nil && c.Warning > 0 && c.Warning < c.Interval
+}
+
+// warning returns true if now is within the warning period at the end of
+// the interval and the warning was not yet reached at prev. Warnings are
+// found by shifting time forward so the warning aligns with the boundary.
+func (c *Command) warning(prev, now time.Time) bool {
+	if !c.warns() {
+		return false
+	}
+	shifted := c.truncate(now.Add(c.Warning), c.Interval)
+	return shifted != c.truncate(now, c.Interval) && shifted != c.truncate(prev.Add(c.Warning), c.Interval)
+}
+
+// Handler is called whenever a new step occurs.
+// It is passed the position of the step within the interval & the day.
+type Handler func(ctx *Context) error
+
+// Context represents the position of a step passed to a Handler.
+type Context struct {
+	Step          int           // current step index
+	Total         int           // total steps per interval
+	IntervalStart time.Time     // start time of the current interval
+	IntervalIndex int           // index of the current interval within the day
+	Remaining     time.Duration // time remaining in the current interval
+	Overtime      int           // steps past the end of an interval held open, if any
+	Now           time.Time     // time of the tick that started the step
+	Label         string        // current label, if any
+}
+
+// IntervalHandler is called whenever a new interval begins.
+type IntervalHandler func(ctx *Context) error
+
+// ProgressHandler is called on every tick with pct percent through the
+// interval, from 0 to 1, so progress can be displayed smoothly between steps.
+type ProgressHandler func(pct float64) error
+
+// WarningHandler is called when the end of an interval is approaching.
+// It is passed the time remaining in the interval.
+type WarningHandler func(remaining time.Duration) error
+
+var (
+	// ErrHandlerTimeout is logged when a command's handlers do not complete
+	// within the command's timeout.
+	ErrHandlerTimeout = errors.New("handler timed out")
+
+	// ErrHandlerRunning is logged when a command is skipped because its
+	// previous execution has not completed.
+	ErrHandlerRunning = errors.New("handler still running, skipped")
+
+	// ErrCommandNotFound is returned when enabling, disabling, or removing
+	// a command that does not exist.
+	ErrCommandNotFound = errors.New("command not found")
+
+	// ErrCommandExists is returned when adding a command whose name is
+	// already in use.
+	ErrCommandExists = errors.New("command already exists")
+
+	// ErrInvalidInterval is returned when adding a command whose interval
+	// is not greater than zero.
+	ErrInvalidInterval = errors.New("interval must be greater than zero")
+
+	// ErrProfileNotFound is returned when switching to a profile that does
+	// not exist.
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+// FatalError marks a handler error as fatal. Fatal errors are not retried
+// and the command is disabled, rather than failing again on every step.
+type FatalError struct {
+	Err error
+}
+
+// Fatal returns err marked as fatal. Returns nil if err is nil.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FatalError{Err: err}
+}
+
+// Error returns the message of the underlying error.
+func (e *FatalError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *FatalError) Unwrap() error { return e.Err }
+
+// IsFatal returns true if err is, or wraps, a fatal error. Every other error
+// is transient & the handler is executed again on the next step.
+func IsFatal(err error) bool {
+	var e *FatalError
+	return errors.As(err, &e)
+}
+
+// Logger represents an object that reports handler executions.
+type Logger interface {
+	Log(e *LogEntry)
+}
+
+// LogEntry represents the result of a single handler execution.
+type LogEntry struct {
+	Time     time.Time     // tick time
+	Command  string        // command name
+	Step     int           // step index within the interval
+	Steps    int           // total steps per interval
+	Interval bool          // true if executed for an interval boundary
+	Warning  bool          // true if executed for an interval warning
+	Progress bool          // true if executed to report progress
+	Duration time.Duration // handler execution time
+	Err      error         // handler error, if any
+}
+
+// TextLogger is a logger that writes handler errors as plain text.
+// Successful executions are not logged.
+type TextLogger struct {
+	logger *log.Logger
+}
+
+// NewTextLogger returns a new instance of TextLogger that writes to l.
+func NewTextLogger(l *log.Logger) *TextLogger {
+	return &TextLogger{logger: l}
+}
+
+// Log writes the entry to the underlying logger if it contains an error.
+func (l *TextLogger) Log(e *LogEntry) {
+	if e.Err != nil {
+		l.logger.Printf("%s: %s", e.Command, e.Err.Error())
+	}
+}
+
+// JSONLogger is a logger that writes every handler execution as a
+// newline-delimited JSON object.
+type JSONLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLogger returns a new instance of JSONLogger that writes to w.
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{w: w}
+}
+
+// Log encodes the entry to the underlying writer.
+func (l *JSONLogger) Log(e *LogEntry) {
+	var errstr string
+	if e.Err != nil {
+		errstr = e.Err.Error()
+	}
+
+	b, err := json.Marshal(&jsonLogEntry{
+		Time:     e.Time.Format(time.RFC3339Nano),
+		Command:  e.Command,
+		Step:     e.Step,
+		Steps:    e.Steps,
+		Interval: e.Interval,
+		Warning:  e.Warning,
+		Progress: e.Progress,
+		Duration: e.Duration.Seconds(),
+		Error:    errstr,
+	})
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(append(b, '\n'))
+}
+
+// jsonLogEntry is the JSON representation of a LogEntry.
+type jsonLogEntry struct {
+	Time     string  `json:"time"`
+	Command  string  `json:"command"`
+	Step     int     `json:"step"`
+	Steps    int     `json:"steps"`
+	Interval bool    `json:"interval"`
+	Warning  bool    `json:"warning,omitempty"`
+	Progress bool    `json:"progress,omitempty"`
+	Duration float64 `json:"duration"`
+	Error    string  `json:"error,omitempty"`
+}
 
 // CommandExecutor is the signature for wrapping os/exec execution.
 type CommandExecutor func(name string, args []string, stdin io.Reader) ([]byte, error)
 
 // DefaultCommandExecutor is the default implementation of CommandExecutor.
+// Commands are executed by DefaultExecutor.
 func DefaultCommandExecutor(name string, args []string, stdin io.Reader) ([]byte, error) {
-	cmd := exec.Command(name, args...)
-	cmd.Stdin = stdin
-	return cmd.CombinedOutput()
+	return DefaultExecutor.Exec(&ExecRequest{Name: name, Args: args, Stdin: stdin})
 }
 
-// ParseColor parses a hex color.
+// ParseColor parses a color. The color can be specified as a 3, 4, 6, or 8
+// digit hex value with an optional "#" prefix, where the last 1 or 2 digits
+// of the longer forms are the alpha, as "rgb(r, g, b)" or "rgba(r, g, b, a)"
+// where a is between 0 and 1, or as a CSS color name such as "tomato".
+// Translucent colors are alpha-premultiplied.
 func ParseColor(s string) (color.RGBA, error) {
-	m := regexp.MustCompile(`^#?([0-9a-fA-F]{2})([0-9a-fA-F]{2})([0-9a-fA-F]{2})$`).FindStringSubmatch(s)
+	str := strings.ToLower(strings.TrimSpace(s))
+
+	// Look up named colors first.
+	if c, ok := colornames.Map[str]; ok {
+		return c, nil
+	}
+
+	// Parse "rgb()" & "rgba()" syntax.
+	if m := colorFuncRegex.FindStringSubmatch(str); m != nil {
+		return parseColorFunc(s, m[1], strings.Split(m[2], ","))
+	}
+
+	// Otherwise parse as hex, expanding the 3 & 4 digit shorthands.
+	str = strings.TrimPrefix(str, "#")
+	if len(str) == 3 || len(str) == 4 {
+		var buf []byte
+		for i := 0; i < len(str); i++ {
+			buf = append(buf, str[i], str[i])
+		}
+		str = string(buf)
+	}
+	m := colorHexRegex.FindStringSubmatch(str)
 	if m == nil {
 		return color.RGBA{}, fmt.Errorf("cannot parse color: %q", s)
 	}
@@ -109,18 +1703,73 @@ func ParseColor(s string) (color.RGBA, error) {
 	r, _ := strconv.ParseUint(m[1], 16, 8)
 	g, _ := strconv.ParseUint(m[2], 16, 8)
 	b, _ := strconv.ParseUint(m[3], 16, 8)
-	return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF}, nil
+	a := uint64(0xFF)
+	if m[4] != "" {
+		a, _ = strconv.ParseUint(m[4], 16, 8)
+	}
+	return color.RGBAModel.Convert(color.NRGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}).(color.RGBA), nil
+}
+
+// FormatColor returns c as a "#rrggbb" hex value, or "#rrggbbaa" if c is
+// translucent. This is the inverse of ParseColor.
+func FormatColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xFF {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
 }
 
-// TransposeColor returns a color that is pct percent between a and b.
+var (
+	colorHexRegex  = regexp.MustCompile(`^([0-9a-f]{2})([0-9a-f]{2})([0-9a-f]{2})([0-9a-f]{2})?$`)
+	colorFuncRegex = regexp.MustCompile(`^(rgba?)\((.*)\)$`)
+)
+
+// parseColorFunc parses the arguments of an "rgb()" or "rgba()" color.
+// The returned color is alpha-premultiplied.
+func parseColorFunc(s, fn string, args []string) (color.RGBA, error) {
+	if (fn == "rgb" && len(args) != 3) || (fn == "rgba" && len(args) != 4) {
+		return color.RGBA{}, fmt.Errorf("cannot parse color: %q", s)
+	}
+
+	// Parse the red, green & blue components.
+	var rgb [3]float64
+	for i := range rgb {
+		v, err := strconv.ParseUint(strings.TrimSpace(args[i]), 10, 8)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("cannot parse color: %q", s)
+		}
+		rgb[i] = float64(v)
+	}
+
+	// Parse the alpha component, if specified.
+	a := 1.0
+	if fn == "rgba" {
+		v, err := strconv.ParseFloat(strings.TrimSpace(args[3]), 64)
+		if err != nil || v < 0 || v > 1 {
+			return color.RGBA{}, fmt.Errorf("cannot parse color: %q", s)
+		}
+		a = v
+	}
+
+	return color.RGBA{
+		R: uint8(math.Round(rgb[0] * a)),
+		G: uint8(math.Round(rgb[1] * a)),
+		B: uint8(math.Round(rgb[2] * a)),
+		A: uint8(math.Round(a * math.MaxUint8)),
+	}, nil
+}
+
+// TransposeColor returns a color that is pct percent between a and b,
+// including their alpha. The returned color is alpha-premultiplied.
 func TransposeColor(a, b color.Color, pct float64) color.Color {
 	ar, ag, ab, aa := a.RGBA()
 	br, bg, bb, ba := b.RGBA()
 	return color.RGBA{
-		R: transposeUint8(uint8(ar), uint8(br), pct),
-		G: transposeUint8(uint8(ag), uint8(bg), pct),
-		B: transposeUint8(uint8(ab), uint8(bb), pct),
-		A: transposeUint8(uint8(aa), uint8(ba), pct),
+		R: transposeUint8(uint8(ar>>8), uint8(br>>8), pct),
+		G: transposeUint8(uint8(ag>>8), uint8(bg>>8), pct),
+		B: transposeUint8(uint8(ab>>8), uint8(bb>>8), pct),
+		A: transposeUint8(uint8(aa>>8), uint8(ba>>8), pct),
 	}
 }
 
@@ -136,6 +1785,153 @@ func transposeUint8(a, b uint8, pct float64) uint8 {
 	}
 }
 
+// TransposeColorHSL returns a color that is pct percent between a and b.
+// Unlike TransposeColor, the colors are interpolated by hue, saturation &
+// lightness which avoids the muddy midpoints of RGB interpolation. Hue is
+// rotated in the shortest direction around the color wheel.
+func TransposeColorHSL(a, b color.Color, pct float64) color.Color {
+	ah, as, al, aa := rgbToHSL(a)
+	bh, bs, bl, ba := rgbToHSL(b)
+
+	// Grays have no hue so use the hue of the other color.
+	if as == 0 {
+		ah = bh
+	} else if bs == 0 {
+		bh = ah
+	}
+
+	// Rotate hue the short way around the wheel.
+	dh := bh - ah
+	if dh > 180 {
+		dh -= 360
+	} else if dh < -180 {
+		dh += 360
+	}
+	h := math.Mod(ah+(dh*pct)+360, 360)
+
+	cr, cg, cb := hslToRGB(h, as+((bs-as)*pct), al+((bl-al)*pct))
+	return color.RGBAModel.Convert(color.NRGBA{R: cr, G: cg, B: cb, A: transposeUint8(aa, ba, pct)})
+}
+
+// rgbToHSL converts c to hue (0-360), saturation (0-1), lightness (0-1) & alpha.
+// Translucent colors are converted without their alpha premultiplied.
+func rgbToHSL(c color.Color) (h, s, l float64, a uint8) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	r, g, b := float64(n.R)/0xFF, float64(n.G)/0xFF, float64(n.B)/0xFF
+
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (hi + lo) / 2
+	if d := hi - lo; d != 0 {
+		if l > 0.5 {
+			s = d / (2 - hi - lo)
+		} else {
+			s = d / (hi + lo)
+		}
+
+		switch hi {
+		case r:
+			h = math.Mod((g-b)/d+6, 6)
+		case g:
+			h = (b-r)/d + 2
+		default:
+			h = (r-g)/d + 4
+		}
+		h *= 60
+	}
+	return h, s, l, n.A
+}
+
+// hslToRGB converts hue (0-360), saturation (0-1) & lightness (0-1) to RGB.
+func hslToRGB(h, s, l float64) (r, g, b uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var fr, fg, fb float64
+	switch {
+	case h < 60:
+		fr, fg, fb = c, x, 0
+	case h < 120:
+		fr, fg, fb = x, c, 0
+	case h < 180:
+		fr, fg, fb = 0, c, x
+	case h < 240:
+		fr, fg, fb = 0, x, c
+	case h < 300:
+		fr, fg, fb = x, 0, c
+	default:
+		fr, fg, fb = c, 0, x
+	}
+	return uint8(math.Round((fr + m) * 0xFF)), uint8(math.Round((fg + m) * 0xFF)), uint8(math.Round((fb + m) * 0xFF))
+}
+
+// ColorSpace represents the space that colors are interpolated in.
+type ColorSpace int
+
+const (
+	RGBColorSpace ColorSpace = iota
+	HSLColorSpace
+)
+
+// Transpose returns a color that is pct percent between a and b in the color space.
+func (s ColorSpace) Transpose(a, b color.Color, pct float64) color.Color {
+	if s == HSLColorSpace {
+		return TransposeColorHSL(a, b, pct)
+	}
+	return TransposeColor(a, b, pct)
+}
+
+// ParseColorSpace parses the name of a color space.
+func ParseColorSpace(s string) (ColorSpace, error) {
+	switch s {
+	case "", "rgb":
+		return RGBColorSpace, nil
+	case "hsl":
+		return HSLColorSpace, nil
+	default:
+		return 0, fmt.Errorf("invalid color space: %q", s)
+	}
+}
+
+// Suppressor represents a condition under which intrusive commands should
+// not execute, such as while a meeting is in progress.
+type Suppressor interface {
+	Suppress(now time.Time) (bool, error)
+}
+
+// SuppressorFunc is a function that implements Suppressor.
+type SuppressorFunc func(now time.Time) (bool, error)
+
+// Suppress calls fn.
+func (fn SuppressorFunc) Suppress(now time.Time) (bool, error) { return fn(now) }
+
+// FocusDetector represents an object that can detect whether the user has
+// enabled a Focus or Do Not Disturb mode.
+type FocusDetector interface {
+	Focused() (bool, error)
+}
+
+// NewFocusSuppressor returns a suppressor that is active while d detects focus.
+func NewFocusSuppressor(d FocusDetector) Suppressor {
+	return SuppressorFunc(func(now time.Time) (bool, error) {
+		return d.Focused()
+	})
+}
+
+// PresentationDetector represents an object that can detect whether the user
+// is presenting, such as by mirroring the screen or sharing it in a call.
+type PresentationDetector interface {
+	Presenting() (bool, error)
+}
+
+// NewPresentationSuppressor returns a suppressor that is active while d
+// detects a presentation.
+func NewPresentationSuppressor(d PresentationDetector) Suppressor {
+	return SuppressorFunc(func(now time.Time) (bool, error) {
+		return d.Presenting()
+	})
+}
+
 // NowFunc is a function that returns the current time.
 type NowFunc func() time.Time
 
@@ -1,62 +1,567 @@
 package boxer
 
 import (
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
+	_ "image/jpeg"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/url"
 	"os"
+	"os/user"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 )
 
-// OSAScriptPath is the path to the "osascript" binary.
-const OSAScriptPath = `/usr/bin/osascript`
+// Wallpaper returns the path of the current desktop background.
+func Wallpaper(exec CommandExecutor) (string, error) {
+	b, err := exec(OSAScriptPath, nil, strings.NewReader(strings.TrimSpace(getWallpaperScript)))
+	if err != nil {
+		return "", fmt.Errorf("exec: %s", b)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
 
-// NewWallpaperHandler returns a handler for visualizing steps with the desktop wallpaper.
-func NewWallpaperHandler(exec CommandExecutor, sizer DesktopSizer, generator WallpaperGenerator, path string) Handler {
-	return func(i, n int) error {
-		// Retrieve desktop size.
-		w, h, err := sizer(exec)
-		if err != nil {
-			return fmt.Errorf("desktop size: %s", err)
-		}
+const getWallpaperScript = `
+tell application "Finder"
+  get POSIX path of (get desktop picture as alias)
+end tell
+`
 
-		// Generate wallpaper if it doesn't exist.
-		// The wallpaper is saved to a common location format so we can tell if
-		// the desktop size changes and recompute a wallpaper on the fly.
-		imgpath := filepath.Join(path, fmt.Sprintf("wallpaper_%04d_%04d_%02d_%02d.png", w, h, i, n))
-		if _, err := os.Stat(imgpath); os.IsNotExist(err) {
-			if err := generator(imgpath, w, h, float64(i)/float64(n)); err != nil {
-				return fmt.Errorf("generate wallpaper: %s", err)
-			}
-		}
+// WallpaperSetter sets the desktop background to the image at path.
+type WallpaperSetter func(exec CommandExecutor, path string) error
 
-		// Execute AppleScript to update the current background.
-		src := fmt.Sprintf(strings.TrimSpace(setWallpaperScript), imgpath)
-		if b, err := exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
-			return fmt.Errorf("exec: %s", b)
-		}
+// ParseWallpaperSetter returns the setter for a wallpaper backend. The
+// backends are "osascript", the default, "native", and "spaces".
+func ParseWallpaperSetter(backend string) (WallpaperSetter, error) {
+	switch backend {
+	case "", "osascript":
+		return SetWallpaper, nil
+	case "native":
+		return SetWallpaperNative, nil
+	case "spaces":
+		return SetWallpaperSpaces, nil
+	default:
+		return nil, fmt.Errorf("invalid wallpaper backend: %q", backend)
+	}
+}
+
+// SetWallpaperNative sets the desktop background with NSWorkspace, which is
+// faster than spawning osascript and does not activate the Finder. If boxer
+// was built without the native backend or it fails then SetWallpaper is used.
+func SetWallpaperNative(exec CommandExecutor, path string) error {
+	if err := nativeSetWallpaper(path); err == nil {
 		return nil
 	}
+	return SetWallpaper(exec, path)
 }
 
-const setWallpaperScript = `
+// SetWallpaper sets the desktop background to the image at path.
+func SetWallpaper(exec CommandExecutor, path string) error {
+	path, err := ScriptPath(path)
+	if err != nil {
+		return err
+	}
+	src, err := renderScript(setWallpaperScript, path)
+	if err != nil {
+		return err
+	}
+	if b, err := exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
+		return fmt.Errorf("exec: %s", b)
+	}
+	return nil
+}
+
+var setWallpaperScript = newScriptTemplate("set wallpaper", `
 tell application "Finder"
-  set desktop picture to POSIX file "%s"
+  set desktop picture to POSIX file {{str .}}
 end tell
-`
+`)
 
-// WallpaperGenerator generates a wallpaper at the given path.
-type WallpaperGenerator func(path string, w, h int, pct float64) error
+// SetWallpaperSpaces sets the background of every desktop with System Events.
+// Unlike SetWallpaper, which only changes the current Space, this updates
+// every display & Space so switching Spaces doesn't hide the progress.
+func SetWallpaperSpaces(exec CommandExecutor, path string) error {
+	path, err := ScriptPath(path)
+	if err != nil {
+		return err
+	}
+	src, err := renderScript(setWallpaperSpacesScript, path)
+	if err != nil {
+		return err
+	}
+	if b, err := exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
+		return fmt.Errorf("exec: %s", b)
+	}
+	return nil
+}
+
+var setWallpaperSpacesScript = newScriptTemplate("set wallpaper spaces", `
+tell application "System Events"
+  repeat with d in every desktop
+    set picture of d to {{str .}}
+  end repeat
+end tell
+`)
 
 // GenerateWallpaper generates a PNG wallpaper with a given size and color.
 // The wallpaper will draw the foreground covering pct percent of the image.
-func NewWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA) (WallpaperGenerator, error) {
+func NewWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, enc *ImageEncoder) (WallpaperGenerator, error) {
+	return NewBarWallpaperGenerator(now, times, foregrounds, backgrounds, space, WallpaperBar{}, enc)
+}
+
+// NewBarWallpaperGenerator returns a generator that draws the foreground
+// covering pct percent of a bar on the background. Bars with a thickness
+// draw the remainder of the bar with a dimmed track so the strip is visible.
+func NewBarWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, bar WallpaperBar, enc *ImageEncoder) (WallpaperGenerator, error) {
+	if err := bar.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc, func(m *image.RGBA, fg, bg color.Color, scale, pct float64) {
+		w, h := m.Bounds().Dx(), m.Bounds().Dy()
+		draw.Draw(m, m.Bounds(), &image.Uniform{bg}, image.ZP, draw.Over)
+
+		bar := bar.scaled(scale)
+		r, fill := bar.rects(w, h, pct)
+		if bar.Thickness > 0 {
+			draw.Draw(m, r, &image.Uniform{space.Transpose(bg, fg, 0.2)}, image.ZP, draw.Over)
+		}
+		draw.Draw(m, fill, &image.Uniform{fg}, image.ZP, draw.Over)
+	})
+}
+
+// NewGradientWallpaperGenerator returns a generator that covers pct percent
+// of the image from the top with a vertical gradient. The gradient fades from
+// the background color at the top edge to the foreground color at the
+// leading edge of the progress.
+func NewGradientWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, enc *ImageEncoder) (WallpaperGenerator, error) {
+	return newWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc, func(m *image.RGBA, fg, bg color.Color, scale, pct float64) {
+		w, h := m.Bounds().Dx(), m.Bounds().Dy()
+		draw.Draw(m, m.Bounds(), &image.Uniform{bg}, image.ZP, draw.Over)
+
+		fill := int(float64(h) * pct)
+		for y := 0; y < fill; y++ {
+			c := space.Transpose(bg, fg, float64(y+1)/float64(fill))
+			draw.Draw(m, image.Rect(0, y, w, y+1), &image.Uniform{c}, image.ZP, draw.Over)
+		}
+	})
+}
+
+// NewRingWallpaperGenerator returns a generator that draws a ring in the
+// center of the image. The foreground color covers pct percent of the ring
+// clockwise from the top and the remainder of the ring is a dimmed track.
+func NewRingWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, enc *ImageEncoder) (WallpaperGenerator, error) {
+	return newWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc, func(m *image.RGBA, fg, bg color.Color, scale, pct float64) {
+		w, h := m.Bounds().Dx(), m.Bounds().Dy()
+		draw.Draw(m, m.Bounds(), &image.Uniform{bg}, image.ZP, draw.Over)
+
+		// Size the ring relative to the smallest dimension.
+		outer := float64(w) * 0.3
+		if h < w {
+			outer = float64(h) * 0.3
+		}
+		drawRing(m, outer, outer*0.8, fg, space.Transpose(bg, fg, 0.2), pct)
+	})
+}
+
+// drawRing draws a ring centered on m between the inner & outer radius.
+// The foreground color covers pct percent of the ring clockwise from the top
+// and the remainder is drawn with the track color.
+func drawRing(m *image.RGBA, outer, inner float64, fg, track color.Color, pct float64) {
+	cx, cy := float64(m.Bounds().Dx())/2, float64(m.Bounds().Dy())/2
+	for y := int(cy - outer); y <= int(cy+outer); y++ {
+		for x := int(cx - outer); x <= int(cx+outer); x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if d := math.Hypot(dx, dy); d < inner || d > outer {
+				continue
+			}
+
+			// Determine the clockwise angle from 12 o'clock as a percentage.
+			angle := math.Atan2(dx, -dy) / (2 * math.Pi)
+			if angle < 0 {
+				angle += 1
+			}
+
+			if angle < pct {
+				m.Set(x, y, fg)
+			} else {
+				m.Set(x, y, track)
+			}
+		}
+	}
+}
+
+// NewSegmentedWallpaperGenerator returns a generator that draws a horizontal
+// bar across the center of the image split into n cells. Cells are filled
+// with the foreground color as progress is made.
+func NewSegmentedWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, n int, enc *ImageEncoder) (WallpaperGenerator, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("segment count must be greater than zero")
+	}
+
+	return newWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc, func(m *image.RGBA, fg, bg color.Color, scale, pct float64) {
+		w, h := m.Bounds().Dx(), m.Bounds().Dy()
+		draw.Draw(m, m.Bounds(), &image.Uniform{bg}, image.ZP, draw.Over)
+
+		// Center a bar with a margin on each side and a small gap between cells.
+		margin, gap := w/10, w/200
+		if gap < 1 {
+			gap = 1
+		}
+		barW, barH := w-(2*margin), h/8
+		top := (h - barH) / 2
+		track := space.Transpose(bg, fg, 0.2)
+
+		filled := int(math.Round(pct * float64(n)))
+		for i := 0; i < n; i++ {
+			x0 := margin + (i * barW / n)
+			x1 := margin + ((i + 1) * barW / n) - gap
+
+			c := track
+			if i < filled {
+				c = fg
+			}
+			draw.Draw(m, image.Rect(x0, top, x1, top+barH), &image.Uniform{c}, image.ZP, draw.Over)
+		}
+	})
+}
+
+// WallpaperGrid represents the layout of the cells in a grid wallpaper. If
+// the rows or columns are zero then they are chosen to fit the cells in a
+// roughly square grid.
+type WallpaperGrid struct {
+	Rows    int
+	Columns int
+	Gap     int // space between cells, in points
+	Radius  int // corner radius of each cell, in points
+}
+
+// layout returns the rows & columns used to fit n cells.
+func (g *WallpaperGrid) layout(n int) (rows, cols int) {
+	rows, cols = g.Rows, g.Columns
+	switch {
+	case rows <= 0 && cols <= 0:
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+		rows = (n + cols - 1) / cols
+	case rows <= 0:
+		rows = (n + cols - 1) / cols
+	case cols <= 0:
+		cols = (n + rows - 1) / rows
+	}
+	return rows, cols
+}
+
+// NewGridWallpaperGenerator returns a generator that draws n square cells,
+// one per step, in a grid centered on the image. Cells are filled with the
+// foreground color in reading order as progress is made.
+func NewGridWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, n int, grid WallpaperGrid, enc *ImageEncoder) (WallpaperGenerator, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cell count must be greater than zero")
+	} else if grid.Rows < 0 || grid.Columns < 0 || grid.Gap < 0 || grid.Radius < 0 {
+		return nil, fmt.Errorf("grid settings must not be negative")
+	}
+	rows, cols := grid.layout(n)
+	if rows*cols < n {
+		return nil, fmt.Errorf("grid of %dx%d cannot fit %d cells", cols, rows, n)
+	}
+
+	return newWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc, func(m *image.RGBA, fg, bg color.Color, scale, pct float64) {
+		w, h := m.Bounds().Dx(), m.Bounds().Dy()
+		draw.Draw(m, m.Bounds(), &image.Uniform{bg}, image.ZP, draw.Over)
+		gap, radius := scaleInt(grid.Gap, scale), scaleInt(grid.Radius, scale)
+
+		// Fit square cells within the center 80% of the image.
+		size := (w*8/10 - (cols-1)*gap) / cols
+		if v := (h*8/10 - (rows-1)*gap) / rows; v < size {
+			size = v
+		}
+		if size < 1 {
+			size = 1
+		}
+		left := (w - (cols*size + (cols-1)*gap)) / 2
+		top := (h - (rows*size + (rows-1)*gap)) / 2
+		track := space.Transpose(bg, fg, 0.2)
+
+		filled := int(math.Round(pct * float64(n)))
+		for i := 0; i < n; i++ {
+			x := left + (i%cols)*(size+gap)
+			y := top + (i/cols)*(size+gap)
+
+			c := track
+			if i < filled {
+				c = fg
+			}
+			fillRoundedRect(m, image.Rect(x, y, x+size, y+size), radius, c)
+		}
+	})
+}
+
+// fillRoundedRect fills r on m with c. Pixels outside of the corner radius
+// are left unchanged.
+func fillRoundedRect(m *image.RGBA, r image.Rectangle, radius int, c color.Color) {
+	if limit := r.Dx() / 2; radius > limit {
+		radius = limit
+	}
+	if limit := r.Dy() / 2; radius > limit {
+		radius = limit
+	}
+	if radius <= 0 {
+		draw.Draw(m, r, &image.Uniform{c}, image.ZP, draw.Over)
+		return
+	}
+
+	rad := float64(radius)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Find the distance from the nearest corner's center, if in a corner.
+			cx, cy := float64(x)+0.5, float64(y)+0.5
+			if left, right := float64(r.Min.X)+rad, float64(r.Max.X)-rad; cx < left {
+				cx -= left
+			} else if cx > right {
+				cx -= right
+			} else {
+				cx = 0
+			}
+			if top, bottom := float64(r.Min.Y)+rad, float64(r.Max.Y)-rad; cy < top {
+				cy -= top
+			} else if cy > bottom {
+				cy -= bottom
+			} else {
+				cy = 0
+			}
+
+			if math.Hypot(cx, cy) <= rad {
+				m.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// BarPlacement represents the edge of the image that a progress bar is drawn along.
+type BarPlacement string
+
+// Progress bar placements.
+const (
+	BarTop    BarPlacement = "top"
+	BarBottom BarPlacement = "bottom"
+	BarLeft   BarPlacement = "left"
+)
+
+// ParseBarPlacement parses a bar placement. Defaults to BarBottom if blank.
+func ParseBarPlacement(s string) (BarPlacement, error) {
+	switch p := BarPlacement(s); p {
+	case "":
+		return BarBottom, nil
+	case BarTop, BarBottom, BarLeft:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid bar placement: %q", s)
+	}
+}
+
+// FillDirection represents the direction that progress fills a bar.
+type FillDirection string
+
+// Fill directions.
+const (
+	FillDown  FillDirection = "down"
+	FillUp    FillDirection = "up"
+	FillLeft  FillDirection = "left"
+	FillRight FillDirection = "right"
+)
+
+// ParseFillDirection parses a fill direction. A blank direction is returned
+// as-is so the bar can choose a direction that matches its placement.
+func ParseFillDirection(s string) (FillDirection, error) {
+	switch d := FillDirection(s); d {
+	case "", FillDown, FillUp, FillLeft, FillRight:
+		return d, nil
+	default:
+		return "", fmt.Errorf("invalid fill direction: %q", s)
+	}
+}
+
+// WallpaperBar represents the size & position of the progress bar drawn on
+// a wallpaper. A bar without a thickness covers the whole image, less the
+// margin. Otherwise it is a strip along the placement edge.
+type WallpaperBar struct {
+	Placement BarPlacement
+	Fill      FillDirection
+	Thickness int // in points
+	Margin    int // space between the bar & the image edges, in points
+}
+
+// Validate returns an error if the fill direction does not run along a strip.
+func (b *WallpaperBar) Validate() error {
+	if b.Thickness < 0 {
+		return fmt.Errorf("bar thickness must not be negative")
+	} else if b.Margin < 0 {
+		return fmt.Errorf("bar margin must not be negative")
+	} else if b.Thickness == 0 {
+		return nil
+	}
+
+	vertical := b.Fill == FillUp || b.Fill == FillDown
+	if b.Placement == BarLeft && (b.Fill == FillLeft || b.Fill == FillRight) {
+		return fmt.Errorf("fill direction %q does not run along a bar on the left edge", b.Fill)
+	} else if b.Placement != BarLeft && vertical {
+		return fmt.Errorf("fill direction %q does not run along a bar on the %s edge", b.Fill, b.placement())
+	}
+	return nil
+}
+
+// placement returns the placement, defaulting to the bottom edge.
+func (b *WallpaperBar) placement() BarPlacement {
+	if b.Placement == "" {
+		return BarBottom
+	}
+	return b.Placement
+}
+
+// direction returns the fill direction. Defaults to filling down for full
+// image bars & left edge strips and filling right for top & bottom strips.
+func (b *WallpaperBar) direction() FillDirection {
+	if b.Fill != "" {
+		return b.Fill
+	} else if b.Thickness > 0 && b.placement() != BarLeft {
+		return FillRight
+	}
+	return FillDown
+}
+
+// scaled returns a copy of the bar with its thickness & margin in pixels.
+func (b WallpaperBar) scaled(scale float64) WallpaperBar {
+	b.Thickness, b.Margin = scaleInt(b.Thickness, scale), scaleInt(b.Margin, scale)
+	return b
+}
+
+// rects returns the bounds of the bar & the portion filled to pct within a
+// w x h image.
+func (b *WallpaperBar) rects(w, h int, pct float64) (bar, fill image.Rectangle) {
+	bar = image.Rect(b.Margin, b.Margin, w-b.Margin, h-b.Margin)
+	if b.Thickness > 0 {
+		switch b.placement() {
+		case BarTop:
+			bar.Max.Y = bar.Min.Y + b.Thickness
+		case BarLeft:
+			bar.Max.X = bar.Min.X + b.Thickness
+		default:
+			bar.Min.Y = bar.Max.Y - b.Thickness
+		}
+	}
+
+	fill = bar
+	switch b.direction() {
+	case FillUp:
+		fill.Min.Y = fill.Max.Y - int(float64(bar.Dy())*pct)
+	case FillLeft:
+		fill.Min.X = fill.Max.X - int(float64(bar.Dx())*pct)
+	case FillRight:
+		fill.Max.X = fill.Min.X + int(float64(bar.Dx())*pct)
+	default:
+		fill.Max.Y = fill.Min.Y + int(float64(bar.Dy())*pct)
+	}
+	return bar, fill
+}
+
+// WallpaperImage represents a photo used as the wallpaper background and the
+// opacity of the progress bar drawn over it.
+type WallpaperImage struct {
+	Image   image.Image
+	Opacity float64 // bar opacity, from 0 to 1
+}
+
+// ReadImage reads a PNG or JPEG image from path.
+func ReadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	m, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %s", err)
+	}
+	return m, nil
+}
+
+// NewImageWallpaperGenerator returns a generator that scales & crops a photo
+// to cover the image and draws a progress bar over it. The foreground color
+// covers pct percent of the bar and the remainder is drawn with the
+// background color. If the bar has no thickness then a thin strip is drawn.
+func NewImageWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, bar WallpaperBar, img WallpaperImage, enc *ImageEncoder) (WallpaperGenerator, error) {
+	if img.Image == nil {
+		return nil, fmt.Errorf("background image required")
+	} else if img.Opacity < 0 || img.Opacity > 1 {
+		return nil, fmt.Errorf("bar opacity must be between 0 and 1")
+	} else if err := bar.Validate(); err != nil {
+		return nil, err
+	}
+	mask := &image.Uniform{color.Alpha{A: uint8(math.Round(img.Opacity * 255))}}
+
+	return newWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc, func(m *image.RGBA, fg, bg color.Color, scale, pct float64) {
+		w, h := m.Bounds().Dx(), m.Bounds().Dy()
+		xdraw.CatmullRom.Scale(m, m.Bounds(), img.Image, coverRect(img.Image.Bounds(), w, h), draw.Src, nil)
+
+		// Size the bar relative to the smallest dimension so the photo is visible.
+		b := bar.scaled(scale)
+		if b.Thickness == 0 {
+			if b.Thickness = w / 40; h < w {
+				b.Thickness = h / 40
+			}
+			if b.Thickness < 1 {
+				b.Thickness = 1
+			}
+		}
+
+		r, fill := b.rects(w, h, pct)
+		draw.DrawMask(m, r, &image.Uniform{bg}, image.ZP, mask, image.ZP, draw.Over)
+		draw.DrawMask(m, fill, &image.Uniform{fg}, image.ZP, mask, image.ZP, draw.Over)
+	})
+}
+
+// scaleInt converts v from points to pixels.
+func scaleInt(v int, scale float64) int {
+	return int(math.Round(float64(v) * scale))
+}
+
+// coverRect returns the centered portion of r with the aspect ratio of w x h
+// so the image covers the desktop without being stretched.
+func coverRect(r image.Rectangle, w, h int) image.Rectangle {
+	sw, sh := r.Dx(), r.Dy()
+	if sw*h > sh*w {
+		cw := sh * w / h
+		x := r.Min.X + (sw-cw)/2
+		return image.Rect(x, r.Min.Y, x+cw, r.Max.Y)
+	}
+	ch := sw * h / w
+	y := r.Min.Y + (sh-ch)/2
+	return image.Rect(r.Min.X, y, r.Max.X, y+ch)
+}
+
+// wallpaperDrawFunc draws the progress onto m using the current colors.
+// Sizes in points are multiplied by scale to convert them to pixels.
+type wallpaperDrawFunc func(m *image.RGBA, fg, bg color.Color, scale, pct float64)
+
+// newWallpaperGenerator validates the colors & times and returns a generator
+// that transposes the colors based on the time of day and draws with fn.
+// Images are written with enc, or as PNGs if enc is nil.
+func newWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrounds []color.RGBA, space ColorSpace, enc *ImageEncoder, fn wallpaperDrawFunc) (WallpaperGenerator, error) {
 	// Validate and normalize foreground colors.
 	if len(foregrounds) == 0 {
 		return nil, fmt.Errorf("foreground color required")
@@ -96,7 +601,7 @@ func NewWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrou
 	}
 
 	// Fill colors to match time slice size.
-	return func(path string, w, h int, pct float64) error {
+	return func(path string, w, h int, scale, pct float64) error {
 		// Retrieve the current time and determine transposition percent.
 		var transPct float64
 		if t := normalizeTime(now()); t.Before(times[0]) {
@@ -108,33 +613,209 @@ func NewWallpaperGenerator(now NowFunc, times []time.Time, foregrounds, backgrou
 		}
 
 		// Transpose colors.
-		fg := TransposeColor(foregrounds[0], foregrounds[1], transPct)
-		bg := TransposeColor(backgrounds[0], backgrounds[1], transPct)
+		fg := space.Transpose(foregrounds[0], foregrounds[1], transPct)
+		bg := space.Transpose(backgrounds[0], backgrounds[1], transPct)
 
 		// Ensure the parent directory exists.
 		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
 			return fmt.Errorf("mkdir: %s", err)
 		}
 
-		// Create image with the foreground color covering a percentage of the background.
-		m := image.NewRGBA(image.Rect(0, 0, w, h))
-		draw.Draw(m, m.Bounds(), &image.Uniform{bg}, image.ZP, draw.Over)
-		draw.Draw(m, image.Rect(0, 0, w, int(float64(h)*pct)), &image.Uniform{fg}, image.Point{X: 0, Y: int(float64(h) * (1.0 - pct))}, draw.Over)
+		// Draw the progress onto a new image at the pixel resolution.
+		if scale <= 0 {
+			scale = 1
+		}
+		m := image.NewRGBA(image.Rect(0, 0, scaleInt(w, scale), scaleInt(h, scale)))
+		fn(m, fg, bg, scale, pct)
+
+		// Encode to file.
+		return enc.WriteFile(path, m)
+	}, nil
+}
+
+// WallpaperProfile represents a wallpaper generator that is used during a
+// window of the day, such as a high-contrast palette in the morning.
+type WallpaperProfile struct {
+	Hours     TimeRange
+	Generator WallpaperGenerator
+}
+
+// NewProfileWallpaperGenerator returns a generator that delegates to the
+// first profile that is active at the current time. If no profile is active
+// then the default generator is used.
+func NewProfileWallpaperGenerator(now NowFunc, profiles []WallpaperProfile, def WallpaperGenerator) WallpaperGenerator {
+	return func(path string, w, h int, scale, pct float64) error {
+		t := now()
+		for i := range profiles {
+			if profiles[i].Hours.Contains(t) {
+				return profiles[i].Generator(path, w, h, scale, pct)
+			}
+		}
+		return def(path, w, h, scale, pct)
+	}
+}
+
+// DarkModeFunc returns true if the system is in dark mode.
+type DarkModeFunc func() (bool, error)
+
+// DarkMode returns true if the macOS appearance is set to dark.
+func DarkMode(exec CommandExecutor) (bool, error) {
+	b, err := exec(OSAScriptPath, nil, strings.NewReader(strings.TrimSpace(darkModeScript)))
+	if err != nil {
+		return false, fmt.Errorf("exec: %s", b)
+	}
+
+	switch v := strings.TrimSpace(string(b)); v {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected exec output: %s", v)
+	}
+}
+
+const darkModeScript = `
+tell application "System Events"
+  tell appearance preferences to get dark mode
+end tell
+`
+
+// NewDarkModeWallpaperGenerator returns a generator that delegates to dark
+// while darkMode reports dark mode and to light otherwise. The appearance is
+// checked every time an image is generated.
+func NewDarkModeWallpaperGenerator(darkMode DarkModeFunc, light, dark WallpaperGenerator) WallpaperGenerator {
+	return func(path string, w, h int, scale, pct float64) error {
+		v, err := darkMode()
+		if err != nil {
+			return fmt.Errorf("dark mode: %s", err)
+		} else if v {
+			return dark(path, w, h, scale, pct)
+		}
+		return light(path, w, h, scale, pct)
+	}
+}
+
+// NewRotatingWallpaperGenerator returns a generator that cycles through
+// generators, one per interval, starting with the first interval of each day.
+// This shows which interval of the day it is at a glance.
+func NewRotatingWallpaperGenerator(now NowFunc, interval time.Duration, generators []WallpaperGenerator) (WallpaperGenerator, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval required for rotation")
+	} else if len(generators) == 0 {
+		return nil, fmt.Errorf("generator required for rotation")
+	}
+
+	return func(path string, w, h int, scale, pct float64) error {
+		return generators[intervalIndex(now(), interval)%len(generators)](path, w, h, scale, pct)
+	}, nil
+}
+
+// NewDailyWallpaperGenerator returns a generator that delegates to the
+// generator created by fn for the current day. This allows colors to
+// transition between times that change daily, such as sunrise & sunset.
+func NewDailyWallpaperGenerator(now NowFunc, fn func(day time.Time) (WallpaperGenerator, error)) WallpaperGenerator {
+	var mu sync.Mutex
+	var day time.Time
+	var generator WallpaperGenerator
+
+	return func(path string, w, h int, scale, pct float64) error {
+		mu.Lock()
+		t := now()
+		y, m, d := t.Date()
+		if today := time.Date(y, m, d, 0, 0, 0, 0, t.Location()); generator == nil || !today.Equal(day) {
+			g, err := fn(today)
+			if err != nil {
+				mu.Unlock()
+				return err
+			}
+			day, generator = today, g
+		}
+		g := generator
+		mu.Unlock()
+
+		return g(path, w, h, scale, pct)
+	}
+}
+
+// NewCaptionWallpaperGenerator returns a generator that draws the text
+// returned by caption in the top right corner of the wallpapers generated by
+// generator. Nothing is drawn if the caption is blank. Cached wallpapers are
+// regenerated at the start of every interval so the caption should only
+// change between intervals, such as the progress toward a goal.
+func NewCaptionWallpaperGenerator(generator WallpaperGenerator, enc *ImageEncoder, caption func() (string, error)) WallpaperGenerator {
+	return func(path string, w, h int, scale, pct float64) error {
+		if err := generator(path, w, h, scale, pct); err != nil {
+			return err
+		}
+
+		text, err := caption()
+		if err != nil {
+			return fmt.Errorf("caption: %s", err)
+		} else if text == "" {
+			return nil
+		}
 
-		// Open output file.
-		f, err := os.Create(path)
+		src, err := ReadImage(path)
 		if err != nil {
 			return err
 		}
-		defer func() { _ = f.Close() }()
+		m := image.NewRGBA(src.Bounds())
+		draw.Draw(m, m.Bounds(), src, image.Point{}, draw.Src)
+		drawCaption(m, text)
+		return enc.WriteFile(path, m)
+	}
+}
 
-		// Encode to file.
-		if err := png.Encode(f, m); err != nil {
-			return fmt.Errorf("png encode: %s", err)
+// NewOverdueWallpaperGenerator returns a generator that draws the end of the
+// interval with generator and then blends the overdue color over it. It is
+// passed the overdue intensity as pct. The overdue color covers 35% of the
+// image at the lowest intensity & 80% at full intensity.
+func NewOverdueWallpaperGenerator(generator WallpaperGenerator, overdue color.Color, enc *ImageEncoder) WallpaperGenerator {
+	return func(path string, w, h int, scale, pct float64) error {
+		if err := generator(path, w, h, scale, 1); err != nil {
+			return err
 		}
 
-		return nil
-	}, nil
+		src, err := ReadImage(path)
+		if err != nil {
+			return err
+		}
+		m := image.NewRGBA(src.Bounds())
+		draw.Draw(m, m.Bounds(), src, image.Point{}, draw.Src)
+		blendRGBA(m, overdue, 0.2+0.6*pct)
+		return enc.WriteFile(path, m)
+	}
+}
+
+// blendRGBA blends c over every pixel of m with opacity pct.
+func blendRGBA(m *image.RGBA, c color.Color, pct float64) {
+	r, g, b, a := c.RGBA()
+	rgba := [4]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8), float64(a >> 8)}
+	for i := range m.Pix {
+		v := float64(m.Pix[i])
+		m.Pix[i] = uint8(v + (rgba[i%4]-v)*pct + 0.5)
+	}
+}
+
+// drawCaption draws white text with a drop shadow in the top right corner of
+// m. The basic bitmap font is scaled up with the height of the image.
+func drawCaption(m *image.RGBA, text string) {
+	face := basicfont.Face7x13
+	src := image.NewRGBA(image.Rect(0, 0, len(text)*face.Advance+1, face.Height+1))
+	shadow := &font.Drawer{Dst: src, Src: &image.Uniform{color.RGBA{0, 0, 0, 160}}, Face: face, Dot: fixed.P(1, face.Ascent+1)}
+	shadow.DrawString(text)
+	d := &font.Drawer{Dst: src, Src: image.White, Face: face, Dot: fixed.P(0, face.Ascent)}
+	d.DrawString(text)
+
+	scale := m.Bounds().Dy() / 480
+	if scale < 1 {
+		scale = 1
+	}
+	w, h := src.Bounds().Dx()*scale, src.Bounds().Dy()*scale
+	margin := 2 * face.Height * scale
+	r := image.Rect(m.Bounds().Max.X-margin-w, margin, m.Bounds().Max.X-margin, margin+h)
+	xdraw.NearestNeighbor.Scale(m, r, src, src.Bounds(), draw.Over, nil)
 }
 
 // normalizeTime removes the year, month, day components of a time.
@@ -142,9 +823,6 @@ func normalizeTime(t time.Time) time.Time {
 	return time.Date(0, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
 }
 
-// DesktopSizer returns the size of the desktop screen.
-type DesktopSizer func(exec CommandExecutor) (w, h int, err error)
-
 // DesktopSize returns the size of the desktop screen.
 func DesktopSize(exec CommandExecutor) (w, h int, err error) {
 	if b, err := exec(OSAScriptPath, nil, strings.NewReader(strings.TrimSpace(desktopSizeScript))); err != nil {
@@ -164,40 +842,777 @@ tell application "Finder"
 end tell
 `
 
-// NewMenuBarHandler returns a handler for flashing the menu bar.
-func NewMenuBarHandler(exec CommandExecutor) Handler {
-	return func(i, n int) error {
-		// Flash menu bar.
-		if b, err := exec(OSAScriptPath, nil, strings.NewReader(strings.TrimSpace(flashDarkModeScript))); err != nil {
-			return fmt.Errorf("exec flash: %s", b)
+// BackingScaleFactor returns the number of pixels per point on the main
+// screen, such as 2 on Retina displays. The desktop size is in points so
+// wallpapers are generated at this scale to stay sharp.
+func BackingScaleFactor(exec CommandExecutor) (float64, error) {
+	b, err := exec(OSAScriptPath, []string{"-l", "JavaScript"}, strings.NewReader(strings.TrimSpace(backingScaleFactorScript)))
+	if err != nil {
+		return 0, fmt.Errorf("exec: %s", b)
+	}
+
+	scale, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+	if err != nil || scale <= 0 {
+		return 0, fmt.Errorf("unexpected exec output: %s", b)
+	}
+	return scale, nil
+}
+
+const backingScaleFactorScript = `
+ObjC.import('AppKit');
+$.NSScreen.mainScreen.backingScaleFactor;
+`
+
+// SystemProfilerDesktopSize returns the size of the main display from
+// system_profiler. This works without Finder automation permission, such as
+// over SSH. The size is in points, like DesktopSize, so Retina resolutions
+// are halved unless the scaled "UI Looks like" size is listed.
+func SystemProfilerDesktopSize(exec CommandExecutor) (w, h int, err error) {
+	b, err := exec(SystemProfilerPath, []string{"SPDisplaysDataType"}, strings.NewReader(""))
+	if err != nil {
+		return 0, 0, fmt.Errorf("exec system_profiler: %s", b)
+	}
+
+	type display struct {
+		w, h int
+		main bool
+	}
+	var displays []display
+	for _, line := range strings.Split(string(b), "\n") {
+		m := displaySizeRegexp.FindStringSubmatch(line)
+		if m == nil || (m[1] != "Resolution" && len(displays) == 0) {
+			continue
 		}
-		return nil
+
+		// Each display's properties begin with its resolution.
+		if m[1] == "Resolution" {
+			displays = append(displays, display{})
+		}
+		d := &displays[len(displays)-1]
+
+		switch m[1] {
+		case "Main Display":
+			d.main = m[4] == "Yes"
+		default:
+			d.w, _ = strconv.Atoi(m[2])
+			d.h, _ = strconv.Atoi(m[3])
+			if m[1] == "Resolution" && strings.Contains(line, "Retina") {
+				d.w, d.h = d.w/2, d.h/2
+			}
+		}
+	}
+	if len(displays) == 0 {
+		return 0, 0, fmt.Errorf("no display resolution found")
 	}
+
+	d := displays[0]
+	for _, other := range displays {
+		if other.main {
+			d = other
+			break
+		}
+	}
+	return d.w, d.h, nil
+}
+
+var displaySizeRegexp = regexp.MustCompile(`^\s*(Resolution|UI Looks like|Main Display):\s*(?:(\d+) x (\d+)|(Yes|No))`)
+
+// NewMacDesktopSizer returns a sizer that asks the Finder for the desktop
+// size, then falls back to system_profiler, and then to the static size, if
+// w & h are set. Changes to the source used are written to logger, if set.
+func NewMacDesktopSizer(logger *log.Logger, w, h int) DesktopSizer {
+	sources := []DesktopSizeSource{
+		{Name: "finder", Sizer: DesktopSize},
+		{Name: "system_profiler", Sizer: SystemProfilerDesktopSize},
+	}
+	if w > 0 && h > 0 {
+		sources = append(sources, DesktopSizeSource{Name: "static", Sizer: StaticDesktopSizer(w, h)})
+	}
+	return NewFallbackDesktopSizer(logger, sources...)
 }
 
-// flashDarkModeScript flashes the menu bar on and off for 30 seconds.
+// MacDesktop is the macOS desktop, controlled with AppleScript. The desktop
+// flashes by toggling dark mode, which flashes the menu bar.
+type MacDesktop struct {
+	Exec CommandExecutor
+
+	// Returns the desktop size. Defaults to the Finder with a fallback to
+	// system_profiler.
+	Sizer DesktopSizer
+
+	// Returns the backing scale factor. Defaults to BackingScaleFactor.
+	Scaler func(exec CommandExecutor) (float64, error)
+
+	// Sets the wallpaper. Defaults to SetWallpaper.
+	Setter WallpaperSetter
+
+	// Displays notifications. Defaults to an OSAScriptNotifier.
+	Notifier Notifier
+}
+
+// SetWallpaper sets the desktop background to the image at path.
+func (d *MacDesktop) SetWallpaper(path string) error {
+	if d.Setter == nil {
+		return SetWallpaper(d.Exec, path)
+	}
+	return d.Setter(d.Exec, path)
+}
+
+// Size returns the size of the desktop screen.
+func (d *MacDesktop) Size() (w, h int, err error) {
+	if d.Sizer == nil {
+		return NewMacDesktopSizer(nil, 0, 0)(d.Exec)
+	}
+	return d.Sizer(d.Exec)
+}
+
+// Scale returns the backing scale factor of the main screen.
+func (d *MacDesktop) Scale() (float64, error) {
+	if d.Scaler == nil {
+		return BackingScaleFactor(d.Exec)
+	}
+	return d.Scaler(d.Exec)
+}
+
+// Notify displays n with the notifier.
+func (d *MacDesktop) Notify(n *Notification) error {
+	if d.Notifier == nil {
+		return (&OSAScriptNotifier{Exec: d.Exec}).Notify(n)
+	}
+	return d.Notifier.Notify(n)
+}
+
+// FlashAttention toggles dark mode on and off.
+func (d *MacDesktop) FlashAttention(n int, delay time.Duration) error {
+	src := fmt.Sprintf(strings.TrimSpace(flashDarkModeScript), n, formatSeconds(delay), formatSeconds(delay))
+	if b, err := d.Exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
+		return fmt.Errorf("exec flash: %s", b)
+	}
+	return nil
+}
+
+// formatSeconds formats d as a decimal number of seconds.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// flashDarkModeScript flashes the menu bar on and off.
 const flashDarkModeScript = `
 tell application "System Events"
   tell appearance preferences
-    repeat 30 times
+    repeat %d times
       set dark mode to true
-      delay 0.5
+      delay %s
       set dark mode to false
-      delay 0.5
+      delay %s
     end repeat
   end tell
 end tell
 `
 
-// NewAnnouncementHandler returns a handler for announcing the current time.
-func NewAnnouncementHandler(exec CommandExecutor) Handler {
-	return func(i, n int) error {
-		src := fmt.Sprintf(displayNotificationScript, time.Now().Format("3:04pm"))
+// DockIconSize is the width & height of generated dock icons.
+const DockIconSize = 256
+
+// NewDockIconHandler returns a handler that sets the icon of the file or
+// folder at path to the progress of the interval every step. When the folder
+// is kept in the Dock, this shows progress without changing the wallpaper.
+//
+// The icon is a ring filled by the progress along with the minutes remaining
+// in the interval. If step is zero then the step number is shown instead.
+// Icons are written to dir before being applied.
+func NewDockIconHandler(exec CommandExecutor, dir, path string, step time.Duration, fg, track color.Color) Handler {
+	return func(ctx *Context) error {
+		i, n := ctx.Step, ctx.Total
+		label := strconv.Itoa(i + 1)
+		if step > 0 {
+			label = strconv.Itoa(int(time.Duration(n-i) * step / time.Minute))
+		}
+
+		// Normalize paths before they are interpolated into the script.
+		icon, err := ScriptPath(filepath.Join(dir, "dock_icon.png"))
+		if err != nil {
+			return err
+		}
+		target, err := ScriptPath(path)
+		if err != nil {
+			return err
+		}
+
+		// Draw the icon & write it to the work directory.
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return fmt.Errorf("mkdir: %s", err)
+		} else if err := writePNG(icon, drawDockIcon(fg, track, float64(i)/float64(n), label)); err != nil {
+			return err
+		}
+
+		// Ensure the target exists so it can be kept in the Dock.
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			if err := os.MkdirAll(target, 0777); err != nil {
+				return fmt.Errorf("mkdir: %s", err)
+			}
+		}
+
+		src, err := renderScript(setIconScript, struct{ Icon, Path string }{icon, target})
+		if err != nil {
+			return err
+		}
+		if b, err := exec(OSAScriptPath, []string{"-l", "JavaScript"}, strings.NewReader(src)); err != nil {
+			return fmt.Errorf("exec set icon: %s", b)
+		}
+		return nil
+	}
+}
+
+var setIconScript = newScriptTemplate("set icon", `
+ObjC.import('AppKit');
+var image = $.NSImage.alloc.initWithContentsOfFile({{js .Icon}});
+$.NSWorkspace.sharedWorkspace.setIconForFileOptions(image, {{js .Path}}, 0);
+`)
+
+// drawDockIcon draws a progress ring with a label in its center on a
+// transparent background.
+func drawDockIcon(fg, track color.Color, pct float64, label string) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, DockIconSize, DockIconSize))
+	outer := float64(DockIconSize) * 0.45
+	drawRing(m, outer, outer*0.75, fg, track, pct)
+
+	// Draw the label with the basic bitmap font and scale it up to fill
+	// the center of the ring.
+	face := basicfont.Face7x13
+	text := image.NewRGBA(image.Rect(0, 0, len(label)*face.Advance, face.Height))
+	d := &font.Drawer{Dst: text, Src: &image.Uniform{fg}, Face: face, Dot: fixed.P(0, face.Ascent)}
+	d.DrawString(label)
+
+	scale := (outer * 0.9) / float64(text.Bounds().Dx())
+	if v := (outer * 0.9) / float64(text.Bounds().Dy()); v < scale {
+		scale = v
+	}
+	w, h := int(float64(text.Bounds().Dx())*scale), int(float64(text.Bounds().Dy())*scale)
+	r := image.Rect((DockIconSize-w)/2, (DockIconSize-h)/2, (DockIconSize+w)/2, (DockIconSize+h)/2)
+	xdraw.NearestNeighbor.Scale(m, r, text, text.Bounds(), draw.Over, nil)
+	return m
+}
+
+// OverlayStyle represents the shape drawn by the overlay handler.
+type OverlayStyle string
+
+// Overlay styles.
+const (
+	OverlayBar  OverlayStyle = "bar"
+	OverlayRing OverlayStyle = "ring"
+)
+
+// ParseOverlayStyle parses an overlay style. Defaults to OverlayBar if blank.
+func ParseOverlayStyle(s string) (OverlayStyle, error) {
+	switch style := OverlayStyle(s); style {
+	case "":
+		return OverlayBar, nil
+	case OverlayBar, OverlayRing:
+		return style, nil
+	default:
+		return "", fmt.Errorf("invalid overlay style: %q", s)
+	}
+}
+
+// NewOverlayHandler returns a handler that writes the progress of the
+// interval as a PNG with a transparent background to path every step. It is
+// meant to be used as an image source in streaming software, such as OBS,
+// that reloads the image when it changes. The image is written to a
+// temporary file & renamed so a partially written image is never read.
+func NewOverlayHandler(path string, style OverlayStyle, width, height int, fg, track color.Color) (Handler, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path required")
+	} else if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("overlay size must be greater than zero")
+	}
+
+	return func(ctx *Context) error {
+		m := drawOverlay(style, width, height, fg, track, float64(ctx.Step)/float64(ctx.Total))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return fmt.Errorf("mkdir: %s", err)
+		}
+		return writePNG(path, m)
+	}, nil
+}
+
+// drawOverlay draws a bar filled from the left or a ring filled clockwise
+// from the top on a transparent background.
+func drawOverlay(style OverlayStyle, width, height int, fg, track color.Color, pct float64) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	switch style {
+	case OverlayRing:
+		outer := float64(width) / 2
+		if h := float64(height) / 2; h < outer {
+			outer = h
+		}
+		drawRing(m, outer, outer*0.75, fg, track, pct)
+	default:
+		fillRoundedRect(m, m.Bounds(), height/2, track)
+		if w := int(math.Round(pct * float64(width))); w > 0 {
+			fillRoundedRect(m, image.Rect(0, 0, w, height), height/2, fg)
+		}
+	}
+	return m
+}
+
+// TerminalApp represents a terminal application whose background can be set.
+type TerminalApp string
+
+// Terminal applications.
+const (
+	TerminalAppITerm2   TerminalApp = "iterm2"
+	TerminalAppTerminal TerminalApp = "terminal"
+)
+
+// ParseTerminalApp parses a terminal application. Defaults to
+// TerminalAppITerm2 if blank.
+func ParseTerminalApp(s string) (TerminalApp, error) {
+	switch app := TerminalApp(strings.ToLower(s)); app {
+	case "":
+		return TerminalAppITerm2, nil
+	case TerminalAppITerm2, TerminalAppTerminal:
+		return app, nil
+	default:
+		return "", fmt.Errorf("invalid terminal app: %q", s)
+	}
+}
+
+// NewTerminalBackgroundHandler returns a handler that shifts the terminal
+// background from one color to another as the interval progresses. If tty
+// is set then an OSC 11 escape sequence, which iTerm2 and most other
+// terminals support, is written to it. Otherwise the background of every
+// session of app is set with AppleScript if app is running.
+func NewTerminalBackgroundHandler(exec CommandExecutor, app TerminalApp, tty string, from, to color.Color, space ColorSpace) Handler {
+	return func(ctx *Context) error {
+		c := color.RGBAModel.Convert(space.Transpose(from, to, float64(ctx.Step)/float64(ctx.Total))).(color.RGBA)
+
+		if tty != "" {
+			f, err := os.OpenFile(tty, os.O_WRONLY, 0)
+			if err != nil {
+				return fmt.Errorf("open tty: %s", err)
+			}
+			defer func() { _ = f.Close() }()
+
+			if _, err := fmt.Fprintf(f, "\x1b]11;rgb:%02x/%02x/%02x\x07", c.R, c.G, c.B); err != nil {
+				return fmt.Errorf("write tty: %s", err)
+			}
+			return f.Close()
+		}
+
+		script := setITermBackgroundScript
+		if app == TerminalAppTerminal {
+			script = setTerminalBackgroundScript
+		}
+
+		// AppleScript colors use 16-bit components.
+		src := fmt.Sprintf(strings.TrimSpace(script), int(c.R)*257, int(c.G)*257, int(c.B)*257)
 		if b, err := exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
-			return fmt.Errorf("exec display notification: %s", b)
+			return fmt.Errorf("exec set terminal background: %s", b)
 		}
 		return nil
 	}
 }
 
-const displayNotificationScript = `display notification %q with title "Boxer"`
+const setITermBackgroundScript = `
+if application "iTerm2" is running then
+  tell application "iTerm2"
+    repeat with w in windows
+      repeat with t in tabs of w
+        repeat with s in sessions of t
+          set background color of s to {%[1]d, %[2]d, %[3]d}
+        end repeat
+      end repeat
+    end repeat
+  end tell
+end if
+`
+
+const setTerminalBackgroundScript = `
+if application "Terminal" is running then
+  tell application "Terminal"
+    set background color of every tab of every window to {%[1]d, %[2]d, %[3]d}
+  end tell
+end if
+`
+
+// OpenPath is the path to the command used to open URLs.
+const OpenPath = `/usr/bin/open`
+
+// DefaultTouchBarCells is the default number of cells in the Touch Bar
+// progress bar.
+const DefaultTouchBarCells = 10
+
+// NewTouchBarHandler returns a handler that shows the progress of the
+// interval on the Touch Bar every step, such as "▰▰▰▱▱▱▱▱▱▱ 21m". The text is
+// sent to the BetterTouchTool widget with the given UUID through its URL
+// scheme so no window is focused.
+func NewTouchBarHandler(exec CommandExecutor, uuid string, cells int) (Handler, error) {
+	if uuid == "" {
+		return nil, fmt.Errorf("widget uuid required")
+	} else if cells <= 0 {
+		return nil, fmt.Errorf("cells must be greater than zero")
+	}
+
+	return func(ctx *Context) error {
+		filled := (ctx.Step * cells) / ctx.Total
+		text := fmt.Sprintf("%s%s %dm",
+			strings.Repeat("▰", filled), strings.Repeat("▱", cells-filled),
+			int(math.Ceil(ctx.Remaining.Minutes())),
+		)
+
+		u := fmt.Sprintf("btt://update_touch_bar_widget/?uuid=%s&text=%s", url.PathEscape(uuid), url.PathEscape(text))
+		if b, err := exec(OpenPath, []string{"-g", u}, strings.NewReader("")); err != nil {
+			return fmt.Errorf("exec open: %s", b)
+		}
+		return nil
+	}, nil
+}
+
+// EnforcerAction is how the enforcer handler stops the user for a break.
+type EnforcerAction string
+
+const (
+	EnforcerDim     = EnforcerAction("dim")     // dims the screen, clicks pass through
+	EnforcerOverlay = EnforcerAction("overlay") // covers the screen with a message
+	EnforcerLock    = EnforcerAction("lock")    // locks the screen
+)
+
+// ParseEnforcerAction returns the enforcer action for s. Defaults to dim.
+func ParseEnforcerAction(s string) (EnforcerAction, error) {
+	switch a := EnforcerAction(s); a {
+	case "":
+		return EnforcerDim, nil
+	case EnforcerDim, EnforcerOverlay, EnforcerLock:
+		return a, nil
+	default:
+		return "", fmt.Errorf("invalid enforcer action: %q", s)
+	}
+}
+
+// PMSetPath is the path to the "pmset" binary.
+const PMSetPath = `/usr/bin/pmset`
+
+// DefaultBreakDuration is the default time the enforcer covers the screen.
+const DefaultBreakDuration = 5 * time.Minute
+
+// enforcerStartWindow is how soon after the interval begins the enforcer
+// must run. Later calls, such as when boxer starts mid-interval, are ignored.
+const enforcerStartWindow = time.Minute
+
+// NewEnforcerHandler returns a handler that stops the user for a break when
+// an interval ends. The screen is dimmed or covered with message for d, or it
+// is locked. If escape is true then the Escape key ends the break early.
+//
+// The break runs in the background so it does not block the ticker. If a
+// previous break is still running then the new break is quietly skipped.
+// Errors from a background break are returned on the next call to the handler.
+func NewEnforcerHandler(exec CommandExecutor, action EnforcerAction, d time.Duration, message string, escape bool) IntervalHandler {
+	var mu sync.Mutex
+	var running bool
+	var lastErr error
+
+	return func(ctx *Context) error {
+		if ctx.Now.Sub(ctx.IntervalStart) > enforcerStartWindow {
+			return nil
+		}
+
+		if action == EnforcerLock {
+			if b, err := exec(PMSetPath, []string{"displaysleepnow"}, strings.NewReader("")); err != nil {
+				return fmt.Errorf("exec pmset: %s", b)
+			}
+			return nil
+		}
+
+		alpha := 0.95
+		if action == EnforcerDim {
+			alpha = 0.6
+		}
+		src, err := renderScript(breakOverlayScript, &breakOverlayData{
+			Seconds: d.Seconds(),
+			Message: message,
+			Alpha:   alpha,
+			Dim:     action == EnforcerDim,
+			Escape:  escape,
+		})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Skip if the previous break is still running. This is not a failure
+		// so it doesn't count toward disabling or quarantining the command.
+		if running {
+			return nil
+		}
+
+		// Pull the error from the previous break, if any.
+		err = lastErr
+		lastErr = nil
+
+		// Cover the screen in the background.
+		running = true
+		go func() {
+			b, err := exec(OSAScriptPath, []string{"-l", "JavaScript"}, strings.NewReader(src))
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = fmt.Errorf("exec break: %s", b)
+			}
+			running = false
+		}()
+
+		return err
+	}
+}
+
+// breakOverlayData is passed to breakOverlayScript.
+type breakOverlayData struct {
+	Seconds float64
+	Message string
+	Alpha   float64
+	Dim     bool // if true, the window ignores the mouse & shows no message
+	Escape  bool
+}
+
+// breakOverlayScript covers every screen with a black window above all other
+// windows until the break is over or, if allowed, Escape is pressed.
+var breakOverlayScript = newScriptTemplate("break overlay", `
+ObjC.import("Cocoa");
+var app = $.NSApplication.sharedApplication;
+app.setActivationPolicy($.NSApplicationActivationPolicyAccessory);
+
+var windows = [];
+var screens = $.NSScreen.screens;
+for (var i = 0; i < screens.count; i++) {
+  var frame = screens.objectAtIndex(i).frame;
+  var win = $.NSWindow.alloc.initWithContentRectStyleMaskBackingDefer(frame, $.NSWindowStyleMaskBorderless, $.NSBackingStoreBuffered, false);
+  win.level = $.NSScreenSaverWindowLevel;
+  win.opaque = false;
+  win.backgroundColor = $.NSColor.colorWithCalibratedWhiteAlpha(0, {{.Alpha}});
+  win.ignoresMouseEvents = {{.Dim}};
+  win.collectionBehavior = $.NSWindowCollectionBehaviorCanJoinAllSpaces;
+{{- if not .Dim}}
+  var label = $.NSTextField.labelWithString({{js .Message}});
+  label.font = $.NSFont.systemFontOfSize(48);
+  label.textColor = $.NSColor.whiteColor;
+  label.sizeToFit;
+  label.setFrameOrigin($.NSMakePoint((frame.size.width - label.frame.size.width) / 2, frame.size.height / 2));
+  win.contentView.addSubview(label);
+{{- end}}
+  win.orderFrontRegardless;
+  windows.push(win);
+}
+app.activateIgnoringOtherApps(true);
+
+var end = $.NSDate.dateWithTimeIntervalSinceNow({{.Seconds}});
+while (end.timeIntervalSinceNow > 0) {
+  var e = app.nextEventMatchingMaskUntilDateInModeDequeue($.NSEventMaskAny, $.NSDate.dateWithTimeIntervalSinceNow(0.1), $.NSDefaultRunLoopMode, true);
+  if (e.isNil()) continue;
+{{- if .Escape}}
+  if (e.type == $.NSEventTypeKeyDown && e.keyCode == 53) break;
+{{- end}}
+  app.sendEvent(e);
+}
+windows.forEach(function(win) { win.close; });
+`)
+
+// writePNG atomically writes m as a PNG file at path.
+func writePNG(path string, m image.Image) error {
+	return (*ImageEncoder)(nil).WriteFile(path, m)
+}
+
+// NewCalendarAppFetcher returns a fetcher for today's events in the Calendar
+// app. If calendars is not empty then only events on calendars with those
+// names are returned. All-day events are ignored.
+func NewCalendarAppFetcher(exec CommandExecutor, calendars []string, now NowFunc) CalendarFetcher {
+	return func() ([]CalendarEvent, error) {
+		src, err := renderScript(calendarEventsScript, calendars)
+		if err != nil {
+			return nil, err
+		}
+
+		// The script returns event times as seconds relative to the
+		// current time to avoid parsing locale-specific dates.
+		t := now()
+		b, err := exec(OSAScriptPath, nil, strings.NewReader(src))
+		if err != nil {
+			return nil, fmt.Errorf("exec calendar events: %s", b)
+		}
+
+		var events []CalendarEvent
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			a := strings.SplitN(line, "\t", 3)
+			if len(a) != 3 {
+				continue
+			}
+
+			start, err := strconv.Atoi(a[0])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected exec output: %s", line)
+			}
+			end, err := strconv.Atoi(a[1])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected exec output: %s", line)
+			}
+
+			events = append(events, CalendarEvent{
+				Summary: a[2],
+				Start:   t.Add(time.Duration(start) * time.Second),
+				End:     t.Add(time.Duration(end) * time.Second),
+			})
+		}
+		return events, nil
+	}
+}
+
+// calendarEventsScript prints today's timed events from the named calendars.
+// Each line contains the start & end offset in seconds and the summary.
+var calendarEventsScript = newScriptTemplate("calendar events", `
+set names to {{list .}}
+set now to current date
+set out to ""
+tell application "Calendar"
+  repeat with c in calendars
+    if (count of names) is 0 or names contains (name of c) then
+      repeat with e in (every event of c whose start date < (now + 86400) and end date > now and allday event is false)
+        set out to out & (((start date of e) - now) as text) & tab & (((end date of e) - now) as text) & tab & (summary of e) & linefeed
+      end repeat
+    end if
+  end repeat
+end tell
+return out
+`)
+
+// FocusAssertionsPath is the path, relative to the home directory, of the
+// file where macOS records manually enabled Focus modes.
+const FocusAssertionsPath = `Library/DoNotDisturb/DB/Assertions.json`
+
+// FileFocusDetector detects Focus & Do Not Disturb by reading the macOS
+// assertions file. Reading the file may require Full Disk Access.
+type FileFocusDetector struct {
+	Path string
+}
+
+// NewFocusDetector returns a detector for the current user's assertions file.
+func NewFocusDetector() (*FileFocusDetector, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+	return &FileFocusDetector{Path: filepath.Join(u.HomeDir, FocusAssertionsPath)}, nil
+}
+
+// Focused returns true if any Focus mode is enabled. Returns false if the
+// assertions file does not exist.
+func (d *FileFocusDetector) Focused() (bool, error) {
+	buf, err := ioutil.ReadFile(d.Path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var doc struct {
+		Data []struct {
+			StoreAssertionRecords []json.RawMessage `json:"storeAssertionRecords"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return false, fmt.Errorf("decode focus assertions: %s", err)
+	}
+
+	for _, data := range doc.Data {
+		if len(data.StoreAssertionRecords) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DefaultPresentationApps are the applications that indicate a presentation
+// while they are frontmost.
+var DefaultPresentationApps = []string{"Keynote", "Microsoft PowerPoint"}
+
+// DefaultScreenShareProcesses are the processes that only run while the
+// screen is being shared. Zoom runs "CptHost" during a screen share.
+var DefaultScreenShareProcesses = []string{"CptHost"}
+
+// SystemProfilerPath is the path to the "system_profiler" binary.
+const SystemProfilerPath = `/usr/sbin/system_profiler`
+
+// ScreenPresentationDetector detects a presentation when the screen is
+// mirrored, when one of Apps is frontmost, or when one of the screen share
+// Processes is running.
+type ScreenPresentationDetector struct {
+	Exec      CommandExecutor
+	Apps      []string
+	Processes []string
+}
+
+// NewPresentationDetector returns a detector for the default presentation
+// apps & screen share processes.
+func NewPresentationDetector(exec CommandExecutor) *ScreenPresentationDetector {
+	return &ScreenPresentationDetector{
+		Exec:      exec,
+		Apps:      DefaultPresentationApps,
+		Processes: DefaultScreenShareProcesses,
+	}
+}
+
+// Presenting returns true if the screen is mirrored or if a presentation
+// app or screen share is detected.
+func (d *ScreenPresentationDetector) Presenting() (bool, error) {
+	if v, err := d.mirrored(); err != nil || v {
+		return v, err
+	}
+
+	// Fetch the frontmost app & whether any screen share process is running.
+	src, err := renderScript(presentationScript, d.Processes)
+	if err != nil {
+		return false, err
+	}
+	b, err := d.Exec(OSAScriptPath, nil, strings.NewReader(src))
+	if err != nil {
+		return false, fmt.Errorf("exec presentation script: %s", b)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		return false, fmt.Errorf("unexpected presentation script output: %q", b)
+	} else if lines[1] == "true" {
+		return true, nil
+	}
+	for _, app := range d.Apps {
+		if strings.EqualFold(lines[0], app) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mirrored returns true if any display is mirroring another display.
+func (d *ScreenPresentationDetector) mirrored() (bool, error) {
+	b, err := d.Exec(SystemProfilerPath, []string{"SPDisplaysDataType"}, strings.NewReader(""))
+	if err != nil {
+		return false, fmt.Errorf("exec system_profiler: %s", b)
+	}
+	return strings.Contains(string(b), "Mirror: On"), nil
+}
+
+// presentationScript prints the name of the frontmost app followed by
+// whether any of the listed processes are running.
+var presentationScript = newScriptTemplate("presentation", `
+set procs to {{list .}}
+tell application "System Events"
+  set frontApp to name of first application process whose frontmost is true
+  set sharing to false
+  repeat with procName in procs
+    if exists (application process procName) then set sharing to true
+  end repeat
+end tell
+return frontApp & linefeed & sharing
+`)
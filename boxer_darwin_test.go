@@ -3,10 +3,20 @@ package boxer_test
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"image"
 	"image/color"
+	"image/draw"
+	"image/png"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,10 +26,12 @@ import (
 // Ensure that wallpaper can be generated on the fly and updated.
 func TestWallpaperHandler(t *testing.T) {
 	// Use mocks to check the parameters passed to each.
-	var sized, generated bool
+	var sized bool
+	var mu sync.Mutex
+	var generated []string
 	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
 		b, _ := ioutil.ReadAll(stdin)
-		if string(b) != `tell application "Finder"`+"\n"+`  set desktop picture to POSIX file "/my/path/wallpaper_0100_0200_01_10.png"`+"\n"+`end tell` {
+		if string(b) != `tell application "Finder"`+"\n"+`  set desktop picture to POSIX file "/my/path/wallpaper_0100_0200_01_10@2x.png"`+"\n"+`end tell` {
 			t.Fatalf("unexpected command:\n\n%s", b)
 		}
 		return nil, nil
@@ -28,31 +40,182 @@ func TestWallpaperHandler(t *testing.T) {
 		sized = true
 		return 100, 200, nil
 	}
-	generator := func(path string, w, h int, pct float64) error {
-		if path != "/my/path/wallpaper_0100_0200_01_10.png" {
-			t.Fatalf("unexpected path: %s", path)
-		} else if w != 100 {
-			t.Fatalf("unexpected width: %d", w)
-		} else if h != 200 {
-			t.Fatalf("unexpected height: %d", h)
-		} else if pct != 0.1 {
-			t.Fatalf("unexpected pct: %f", pct)
-		}
-		generated = true
+	scaler := func(exec boxer.CommandExecutor) (float64, error) { return 2, nil }
+	generator := func(path string, w, h int, scale, pct float64) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// The current step should be generated first.
+		if len(generated) == 0 {
+			if path != "/my/path/wallpaper_0100_0200_01_10@2x.png" {
+				t.Fatalf("unexpected path: %s", path)
+			} else if w != 100 {
+				t.Fatalf("unexpected width: %d", w)
+			} else if h != 200 {
+				t.Fatalf("unexpected height: %d", h)
+			} else if scale != 2 {
+				t.Fatalf("unexpected scale: %f", scale)
+			} else if pct != 0.1 {
+				t.Fatalf("unexpected pct: %f", pct)
+			}
+		}
+		generated = append(generated, path)
 		return nil
 	}
 
 	// Create handler with mocks.
 	path := "/my/path"
-	h := boxer.NewWallpaperHandler(exec, sizer, generator, path)
+	h := boxer.NewWallpaperHandler(&boxer.MacDesktop{Exec: exec, Sizer: sizer, Scaler: scaler}, generator, path)
 
 	// Call handler for the first step of fifteen.
-	if err := h(1, 10); err != nil {
+	// The remaining steps should be pregenerated since this is the first call.
+	if err := h(&boxer.Context{Step: 1, Total: 10}); err != nil {
 		t.Fatal(err)
 	} else if !sized {
 		t.Fatal("sizer not called")
-	} else if !generated {
-		t.Fatal("generator not called")
+	} else if len(generated) != 10 {
+		t.Fatalf("unexpected generated count: %d", len(generated))
+	}
+	sort.Strings(generated[1:])
+	if generated[1] != "/my/path/wallpaper_0100_0200_00_10@2x.png" || generated[9] != "/my/path/wallpaper_0100_0200_09_10@2x.png" {
+		t.Fatalf("unexpected generated paths: %v", generated)
+	}
+}
+
+// Ensure the wallpaper cache only generates missing images.
+func TestWallpaperCache_Get(t *testing.T) {
+	path := MustTempDir()
+	defer os.RemoveAll(path)
+
+	var n int
+	c := boxer.NewWallpaperCache(path, func(path string, w, h int, scale, pct float64) error {
+		n++
+		return ioutil.WriteFile(path, []byte("IMAGE"), 0666)
+	})
+
+	if p, err := c.Get(100, 200, 1, 3, 10); err != nil {
+		t.Fatal(err)
+	} else if p != filepath.Join(path, "wallpaper_0100_0200_03_10.png") {
+		t.Fatalf("unexpected path: %s", p)
+	} else if _, err := c.Get(100, 200, 1, 3, 10); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected generate count: %d", n)
+	}
+}
+
+// Ensure the wallpaper cache pregenerates images concurrently & reports progress.
+func TestWallpaperCache_Pregenerate(t *testing.T) {
+	path := MustTempDir()
+	defer os.RemoveAll(path)
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	c := boxer.NewWallpaperCache(path, func(path string, w, h int, scale, pct float64) error {
+		mu.Lock()
+		if running++; running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return ioutil.WriteFile(path, []byte("IMAGE"), 0666)
+	})
+	c.Workers = 3
+
+	var progress [][2]int
+	c.Progress = func(done, total int) { progress = append(progress, [2]int{done, total}) }
+
+	if err := c.Pregenerate(100, 200, 1, 10, 2); err != nil {
+		t.Fatal(err)
+	} else if maxRunning < 2 || maxRunning > 3 {
+		t.Fatalf("unexpected concurrency: %d", maxRunning)
+	} else if len(progress) != 10 || progress[0] != [2]int{0, 9} || progress[9] != [2]int{9, 9} {
+		t.Fatalf("unexpected progress: %v", progress)
+	} else if _, err := os.Stat(c.Path(100, 200, 1, 2, 10)); !os.IsNotExist(err) {
+		t.Fatal("expected skipped step to not be generated")
+	}
+}
+
+// Ensure the wallpaper cache removes stale images oldest first until it fits.
+func TestWallpaperCache_Prune(t *testing.T) {
+	path := MustTempDir()
+	defer os.RemoveAll(path)
+
+	c := boxer.NewWallpaperCache(path, func(path string, w, h int, scale, pct float64) error {
+		return ioutil.WriteFile(path, make([]byte, 100), 0666)
+	})
+	c.MaxSize = 500
+
+	// Generate images for two old desktop sizes and the current size.
+	if err := c.Pregenerate(10, 10, 1, 2); err != nil {
+		t.Fatal(err)
+	} else if err := c.Pregenerate(20, 20, 1, 2); err != nil {
+		t.Fatal(err)
+	} else if err := c.Pregenerate(30, 30, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(c.Path(10, 10, 1, 0, 2), time.Unix(1, 0), time.Unix(1, 0))
+	os.Chtimes(c.Path(10, 10, 1, 1, 2), time.Unix(2, 0), time.Unix(2, 0))
+
+	// Add an unrelated file which should never be removed & a temporary file
+	// left by a crash which should always be removed.
+	ioutil.WriteFile(filepath.Join(path, "other.png"), make([]byte, 1000), 0666)
+	ioutil.WriteFile(filepath.Join(path, "wallpaper_0030_0030_00_02.png.tmp"), make([]byte, 10), 0666)
+
+	// Prune down to 500 bytes which should remove the oldest image.
+	if err := c.Prune(30, 30, 1, 2); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(c.Path(10, 10, 1, 0, 2)); !os.IsNotExist(err) {
+		t.Fatal("expected oldest image to be removed")
+	} else if _, err := os.Stat(c.Path(10, 10, 1, 1, 2)); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(filepath.Join(path, "other.png")); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(filepath.Join(path, "wallpaper_0030_0030_00_02.png.tmp")); !os.IsNotExist(err) {
+		t.Fatal("expected temporary file to be removed")
+	}
+
+	// Prune with no max size should remove all stale images.
+	c.MaxSize = 0
+	if err := c.Prune(30, 30, 1, 2); err != nil {
+		t.Fatal(err)
+	} else if fis, _ := ioutil.ReadDir(path); len(fis) != 3 {
+		t.Fatalf("unexpected file count: %d", len(fis))
+	}
+}
+
+// Ensure the wallpaper cache includes the key in filenames & removes images
+// generated with another key.
+func TestWallpaperCache_Prune_Key(t *testing.T) {
+	path := MustTempDir()
+	defer os.RemoveAll(path)
+
+	c := boxer.NewWallpaperCache(path, func(path string, w, h int, scale, pct float64) error {
+		return ioutil.WriteFile(path, make([]byte, 100), 0666)
+	})
+	c.MaxSize = 1000
+	if err := c.Pregenerate(30, 30, 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	old := c.Path(30, 30, 2, 0, 2)
+
+	// Change the key & regenerate.
+	c.Key = "0123abcd"
+	if p := c.Path(30, 30, 2, 0, 2); p != filepath.Join(path, "wallpaper_0030_0030_00_02_0123abcd@2x.png") {
+		t.Fatalf("unexpected path: %s", p)
+	} else if err := c.Pregenerate(30, 30, 2, 2); err != nil {
+		t.Fatal(err)
+	} else if err := c.Prune(30, 30, 2, 2); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatal("expected image with old key to be removed")
+	} else if fis, _ := ioutil.ReadDir(path); len(fis) != 2 {
+		t.Fatalf("unexpected file count: %d", len(fis))
 	}
 }
 
@@ -62,8 +225,8 @@ func TestWallpaperHandler_ErrSizer(t *testing.T) {
 		return 0, 0, errors.New("no size found")
 	}
 
-	h := boxer.NewWallpaperHandler(nil, sizer, nil, "")
-	if err := h(0, 10); err == nil || err.Error() != `desktop size: no size found` {
+	h := boxer.NewWallpaperHandler(&boxer.MacDesktop{Sizer: sizer}, nil, "")
+	if err := h(&boxer.Context{Step: 0, Total: 10}); err == nil || err.Error() != `desktop size: no size found` {
 		t.Fatal(err)
 	}
 }
@@ -71,10 +234,11 @@ func TestWallpaperHandler_ErrSizer(t *testing.T) {
 // Ensure that wallpaper returns an error if the generator fails.
 func TestWallpaperHandler_ErrGenerator(t *testing.T) {
 	sizer := func(exec boxer.CommandExecutor) (w, h int, err error) { return 0, 0, nil }
-	generator := func(path string, w, h int, pct float64) error { return errors.New("bad generator") }
+	scaler := func(exec boxer.CommandExecutor) (float64, error) { return 1, nil }
+	generator := func(path string, w, h int, scale, pct float64) error { return errors.New("bad generator") }
 
-	h := boxer.NewWallpaperHandler(nil, sizer, generator, "")
-	if err := h(0, 10); err == nil || err.Error() != `generate wallpaper: bad generator` {
+	h := boxer.NewWallpaperHandler(&boxer.MacDesktop{Sizer: sizer, Scaler: scaler}, generator, "")
+	if err := h(&boxer.Context{Step: 0, Total: 10}); err == nil || err.Error() != `generate wallpaper: bad generator` {
 		t.Fatal(err)
 	}
 }
@@ -82,13 +246,144 @@ func TestWallpaperHandler_ErrGenerator(t *testing.T) {
 // Ensure that wallpaper returns an error if the update fails.
 func TestWallpaperHandler_ErrSetWallpaper(t *testing.T) {
 	sizer := func(exec boxer.CommandExecutor) (w, h int, err error) { return 0, 0, nil }
-	generator := func(path string, w, h int, pct float64) error { return nil }
+	scaler := func(exec boxer.CommandExecutor) (float64, error) { return 1, nil }
+	generator := func(path string, w, h int, scale, pct float64) error { return nil }
 	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
 		return []byte("bad exec"), errors.New("")
 	}
 
-	h := boxer.NewWallpaperHandler(exec, sizer, generator, "")
-	if err := h(0, 10); err == nil || err.Error() != `exec: bad exec` {
+	h := boxer.NewWallpaperHandler(&boxer.MacDesktop{Exec: exec, Sizer: sizer, Scaler: scaler}, generator, "")
+	if err := h(&boxer.Context{Step: 0, Total: 10}); err == nil || err.Error() != `exec: bad exec` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the caption is drawn in the top right corner of the generated wallpaper.
+func TestCaptionWallpaperGenerator(t *testing.T) {
+	path := filepath.Join(MustTempDir(), "wallpaper.png")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	black := []color.RGBA{{A: 0xFF}}
+	generator, err := boxer.NewWallpaperGenerator(time.Now, nil, black, black, boxer.RGBColorSpace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := boxer.NewCaptionWallpaperGenerator(generator, nil, func() (string, error) { return "7 of 10 boxes today", nil })
+	if err := g(path, 640, 480, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := boxer.ReadImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left, right int
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 640; x++ {
+			if r, _, _, _ := m.At(x, y).RGBA(); r == 0xFFFF && x < 320 {
+				left++
+			} else if r == 0xFFFF {
+				right++
+			}
+		}
+	}
+	if left != 0 || right == 0 {
+		t.Fatalf("unexpected caption pixels: left=%d right=%d", left, right)
+	}
+}
+
+// Ensure steps of overtime are drawn with the overdue generator with an
+// intensity that stops increasing after OverdueSteps.
+func TestWallpaperHandler_Overtime(t *testing.T) {
+	var set []string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		set = append(set, string(b))
+		return nil, nil
+	}
+	sizer := func(exec boxer.CommandExecutor) (w, h int, err error) { return 100, 200, nil }
+	scaler := func(exec boxer.CommandExecutor) (float64, error) { return 1, nil }
+
+	var generated []string
+	cache := boxer.NewWallpaperCache("/my/path", func(path string, w, h int, scale, pct float64) error {
+		t.Fatalf("unexpected generate: %s", path)
+		return nil
+	})
+	cache.Overdue = func(path string, w, h int, scale, pct float64) error {
+		generated = append(generated, fmt.Sprintf("%s %.2f", filepath.Base(path), pct))
+		return nil
+	}
+	h := boxer.NewCachedWallpaperHandler(&boxer.MacDesktop{Exec: exec, Sizer: sizer, Scaler: scaler}, cache)
+
+	for _, overtime := range []int{1, 2, 6} {
+		if err := h(&boxer.Context{Step: 9, Total: 10, Overtime: overtime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(generated, []string{
+		"wallpaper_0100_0200_10_10.png 0.25",
+		"wallpaper_0100_0200_11_10.png 0.50",
+		"wallpaper_0100_0200_13_10.png 1.00",
+	}) {
+		t.Fatalf("unexpected generated: %#v", generated)
+	} else if len(set) != 3 || !strings.Contains(set[2], "wallpaper_0100_0200_13_10.png") {
+		t.Fatalf("unexpected wallpapers set: %#v", set)
+	}
+}
+
+// Ensure the overdue color is blended over the end of the interval.
+func TestNewOverdueWallpaperGenerator(t *testing.T) {
+	path := filepath.Join(MustTempDir(), "wallpaper.png")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var pcts []float64
+	black := []color.RGBA{{A: 0xFF}}
+	generator, err := boxer.NewWallpaperGenerator(time.Now, nil, black, black, boxer.RGBColorSpace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := boxer.NewOverdueWallpaperGenerator(func(path string, w, h int, scale, pct float64) error {
+		pcts = append(pcts, pct)
+		return generator(path, w, h, scale, pct)
+	}, color.RGBA{R: 0xFF, A: 0xFF}, nil)
+
+	for _, tt := range []struct {
+		pct float64
+		r   uint8
+	}{{0.25, 89}, {1, 204}} {
+		if err := g(path, 64, 48, 1, tt.pct); err != nil {
+			t.Fatal(err)
+		}
+		m, err := boxer.ReadImage(path)
+		if err != nil {
+			t.Fatal(err)
+		} else if c := color.RGBAModel.Convert(m.At(10, 10)).(color.RGBA); c != (color.RGBA{R: tt.r, A: 0xFF}) {
+			t.Fatalf("%v: unexpected color: %#v", tt.pct, c)
+		}
+	}
+	if !reflect.DeepEqual(pcts, []float64{1, 1}) {
+		t.Fatalf("unexpected generator pcts: %v", pcts)
+	}
+}
+
+// Ensure the current wallpaper path can be retrieved.
+func TestWallpaper(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte("/Library/Desktop Pictures/Yosemite.jpg\n"), nil
+	}
+	if path, err := boxer.Wallpaper(exec); err != nil {
+		t.Fatal(err)
+	} else if path != "/Library/Desktop Pictures/Yosemite.jpg" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+}
+
+// Ensure an error is returned if the current wallpaper cannot be retrieved.
+func TestWallpaper_ErrExec(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte("bad exec"), errors.New("")
+	}
+	if _, err := boxer.Wallpaper(exec); err == nil || err.Error() != `exec: bad exec` {
 		t.Fatal(err)
 	}
 }
@@ -105,11 +400,13 @@ func TestGenerateWallpaper(t *testing.T) {
 		},
 		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, {R: 0xDD, G: 0xDD, B: 0xDD, A: 0xFF}},
 		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}, {R: 0x22, G: 0x22, B: 0x22, A: 0xFF}},
+		boxer.RGBColorSpace,
+		nil,
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := fn(path, 100, 200, 0.28371); err != nil {
+	if err := fn(path, 100, 200, 1, 0.28371); err != nil {
 		t.Fatal(err)
 	}
 
@@ -123,6 +420,247 @@ func TestGenerateWallpaper(t *testing.T) {
 	os.Remove(path)
 }
 
+// Ensure that a gradient wallpaper fades into the foreground color.
+func TestGenerateGradientWallpaper(t *testing.T) {
+	fn, err := boxer.NewGradientWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MustGenerateWallpaper(fn, 10, 100, 0.5)
+
+	if c := m.At(0, 49); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected leading edge color: %#v", c)
+	} else if c := m.At(0, 0); c != (color.RGBA{R: 0x05, G: 0x05, B: 0x05, A: 0xFF}) {
+		t.Fatalf("unexpected top color: %#v", c)
+	} else if c := m.At(0, 50); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected background color: %#v", c)
+	}
+}
+
+// Ensure that a ring wallpaper covers the ring clockwise from the top.
+func TestGenerateRingWallpaper(t *testing.T) {
+	fn, err := boxer.NewRingWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MustGenerateWallpaper(fn, 100, 100, 0.5)
+
+	if c := m.At(77, 50); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected right side color: %#v", c)
+	} else if c := m.At(22, 50); c != (color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xFF}) {
+		t.Fatalf("unexpected left side color: %#v", c)
+	} else if c := m.At(50, 50); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected center color: %#v", c)
+	}
+}
+
+// Ensure that a segmented wallpaper fills one cell per completed segment.
+func TestGenerateSegmentedWallpaper(t *testing.T) {
+	fn, err := boxer.NewSegmentedWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		4,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MustGenerateWallpaper(fn, 1000, 800, 0.5)
+
+	if c := m.At(200, 400); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected first cell color: %#v", c)
+	} else if c := m.At(400, 400); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected second cell color: %#v", c)
+	} else if c := m.At(600, 400); c != (color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xFF}) {
+		t.Fatalf("unexpected third cell color: %#v", c)
+	} else if c := m.At(200, 100); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected background color: %#v", c)
+	}
+}
+
+// Ensure that a bar wallpaper draws a strip along the placement edge.
+func TestGenerateBarWallpaper(t *testing.T) {
+	fn, err := boxer.NewBarWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		boxer.WallpaperBar{Placement: boxer.BarBottom, Fill: boxer.FillLeft, Thickness: 40, Margin: 10},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MustGenerateWallpaper(fn, 1000, 800, 0.5)
+
+	if c := m.At(900, 770); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected progress color: %#v", c)
+	} else if c := m.At(100, 770); c != (color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xFF}) {
+		t.Fatalf("unexpected track color: %#v", c)
+	} else if c := m.At(900, 795); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected margin color: %#v", c)
+	} else if c := m.At(500, 400); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected background color: %#v", c)
+	}
+}
+
+// Ensure that a wallpaper is generated at pixel resolution with sizes in points.
+func TestGenerateBarWallpaper_Scale(t *testing.T) {
+	fn, err := boxer.NewBarWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		boxer.WallpaperBar{Placement: boxer.BarBottom, Fill: boxer.FillLeft, Thickness: 40, Margin: 10},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := NewTempFile()
+	defer os.Remove(path)
+	if err := fn(path, 500, 400, 2, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	m, err := boxer.ReadImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := m.Bounds(); b.Dx() != 1000 || b.Dy() != 800 {
+		t.Fatalf("unexpected bounds: %v", b)
+	} else if c := m.At(900, 710); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected progress color: %#v", c)
+	} else if c := m.At(900, 690); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected background color: %#v", c)
+	} else if c := m.At(900, 785); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected margin color: %#v", c)
+	}
+}
+
+// Ensure that a bar's fill direction must run along the strip.
+func TestNewBarWallpaperGenerator_ErrFill(t *testing.T) {
+	c := []color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+	bar := boxer.WallpaperBar{Placement: boxer.BarTop, Fill: boxer.FillUp, Thickness: 40}
+	if _, err := boxer.NewBarWallpaperGenerator(time.Now, nil, c, c, boxer.RGBColorSpace, bar, nil); err == nil || err.Error() != `fill direction "up" does not run along a bar on the top edge` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure that an image wallpaper draws a progress bar over the photo.
+func TestGenerateImageWallpaper(t *testing.T) {
+	// Use a photo with a different aspect ratio so it is cropped.
+	photo := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(photo, photo.Bounds(), &image.Uniform{color.RGBA{R: 0xFF, A: 0xFF}}, image.ZP, draw.Src)
+
+	fn, err := boxer.NewImageWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		boxer.WallpaperBar{Placement: boxer.BarLeft},
+		boxer.WallpaperImage{Image: photo, Opacity: 1},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MustGenerateWallpaper(fn, 800, 800, 0.5)
+
+	if c := m.At(5, 100); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected progress color: %#v", c)
+	} else if c := m.At(5, 700); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected track color: %#v", c)
+	} else if c := m.At(400, 400); c != (color.RGBA{R: 0xFF, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected photo color: %#v", c)
+	}
+}
+
+// Ensure that an image wallpaper requires an image.
+func TestNewImageWallpaperGenerator_ErrNoImage(t *testing.T) {
+	c := []color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+	if _, err := boxer.NewImageWallpaperGenerator(time.Now, nil, c, c, boxer.RGBColorSpace, boxer.WallpaperBar{}, boxer.WallpaperImage{}, nil); err == nil || err.Error() != `background image required` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure that a grid wallpaper fills one cell per completed step.
+func TestGenerateGridWallpaper(t *testing.T) {
+	fn, err := boxer.NewGridWallpaperGenerator(time.Now, nil,
+		[]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		[]color.RGBA{{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}},
+		boxer.RGBColorSpace,
+		4, boxer.WallpaperGrid{Gap: 20, Radius: 50},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MustGenerateWallpaper(fn, 1000, 1000, 0.75)
+
+	// Cells are 390px squares in a 2x2 grid starting at (100, 100).
+	if c := m.At(300, 300); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected first cell color: %#v", c)
+	} else if c := m.At(300, 700); c != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Fatalf("unexpected third cell color: %#v", c)
+	} else if c := m.At(700, 700); c != (color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xFF}) {
+		t.Fatalf("unexpected fourth cell color: %#v", c)
+	} else if c := m.At(101, 101); c != (color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}) {
+		t.Fatalf("unexpected rounded corner color: %#v", c)
+	}
+}
+
+// Ensure that a grid wallpaper must fit every cell.
+func TestNewGridWallpaperGenerator_ErrTooSmall(t *testing.T) {
+	c := []color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+	if _, err := boxer.NewGridWallpaperGenerator(time.Now, nil, c, c, boxer.RGBColorSpace, 15, boxer.WallpaperGrid{Rows: 2, Columns: 5}, nil); err == nil || err.Error() != `grid of 5x2 cannot fit 15 cells` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure that a segmented wallpaper requires at least one segment.
+func TestNewSegmentedWallpaperGenerator_ErrNoSegments(t *testing.T) {
+	c := []color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+	if _, err := boxer.NewSegmentedWallpaperGenerator(time.Now, nil, c, c, boxer.RGBColorSpace, 0, nil); err == nil || err.Error() != `segment count must be greater than zero` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the profile generator delegates to the profile active at the current time.
+func TestNewProfileWallpaperGenerator(t *testing.T) {
+	var called string
+	gen := func(name string) boxer.WallpaperGenerator {
+		return func(path string, w, h int, scale, pct float64) error { called = name; return nil }
+	}
+
+	var now time.Time
+	g := boxer.NewProfileWallpaperGenerator(func() time.Time { return now }, []boxer.WallpaperProfile{
+		{Hours: boxer.TimeRange{Start: 6 * time.Hour, End: 12 * time.Hour}, Generator: gen("morning")},
+		{Hours: boxer.TimeRange{Start: 18 * time.Hour, End: 2 * time.Hour}, Generator: gen("evening")},
+	}, gen("default"))
+
+	for _, tt := range []struct {
+		hour int
+		exp  string
+	}{{7, "morning"}, {12, "default"}, {20, "evening"}, {1, "evening"}} {
+		now = time.Date(2000, 1, 1, tt.hour, 0, 0, 0, time.UTC)
+		if err := g("", 10, 10, 1, 0.5); err != nil {
+			t.Fatal(err)
+		} else if called != tt.exp {
+			t.Fatalf("%02d:00: unexpected generator: %s", tt.hour, called)
+		}
+	}
+}
+
 // Ensure the desktop size can be calculated via AppleScript.
 func TestDesktopSize(t *testing.T) {
 	// Return the expected output.
@@ -140,6 +678,86 @@ func TestDesktopSize(t *testing.T) {
 	}
 }
 
+// Ensure dark mode is detected from the appearance preferences.
+func TestDarkMode(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte("true\n"), nil
+	}
+	if v, err := boxer.DarkMode(exec); err != nil {
+		t.Fatal(err)
+	} else if !v {
+		t.Fatal("expected dark mode")
+	}
+}
+
+// Ensure the dark mode generator delegates based on the appearance.
+func TestNewDarkModeWallpaperGenerator(t *testing.T) {
+	var dark bool
+	var got string
+	fn := boxer.NewDarkModeWallpaperGenerator(
+		func() (bool, error) { return dark, nil },
+		func(path string, w, h int, scale, pct float64) error { got = "light"; return nil },
+		func(path string, w, h int, scale, pct float64) error { got = "dark"; return nil },
+	)
+
+	if err := fn("", 1, 1, 1, 0); err != nil {
+		t.Fatal(err)
+	} else if got != "light" {
+		t.Fatalf("unexpected generator: %s", got)
+	}
+
+	dark = true
+	if err := fn("", 1, 1, 1, 0); err != nil {
+		t.Fatal(err)
+	} else if got != "dark" {
+		t.Fatalf("unexpected generator: %s", got)
+	}
+}
+
+// Ensure the rotating generator uses the next generator each interval and
+// restarts at the first generator each day.
+func TestNewRotatingWallpaperGenerator(t *testing.T) {
+	var now time.Time
+	var got []int
+	generators := make([]boxer.WallpaperGenerator, 3)
+	for i := range generators {
+		i := i
+		generators[i] = func(path string, w, h int, scale, pct float64) error { got = append(got, i); return nil }
+	}
+
+	fn, err := boxer.NewRotatingWallpaperGenerator(func() time.Time { return now }, 30*time.Minute, generators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []time.Time{
+		time.Date(2000, 1, 1, 0, 10, 0, 0, time.UTC),
+		time.Date(2000, 1, 1, 0, 29, 0, 0, time.UTC),
+		time.Date(2000, 1, 1, 0, 30, 0, 0, time.UTC),
+		time.Date(2000, 1, 1, 1, 30, 0, 0, time.UTC),
+		time.Date(2000, 1, 1, 23, 59, 0, 0, time.UTC),
+		time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		now = v
+		if err := fn("", 1, 1, 1, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(got, []int{0, 0, 1, 0, 2, 0}) {
+		t.Fatalf("unexpected generators: %v", got)
+	}
+}
+
+// Ensure the rotating generator requires an interval & generators.
+func TestNewRotatingWallpaperGenerator_Err(t *testing.T) {
+	generators := []boxer.WallpaperGenerator{func(path string, w, h int, scale, pct float64) error { return nil }}
+	if _, err := boxer.NewRotatingWallpaperGenerator(time.Now, 0, generators); err == nil || err.Error() != `interval required for rotation` {
+		t.Fatal(err)
+	} else if _, err := boxer.NewRotatingWallpaperGenerator(time.Now, time.Hour, nil); err == nil || err.Error() != `generator required for rotation` {
+		t.Fatal(err)
+	}
+}
+
 // Ensure the desktop size returns an error if osascript cannot be executed.
 func TestDesktopSize_ErrSystem(t *testing.T) {
 	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
@@ -160,6 +778,391 @@ func TestDesktopSize_ErrUnexpectedOutput(t *testing.T) {
 	}
 }
 
+// Ensure the size of the main display is read from system_profiler.
+func TestSystemProfilerDesktopSize(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.SystemProfilerPath {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		return []byte(`Graphics/Displays:
+
+    Apple M1 Pro:
+
+      Displays:
+        Color LCD:
+          Display Type: Built-in Liquid Retina XDR Display
+          Resolution: 3024 x 1964 Retina
+          Mirror: Off
+          Online: Yes
+        DELL U2720Q:
+          Resolution: 3840 x 2160 (2160p/4K UHD 1 - Ultra High Definition)
+          UI Looks like: 2560 x 1440 @ 60.00Hz
+          Main Display: Yes
+          Mirror: Off
+          Online: Yes
+`), nil
+	}
+
+	if w, h, err := boxer.SystemProfilerDesktopSize(exec); err != nil {
+		t.Fatal(err)
+	} else if w != 2560 || h != 1440 {
+		t.Fatalf("unexpected size: %dx%d", w, h)
+	}
+}
+
+// Ensure the mac sizer falls back to system_profiler & then the static size
+// and logs the source whenever it changes.
+func TestNewMacDesktopSizer(t *testing.T) {
+	var profiler bool
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name == boxer.SystemProfilerPath && profiler {
+			return []byte("Resolution: 1920 x 1080 @ 60Hz\n"), nil
+		}
+		return []byte("not authorized"), errors.New("")
+	}
+
+	var buf bytes.Buffer
+	sizer := boxer.NewMacDesktopSizer(log.New(&buf, "", 0), 800, 600)
+	if w, h, err := sizer(exec); err != nil {
+		t.Fatal(err)
+	} else if w != 800 || h != 600 {
+		t.Fatalf("unexpected size: %dx%d", w, h)
+	}
+
+	profiler = true
+	if w, h, err := sizer(exec); err != nil {
+		t.Fatal(err)
+	} else if w != 1920 || h != 1080 {
+		t.Fatalf("unexpected size: %dx%d", w, h)
+	} else if _, _, err := sizer(exec); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := "desktop size: using static (finder: exec: not authorized; system_profiler: exec system_profiler: not authorized)\n" +
+		"desktop size: using system_profiler (finder: exec: not authorized)\n"; buf.String() != exp {
+		t.Fatalf("unexpected log:\n%s", buf.String())
+	}
+
+	// Without a static size, every source's error is returned.
+	if _, _, err := boxer.NewMacDesktopSizer(nil, 0, 0)(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte("denied"), errors.New("")
+	}); err == nil || err.Error() != `finder: exec: denied; system_profiler: exec system_profiler: denied` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the cached sizer only queries the desktop after the TTL expires.
+func TestCachedDesktopSizer(t *testing.T) {
+	var n int
+	sizer := func(exec boxer.CommandExecutor) (int, int, error) {
+		n++
+		return 100 * n, 200, nil
+	}
+
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	fn := boxer.NewCachedDesktopSizer(sizer, 1*time.Minute, func() time.Time { return now })
+
+	// Query the desktop the first time and use the cache afterward.
+	for i := 0; i < 2; i++ {
+		if w, h, err := fn(nil); err != nil {
+			t.Fatal(err)
+		} else if w != 100 || h != 200 {
+			t.Fatalf("unexpected size: %dx%d", w, h)
+		}
+	}
+
+	// Query the desktop again once the TTL expires.
+	now = now.Add(1 * time.Minute)
+	if w, _, err := fn(nil); err != nil {
+		t.Fatal(err)
+	} else if w != 200 {
+		t.Fatalf("unexpected width: %d", w)
+	} else if n != 2 {
+		t.Fatalf("unexpected query count: %d", n)
+	}
+}
+
+// Ensure the wallpaper path is escaped in the script.
+func TestSetWallpaper_Escape(t *testing.T) {
+	var src string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		src = string(b)
+		return nil, nil
+	}
+
+	if err := boxer.SetWallpaper(exec, `/my/"work"/wallpaper.png`); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(src, `POSIX file "/my/\"work\"/wallpaper.png"`) {
+		t.Fatalf("unexpected script:\n%s", src)
+	}
+}
+
+// Ensure the wallpaper of every desktop is set with System Events.
+func TestSetWallpaperSpaces(t *testing.T) {
+	var src string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		src = string(b)
+		return nil, nil
+	}
+
+	if err := boxer.SetWallpaperSpaces(exec, `/my/"work"/wallpaper.png`); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(src, `repeat with d in every desktop`) {
+		t.Fatalf("expected every desktop:\n%s", src)
+	} else if !strings.Contains(src, `set picture of d to "/my/\"work\"/wallpaper.png"`) {
+		t.Fatalf("unexpected script:\n%s", src)
+	}
+}
+
+// Ensure the dock icon handler draws an icon and applies it to the target path.
+func TestDockIconHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "Boxer")
+	var src string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.OSAScriptPath || strings.Join(args, " ") != "-l JavaScript" {
+			t.Fatalf("unexpected command: %s %v", name, args)
+		}
+		b, _ := ioutil.ReadAll(stdin)
+		src = string(b)
+		return nil, nil
+	}
+
+	h := boxer.NewDockIconHandler(exec, filepath.Join(dir, "work"), target, 1*time.Minute, color.White, color.Black)
+	if err := h(&boxer.Context{Step: 7, Total: 15}); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(src, `initWithContentsOfFile("`+filepath.Join(dir, "work", "dock_icon.png")+`")`) || !strings.Contains(src, `"`+target+`"`) {
+		t.Fatalf("unexpected script:\n%s", src)
+	} else if fi, err := os.Stat(target); err != nil || !fi.IsDir() {
+		t.Fatalf("expected target folder: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "work", "dock_icon.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if cfg, err := png.DecodeConfig(f); err != nil {
+		t.Fatal(err)
+	} else if cfg.Width != boxer.DockIconSize || cfg.Height != boxer.DockIconSize {
+		t.Fatalf("unexpected size: %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+// Ensure the overlay handler writes a transparent progress bar to the path.
+func TestOverlayHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "obs", "overlay.png")
+	h, err := boxer.NewOverlayHandler(path, boxer.OverlayBar, 100, 10, color.White, color.Transparent)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Step: 1, Total: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	} else if m.Bounds().Dx() != 100 || m.Bounds().Dy() != 10 {
+		t.Fatalf("unexpected size: %s", m.Bounds())
+	} else if _, _, _, a := m.At(25, 5).RGBA(); a != 0xFFFF {
+		t.Fatalf("expected opaque fill: %d", a)
+	} else if _, _, _, a := m.At(75, 5).RGBA(); a != 0 {
+		t.Fatalf("expected transparent track: %d", a)
+	} else if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatal("expected temporary file to be renamed")
+	}
+}
+
+// Ensure an invalid overlay style returns an error.
+func TestParseOverlayStyle_ErrInvalid(t *testing.T) {
+	if _, err := boxer.ParseOverlayStyle("pie"); err == nil || err.Error() != `invalid overlay style: "pie"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure an invalid wallpaper backend returns an error.
+func TestParseWallpaperSetter_ErrInvalid(t *testing.T) {
+	if _, err := boxer.ParseWallpaperSetter("quartz"); err == nil || err.Error() != `invalid wallpaper backend: "quartz"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the terminal background handler sets the transposed color with AppleScript.
+func TestTerminalBackgroundHandler(t *testing.T) {
+	var src string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		src = string(b)
+		return nil, nil
+	}
+
+	h := boxer.NewTerminalBackgroundHandler(exec, boxer.TerminalAppTerminal, "", color.Black, color.White, boxer.RGBColorSpace)
+	if err := h(&boxer.Context{Step: 1, Total: 2}); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(src, `tell application "Terminal"`) || !strings.Contains(src, "{32639, 32639, 32639}") {
+		t.Fatalf("unexpected script:\n%s", src)
+	}
+}
+
+// Ensure the terminal background handler writes an escape sequence to the tty.
+func TestTerminalBackgroundHandler_TTY(t *testing.T) {
+	f, err := ioutil.TempFile("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	h := boxer.NewTerminalBackgroundHandler(nil, boxer.TerminalAppITerm2, f.Name(), color.Black, color.RGBA{0xFF, 0, 0, 0xFF}, boxer.RGBColorSpace)
+	if err := h(&boxer.Context{Step: 0, Total: 2}); err != nil {
+		t.Fatal(err)
+	} else if b, err := ioutil.ReadFile(f.Name()); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "\x1b]11;rgb:00/00/00\x07" {
+		t.Fatalf("unexpected output: %q", b)
+	}
+}
+
+// Ensure the Touch Bar handler updates the BetterTouchTool widget with the progress.
+func TestTouchBarHandler(t *testing.T) {
+	var u string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.OpenPath || len(args) != 2 || args[0] != "-g" {
+			t.Fatalf("unexpected command: %s %v", name, args)
+		}
+		u = args[1]
+		return nil, nil
+	}
+
+	h, err := boxer.NewTouchBarHandler(exec, "ABC-123", 4)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Step: 2, Total: 4, Remaining: 90 * time.Second}); err != nil {
+		t.Fatal(err)
+	} else if u != "btt://update_touch_bar_widget/?uuid=ABC-123&text=%E2%96%B0%E2%96%B0%E2%96%B1%E2%96%B1%202m" {
+		t.Fatalf("unexpected url: %s", u)
+	}
+}
+
+// Ensure the menu bar handler flashes in the background with the configured settings.
+func TestMenuBarHandler(t *testing.T) {
+	release := make(chan struct{})
+	executed := make(chan string, 1)
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		executed <- string(b)
+		<-release
+		return []byte("bad exec"), errors.New("")
+	}
+	h := boxer.NewMenuBarHandler(&boxer.MacDesktop{Exec: exec}, 5, 250*time.Millisecond)
+
+	// Start the flash and verify the script.
+	if err := h(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	} else if src := <-executed; !strings.Contains(src, "repeat 5 times") || !strings.Contains(src, "delay 0.25") {
+		t.Fatalf("unexpected script:\n\n%s", src)
+	}
+
+	// Ensure an overlapping flash is skipped without an error.
+	if err := h(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finish the flash and ensure its error is returned on the next call.
+	close(release)
+	for {
+		err := h(&boxer.Context{})
+		if err == nil {
+			time.Sleep(time.Millisecond)
+			continue
+		} else if err.Error() != `exec flash: bad exec` {
+			t.Fatal(err)
+		}
+		break
+	}
+	<-executed
+}
+
+// Ensure the enforcer covers the screen with the message in the background.
+func TestEnforcerHandler(t *testing.T) {
+	executed := make(chan string, 1)
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		executed <- string(b)
+		return nil, nil
+	}
+	h := boxer.NewEnforcerHandler(exec, boxer.EnforcerOverlay, 5*time.Minute, `Take a "break"`, true)
+
+	now := time.Now()
+	if err := h(&boxer.Context{IntervalStart: now, Now: now}); err != nil {
+		t.Fatal(err)
+	}
+	src := <-executed
+	if !strings.Contains(src, `$.NSTextField.labelWithString("Take a \"break\"")`) {
+		t.Fatalf("expected message:\n%s", src)
+	} else if !strings.Contains(src, `dateWithTimeIntervalSinceNow(300)`) {
+		t.Fatalf("expected duration:\n%s", src)
+	} else if !strings.Contains(src, `e.keyCode == 53`) {
+		t.Fatalf("expected escape hatch:\n%s", src)
+	}
+}
+
+// Ensure the enforcer locks the screen & ignores intervals that began earlier, such as at startup.
+func TestEnforcerHandler_Lock(t *testing.T) {
+	var n int
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.PMSetPath || !reflect.DeepEqual(args, []string{"displaysleepnow"}) {
+			t.Fatalf("unexpected command: %s %v", name, args)
+		}
+		n++
+		return nil, nil
+	}
+	h := boxer.NewEnforcerHandler(exec, boxer.EnforcerLock, 0, "", false)
+
+	now := time.Now()
+	if err := h(&boxer.Context{IntervalStart: now.Add(-10 * time.Minute), Now: now}); err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{IntervalStart: now, Now: now}); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected lock count: %d", n)
+	}
+}
+
+// Ensure an invalid enforcer action returns an error.
+func TestParseEnforcerAction_ErrInvalid(t *testing.T) {
+	if _, err := boxer.ParseEnforcerAction("shutdown"); err == nil || err.Error() != `invalid enforcer action: "shutdown"` {
+		t.Fatal(err)
+	}
+}
+
+// MustTempDir returns a path to a new temporary directory.
+func MustTempDir() string {
+	path, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
 // NewTempFile returns a path to a non-existent temporary file path.
 func NewTempFile() string {
 	f, _ := ioutil.TempFile("", "")
@@ -167,6 +1170,28 @@ func NewTempFile() string {
 	return f.Name()
 }
 
+// MustGenerateWallpaper generates a wallpaper to a temp file and returns the decoded image.
+func MustGenerateWallpaper(fn boxer.WallpaperGenerator, w, h int, pct float64) image.Image {
+	path := NewTempFile()
+	defer os.Remove(path)
+
+	if err := fn(path, w, h, 1, pct); err != nil {
+		panic(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	m, err := png.Decode(f)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
 // FilesEqual returns true if two files contain the same data.
 func FilesEqual(a, b string) bool {
 	if abuf, err := ioutil.ReadFile(a); err != nil {
@@ -177,3 +1202,53 @@ func FilesEqual(a, b string) bool {
 		return bytes.Equal(abuf, bbuf)
 	}
 }
+
+// Ensure focus is detected from the assertions file.
+func TestFileFocusDetector_Focused(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	d := &boxer.FileFocusDetector{Path: filepath.Join(dir, "Assertions.json")}
+
+	// A missing assertions file means no focus mode is enabled.
+	if v, err := d.Focused(); err != nil {
+		t.Fatal(err)
+	} else if v {
+		t.Fatal("expected not focused")
+	}
+
+	// Write an assertion to the file.
+	if err := ioutil.WriteFile(d.Path, []byte(`{"data":[{"storeAssertionRecords":[{"assertionDetails":{"assertionDetailsModeIdentifier":"com.apple.donotdisturb.mode.default"}}]}]}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.Focused(); err != nil {
+		t.Fatal(err)
+	} else if !v {
+		t.Fatal("expected focused")
+	}
+}
+
+// Ensure a presentation is detected by mirroring, screen sharing, or the frontmost app.
+func TestScreenPresentationDetector_Presenting(t *testing.T) {
+	for _, tt := range []struct {
+		profile, script string
+		presenting      bool
+	}{
+		{"Mirror: Off", "Safari\nfalse", false},
+		{"Mirror: On", "", true},
+		{"Mirror: Off", "Safari\ntrue", true},
+		{"Mirror: Off", "Keynote\nfalse", true},
+	} {
+		d := boxer.NewPresentationDetector(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+			if name == boxer.SystemProfilerPath {
+				return []byte(tt.profile), nil
+			}
+			return []byte(tt.script), nil
+		})
+		if v, err := d.Presenting(); err != nil {
+			t.Fatal(err)
+		} else if v != tt.presenting {
+			t.Fatalf("unexpected presenting for %q/%q: %v", tt.profile, tt.script, v)
+		}
+	}
+}
@@ -1,14 +1,23 @@
 package boxer_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"image/color"
+	"io/ioutil"
+	"log"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
 )
 
 // Ensure the ticker can tick for each new step and interval.
@@ -18,16 +27,17 @@ func TestTicker_Tick(t *testing.T) {
 
 	// Mock the current time.
 	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
-	ticker.Now = func() time.Time { return now }
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
 
 	// Setup command with a handler.
 	var stepN, intervalN int
 	cmd := boxer.Command{
 		Step:     1 * time.Minute,
 		Interval: 15 * time.Minute,
-		Handler: func(i, n int) error {
+		Handler: func(ctx *boxer.Context) error {
 			stepN++
-			if i == 0 {
+			if ctx.Step == 0 {
 				intervalN++
 			}
 			return nil
@@ -41,7 +51,443 @@ func TestTicker_Tick(t *testing.T) {
 	// Move forward 10 seconds at a time for 1h.
 	start := now
 	for i := time.Duration(0); i <= 1*time.Hour; i += 10 * time.Second {
-		now = start.Add(i)
+		clk.Set(start.Add(i))
+		ticker.Tick()
+	}
+
+	// Ensure the step and interval count are correct.
+	if stepN != 61 {
+		t.Fatalf("unexpected step count: %d", stepN)
+	} else if intervalN != 5 {
+		t.Fatalf("unexpected interval count: %d", intervalN)
+	}
+}
+
+// Ensure the ticker logs why each command does or does not execute.
+func TestTicker_Tick_Debug(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+
+	var buf bytes.Buffer
+	ticker := boxer.NewTicker()
+	ticker.Clock = clk
+	ticker.Debug = log.New(&buf, "", 0)
+	ticker.Commands = []boxer.Command{
+		{Name: "wallpaper", Step: time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { return nil }},
+		{Name: "sound", Step: time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { return nil }},
+	}
+	if err := ticker.Disable("sound"); err != nil {
+		t.Fatal(err)
+	}
+
+	ticker.Tick()
+	clk.Set(now.Add(10 * time.Second))
+	ticker.Tick()
+	ticker.Pause()
+	ticker.Tick()
+
+	if exp := "wallpaper: step 1/15: executing (interval=false, step=true, warning=false, progress=false)\n" +
+		"sound: step 1/15: disabled\n" +
+		"wallpaper: step 1/15: waiting until 00:01:00\n" +
+		"sound: step 1/15: disabled\n" +
+		"tick: paused\n"; buf.String() != exp {
+		t.Fatalf("unexpected log:\n%s", buf.String())
+	}
+}
+
+// Ensure the ticker passes the step's position & the label to the handler.
+func TestTicker_Tick_Context(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 10, 20, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+	ticker.SetLabel("write report")
+
+	var ctx *boxer.Context
+	ticker.Commands = []boxer.Command{{
+		Step:     5 * time.Minute,
+		Interval: 30 * time.Minute,
+		Handler:  func(c *boxer.Context) error { ctx = c; return nil },
+	}}
+	ticker.Tick()
+
+	if !reflect.DeepEqual(ctx, &boxer.Context{
+		Step:          4,
+		Total:         6,
+		IntervalStart: time.Date(2000, time.January, 1, 10, 0, 0, 0, time.UTC),
+		IntervalIndex: 20,
+		Remaining:     10 * time.Minute,
+		Now:           now,
+		Label:         "write report",
+	}) {
+		t.Fatalf("unexpected context: %#v", ctx)
+	}
+}
+
+// Ensure the ticker plans intervals clipped to the schedule.
+func TestTicker_Plan(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.ActiveHours = &boxer.TimeRange{Start: 9 * time.Hour, End: 10*time.Hour + 15*time.Minute}
+	ticker.Commands = []boxer.Command{{Name: "wallpaper", Interval: 30 * time.Minute}}
+
+	var a []string
+	from := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, r := range ticker.Plan(from, from.AddDate(0, 0, 2)) {
+		a = append(a, r.Command+" "+r.Start.Format("Jan 2 15:04")+"-"+r.End.Format("15:04"))
+	}
+	if !reflect.DeepEqual(a, []string{
+		"wallpaper Jan 1 09:00-09:30",
+		"wallpaper Jan 1 09:30-10:00",
+		"wallpaper Jan 1 10:00-10:15",
+		"wallpaper Jan 2 09:00-09:30",
+		"wallpaper Jan 2 09:30-10:00",
+		"wallpaper Jan 2 10:00-10:15",
+	}) {
+		t.Fatalf("unexpected plan: %#v", a)
+	}
+}
+
+// Ensure a planned interval ends when the active schedule rule changes.
+func TestTicker_Plan_Schedule(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Schedule = boxer.Schedule{
+		{Start: 9 * time.Hour, End: 10 * time.Hour, Interval: 25 * time.Minute},
+		{Start: 10 * time.Hour, End: 11 * time.Hour},
+	}
+	ticker.Commands = []boxer.Command{{Name: "wallpaper", Interval: 30 * time.Minute}}
+
+	var a []string
+	from := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, r := range ticker.Plan(from, from.AddDate(0, 0, 1)) {
+		a = append(a, r.Start.Format("15:04")+"-"+r.End.Format("15:04"))
+	}
+	if !reflect.DeepEqual(a, []string{"09:00-09:25", "09:25-09:50", "09:50-10:00", "10:00-10:30", "10:30-11:00"}) {
+		t.Fatalf("unexpected plan: %#v", a)
+	}
+}
+
+// Ensure the ticker applies each command's catch-up policy when several
+// steps pass between ticks.
+func TestTicker_Tick_CatchUp(t *testing.T) {
+	for _, tt := range []struct {
+		policy boxer.CatchUpPolicy
+		steps  []int
+	}{
+		{boxer.CatchUpLatest, []int{0, 4, 5}},
+		{boxer.CatchUpAll, []int{0, 1, 2, 3, 4, 5}},
+		{boxer.CatchUpSkip, []int{0, 5}},
+	} {
+		ticker := boxer.NewTicker()
+		clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+		ticker.Clock = clk
+
+		var steps []int
+		ticker.Commands = []boxer.Command{{
+			Step:     1 * time.Minute,
+			Interval: 15 * time.Minute,
+			CatchUp:  tt.policy,
+			Handler:  func(ctx *boxer.Context) error { steps = append(steps, ctx.Step); return nil },
+		}}
+
+		// Miss several steps between ticks & then tick normally.
+		ticker.Tick()
+		clk.Add(4 * time.Minute)
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+		ticker.Tick()
+
+		if !reflect.DeepEqual(steps, tt.steps) {
+			t.Fatalf("unexpected steps for policy %d: %v", tt.policy, steps)
+		}
+	}
+}
+
+// Ensure an invalid catch-up policy returns an error.
+func TestParseCatchUpPolicy_ErrInvalid(t *testing.T) {
+	if _, err := boxer.ParseCatchUpPolicy("some"); err == nil || err.Error() != `invalid catch-up policy: "some"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure intervals are aligned to the command's anchor instead of the wall clock.
+func TestTicker_Tick_Anchor(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 10, 7, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var starts []string
+	ticker.Commands = []boxer.Command{{
+		Interval:        25 * time.Minute,
+		Anchor:          now,
+		IntervalHandler: func(ctx *boxer.Context) error { starts = append(starts, clk.Now().Format("15:04")); return nil },
+	}}
+	for i := 0; i < 60; i++ {
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+	}
+
+	if !reflect.DeepEqual(starts, []string{"10:07", "10:32", "10:57"}) {
+		t.Fatalf("unexpected interval starts: %v", starts)
+	} else if cs := ticker.Status().Commands[0]; !cs.IntervalStart.Equal(time.Date(2000, time.January, 1, 10, 57, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected interval start: %s", cs.IntervalStart)
+	}
+}
+
+// Ensure starting the ticker begins a new interval for every command now.
+func TestTicker_Start(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 10, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var starts []string
+	ticker.Commands = []boxer.Command{{
+		Interval:        15 * time.Minute,
+		Offset:          9 * time.Hour,
+		IntervalHandler: func(ctx *boxer.Context) error { starts = append(starts, clk.Now().Format("15:04")); return nil },
+	}}
+	ticker.Tick()
+
+	clk.Add(7 * time.Minute)
+	if err := ticker.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+	}
+
+	if !reflect.DeepEqual(starts, []string{"10:00", "10:07", "10:22"}) {
+		t.Fatalf("unexpected interval starts: %v", starts)
+	}
+}
+
+// Ensure an interval begun by Start is held open past its end with the hold
+// overtime policy until Start is called again.
+func TestTicker_Tick_OvertimeHold(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 10, 3, 0, 0, time.UTC))
+	ticker.Clock = clk
+	ticker.Overtime = boxer.OvertimeHold
+
+	var a []string
+	var pcts []float64
+	ticker.Commands = []boxer.Command{{
+		Step:     5 * time.Minute,
+		Interval: 10 * time.Minute,
+		Handler: func(ctx *boxer.Context) error {
+			a = append(a, fmt.Sprintf("%s step %d/%d +%d %s", ctx.Now.Format("15:04"), ctx.Step+1, ctx.Total, ctx.Overtime, ctx.Remaining))
+			return nil
+		},
+		IntervalHandler: func(ctx *boxer.Context) error { a = append(a, ctx.Now.Format("15:04")+" interval"); return nil },
+		ProgressHandler: func(pct float64) error { pcts = append(pcts, pct); return nil },
+	}}
+	if err := ticker.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		ticker.Tick()
+		clk.Add(5 * time.Minute)
+	}
+	if s := ticker.Status(); s.Commands[0].Overtime != 2 || s.Commands[0].Step != 1 || s.Commands[0].Remaining != 0 || !s.Commands[0].IntervalStart.Equal(ticker.Anchor()) {
+		t.Fatalf("unexpected status: %#v", s.Commands[0])
+	}
+
+	if err := ticker.Start(); err != nil {
+		t.Fatal(err)
+	}
+	ticker.Tick()
+
+	if !reflect.DeepEqual(a, []string{
+		"10:03 interval", "10:03 step 1/2 +0 10m0s",
+		"10:08 step 2/2 +0 5m0s",
+		"10:13 step 2/2 +1 0s",
+		"10:18 step 2/2 +2 0s",
+		"10:23 interval", "10:23 step 1/2 +0 10m0s",
+	}) {
+		t.Fatalf("unexpected calls: %#v", a)
+	} else if !reflect.DeepEqual(pcts, []float64{0, 0.5, 1, 1, 0}) {
+		t.Fatalf("unexpected progress: %v", pcts)
+	}
+}
+
+// Ensure intervals begin at their end without an overtime policy.
+func TestTicker_Tick_OvertimeNone(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 10, 3, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var a []string
+	ticker.Commands = []boxer.Command{{
+		Step:     5 * time.Minute,
+		Interval: 10 * time.Minute,
+		Handler: func(ctx *boxer.Context) error {
+			a = append(a, fmt.Sprintf("step %d/%d +%d", ctx.Step+1, ctx.Total, ctx.Overtime))
+			return nil
+		},
+	}}
+	if err := ticker.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		ticker.Tick()
+		clk.Add(5 * time.Minute)
+	}
+
+	if !reflect.DeepEqual(a, []string{"step 1/2 +0", "step 2/2 +0", "step 1/2 +0"}) {
+		t.Fatalf("unexpected calls: %#v", a)
+	}
+}
+
+// Ensure overtime policies can be parsed.
+func TestParseOvertimePolicy(t *testing.T) {
+	if p, err := boxer.ParseOvertimePolicy(""); err != nil || p != boxer.OvertimeNone {
+		t.Fatalf("unexpected policy: %v, %v", p, err)
+	} else if p, err := boxer.ParseOvertimePolicy("hold"); err != nil || p != boxer.OvertimeHold {
+		t.Fatalf("unexpected policy: %v, %v", p, err)
+	} else if _, err := boxer.ParseOvertimePolicy("forever"); err == nil || err.Error() != `invalid overtime policy: "forever"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure intervals realign to the command's time of day every day.
+func TestTicker_Tick_Offset(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 8, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var starts []string
+	ticker.Commands = []boxer.Command{{
+		Interval:        25 * time.Minute,
+		Offset:          9 * time.Hour,
+		IntervalHandler: func(ctx *boxer.Context) error { starts = append(starts, clk.Now().Format("15:04")); return nil },
+	}}
+
+	// Tick around the time of day on consecutive days.
+	for _, d := range []time.Duration{40 * time.Minute, 25 * time.Minute, 23*time.Hour + 53*time.Minute, 2 * time.Minute} {
+		ticker.Tick()
+		clk.Add(d)
+	}
+	ticker.Tick()
+
+	if !reflect.DeepEqual(starts, []string{"08:00", "08:40", "09:05", "08:58", "09:00"}) {
+		t.Fatalf("unexpected interval starts: %v", starts)
+	}
+}
+
+// Ensure steps stay on the local hour when clocks spring forward & fall back
+// and that each change of the UTC offset is emitted.
+func TestTicker_Tick_DST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	for _, tt := range []struct {
+		start time.Time
+		steps []string
+	}{
+		// 2:00am EST jumps to 3:00am EDT.
+		{
+			start: time.Date(2023, time.March, 12, 5, 0, 0, 0, time.UTC),
+			steps: []string{"00:00 EST 0", "00:30 EST 1", "01:00 EST 0", "01:30 EST 1", "03:00 EDT 0", "03:30 EDT 1"},
+		},
+
+		// 2:00am EDT falls back to 1:00am EST.
+		{
+			start: time.Date(2023, time.November, 5, 4, 0, 0, 0, time.UTC),
+			steps: []string{"00:00 EDT 0", "00:30 EDT 1", "01:00 EDT 0", "01:30 EDT 1", "01:00 EST 0", "01:30 EST 1"},
+		},
+	} {
+		ticker := boxer.NewTicker()
+		ticker.Location = loc
+		clk := clock.NewMock(tt.start)
+		ticker.Clock = clk
+
+		var steps []string
+		ticker.Commands = []boxer.Command{{
+			Step:     30 * time.Minute,
+			Interval: 1 * time.Hour,
+			Handler: func(ctx *boxer.Context) error {
+				steps = append(steps, fmt.Sprintf("%s %d", ctx.IntervalStart.Add(time.Duration(ctx.Step)*30*time.Minute).Format("15:04 MST"), ctx.Step))
+				return nil
+			},
+			CatchUp: boxer.CatchUpAll,
+		}}
+
+		ch, unsubscribe := ticker.Subscribe(10)
+		for i := 0; i < 6; i++ {
+			ticker.Tick()
+			clk.Add(30 * time.Minute)
+		}
+		unsubscribe()
+
+		var changes int
+		for e := range ch {
+			if _, ok := e.(*boxer.ZoneChangedEvent); ok {
+				changes++
+			}
+		}
+
+		if !reflect.DeepEqual(steps, tt.steps) {
+			t.Fatalf("unexpected steps: %q", steps)
+		} else if changes != 1 {
+			t.Fatalf("unexpected zone changes: %d", changes)
+		}
+	}
+}
+
+// Ensure a daily time of day is kept on the wall clock on a DST transition day.
+func TestTicker_Tick_Offset_DST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	ticker := boxer.NewTicker()
+	ticker.Location = loc
+	clk := clock.NewMock(time.Date(2023, time.March, 12, 8, 55, 0, 0, loc))
+	ticker.Clock = clk
+
+	var starts []string
+	ticker.Commands = []boxer.Command{{
+		Interval:        25 * time.Minute,
+		Offset:          9 * time.Hour,
+		IntervalHandler: func(ctx *boxer.Context) error { starts = append(starts, ctx.IntervalStart.Format("15:04")); return nil },
+	}}
+
+	ticker.Tick()
+	clk.Add(5 * time.Minute)
+	ticker.Tick()
+
+	if !reflect.DeepEqual(starts, []string{"08:55", "09:00"}) {
+		t.Fatalf("unexpected interval starts: %v", starts)
+	}
+}
+
+// Ensure the ticker calls the interval handler only when a new interval begins.
+func TestTicker_Tick_IntervalHandler(t *testing.T) {
+	ticker := boxer.NewTicker()
+
+	// Mock the current time.
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	// Setup command with both a step & interval handler.
+	var stepN, intervalN int
+	ticker.Commands = append(ticker.Commands, boxer.Command{
+		Step:            1 * time.Minute,
+		Interval:        15 * time.Minute,
+		Handler:         func(ctx *boxer.Context) error { stepN++; return nil },
+		IntervalHandler: func(ctx *boxer.Context) error { intervalN++; return nil },
+	})
+
+	// Move forward 10 seconds at a time for 1h.
+	start := now
+	for i := time.Duration(0); i <= 1*time.Hour; i += 10 * time.Second {
+		clk.Set(start.Add(i))
 		ticker.Tick()
 	}
 
@@ -114,6 +560,53 @@ func TestTransposeColor(t *testing.T) {
 	}
 }
 
+// Ensure colors are transposed through hue instead of directly through RGB.
+func TestTransposeColorHSL(t *testing.T) {
+	for i, tt := range []struct {
+		a      color.Color
+		b      color.Color
+		pct    float64
+		result color.Color
+	}{
+		// 0. Transpose from red to blue through magenta.
+		{
+			a:      color.RGBA{R: 0xFF, G: 0x00, B: 0x00, A: 0xFF},
+			b:      color.RGBA{R: 0x00, G: 0x00, B: 0xFF, A: 0xFF},
+			pct:    0.5,
+			result: color.RGBA{R: 0xFF, G: 0x00, B: 0xFF, A: 0xFF},
+		},
+
+		// 1. Transpose from a gray keeps the hue of the other color.
+		{
+			a:      color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+			b:      color.RGBA{R: 0x00, G: 0xFF, B: 0x00, A: 0xFF},
+			pct:    0.5,
+			result: color.RGBA{R: 0x20, G: 0x60, B: 0x20, A: 0xFF},
+		},
+
+		// 2. Transpose with zero pct.
+		{
+			a:      color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF},
+			b:      color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+			pct:    0,
+			result: color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF},
+		},
+
+		// 3. Transpose translucent colors without their alpha premultiplied.
+		{
+			a:      color.RGBA{R: 0x80, G: 0x00, B: 0x00, A: 0x80},
+			b:      color.RGBA{R: 0x00, G: 0x00, B: 0x80, A: 0x80},
+			pct:    0.5,
+			result: color.RGBA{R: 0x80, G: 0x00, B: 0x80, A: 0x80},
+		},
+	} {
+		result := boxer.TransposeColorHSL(tt.a, tt.b, tt.pct)
+		if !reflect.DeepEqual(tt.result, result) {
+			t.Errorf("%d. mismatch:\n\nexp=%#v\n\ngot=%#v", i, tt.result, result)
+		}
+	}
+}
+
 // Ensure colors in the "#000000" format can be parsed.
 func TestParseColor_WithHash(t *testing.T) {
 	if c, err := boxer.ParseColor("#102030"); err != nil {
@@ -132,9 +625,947 @@ func TestParseColor_WithoutHash(t *testing.T) {
 	}
 }
 
+// Ensure colors in the "#000" shorthand format can be parsed.
+func TestParseColor_Shorthand(t *testing.T) {
+	if c, err := boxer.ParseColor("#1af"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 0x11, G: 0xAA, B: 0xFF, A: 255}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+}
+
+// Ensure colors in the "#00000000" & "#0000" formats are parsed with alpha.
+func TestParseColor_Alpha(t *testing.T) {
+	if c, err := boxer.ParseColor("#10203080"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 8, G: 16, B: 24, A: 128}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+
+	if c, err := boxer.ParseColor("#102030FF"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 16, G: 32, B: 48, A: 255}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+
+	if c, err := boxer.ParseColor("#1af8"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 9, G: 91, B: 136, A: 136}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+
+	if _, err := boxer.ParseColor("#1020304"); err == nil || err.Error() != `cannot parse color: "#1020304"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure colors are formatted as hex values that can be parsed again.
+func TestFormatColor(t *testing.T) {
+	if s := boxer.FormatColor(color.RGBA{R: 0x53, G: 0x4B, B: 0x4D, A: 0xFF}); s != "#534b4d" {
+		t.Fatalf("unexpected string: %s", s)
+	}
+
+	if s := boxer.FormatColor(color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x80}); s != "#10203080" {
+		t.Fatalf("unexpected string: %s", s)
+	}
+}
+
+// Ensure CSS color names can be parsed.
+func TestParseColor_Named(t *testing.T) {
+	if c, err := boxer.ParseColor("SteelBlue"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 70, G: 130, B: 180, A: 255}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+}
+
+// Ensure colors in the "rgb()" and "rgba()" formats can be parsed.
+func TestParseColor_RGB(t *testing.T) {
+	if c, err := boxer.ParseColor("rgb(16, 32, 48)"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 16, G: 32, B: 48, A: 255}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+
+	if c, err := boxer.ParseColor("rgba(200, 100, 50, 0.5)"); err != nil {
+		t.Fatal(err)
+	} else if c != (color.RGBA{R: 100, G: 50, B: 25, A: 128}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+
+	if _, err := boxer.ParseColor("rgb(256, 0, 0)"); err == nil || err.Error() != `cannot parse color: "rgb(256, 0, 0)"` {
+		t.Fatal(err)
+	}
+}
+
 // Ensure colors with an invalid format return an error.
 func TestParseColor_ErrInvalid(t *testing.T) {
 	if _, err := boxer.ParseColor("bad_color"); err == nil || err.Error() != `cannot parse color: "bad_color"` {
 		t.Fatal(err)
 	}
 }
+
+// Ensure the text logger only writes entries with errors.
+func TestTextLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	l := boxer.NewTextLogger(log.New(&buf, "", 0))
+	l.Log(&boxer.LogEntry{Command: "foo"})
+	l.Log(&boxer.LogEntry{Command: "bar", Err: errors.New("marker")})
+
+	if buf.String() != "bar: marker\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+}
+
+// Ensure the JSON logger writes each entry as a line of JSON.
+func TestJSONLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	l := boxer.NewJSONLogger(&buf)
+	l.Log(&boxer.LogEntry{
+		Time:     time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Command:  "wallpaper",
+		Step:     2,
+		Steps:    15,
+		Duration: 500 * time.Millisecond,
+		Err:      errors.New("marker"),
+	})
+	l.Log(&boxer.LogEntry{Command: "announcement", Steps: 1, Interval: true})
+
+	if exp := `{"time":"2000-01-01T00:00:00Z","command":"wallpaper","step":2,"steps":15,"interval":false,"duration":0.5,"error":"marker"}` + "\n" +
+		`{"time":"0001-01-01T00:00:00Z","command":"announcement","step":0,"steps":1,"interval":true,"duration":0}` + "\n"; buf.String() != exp {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+// Ensure the ticker reports the position and last error of each command.
+func TestTicker_Status(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = boxer.NewTextLogger(log.New(ioutil.Discard, "", 0))
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 20, 0, 0, time.UTC))
+	ticker.Commands = []boxer.Command{
+		{Name: "foo", Step: 5 * time.Minute, Interval: 30 * time.Minute, Handler: func(ctx *boxer.Context) error { return errors.New("marker") }},
+		{Name: "bar", Interval: 1 * time.Hour, IntervalHandler: func(ctx *boxer.Context) error { return nil }},
+	}
+	ticker.Tick()
+
+	s := ticker.Status()
+	if len(s.Commands) != 2 {
+		t.Fatalf("unexpected command count: %d", len(s.Commands))
+	} else if c := s.Commands[0]; c.Name != "foo" || c.Step != 4 || c.Steps != 6 || c.Elapsed != 1200 {
+		t.Fatalf("unexpected status(0): %#v", c)
+	} else if c.LastError != "marker" || c.LastErrorTime == nil || !c.LastErrorTime.Equal(s.Time) {
+		t.Fatalf("unexpected status(0) error: %#v", c)
+	} else if c := s.Commands[1]; c.Name != "bar" || c.Step != 0 || c.Steps != 1 || c.LastError != "" {
+		t.Fatalf("unexpected status(1): %#v", c)
+	}
+}
+
+// Ensure the ticker stops running once its context is cancelled.
+func TestTicker_Run(t *testing.T) {
+	ticker := boxer.NewTicker()
+
+	// Cancel the context from within the handler.
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int
+	ticker.Commands = []boxer.Command{{
+		Step: 1, Interval: 1,
+		Handler: func(*boxer.Context) error {
+			if n++; n == 3 {
+				cancel()
+			}
+			return nil
+		},
+	}}
+
+	if err := ticker.Run(ctx); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 3 {
+		t.Fatalf("unexpected tick count: %d", n)
+	}
+}
+
+// Ensure the ticker wakes at the next step boundary delayed by the jitter.
+func TestRun_Jitter(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := &timerClock{Mock: clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)), durations: make(chan time.Duration)}
+	ticker.Clock = clk
+	ticker.Commands = []boxer.Command{{Name: "wallpaper", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(*boxer.Context) error { return nil }}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- boxer.Run(ctx, ticker, boxer.RunOptions{Align: true, Jitter: 30 * time.Second}) }()
+
+	// Stop after the first sleep begins.
+	d := <-clk.durations
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d < 1*time.Minute || d >= 90*time.Second {
+		t.Fatalf("unexpected sleep: %s", d)
+	}
+}
+
+// Ensure the progress handler executes on every tick with the progress
+// through the interval while the step handler only executes on step edges.
+func TestTicker_Tick_Progress(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var steps []int
+	var progress []float64
+	ticker.Commands = []boxer.Command{{
+		Name: "hue", Step: 5 * time.Minute, Interval: 15 * time.Minute,
+		Handler:         func(ctx *boxer.Context) error { steps = append(steps, ctx.Step); return nil },
+		ProgressHandler: func(pct float64) error { progress = append(progress, pct); return nil },
+	}}
+
+	for i := 0; i < 4; i++ {
+		ticker.Tick()
+		clk.Add(150 * time.Second)
+	}
+
+	if !reflect.DeepEqual(steps, []int{0, 1}) {
+		t.Fatalf("unexpected steps: %v", steps)
+	} else if !reflect.DeepEqual(progress, []float64{0, 1.0 / 6, 2.0 / 6, 3.0 / 6}) {
+		t.Fatalf("unexpected progress: %v", progress)
+	}
+}
+
+// Ensure successful progress on every tick does not reset the failures of
+// the step handler.
+func TestTicker_Tick_Progress_MaxFailures(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+	ticker.MaxFailures = 3
+
+	var n int
+	ticker.Commands = []boxer.Command{{
+		Name: "hue", Step: 1 * time.Minute, Interval: 15 * time.Minute,
+		Handler:         func(ctx *boxer.Context) error { n++; return errors.New("marker") },
+		ProgressHandler: func(pct float64) error { return nil },
+	}}
+
+	for i := 0; i < 10; i++ {
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+	}
+
+	if n != 3 {
+		t.Fatalf("unexpected executions: %d", n)
+	} else if !ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to be disabled")
+	}
+}
+
+// Ensure the ticker wakes every progress interval between steps.
+func TestRun_ProgressInterval(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := &timerClock{Mock: clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)), durations: make(chan time.Duration)}
+	ticker.Clock = clk
+	ticker.Commands = []boxer.Command{{
+		Name: "hue", Step: 5 * time.Minute, Interval: 15 * time.Minute,
+		ProgressHandler:  func(pct float64) error { return nil },
+		ProgressInterval: 10 * time.Second,
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- boxer.Run(ctx, ticker, boxer.RunOptions{Align: true}) }()
+
+	d := <-clk.durations
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d != 10*time.Second {
+		t.Fatalf("unexpected sleep: %s", d)
+	}
+}
+
+// Ensure a command without an interval executes without panicking.
+func TestTicker_Tick_ZeroInterval(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var indexes []int
+	ticker.Commands = []boxer.Command{{
+		Name:    "a",
+		Handler: func(ctx *boxer.Context) error { indexes = append(indexes, ctx.IntervalIndex); return nil },
+	}}
+	for i := 0; i < 3; i++ {
+		ticker.Tick()
+		clk.Add(5 * time.Minute)
+	}
+
+	if !reflect.DeepEqual(indexes, []int{0, 0, 0}) {
+		t.Fatalf("unexpected interval indexes: %v", indexes)
+	}
+}
+
+// Ensure commands that set the label complete before other commands read it.
+func TestTicker_Tick_SetsLabel(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	var label string
+	ticker.Commands = []boxer.Command{
+		{Name: "reader", Interval: 15 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { label = ctx.Label; return nil }},
+		{Name: "writer", Interval: 15 * time.Minute, SetsLabel: true, IntervalHandler: func(ctx *boxer.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			ticker.SetLabel("fix login")
+			return nil
+		}},
+	}
+	ticker.Tick()
+
+	if label != "fix login" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+}
+
+// Ensure commands aligned to the start are anchored to the first tick by the
+// ticker's clock.
+func TestTicker_Tick_AlignStart(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	start := time.Date(2000, time.January, 1, 9, 7, 0, 0, time.UTC)
+	clk := clock.NewMock(start)
+	ticker.Clock = clk
+
+	var a []string
+	ticker.Commands = []boxer.Command{{
+		Name: "a", Step: 5 * time.Minute, Interval: 15 * time.Minute, AlignStart: true,
+		Handler: func(ctx *boxer.Context) error {
+			a = append(a, fmt.Sprintf("%s %d", ctx.IntervalStart.Format("15:04"), ctx.Step))
+			return nil
+		},
+	}}
+	for i := 0; i < 4; i++ {
+		ticker.Tick()
+		clk.Add(5 * time.Minute)
+	}
+
+	if !reflect.DeepEqual(a, []string{"09:07 0", "09:07 1", "09:07 2", "09:22 0"}) {
+		t.Fatalf("unexpected steps: %v", a)
+	} else if !ticker.Commands[0].Anchor.Equal(start) {
+		t.Fatalf("unexpected anchor: %s", ticker.Commands[0].Anchor)
+	}
+}
+
+// Ensure steps as short as the minimum step execute on their boundaries.
+func TestTicker_Tick_SubSecond(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := &timerClock{Mock: clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)), durations: make(chan time.Duration, 1)}
+	ticker.Clock = clk
+
+	steps := make(chan int, 10)
+	ticker.Commands = []boxer.Command{{
+		Name: "demo", Step: boxer.MinStep, Interval: 1 * time.Second,
+		Handler: func(ctx *boxer.Context) error { steps <- ctx.Step; return nil },
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- boxer.Run(ctx, ticker, boxer.RunOptions{Align: true}) }()
+
+	// Sleep until each step boundary & move the clock to it.
+	var a []int
+	for i := 0; i < 3; i++ {
+		a = append(a, <-steps)
+		d := <-clk.durations
+		if d != boxer.MinStep {
+			t.Fatalf("unexpected sleep: %s", d)
+		}
+		clk.Add(d)
+	}
+	a = append(a, <-steps)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(a, []int{0, 1, 2, 3}) {
+		t.Fatalf("unexpected steps: %v", a)
+	}
+}
+
+// Ensure a handler that panics is logged as failed & other handlers still run.
+func TestTicker_Tick_Panic(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	var mu sync.Mutex
+	var errs []string
+	ticker.Logger = LoggerFunc(func(e *boxer.LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Err != nil {
+			errs = append(errs, e.Command+": "+e.Err.Error())
+		}
+	})
+
+	var n int32
+	ticker.Commands = []boxer.Command{
+		{Name: "bad", Interval: 1 * time.Minute, Handler: func(*boxer.Context) error { panic("marker") }},
+		{Name: "good", Interval: 1 * time.Minute, Handler: func(*boxer.Context) error { atomic.AddInt32(&n, 1); return nil }},
+	}
+	ticker.Tick()
+
+	if len(errs) != 1 || errs[0] != "bad: panic: marker" {
+		t.Fatalf("unexpected errors: %v", errs)
+	} else if atomic.LoadInt32(&n) != 1 {
+		t.Fatalf("unexpected good handler count: %d", n)
+	}
+}
+
+// Ensure a command that exceeds its timeout is logged and skipped until it completes.
+func TestTicker_Tick_Timeout(t *testing.T) {
+	ticker := boxer.NewTicker()
+
+	// Capture log entries with errors.
+	var mu sync.Mutex
+	var errs []error
+	ticker.Logger = LoggerFunc(func(e *boxer.LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Err != nil {
+			errs = append(errs, e.Err)
+		}
+	})
+
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	// Block the slow handler until released. The fast handler should still run.
+	release := make(chan struct{})
+	var slowN int32
+	var fastN int
+	ticker.Commands = []boxer.Command{
+		{Name: "slow", Interval: 1 * time.Minute, Timeout: 10 * time.Millisecond, Handler: func(ctx *boxer.Context) error { atomic.AddInt32(&slowN, 1); <-release; return nil }},
+		{Name: "fast", Interval: 1 * time.Minute, Handler: func(ctx *boxer.Context) error { fastN++; return nil }},
+	}
+
+	// The first tick should time out the slow handler.
+	ticker.Tick()
+	mu.Lock()
+	if len(errs) != 1 || errs[0] != boxer.ErrHandlerTimeout {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	mu.Unlock()
+
+	// The next step should skip the slow handler since it is still running.
+	clk.Add(1 * time.Minute)
+	ticker.Tick()
+	mu.Lock()
+	if len(errs) != 2 || errs[1] != boxer.ErrHandlerRunning {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	mu.Unlock()
+
+	// Release the slow handler and tick until it executes again.
+	close(release)
+	for atomic.LoadInt32(&slowN) != 2 {
+		time.Sleep(time.Millisecond)
+		clk.Add(1 * time.Minute)
+		ticker.Tick()
+	}
+
+	if fastN < 3 {
+		t.Fatalf("unexpected fast handler count: %d", fastN)
+	}
+}
+
+// LoggerFunc is a function that implements boxer.Logger.
+type LoggerFunc func(e *boxer.LogEntry)
+
+func (fn LoggerFunc) Log(e *boxer.LogEntry) { fn(e) }
+
+// timerClock is a mock clock that sends the duration of every new timer.
+type timerClock struct {
+	*clock.Mock
+	durations chan time.Duration
+}
+
+func (c *timerClock) NewTimer(d time.Duration) *clock.Timer {
+	c.durations <- d
+	return c.Mock.NewTimer(d)
+}
+
+// Ensure a paused ticker does not execute handlers and resyncs when resumed.
+func TestTicker_Pause(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var steps []int
+	ticker.Commands = []boxer.Command{{
+		Step:     1 * time.Minute,
+		Interval: 15 * time.Minute,
+		Handler:  func(ctx *boxer.Context) error { steps = append(steps, ctx.Step); return nil },
+	}}
+
+	ticker.Tick()
+	ticker.Pause()
+	clk.Add(1 * time.Minute)
+	ticker.Tick()
+	clk.Add(90 * time.Second)
+	ticker.Tick()
+
+	// Resuming should execute the current step immediately.
+	ticker.Resume()
+	ticker.Tick()
+
+	if !reflect.DeepEqual(steps, []int{0, 2}) {
+		t.Fatalf("unexpected steps: %v", steps)
+	}
+}
+
+// Ensure a skipped interval does not execute handlers until the next interval.
+func TestTicker_Skip(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var steps []int
+	ticker.Commands = []boxer.Command{{
+		Step:     5 * time.Minute,
+		Interval: 15 * time.Minute,
+		Handler:  func(ctx *boxer.Context) error { steps = append(steps, ctx.Step); return nil },
+	}}
+
+	ticker.Tick()
+	ticker.Skip()
+	for i := 0; i < 5; i++ {
+		clk.Add(5 * time.Minute)
+		ticker.Tick()
+	}
+
+	if !reflect.DeepEqual(steps, []int{0, 0, 1, 2}) {
+		t.Fatalf("unexpected steps: %v", steps)
+	}
+}
+
+// Ensure a disabled command does not execute while other commands continue
+// and that it resyncs when enabled.
+func TestTicker_Disable(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var a, b []int
+	ticker.Commands = []boxer.Command{
+		{Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { a = append(a, ctx.Step); return nil }},
+		{Name: "b", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { b = append(b, ctx.Step); return nil }},
+	}
+
+	ticker.Tick()
+	if err := ticker.Disable("a"); err != nil {
+		t.Fatal(err)
+	} else if !ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to be disabled")
+	}
+	clk.Add(1 * time.Minute)
+	ticker.Tick()
+	clk.Add(90 * time.Second)
+	ticker.Tick()
+
+	// Enabling should execute the current step immediately.
+	if err := ticker.Enable("a"); err != nil {
+		t.Fatal(err)
+	}
+	ticker.Tick()
+
+	if !reflect.DeepEqual(a, []int{0, 2}) {
+		t.Fatalf("unexpected steps: %v", a)
+	} else if !reflect.DeepEqual(b, []int{0, 1, 2}) {
+		t.Fatalf("unexpected steps: %v", b)
+	} else if ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to be enabled")
+	}
+}
+
+// Ensure a command is disabled after MaxFailures consecutive failures & a
+// success resets the count.
+func TestTicker_Tick_MaxFailures(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+	ticker.MaxFailures = 2
+
+	// Fail every step except the second.
+	var steps []int
+	ticker.Commands = []boxer.Command{{Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error {
+		if steps = append(steps, ctx.Step); ctx.Step == 1 {
+			return nil
+		}
+		return errors.New("marker")
+	}}}
+
+	ch, unsubscribe := ticker.Subscribe(100)
+	for i := 0; i < 5; i++ {
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+	}
+	unsubscribe()
+
+	if !reflect.DeepEqual(steps, []int{0, 1, 2, 3}) {
+		t.Fatalf("unexpected steps: %v", steps)
+	} else if !ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to be disabled")
+	}
+	for e := range ch {
+		if e, ok := e.(*boxer.CommandDisabledEvent); ok {
+			if e.Command != "a" || e.Failures != 2 || e.Err.Error() != "marker" {
+				t.Fatalf("unexpected event: %#v", e)
+			}
+			return
+		}
+	}
+	t.Fatal("expected command disabled event")
+}
+
+// Ensure a fatal error disables the command immediately without retrying.
+func TestTicker_Tick_Fatal(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	var n int
+	ticker.Commands = []boxer.Command{{
+		Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute,
+		Retry:   boxer.RetryPolicy{Attempts: 3},
+		Handler: func(ctx *boxer.Context) error { n++; return boxer.Fatal(errors.New("marker")) },
+	}}
+	ticker.Tick()
+
+	if n != 1 {
+		t.Fatalf("unexpected executions: %d", n)
+	} else if !ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to be disabled")
+	} else if !boxer.IsFatal(boxer.Fatal(errors.New("marker"))) || boxer.IsFatal(errors.New("marker")) || boxer.Fatal(nil) != nil {
+		t.Fatal("unexpected classification")
+	} else if !boxer.IsFatal(fmt.Errorf("wrapped: %w", boxer.Fatal(errors.New("marker")))) {
+		t.Fatal("expected wrapped error to be fatal")
+	}
+}
+
+// Ensure suppressor errors are logged but do not count as failures of the command.
+func TestTicker_Tick_MaxFailures_SuppressorError(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+	ticker.MaxFailures = 2
+
+	var errs int
+	ticker.Logger = LoggerFunc(func(e *boxer.LogEntry) {
+		if e.Err != nil {
+			errs++
+		}
+	})
+	ticker.Suppressors = []boxer.Suppressor{
+		boxer.SuppressorFunc(func(now time.Time) (bool, error) { return false, errors.New("marker") }),
+		boxer.SuppressorFunc(func(now time.Time) (bool, error) { return true, nil }),
+	}
+
+	var n int
+	ticker.Commands = []boxer.Command{{
+		Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute, Intrusive: true,
+		Handler: func(ctx *boxer.Context) error { n++; return nil },
+	}}
+	for i := 0; i < 5; i++ {
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+	}
+
+	if n != 0 {
+		t.Fatalf("unexpected executions: %d", n)
+	} else if errs != 5 {
+		t.Fatalf("unexpected logged errors: %d", errs)
+	} else if ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to stay enabled")
+	}
+}
+
+// Ensure enabling or disabling an unknown command returns an error.
+func TestTicker_Disable_ErrCommandNotFound(t *testing.T) {
+	ticker := boxer.NewTicker()
+	if err := ticker.Disable("nope"); err != boxer.ErrCommandNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ticker.Enable("nope"); err != boxer.ErrCommandNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure commands can be added, removed & replaced between ticks. Added
+// commands execute their current step on the next tick.
+func TestTicker_AddCommand(t *testing.T) {
+	ticker := boxer.NewTicker()
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var a, b []int
+	cmdA := boxer.Command{Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { a = append(a, ctx.Step); return nil }}
+	cmdB := boxer.Command{Name: "b", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { b = append(b, ctx.Step); return nil }}
+	ticker.Commands = []boxer.Command{cmdA}
+	ticker.Tick()
+
+	// Add a command mid-step so it executes immediately.
+	clk.Add(90 * time.Second)
+	if err := ticker.AddCommand(cmdB); err != nil {
+		t.Fatal(err)
+	} else if err := ticker.AddCommand(cmdB); err != boxer.ErrCommandExists {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := ticker.AddCommand(boxer.Command{Name: "c"}); err != boxer.ErrInvalidInterval {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ticker.Tick()
+
+	// Remove the first command so it no longer executes.
+	if err := ticker.RemoveCommand("a"); err != nil {
+		t.Fatal(err)
+	} else if err := ticker.RemoveCommand("a"); err != boxer.ErrCommandNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clk.Add(1 * time.Minute)
+	ticker.Tick()
+
+	// Replace all commands.
+	ticker.ReplaceCommands([]boxer.Command{cmdA})
+	ticker.Tick()
+
+	if !reflect.DeepEqual(a, []int{0, 1, 2}) {
+		t.Fatalf("unexpected steps: %v", a)
+	} else if !reflect.DeepEqual(b, []int{1, 2}) {
+		t.Fatalf("unexpected steps: %v", b)
+	} else if s := ticker.Status(); len(s.Commands) != 1 || s.Commands[0].Name != "a" {
+		t.Fatalf("unexpected status: %#v", s.Commands)
+	}
+}
+
+// Ensure commands can be changed while the ticker is running.
+func TestTicker_AddCommand_Concurrent(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = boxer.NewTextLogger(log.New(ioutil.Discard, "", 0))
+	ticker.MaxSleep = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ticker.Run(ctx) }()
+
+	for i := 0; i < 100; i++ {
+		cmd := boxer.Command{Name: "a", Interval: time.Millisecond, Handler: func(ctx *boxer.Context) error { return nil }}
+		if err := ticker.AddCommand(cmd); err != nil {
+			t.Fatal(err)
+		} else if err := ticker.RemoveCommand("a"); err != nil {
+			t.Fatal(err)
+		}
+		ticker.ReplaceCommands([]boxer.Command{cmd})
+		ticker.ReplaceCommands(nil)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the warning handler executes once when the end of each interval approaches.
+func TestTicker_Tick_Warning(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var a []time.Duration
+	ticker.Commands = []boxer.Command{{
+		Interval:       30 * time.Minute,
+		Warning:        5 * time.Minute,
+		WarningHandler: func(remaining time.Duration) error { a = append(a, remaining); return nil },
+	}}
+
+	// Tick every minute for an hour.
+	for i := 0; i < 60; i++ {
+		clk.Set(now.Add(time.Duration(i) * time.Minute))
+		ticker.Tick()
+	}
+
+	if !reflect.DeepEqual(a, []time.Duration{5 * time.Minute, 5 * time.Minute}) {
+		t.Fatalf("unexpected warnings: %v", a)
+	}
+}
+
+// Ensure intrusive commands are skipped while suppressed but other commands still execute.
+func TestTicker_Tick_Suppressed(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var suppressed bool
+	ticker.Suppressors = []boxer.Suppressor{boxer.SuppressorFunc(func(now time.Time) (bool, error) {
+		return suppressed, nil
+	})}
+
+	var intrusive, quiet int
+	ticker.Commands = []boxer.Command{
+		{Interval: 5 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { intrusive++; return nil }, Intrusive: true},
+		{Interval: 5 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { quiet++; return nil }},
+	}
+
+	ticker.Tick()
+	suppressed = true
+	clk.Add(5 * time.Minute)
+	ticker.Tick()
+	suppressed = false
+	clk.Add(5 * time.Minute)
+	ticker.Tick()
+
+	if intrusive != 2 {
+		t.Fatalf("unexpected intrusive executions: %d", intrusive)
+	} else if quiet != 3 {
+		t.Fatalf("unexpected quiet executions: %d", quiet)
+	}
+}
+
+// Ensure a command's own suppressors skip it even if it is not intrusive.
+func TestTicker_Tick_CommandSuppressors(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var suppressed bool
+	s := boxer.SuppressorFunc(func(now time.Time) (bool, error) { return suppressed, nil })
+
+	var a, b int
+	ticker.Commands = []boxer.Command{
+		{Interval: 5 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { a++; return nil }, Suppressors: []boxer.Suppressor{s}},
+		{Interval: 5 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { b++; return nil }},
+	}
+
+	ticker.Tick()
+	suppressed = true
+	clk.Add(5 * time.Minute)
+	ticker.Tick()
+
+	if a != 1 {
+		t.Fatalf("unexpected suppressed executions: %d", a)
+	} else if b != 2 {
+		t.Fatalf("unexpected executions: %d", b)
+	}
+}
+
+// Ensure intrusive commands are skipped while snoozed but other commands still execute.
+func TestTicker_Snooze(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var intrusive, quiet int
+	ticker.Commands = []boxer.Command{
+		{Interval: 5 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { intrusive++; return nil }, Intrusive: true},
+		{Interval: 5 * time.Minute, IntervalHandler: func(ctx *boxer.Context) error { quiet++; return nil }},
+	}
+
+	ticker.Snooze(10 * time.Minute)
+	if s := ticker.Status(); s.SnoozedUntil == nil || !s.SnoozedUntil.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("unexpected snooze: %v", s.SnoozedUntil)
+	}
+	for i := 0; i < 3; i++ {
+		ticker.Tick()
+		clk.Add(5 * time.Minute)
+	}
+
+	// Ending the snooze early executes intrusive commands again.
+	ticker.Snooze(10 * time.Minute)
+	ticker.Snooze(0)
+	ticker.Tick()
+
+	if intrusive != 2 {
+		t.Fatalf("unexpected intrusive executions: %d", intrusive)
+	} else if quiet != 4 {
+		t.Fatalf("unexpected quiet executions: %d", quiet)
+	} else if s := ticker.Status(); s.SnoozedUntil != nil {
+		t.Fatalf("unexpected snooze: %v", s.SnoozedUntil)
+	}
+}
+
+// Ensure failed handlers are retried within the same step.
+func TestTicker_Tick_Retry(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = boxer.NewTextLogger(log.New(ioutil.Discard, "", 0))
+	ticker.Clock = clock.New()
+
+	var attempts int
+	ticker.Commands = []boxer.Command{{
+		Interval: 1 * time.Hour,
+		Handler: func(ctx *boxer.Context) error {
+			if attempts++; attempts < 3 {
+				return errors.New("marker")
+			}
+			return nil
+		},
+		Retry: boxer.RetryPolicy{Attempts: 5, Backoff: time.Millisecond},
+	}}
+	ticker.Tick()
+
+	if attempts != 3 {
+		t.Fatalf("unexpected attempts: %d", attempts)
+	} else if s := ticker.Status(); s.Commands[0].LastError != "" {
+		t.Fatalf("unexpected last error: %s", s.Commands[0].LastError)
+	}
+}
+
+// Ensure the ticker resyncs the current step after waking without firing
+// interval handlers late.
+func TestTicker_Tick_Wake(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+	ticker.WakeThreshold = 1 * time.Minute
+
+	var steps []int
+	var intervals int
+	ticker.Commands = []boxer.Command{{
+		Name:            "foo",
+		Step:            5 * time.Minute,
+		Interval:        15 * time.Minute,
+		Handler:         func(ctx *boxer.Context) error { steps = append(steps, ctx.Step); return nil },
+		IntervalHandler: func(ctx *boxer.Context) error { intervals++; return nil },
+	}}
+
+	ch, unsubscribe := ticker.Subscribe(10)
+
+	// Tick and then wake two hours & ten minutes later.
+	ticker.Tick()
+	clk.Add(2*time.Hour + 10*time.Minute)
+	ticker.Tick()
+
+	if !reflect.DeepEqual(steps, []int{0, 2}) {
+		t.Fatalf("unexpected steps: %v", steps)
+	} else if intervals != 1 {
+		t.Fatalf("unexpected interval executions: %d", intervals)
+	}
+
+	// Verify a wake event was emitted with the missed intervals.
+	unsubscribe()
+	for e := range ch {
+		if e, ok := e.(*boxer.WakeEvent); ok {
+			if !reflect.DeepEqual(e.Missed, map[string]int{"foo": 7}) {
+				t.Fatalf("unexpected missed intervals: %v", e.Missed)
+			}
+			return
+		}
+	}
+	t.Fatal("expected wake event")
+}
@@ -0,0 +1,50 @@
+package boxer
+
+import (
+	"fmt"
+	"time"
+)
+
+// BreakerPolicy represents when a persistently failing command is
+// quarantined. A quarantined command is skipped until its retry interval
+// elapses and then executes once. A success closes the breaker & a failure
+// quarantines the command again.
+type BreakerPolicy struct {
+	// The number of consecutive failures before the command is quarantined.
+	// If zero, the command is never quarantined.
+	Failures int
+
+	// The time the command is skipped while quarantined.
+	// Defaults to DefaultBreakerInterval.
+	Interval time.Duration
+}
+
+// DefaultBreakerInterval is the default time a command is quarantined.
+const DefaultBreakerInterval = 30 * time.Minute
+
+// interval returns the quarantine interval or the default if unset.
+func (p BreakerPolicy) interval() time.Duration {
+	if p.Interval <= 0 {
+		return DefaultBreakerInterval
+	}
+	return p.Interval
+}
+
+// QuarantineHandler is called once when a command is quarantined. It is not
+// called again while the command keeps failing its retries.
+type QuarantineHandler func(e *CommandQuarantinedEvent) error
+
+// NewQuarantineNotificationHandler returns a handler that displays a
+// notification when a command is quarantined. The title, subtitle & sound
+// are copied from n.
+func NewQuarantineNotificationHandler(notifier Notifier, n Notification) QuarantineHandler {
+	if n.Title == "" {
+		n.Title = DefaultNotificationTitle
+	}
+
+	return func(e *CommandQuarantinedEvent) error {
+		n := n
+		n.Message = fmt.Sprintf("%s failed %d times in a row & is paused until %s: %s", e.Command, e.Failures, e.Until.Format("3:04pm"), e.Err)
+		return notifier.Notify(&n)
+	}
+}
@@ -0,0 +1,93 @@
+package boxer_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// Ensure a failing command is quarantined, retried every breaker interval,
+// and only notifies once until it recovers.
+func TestTicker_Tick_Breaker(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var messages []string
+	ticker.QuarantineHandlers = []boxer.QuarantineHandler{boxer.NewQuarantineNotificationHandler(NotifierFunc(func(n *boxer.Notification) error {
+		messages = append(messages, n.Message)
+		return nil
+	}), boxer.Notification{})}
+
+	// Fail until the 11th minute.
+	var steps []int
+	ticker.Commands = []boxer.Command{{
+		Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute,
+		Breaker: boxer.BreakerPolicy{Failures: 2, Interval: 5 * time.Minute},
+		Handler: func(ctx *boxer.Context) error {
+			if steps = append(steps, ctx.Step); ctx.Step < 11 {
+				return errors.New("marker")
+			}
+			return nil
+		},
+	}}
+
+	for i := 0; i < 13; i++ {
+		ticker.Tick()
+		if i == 1 {
+			if until := ticker.Status().Commands[0].QuarantinedUntil; until == nil || !until.Equal(now.Add(6*time.Minute)) {
+				t.Fatalf("unexpected quarantine: %v", until)
+			}
+		}
+		clk.Add(1 * time.Minute)
+	}
+
+	if !reflect.DeepEqual(steps, []int{0, 1, 6, 11, 12}) {
+		t.Fatalf("unexpected steps: %v", steps)
+	} else if !reflect.DeepEqual(messages, []string{"a failed 2 times in a row & is paused until 12:06am: marker"}) {
+		t.Fatalf("unexpected messages: %q", messages)
+	} else if until := ticker.Status().Commands[0].QuarantinedUntil; until != nil {
+		t.Fatalf("expected quarantine to be lifted: %v", until)
+	}
+}
+
+// Ensure suppressor errors do not trip the breaker since the handler never ran.
+func TestTicker_Tick_Breaker_SuppressorError(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = LoggerFunc(func(*boxer.LogEntry) {})
+	clk := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Clock = clk
+
+	var quarantined int
+	ticker.QuarantineHandlers = []boxer.QuarantineHandler{func(e *boxer.CommandQuarantinedEvent) error {
+		quarantined++
+		return nil
+	}}
+
+	ticker.Commands = []boxer.Command{{
+		Name: "a", Step: 1 * time.Minute, Interval: 15 * time.Minute,
+		Breaker: boxer.BreakerPolicy{Failures: 2, Interval: 5 * time.Minute},
+		Suppressors: []boxer.Suppressor{
+			boxer.SuppressorFunc(func(now time.Time) (bool, error) { return false, errors.New("marker") }),
+			boxer.SuppressorFunc(func(now time.Time) (bool, error) { return true, nil }),
+		},
+		Handler: func(ctx *boxer.Context) error { return nil },
+	}}
+
+	for i := 0; i < 5; i++ {
+		ticker.Tick()
+		clk.Add(1 * time.Minute)
+	}
+
+	if quarantined != 0 {
+		t.Fatalf("unexpected quarantines: %d", quarantined)
+	} else if until := ticker.Status().Commands[0].QuarantinedUntil; until != nil {
+		t.Fatalf("unexpected quarantine: %v", until)
+	}
+}
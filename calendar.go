@@ -0,0 +1,245 @@
+package boxer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultCalendarRefreshInterval is the default time between calendar fetches.
+const DefaultCalendarRefreshInterval = 5 * time.Minute
+
+// CalendarEvent represents a single event on a calendar.
+type CalendarEvent struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// Contains returns true if t is within the event.
+func (e *CalendarEvent) Contains(t time.Time) bool {
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+// CalendarFetcher returns the events on a calendar.
+type CalendarFetcher func() ([]CalendarEvent, error)
+
+// CalendarSuppressor suppresses intrusive commands while an event is in
+// progress on any of its calendars. Events are cached between fetches.
+type CalendarSuppressor struct {
+	mu      sync.Mutex
+	events  []CalendarEvent
+	fetched time.Time
+
+	// The functions used to retrieve calendar events.
+	Fetchers []CalendarFetcher
+
+	// The time between fetching events from the calendars.
+	RefreshInterval time.Duration
+}
+
+// NewCalendarSuppressor returns a new suppressor for a set of calendars.
+func NewCalendarSuppressor(fetchers ...CalendarFetcher) *CalendarSuppressor {
+	return &CalendarSuppressor{
+		Fetchers:        fetchers,
+		RefreshInterval: DefaultCalendarRefreshInterval,
+	}
+}
+
+// Suppress returns true if an event is in progress at now.
+func (s *CalendarSuppressor) Suppress(now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Refetch events if the cache has expired.
+	if s.fetched.IsZero() || now.Sub(s.fetched) >= s.RefreshInterval {
+		var events []CalendarEvent
+		for _, fn := range s.Fetchers {
+			a, err := fn()
+			if err != nil {
+				return false, fmt.Errorf("fetch calendar: %s", err)
+			}
+			events = append(events, a...)
+		}
+		s.events, s.fetched = events, now
+	}
+
+	for i := range s.events {
+		if s.events[i].Contains(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NewICSCalendarFetcher returns a fetcher for an iCalendar file at a URL.
+func NewICSCalendarFetcher(url string) CalendarFetcher {
+	return func() ([]CalendarEvent, error) {
+		resp, err := http.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return ParseICS(resp.Body)
+	}
+}
+
+// ParseICS parses the events from an iCalendar file.
+//
+// All-day, cancelled, and free ("transparent") events are ignored as they
+// do not represent time that is busy. Recurrence rules are not expanded so
+// only the first occurrence of a recurring event is returned.
+func ParseICS(r io.Reader) ([]CalendarEvent, error) {
+	// Read lines and unfold continuation lines.
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var events []CalendarEvent
+	var e *CalendarEvent
+	var skip bool
+	for _, line := range lines {
+		name, params, value := parseICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			e, skip = &CalendarEvent{}, false
+		case name == "END" && value == "VEVENT":
+			if e != nil && !skip && !e.Start.IsZero() && e.End.After(e.Start) {
+				events = append(events, *e)
+			}
+			e = nil
+		case e == nil:
+			continue
+		case name == "SUMMARY":
+			e.Summary = value
+		case name == "STATUS":
+			skip = skip || value == "CANCELLED"
+		case name == "TRANSP":
+			skip = skip || value == "TRANSPARENT"
+		case name == "DTSTART", name == "DTEND":
+			// Ignore all-day events.
+			if params["VALUE"] == "DATE" || len(value) == 8 {
+				skip = true
+				continue
+			}
+
+			t, err := parseICSTime(value, params["TZID"])
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %s", strings.ToLower(name), err)
+			}
+			if name == "DTSTART" {
+				e.Start = t
+			} else {
+				e.End = t
+			}
+		}
+	}
+	return events, nil
+}
+
+// parseICSLine splits an iCalendar content line into its name, parameters & value.
+func parseICSLine(line string) (name string, params map[string]string, value string) {
+	i := strings.Index(line, ":")
+	if i == -1 {
+		return line, nil, ""
+	}
+	value = line[i+1:]
+
+	a := strings.Split(line[:i], ";")
+	name = strings.ToUpper(a[0])
+	params = make(map[string]string)
+	for _, p := range a[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return name, params, value
+}
+
+// parseICSTime parses an iCalendar date-time. Times ending in "Z" are UTC
+// and all other times are in the tzid location or local time, if unset.
+func parseICSTime(value, tzid string) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	loc := time.Local
+	if tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// WriteICS writes events to w as an iCalendar file. Times are written in UTC
+// and stamp is used as the time the events were created. Each event's UID is
+// derived from its times so re-importing an export updates existing events.
+func WriteICS(w io.Writer, events []CalendarEvent, stamp time.Time) error {
+	const layout = "20060102T150405Z"
+
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//benbjohnson//boxer//EN", "CALSCALE:GREGORIAN"}
+	for _, e := range events {
+		start, end := e.Start.UTC().Format(layout), e.End.UTC().Format(layout)
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+start+"-"+end+"@boxer",
+			"DTSTAMP:"+stamp.UTC().Format(layout),
+			"DTSTART:"+start,
+			"DTEND:"+end,
+			"SUMMARY:"+escapeICSText(e.Summary),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeICSText escapes the special characters of an iCalendar text value.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 octets into continuation lines.
+// Lines are only split between UTF-8 characters.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package boxer_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure timed events are parsed from an iCalendar file.
+func TestParseICS(t *testing.T) {
+	events, err := boxer.ParseICS(strings.NewReader(strings.Replace(`BEGIN:VCALENDAR
+BEGIN:VEVENT
+SUMMARY:Standup
+DTSTART:20000101T090000Z
+DTEND:20000101T091500Z
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Planning with a
+  long title
+DTSTART;TZID=America/Denver:20000101T100000
+DTEND;TZID=America/Denver:20000101T110000
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20000101
+DTEND;VALUE=DATE:20000102
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Cancelled
+STATUS:CANCELLED
+DTSTART:20000101T120000Z
+DTEND:20000101T130000Z
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n", -1)))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 2 {
+		t.Fatalf("unexpected event count: %d", len(events))
+	}
+
+	if e := events[0]; e.Summary != "Standup" || !e.Start.Equal(time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC)) || !e.End.Equal(time.Date(2000, 1, 1, 9, 15, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+	if e := events[1]; e.Summary != "Planning with a long title" || !e.Start.Equal(time.Date(2000, 1, 1, 17, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
+
+// Ensure the calendar suppressor is active during events and caches fetched events.
+func TestCalendarSuppressor_Suppress(t *testing.T) {
+	var n int
+	s := boxer.NewCalendarSuppressor(func() ([]boxer.CalendarEvent, error) {
+		n++
+		return []boxer.CalendarEvent{{
+			Start: time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC),
+			End:   time.Date(2000, 1, 1, 10, 0, 0, 0, time.UTC),
+		}}, nil
+	})
+
+	for _, tt := range []struct {
+		t   time.Time
+		exp bool
+	}{
+		{time.Date(2000, 1, 1, 8, 59, 0, 0, time.UTC), false},
+		{time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC), true},
+		{time.Date(2000, 1, 1, 9, 59, 0, 0, time.UTC), true},
+		{time.Date(2000, 1, 1, 10, 0, 0, 0, time.UTC), false},
+	} {
+		if v, err := s.Suppress(tt.t); err != nil {
+			t.Fatal(err)
+		} else if v != tt.exp {
+			t.Fatalf("unexpected suppression at %s: %v", tt.t, v)
+		}
+	}
+
+	// Events are refetched once every refresh interval.
+	if n != 2 {
+		t.Fatalf("unexpected fetch count: %d", n)
+	}
+}
+
+// Ensure fetch errors are returned from the calendar suppressor.
+func TestCalendarSuppressor_Suppress_ErrFetch(t *testing.T) {
+	s := boxer.NewCalendarSuppressor(func() ([]boxer.CalendarEvent, error) {
+		return nil, errors.New("marker")
+	})
+	if _, err := s.Suppress(time.Now()); err == nil || err.Error() != `fetch calendar: marker` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure events written as an iCalendar file can be parsed back.
+func TestWriteICS(t *testing.T) {
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := boxer.WriteICS(&buf, []boxer.CalendarEvent{
+		{Summary: "Timebox", Start: start, End: start.Add(30 * time.Minute)},
+		{Summary: "Deep work; " + strings.Repeat("x", 80), Start: start.Add(30 * time.Minute), End: start.Add(1 * time.Hour)},
+	}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line not folded: %q", line)
+		}
+	}
+	if !strings.Contains(buf.String(), "UID:20000101T090000Z-20000101T093000Z@boxer\r\n") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	events, err := boxer.ParseICS(&buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 2 {
+		t.Fatalf("unexpected event count: %d", len(events))
+	} else if e := events[0]; e.Summary != "Timebox" || !e.Start.Equal(start) || !e.End.Equal(start.Add(30*time.Minute)) {
+		t.Fatalf("unexpected event: %#v", e)
+	} else if e := events[1]; e.Summary != `Deep work\; `+strings.Repeat("x", 80) {
+		t.Fatalf("unexpected summary: %q", e.Summary)
+	}
+}
@@ -0,0 +1,154 @@
+// Package clock provides an abstraction of the system clock so the passage
+// of time can be controlled in tests and simulations.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock represents an interface to the current time and to timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for d to elapse and then sends the time on the channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a timer that fires once d has elapsed.
+	NewTimer(d time.Duration) *Timer
+}
+
+// Timer represents a single event. The time is sent on C when it fires.
+type Timer struct {
+	C    <-chan time.Time
+	stop func() bool
+}
+
+// Stop prevents the timer from firing. Returns false if the timer has
+// already fired or been stopped.
+func (t *Timer) Stop() bool { return t.stop() }
+
+// New returns a clock backed by the system clock.
+func New() Clock { return &clock{} }
+
+// clock represents the system clock.
+type clock struct{}
+
+func (c *clock) Now() time.Time                         { return time.Now() }
+func (c *clock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (c *clock) NewTimer(d time.Duration) *Timer {
+	t := time.NewTimer(d)
+	return &Timer{C: t.C, stop: t.Stop}
+}
+
+// Scaled represents a clock that runs at a multiple of the system clock's
+// speed, starting from an arbitrary time.
+type Scaled struct {
+	start  time.Time // simulated start time
+	origin time.Time // system time at start
+	speed  float64
+}
+
+// NewScaled returns a clock that begins at start and advances speed times
+// faster than the system clock.
+func NewScaled(start time.Time, speed float64) *Scaled {
+	return &Scaled{start: start, origin: time.Now(), speed: speed}
+}
+
+// Now returns the current scaled time.
+func (c *Scaled) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.origin)) * c.speed))
+}
+
+// After waits for d of scaled time to elapse and then sends the scaled time.
+func (c *Scaled) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C
+}
+
+// NewTimer returns a timer that fires once d of scaled time has elapsed.
+func (c *Scaled) NewTimer(d time.Duration) *Timer {
+	ch := make(chan time.Time, 1)
+	t := time.AfterFunc(time.Duration(float64(d)/c.speed), func() { ch <- c.Now() })
+	return &Timer{C: ch, stop: t.Stop}
+}
+
+// Mock represents a clock that only moves when it is set or added to.
+// Timers fire, in order, as the clock passes their deadlines.
+type Mock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*mockTimer
+}
+
+// mockTimer represents a timer waiting on a mock clock.
+type mockTimer struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewMock returns a mock clock set to now.
+func NewMock(now time.Time) *Mock {
+	return &Mock{now: now}
+}
+
+// Now returns the current time of the mock clock.
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// After waits for the clock to move forward by d and then sends the time.
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.NewTimer(d).C
+}
+
+// NewTimer returns a timer that fires once the clock moves forward by d.
+func (m *Mock) NewTimer(d time.Duration) *Timer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &mockTimer{deadline: m.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- m.now
+		return &Timer{C: t.c, stop: func() bool { return false }}
+	}
+	m.timers = append(m.timers, t)
+	return &Timer{C: t.c, stop: func() bool { return m.remove(t) }}
+}
+
+// Add moves the clock forward by d.
+func (m *Mock) Add(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Set moves the clock to t and fires any timers whose deadline has passed.
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = t
+
+	// Fire expired timers in the order of their deadlines.
+	sort.SliceStable(m.timers, func(i, j int) bool { return m.timers[i].deadline.Before(m.timers[j].deadline) })
+	for len(m.timers) > 0 && !m.timers[0].deadline.After(t) {
+		m.timers[0].c <- t
+		m.timers = m.timers[1:]
+	}
+}
+
+// remove removes a timer. Returns false if the timer has already fired.
+func (m *Mock) remove(t *mockTimer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.timers {
+		if m.timers[i] == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// Ensure the mock clock fires timers once their deadline passes.
+func TestMock_Set(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	m := clock.NewMock(now)
+
+	a, b := m.NewTimer(1*time.Minute), m.After(2*time.Minute)
+	m.Add(90 * time.Second)
+
+	select {
+	case v := <-a.C:
+		if !v.Equal(now.Add(90 * time.Second)) {
+			t.Fatalf("unexpected time: %s", v)
+		}
+	default:
+		t.Fatal("expected timer to fire")
+	}
+
+	select {
+	case <-b:
+		t.Fatal("unexpected timer fire")
+	default:
+	}
+
+	m.Add(30 * time.Second)
+	select {
+	case <-b:
+	default:
+		t.Fatal("expected timer to fire")
+	}
+}
+
+// Ensure a stopped mock timer does not fire.
+func TestMock_Timer_Stop(t *testing.T) {
+	m := clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	timer := m.NewTimer(1 * time.Minute)
+	if !timer.Stop() {
+		t.Fatal("expected timer to stop")
+	} else if timer.Stop() {
+		t.Fatal("expected timer to already be stopped")
+	}
+
+	m.Add(1 * time.Minute)
+	select {
+	case <-timer.C:
+		t.Fatal("unexpected timer fire")
+	default:
+	}
+}
+
+// Ensure the scaled clock runs faster than the system clock.
+func TestScaled(t *testing.T) {
+	start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewScaled(start, 3600)
+
+	select {
+	case <-c.After(1 * time.Minute):
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	if d := c.Now().Sub(start); d < 1*time.Minute {
+		t.Fatalf("unexpected elapsed time: %s", d)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// AutomationApps returns the apps boxer sends Apple events to with config, in
+// the order their permission prompts are triggered. Returns nil if the
+// desktop is not macOS.
+func AutomationApps(config *Config) []string {
+	if desktopName(config) != "macos" {
+		return nil
+	}
+
+	// The Finder & System Events are used for the wallpaper, desktop size,
+	// dark mode, & flashing the menu bar.
+	apps := []string{"Finder", "System Events"}
+	if config.Calendar.Enabled && (len(config.Calendar.URLs) == 0 || len(config.Calendar.Calendars) > 0) {
+		apps = append(apps, "Calendar")
+	}
+	return apps
+}
+
+// PreflightAutomation requests permission to control each app in order before
+// the ticker starts. Apps that have not been granted before are announced so
+// the user expects the macOS prompts. Granted apps are recorded at path.
+//
+// Returns an error with instructions if any permission is denied, rather than
+// failing every step afterward.
+func (m *Main) PreflightAutomation(exec boxer.CommandExecutor, path string, apps []string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read automation file: %s", err)
+	}
+	granted := make(map[string]bool)
+	for _, app := range strings.Split(string(buf), "\n") {
+		granted[app] = true
+	}
+
+	var changed bool
+	for _, app := range apps {
+		if !granted[app] {
+			m.Logger.Printf("Requesting permission to control %s, click \"OK\" if macOS asks...", app)
+		}
+
+		if err := boxer.RequestAutomation(exec, app); err != nil {
+			if _, ok := err.(*boxer.AutomationError); ok {
+				return fmt.Errorf("%s, then restart boxer", err)
+			}
+			return fmt.Errorf("request automation %s: %s", app, err)
+		}
+
+		if !granted[app] {
+			granted[app], changed = true, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	var a []string
+	for app := range granted {
+		if app != "" {
+			a = append(a, app)
+		}
+	}
+	sort.Strings(a)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return fmt.Errorf("mkdir: %s", err)
+	} else if err := ioutil.WriteFile(path, []byte(strings.Join(a, "\n")+"\n"), 0666); err != nil {
+		return fmt.Errorf("write automation file: %s", err)
+	}
+	return nil
+}
+
+// DefaultAutomationPath returns the default path of the granted automation
+// permissions. The default path is the "automation" file in the data
+// directory.
+func DefaultAutomationPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "automation"), nil
+}
+
+// desktopName returns the configured desktop or the detected desktop if
+// none is set.
+func desktopName(config *Config) string {
+	if config.Desktop != "" {
+		return config.Desktop
+	}
+	return boxer.DetectDesktop(os.Getenv)
+}
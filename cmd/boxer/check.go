@@ -0,0 +1,754 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/benbjohnson/boxer"
+)
+
+// RunCheckConfig validates a config file and prints every problem found.
+// If no path is provided then the default path is used. With -contrast, it
+// also warns about wallpaper colors that are hard to tell apart.
+func (m *Main) RunCheckConfig(args []string) error {
+	fs := flag.NewFlagSet("boxer-check-config", flag.ContinueOnError)
+	contrast := fs.Bool("contrast", false, "warn about colors that are hard to tell apart")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 1 {
+		return fmt.Errorf("usage: boxer check-config [-contrast] [path]")
+	}
+
+	path := ""
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	} else {
+		str, err := DefaultConfigPath()
+		if err != nil {
+			return fmt.Errorf("default config path: %s", err)
+		}
+		path = str
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	problems := CheckConfig(string(buf))
+	for _, p := range problems {
+		m.printConfigProblem(path, "", p)
+	}
+
+	// Warnings are only printed for a valid config & do not fail the check.
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	} else if *contrast {
+		for _, p := range CheckConfigContrast(string(buf)) {
+			m.printConfigProblem(path, "warning: ", p)
+		}
+	}
+	fmt.Fprintf(m.Stdout, "%s: ok\n", path)
+	return nil
+}
+
+// printConfigProblem prints p with its line, if known.
+func (m *Main) printConfigProblem(path, prefix string, p *ConfigProblem) {
+	if p.Line > 0 {
+		fmt.Fprintf(m.Stdout, "%s:%d: %s%s\n", path, p.Line, prefix, p.Message)
+		fmt.Fprintf(m.Stdout, "\t%s\n", strings.TrimSpace(p.Text))
+	} else {
+		fmt.Fprintf(m.Stdout, "%s: %s%s\n", path, prefix, p.Message)
+	}
+}
+
+// ConfigProblem represents a single problem found in a config file.
+type ConfigProblem struct {
+	Key     string // dotted key path, e.g. "wallpaper.step" or "schedule.0.start"
+	Message string
+	Line    int    // line number, if known
+	Text    string // text of the line, if known
+}
+
+// CheckConfig parses & validates the TOML config in src and returns all
+// problems found, annotated with their line in src where possible.
+func CheckConfig(src string) []*ConfigProblem {
+	lines := strings.Split(src, "\n")
+
+	// Expansion stops at the first environment variable that is unset or
+	// cannot be used so only it is reported.
+	expanded, err := ExpandConfig(src, os.LookupEnv)
+	if e, ok := err.(*ExpandError); ok {
+		return []*ConfigProblem{{
+			Message: e.Message(),
+			Line:    e.Line,
+			Text:    lines[e.Line-1],
+		}}
+	}
+
+	// Decoding stops at the first syntax or type error so only it is reported.
+	config := NewConfig()
+	md, err := toml.Decode(expanded, &config)
+	if err != nil {
+		p := &ConfigProblem{Message: err.Error()}
+		if n, ok := parseErrorLine(err.Error()); ok && n <= len(lines) {
+			p.Line, p.Text = n, lines[n-1]
+		}
+		return []*ConfigProblem{p}
+	}
+
+	config.md = md
+
+	// Profiles are decoded first so their keys are not reported as unknown.
+	profileProblems := config.validateProfiles()
+
+	var problems []*ConfigProblem
+	for _, key := range md.Undecoded() {
+		problems = append(problems, &ConfigProblem{Key: key.String(), Message: "unknown key"})
+	}
+	problems = append(problems, config.Validate()...)
+	problems = append(problems, profileProblems...)
+
+	annotateConfigProblems(lines, problems)
+	return problems
+}
+
+// CheckConfigContrast returns a problem for each wallpaper foreground in the
+// valid TOML config in src that is hard to tell apart from its background,
+// including with color blindness. Use CheckConfig to validate src first.
+func CheckConfigContrast(src string) []*ConfigProblem {
+	expanded, err := ExpandConfig(src, os.LookupEnv)
+	if err != nil {
+		return nil
+	}
+	config := NewConfig()
+	if _, err := toml.Decode(expanded, &config); err != nil {
+		return nil
+	}
+
+	problems := config.Wallpaper.ContrastProblems()
+	annotateConfigProblems(strings.Split(src, "\n"), problems)
+	return problems
+}
+
+// annotateConfigProblems attaches line context to each problem with a key &
+// prefixes its message with the key.
+func annotateConfigProblems(lines []string, problems []*ConfigProblem) {
+	for _, p := range problems {
+		if p.Key == "" {
+			continue
+		}
+		p.Message = p.Key + ": " + p.Message
+		if n := configKeyLine(lines, p.Key); n > 0 {
+			p.Line, p.Text = n, lines[n-1]
+		}
+	}
+}
+
+// ContrastProblems returns a problem for each foreground that is hard to
+// tell apart from its background. Foregrounds & backgrounds are paired in
+// order, as they are drawn. Palette colors are checked against the palette
+// key & colors that cannot be parsed are skipped.
+func (wc *WallpaperConfig) ContrastProblems() []*ConfigProblem {
+	key := "wallpaper"
+	if wc.Palette != "" && len(wc.Foregrounds) == 0 && len(wc.Backgrounds) == 0 {
+		key = "wallpaper.palette"
+	}
+	wc, err := wc.WithPalette()
+	if err != nil {
+		return nil
+	}
+
+	problems := contrastProblems(key, wc.Foregrounds, wc.Backgrounds)
+	for i, pc := range wc.Rotation {
+		problems = append(problems, contrastProblems("wallpaper.rotation."+strconv.Itoa(i), pc.Foregrounds, pc.Backgrounds)...)
+	}
+	if wc.Dark != nil {
+		problems = append(problems, contrastProblems("wallpaper.dark", wc.Dark.Foregrounds, wc.Dark.Backgrounds)...)
+	}
+	for i, pc := range wc.Profiles {
+		problems = append(problems, contrastProblems("wallpaper.profile."+strconv.Itoa(i), pc.Foregrounds, pc.Backgrounds)...)
+	}
+	return problems
+}
+
+// contrastProblems returns a problem for each pair of foreground & background
+// colors that is hard to tell apart. A single color is paired with each color
+// of the other list. Problems for the same pair are combined.
+func contrastProblems(key string, foregrounds, backgrounds []string) []*ConfigProblem {
+	n := len(foregrounds)
+	if len(backgrounds) > n {
+		n = len(backgrounds)
+	}
+
+	var problems []*ConfigProblem
+	for i := 0; i < n; i++ {
+		fgs, bgs := foregrounds[i%len(foregrounds)], backgrounds[i%len(backgrounds)]
+		fg, err := boxer.ParseColor(fgs)
+		if err != nil {
+			continue
+		}
+		bg, err := boxer.ParseColor(bgs)
+		if err != nil {
+			continue
+		}
+
+		warnings := boxer.CheckContrast(fg, bg)
+		if len(warnings) == 0 {
+			continue
+		}
+
+		// Only report color blindness if the colors are fine otherwise.
+		var msg string
+		if w := warnings[0]; w.Vision == boxer.NormalVision {
+			msg = fmt.Sprintf("%q on %q has too little contrast (%.1f:1)", fgs, bgs, w.Ratio)
+		} else {
+			visions := make([]string, len(warnings))
+			for j, w := range warnings {
+				visions[j] = fmt.Sprintf("%s (contrast %.1f:1)", w.Vision, w.Ratio)
+			}
+			msg = fmt.Sprintf("%q on %q is hard to tell apart with %s", fgs, bgs, strings.Join(visions, ", "))
+		}
+		problems = append(problems, &ConfigProblem{
+			Key:     key + ".foregrounds",
+			Message: fmt.Sprintf("%s, try %q", msg, boxer.FormatColor(warnings[0].Suggestion)),
+		})
+	}
+	return problems
+}
+
+// configTablePath returns the path of a table name with the index of the
+// latest element of each enclosing array of tables, such as "command.0.options"
+// for "command.options".
+func configTablePath(name string, arrays map[string]int) string {
+	var path string
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if i > 0 {
+			path += "."
+		}
+		path += part
+		if i < len(parts)-1 && arrays[path] > 0 {
+			path += "." + strconv.Itoa(arrays[path]-1)
+		}
+	}
+	return path
+}
+
+// parseErrorLine returns the line number from a TOML parse error message.
+func parseErrorLine(msg string) (int, bool) {
+	const prefix = "Near line "
+	if !strings.HasPrefix(msg, prefix) {
+		return 0, false
+	}
+	msg = msg[len(prefix):]
+	if i := strings.IndexByte(msg, ' '); i != -1 {
+		msg = msg[:i]
+	}
+	n, err := strconv.Atoi(msg)
+	return n, err == nil
+}
+
+// configKeyLine returns the 1-based line that defines key in lines. Indexes
+// into arrays of tables are specified as path elements (e.g. "schedule.0.start").
+// Returns zero if the key cannot be found.
+func configKeyLine(lines []string, key string) int {
+	var table string
+	arrays := make(map[string]int)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "[["):
+			name := configTablePath(strings.TrimSpace(strings.Trim(line, "[]")), arrays)
+			table = name + "." + strconv.Itoa(arrays[name])
+			arrays[name]++
+			if table == key {
+				return i + 1
+			}
+
+		case strings.HasPrefix(line, "["):
+			if table = configTablePath(strings.TrimSpace(strings.Trim(line, "[]")), arrays); table == key {
+				return i + 1
+			}
+
+		case strings.Contains(line, "=") && !strings.HasPrefix(line, "#"):
+			k := strings.TrimSpace(line[:strings.Index(line, "=")])
+			if table != "" {
+				k = table + "." + k
+			}
+			if k == key {
+				return i + 1
+			}
+		}
+	}
+
+	// Fall back to the table header if the key itself is not in the file.
+	if i := strings.LastIndex(key, "."); i != -1 {
+		return configKeyLine(lines, key[:i])
+	}
+	return 0
+}
+
+// validateProfiles returns the problems with each profile applied to the
+// config, except for those the config has without a profile. Keys are
+// prefixed with "profile.NAME.".
+func (c *Config) validateProfiles() []*ConfigProblem {
+	seen := make(map[string]bool)
+	for _, p := range c.Validate() {
+		seen[p.Key+": "+p.Message] = true
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []*ConfigProblem
+	for _, name := range names {
+		prefix := "profile." + name
+		other, err := c.WithProfile(name)
+		if err != nil {
+			problems = append(problems, &ConfigProblem{Key: prefix, Message: err.Error()})
+			continue
+		}
+
+		for _, p := range other.Validate() {
+			if !seen[p.Key+": "+p.Message] {
+				p.Key = prefix + "." + p.Key
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+// Validate returns all problems with the values in the config.
+// Only enabled sections are validated.
+func (c *Config) Validate() []*ConfigProblem {
+	var problems []*ConfigProblem
+	add := func(key, format string, v ...interface{}) {
+		problems = append(problems, &ConfigProblem{Key: key, Message: fmt.Sprintf(format, v...)})
+	}
+
+	switch c.LogFormat {
+	case "", "text", "json":
+	default:
+		add("log_format", "must be %q or %q", "text", "json")
+	}
+	switch c.Desktop {
+	case "", "macos", "gnome", "kde", "sway":
+	default:
+		add("desktop", "unknown desktop %q, expected \"macos\", \"gnome\", \"kde\", or \"sway\"", c.Desktop)
+	}
+	if c.WakeThreshold.Duration < 0 {
+		add("wake_threshold", "must not be negative")
+	}
+	if c.MaxFailures < 0 {
+		add("max_failures", "must not be negative")
+	}
+	if c.Breaker.Failures < 0 {
+		add("breaker.failures", "must not be negative")
+	}
+	if c.Breaker.Interval.Duration < 0 {
+		add("breaker.interval", "must not be negative")
+	}
+	if _, err := boxer.NewNotifier(c.Breaker.Backend, nil); err != nil {
+		add("breaker.notifier", "unknown notifier %q, expected \"osascript\", \"native\", \"terminal-notifier\", \"notify-send\", or \"none\"", c.Breaker.Backend)
+	}
+	if d := c.Resolution.Duration; d < 0 {
+		add("resolution", "must not be negative")
+	} else if d > 0 && d < boxer.MinStep {
+		add("resolution", "must be at least %s", boxer.MinStep)
+	}
+	if c.Jitter.Duration < 0 {
+		add("jitter", "must not be negative")
+	}
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			add("timezone", "unknown time zone %q, expected a name such as \"America/New_York\" or \"UTC\"", c.Timezone)
+		}
+	}
+	if _, err := boxer.ParseOvertimePolicy(c.Overtime); err != nil {
+		add("overtime", "invalid overtime policy %q, expected \"none\" or \"hold\"", c.Overtime)
+	}
+	for _, path := range c.Exec.Allow {
+		if !filepath.IsAbs(path) {
+			add("exec.allow", "path %q must be absolute", path)
+		}
+	}
+
+	if c.ActiveHours.Start != "" || c.ActiveHours.End != "" {
+		if _, err := ParseTimeOfDay(c.ActiveHours.Start); err != nil {
+			add("active_hours.start", "invalid time %q, expected a time such as \"9:00am\"", c.ActiveHours.Start)
+		}
+		if _, err := ParseTimeOfDay(c.ActiveHours.End); err != nil {
+			add("active_hours.end", "invalid time %q, expected a time such as \"5:00pm\"", c.ActiveHours.End)
+		}
+	}
+
+	for i, sc := range c.Schedule {
+		prefix := "schedule." + strconv.Itoa(i)
+		if _, err := ParseWeekdays(sc.Days); err != nil {
+			add(prefix+".days", "%s", err)
+		}
+		if _, err := ParseTimeOfDay(sc.Start); err != nil {
+			add(prefix+".start", "invalid time %q, expected a time such as \"9:00am\"", sc.Start)
+		}
+		if _, err := ParseTimeOfDay(sc.End); err != nil {
+			add(prefix+".end", "invalid time %q, expected a time such as \"5:00pm\"", sc.End)
+		}
+		if sc.Step.Duration < 0 {
+			add(prefix+".step", "must not be negative")
+		} else if sc.Interval.Duration < 0 {
+			add(prefix+".interval", "must not be negative")
+		} else if sc.Step.Duration > 0 && sc.Interval.Duration > 0 && sc.Interval.Duration%sc.Step.Duration != 0 {
+			add(prefix+".step", "step (%s) must divide interval (%s) evenly", sc.Step.Duration, sc.Interval.Duration)
+		}
+	}
+
+	if c.Summary.Enabled {
+		sc := &c.Summary
+		if !c.Journal.Enabled {
+			add("summary.enabled", "journal must be enabled")
+		}
+		if sc.At != "" {
+			if _, err := ParseTimeOfDay(sc.At); err != nil {
+				add("summary.at", "invalid time %q, expected a time such as \"5:00pm\"", sc.At)
+			}
+		} else if c.ActiveHours.End == "" {
+			add("summary.at", "time required if active hours are not set")
+		}
+		if _, err := boxer.NewNotifier(sc.Backend, nil); err != nil {
+			add("summary.notifier", "unknown notifier %q, expected \"osascript\", \"native\", \"terminal-notifier\", \"notify-send\", or \"none\"", sc.Backend)
+		}
+	}
+
+	if c.Goal.Enabled {
+		gc := &c.Goal
+		if !c.Journal.Enabled {
+			add("goal.enabled", "journal must be enabled")
+		}
+		if _, err := boxer.ParseGoalPeriod(gc.Period); err != nil {
+			add("goal.period", "invalid period %q, expected \"day\" or \"week\"", gc.Period)
+		}
+		if gc.Target <= 0 {
+			add("goal.target", "must be greater than zero")
+		}
+		if _, err := boxer.NewNotifier(gc.Backend, nil); err != nil {
+			add("goal.notifier", "unknown notifier %q, expected \"osascript\", \"native\", \"terminal-notifier\", \"notify-send\", or \"none\"", gc.Backend)
+		}
+	}
+
+	if c.Calendar.Enabled && c.Calendar.RefreshInterval.Duration <= 0 {
+		add("calendar.refresh_interval", "must be greater than zero")
+	}
+
+	if c.Wallpaper.Enabled {
+		wc := &c.Wallpaper
+		problems = append(problems, validateCommand("wallpaper", wc.Step, wc.Interval, wc.Timeout, wc.Retries, wc.CatchUp, wc.Align)...)
+
+		if other, err := wc.WithPalette(); err != nil {
+			add("wallpaper.palette", "unknown palette %q, expected one of: %s", wc.Palette, strings.Join(boxer.PaletteNames(), ", "))
+		} else {
+			wc = other
+		}
+
+		switch wc.Style {
+		case "", "solid", "gradient", "ring", "segmented", "grid", "image":
+		default:
+			add("wallpaper.style", "invalid style %q, expected \"solid\", \"gradient\", \"ring\", \"segmented\", \"grid\", or \"image\"", wc.Style)
+		}
+		if wc.GoalCaption && !c.Goal.Enabled {
+			add("wallpaper.goal_caption", "goal must be enabled")
+		}
+		if (wc.Style == "segmented" || wc.Style == "grid") && wc.Step.Duration <= 0 {
+			add("wallpaper.step", "step required for %s style", wc.Style)
+		}
+		if wc.Style == "grid" && wc.Step.Duration > 0 && wc.GridRows > 0 && wc.GridColumns > 0 {
+			if n := int(wc.Interval.Duration / wc.Step.Duration); wc.GridRows*wc.GridColumns < n {
+				add("wallpaper.grid_rows", "grid of %dx%d cannot fit %d steps", wc.GridColumns, wc.GridRows, n)
+			}
+		}
+		if wc.GridRows < 0 || wc.GridColumns < 0 || wc.GridGap < 0 || wc.GridRadius < 0 {
+			add("wallpaper.grid_rows", "grid settings must not be negative")
+		}
+		if wc.Style == "image" {
+			if wc.Image == "" {
+				add("wallpaper.image", "image required for image style")
+			} else if _, err := os.Stat(wc.Image); err != nil {
+				add("wallpaper.image", "%s", err)
+			}
+		}
+		if _, err := boxer.ParseWallpaperSetter(wc.Backend); err != nil {
+			add("wallpaper.backend", "invalid backend %q, expected \"osascript\", \"native\", or \"spaces\"", wc.Backend)
+		}
+		placement, err := boxer.ParseBarPlacement(wc.BarPlacement)
+		if err != nil {
+			add("wallpaper.bar_placement", "invalid placement %q, expected \"top\", \"bottom\", or \"left\"", wc.BarPlacement)
+		}
+		fill, err := boxer.ParseFillDirection(wc.BarFill)
+		if err != nil {
+			add("wallpaper.bar_fill", "invalid direction %q, expected \"down\", \"up\", \"left\", or \"right\"", wc.BarFill)
+		}
+		bar := boxer.WallpaperBar{Placement: placement, Fill: fill, Thickness: wc.BarThickness, Margin: wc.BarMargin}
+		if err := bar.Validate(); err != nil {
+			add("wallpaper.bar_fill", "%s", err)
+		}
+		if wc.BarOpacity < 0 || wc.BarOpacity > 1 {
+			add("wallpaper.bar_opacity", "must be between 0 and 1")
+		}
+		if _, err := boxer.ParseImageFormat(wc.Format); err != nil {
+			add("wallpaper.format", "invalid format %q, expected \"png\" or \"jpeg\"", wc.Format)
+		}
+		if wc.Workers < 0 {
+			add("wallpaper.workers", "must not be negative")
+		}
+		if wc.Quality < 0 || wc.Quality > 100 {
+			add("wallpaper.quality", "must be between 1 and 100")
+		}
+		if _, err := boxer.ParsePNGCompression(wc.Compression); err != nil {
+			add("wallpaper.compression", "invalid compression %q, expected \"default\", \"none\", \"fast\", or \"best\"", wc.Compression)
+		}
+
+		if len(wc.Times) > 2 {
+			add("wallpaper.times", "too many times specified, expected at most 2")
+		}
+		var times []time.Time
+		var sun bool
+		for _, s := range wc.Times {
+			if s == "sunrise" || s == "sunset" {
+				sun = true
+				continue
+			}
+			t, err := time.Parse("3:04pm", s)
+			if err != nil {
+				add("wallpaper.times", "invalid time %q, expected a time such as \"9:00am\", \"sunrise\", or \"sunset\"", s)
+				continue
+			}
+			times = append(times, t)
+		}
+		if len(times) == 2 && times[0].After(times[1]) {
+			add("wallpaper.times", "times are out of order")
+		} else if len(wc.Times) == 2 && wc.Times[0] == "sunset" && wc.Times[1] == "sunrise" {
+			add("wallpaper.times", "times are out of order")
+		}
+
+		if sun && (wc.Latitude == nil || wc.Longitude == nil) {
+			add("wallpaper.times", "latitude & longitude required for sunrise & sunset times")
+		}
+		if wc.Latitude != nil && (*wc.Latitude < -90 || *wc.Latitude > 90) {
+			add("wallpaper.latitude", "must be between -90 and 90")
+		}
+		if wc.Longitude != nil && (*wc.Longitude < -180 || *wc.Longitude > 180) {
+			add("wallpaper.longitude", "must be between -180 and 180")
+		}
+
+		problems = append(problems, validateColors("wallpaper.foregrounds", "foreground", wc.Foregrounds)...)
+		problems = append(problems, validateColors("wallpaper.backgrounds", "background", wc.Backgrounds)...)
+		if _, err := boxer.ParseColor(wc.Overdue); wc.Overdue != "" && err != nil {
+			add("wallpaper.overdue", "%s", err)
+		}
+
+		for i, pc := range wc.Rotation {
+			prefix := "wallpaper.rotation." + strconv.Itoa(i)
+			problems = append(problems, validateColors(prefix+".foregrounds", "foreground", pc.Foregrounds)...)
+			problems = append(problems, validateColors(prefix+".backgrounds", "background", pc.Backgrounds)...)
+		}
+
+		if wc.Dark != nil {
+			problems = append(problems, validateColors("wallpaper.dark.foregrounds", "foreground", wc.Dark.Foregrounds)...)
+			problems = append(problems, validateColors("wallpaper.dark.backgrounds", "background", wc.Dark.Backgrounds)...)
+		}
+
+		for i, pc := range wc.Profiles {
+			prefix := "wallpaper.profile." + strconv.Itoa(i)
+			if _, err := ParseTimeOfDay(pc.Start); err != nil {
+				add(prefix+".start", "invalid time %q, expected a time such as \"9:00am\"", pc.Start)
+			}
+			if _, err := ParseTimeOfDay(pc.End); err != nil {
+				add(prefix+".end", "invalid time %q, expected a time such as \"5:00pm\"", pc.End)
+			}
+			problems = append(problems, validateColors(prefix+".foregrounds", "foreground", pc.Foregrounds)...)
+			problems = append(problems, validateColors(prefix+".backgrounds", "background", pc.Backgrounds)...)
+		}
+
+		if _, err := boxer.ParseColorSpace(wc.ColorSpace); err != nil {
+			add("wallpaper.color_space", "%s", err)
+		}
+		if wc.MaxCacheSize < 0 {
+			add("wallpaper.max_cache_size", "must not be negative")
+		}
+		if wc.DesktopSizeTTL.Duration < 0 {
+			add("wallpaper.desktop_size_ttl", "must not be negative")
+		}
+		if wc.DesktopWidth < 0 || wc.DesktopHeight < 0 || (wc.DesktopWidth > 0) != (wc.DesktopHeight > 0) {
+			add("wallpaper.desktop_width", "desktop width & height must both be set to a positive size")
+		}
+	}
+
+	if c.MenuBar.Enabled {
+		mc := &c.MenuBar
+		problems = append(problems, validateCommand("menu_bar", Duration{}, mc.Interval, mc.Timeout, mc.Retries, mc.CatchUp, mc.Align)...)
+		if mc.Flashes < 0 {
+			add("menu_bar.flashes", "must not be negative")
+		}
+		if mc.Delay.Duration < 0 {
+			add("menu_bar.delay", "must not be negative")
+		}
+	}
+
+	if c.Announcement.Enabled {
+		ac := &c.Announcement
+		problems = append(problems, validateCommand("announcement", Duration{}, ac.Interval, ac.Timeout, ac.Retries, ac.CatchUp, ac.Align)...)
+		if _, err := boxer.NewNotifier(ac.Backend, nil); err != nil {
+			add("announcement.notifier", "unknown notifier %q, expected \"osascript\", \"native\", \"terminal-notifier\", \"notify-send\", or \"none\"", ac.Backend)
+		}
+		for _, a := range ac.Actions {
+			if a.Label == "" {
+				add("announcement.actions", "label required")
+			}
+			if _, err := ControlPath(a.Command); err != nil {
+				add("announcement.actions", "%s", err)
+			}
+		}
+		if _, err := boxer.NewAnnouncementHandler(boxer.NopNotifier{}, boxer.Notification{}, ac.Template, nil); err != nil {
+			add("announcement.template", "%s", err)
+		}
+		if ac.Source != "" {
+			if _, err := boxer.NewMessageSource(ac.Source, nil); err != nil {
+				add("announcement.source", "%s", err)
+			}
+		}
+	}
+
+	if c.Toggl.Enabled {
+		tc := &c.Toggl
+		problems = append(problems, validateCommand("toggl", Duration{}, tc.Interval, tc.Timeout, tc.Retries, tc.CatchUp, tc.Align)...)
+		switch tc.Service {
+		case "", "toggl", "clockify":
+		default:
+			add("toggl.service", "invalid service %q, expected \"toggl\" or \"clockify\"", tc.Service)
+		}
+		if tc.Token == "" {
+			add("toggl.token", "api token required")
+		}
+		if tc.Workspace == "" {
+			add("toggl.workspace", "workspace required")
+		} else if _, err := strconv.ParseInt(tc.Workspace, 10, 64); err != nil && tc.Service != "clockify" {
+			add("toggl.workspace", "invalid toggl workspace %q, expected a numeric ID", tc.Workspace)
+		}
+	}
+
+	if c.Sound.Enabled {
+		sc := &c.Sound
+		problems = append(problems, validateCommand("sound", sc.Step, sc.Interval, sc.Timeout, sc.Retries, sc.CatchUp, sc.Align)...)
+		if sc.Volume < 0 || sc.Volume > 1 {
+			add("sound.volume", "must be between 0 and 1")
+		}
+	}
+
+	for i, cc := range c.Commands {
+		prefix := "command." + strconv.Itoa(i)
+		if cc.Type == "" {
+			add(prefix+".type", "type required")
+		} else if !isHandlerFactory(cc.Type) {
+			add(prefix+".type", "unknown command type %q", cc.Type)
+		}
+		problems = append(problems, validateCommand(prefix, cc.Step, cc.Interval, cc.Timeout, cc.Retries, cc.CatchUp, cc.Align)...)
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Tickers {
+		tc := &c.Tickers[i]
+		prefix := "ticker." + strconv.Itoa(i)
+		if tc.Name == "" {
+			add(prefix+".name", "name required")
+		} else if names[tc.Name] {
+			add(prefix+".name", "duplicate ticker name %q", tc.Name)
+		}
+		names[tc.Name] = true
+
+		for j, cc := range tc.CommandConfigs() {
+			prefix := prefix + ".command." + strconv.Itoa(j)
+			if cc.Type == "" {
+				add(prefix+".type", "type required")
+			} else if !isHandlerFactory(cc.Type) {
+				add(prefix+".type", "unknown command type %q", cc.Type)
+			}
+			problems = append(problems, validateCommand(prefix, cc.Step, cc.Interval, cc.Timeout, cc.Retries, cc.CatchUp, cc.Align)...)
+		}
+	}
+
+	return problems
+}
+
+// validateCommand returns problems with the timing settings of a command section.
+func validateCommand(prefix string, step, interval, timeout Duration, retries int, catchUp, align string) []*ConfigProblem {
+	var problems []*ConfigProblem
+	add := func(key, format string, v ...interface{}) {
+		problems = append(problems, &ConfigProblem{Key: prefix + "." + key, Message: fmt.Sprintf(format, v...)})
+	}
+
+	if interval.Duration <= 0 {
+		add("interval", "must be greater than zero")
+	} else if interval.Duration < boxer.MinStep {
+		add("interval", "must be at least %s", boxer.MinStep)
+	}
+	if step.Duration < 0 {
+		add("step", "must not be negative")
+	} else if step.Duration > 0 && step.Duration < boxer.MinStep {
+		add("step", "must be at least %s", boxer.MinStep)
+	} else if step.Duration > 0 && interval.Duration > 0 && interval.Duration%step.Duration != 0 {
+		add("step", "step (%s) must divide interval (%s) evenly", step.Duration, interval.Duration)
+	}
+	if timeout.Duration < 0 {
+		add("timeout", "must not be negative")
+	}
+	if retries < 0 {
+		add("retries", "must not be negative")
+	}
+	if _, err := boxer.ParseCatchUpPolicy(catchUp); err != nil {
+		add("catch_up", "invalid policy %q, expected \"latest\", \"all\", or \"skip\"", catchUp)
+	}
+	if align != "" && align != "start" {
+		if _, err := ParseTimeOfDay(align); err != nil {
+			add("align", "invalid alignment %q, expected \"start\" or a time such as \"9:00am\"", align)
+		}
+	}
+	return problems
+}
+
+// validateColors returns problems with a list of one or two colors.
+func validateColors(key, name string, a []string) []*ConfigProblem {
+	var problems []*ConfigProblem
+	if len(a) == 0 {
+		problems = append(problems, &ConfigProblem{Key: key, Message: name + " color required"})
+	} else if len(a) > 2 {
+		problems = append(problems, &ConfigProblem{Key: key, Message: "too many " + name + " colors specified, expected at most 2"})
+	}
+	for _, s := range a {
+		if _, err := boxer.ParseColor(s); err != nil {
+			problems = append(problems, &ConfigProblem{Key: key, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// isHandlerFactory returns true if a handler factory is registered under name.
+func isHandlerFactory(name string) bool {
+	for _, s := range boxer.HandlerFactoryNames() {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunControl sends a control command, such as "pause", to the running daemon.
+func (m *Main) RunControl(name string, args []string) error {
+	fs := flag.NewFlagSet("boxer-"+name, flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	tickerName := fs.String("ticker", "", "name of the ticker to control")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Send the command to the daemon.
+	var status boxer.Status
+	if err := NewTickerClient(*socketPath, *tickerName).Do("POST", "/"+name, &status); err != nil {
+		return err
+	}
+
+	if status.Paused {
+		fmt.Fprintln(m.Stdout, "boxer is paused")
+	} else {
+		fmt.Fprintln(m.Stdout, "boxer is running")
+	}
+	return nil
+}
+
+// RunCommandControl enables or disables a command in the running daemon.
+func (m *Main) RunCommandControl(name string, args []string) error {
+	fs := flag.NewFlagSet("boxer-"+name, flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	tickerName := fs.String("ticker", "", "name of the ticker to control")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: boxer %s COMMAND", name)
+	}
+	command := fs.Arg(0)
+
+	var status boxer.Status
+	if err := NewTickerClient(*socketPath, *tickerName).Do("POST", "/"+name+"?"+url.Values{"command": {command}}.Encode(), &status); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(m.Stdout, "%s %sd\n", command, name)
+	return nil
+}
+
+// RunSnooze skips intrusive commands in the running daemon, such as
+// announcements & menu bar flashes, for a duration. A duration of zero ends
+// the snooze.
+func (m *Main) RunSnooze(args []string) error {
+	fs := flag.NewFlagSet("boxer-snooze", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	tickerName := fs.String("ticker", "", "name of the ticker to control")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: boxer snooze DURATION")
+	}
+
+	var status boxer.Status
+	if err := NewTickerClient(*socketPath, *tickerName).Do("POST", "/snooze?"+url.Values{"duration": {fs.Arg(0)}}.Encode(), &status); err != nil {
+		return err
+	}
+
+	if status.SnoozedUntil == nil {
+		fmt.Fprintln(m.Stdout, "snooze ended")
+	} else {
+		fmt.Fprintf(m.Stdout, "snoozed until %s\n", status.SnoozedUntil.Local().Format("3:04pm"))
+	}
+	return nil
+}
+
+// RunStart begins a new interval in the running daemon now instead of at the
+// next boundary of the clock. Pass -reset to realign intervals to the clock.
+func (m *Main) RunStart(args []string) error {
+	fs := flag.NewFlagSet("boxer-start", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	tickerName := fs.String("ticker", "", "name of the ticker to control")
+	reset := fs.Bool("reset", false, "realign intervals to the clock")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := "/start"
+	if *reset {
+		path += "?" + url.Values{"reset": {"true"}}.Encode()
+	}
+
+	var status boxer.Status
+	if err := NewTickerClient(*socketPath, *tickerName).Do("POST", path, &status); err != nil {
+		return err
+	}
+
+	if status.Anchor == nil {
+		fmt.Fprintln(m.Stdout, "intervals aligned to the clock")
+	} else {
+		fmt.Fprintf(m.Stdout, "interval started at %s\n", status.Anchor.Local().Format("3:04pm"))
+	}
+	return nil
+}
+
+// RunLabel sets the label of the running daemon. The label is cleared if
+// no text is specified.
+func (m *Main) RunLabel(args []string) error {
+	fs := flag.NewFlagSet("boxer-label", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	tickerName := fs.String("ticker", "", "name of the ticker to control")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	label := strings.Join(fs.Args(), " ")
+
+	var status boxer.Status
+	if err := NewTickerClient(*socketPath, *tickerName).Do("POST", "/label?"+url.Values{"label": {label}}.Encode(), &status); err != nil {
+		return err
+	}
+
+	if status.Label == "" {
+		fmt.Fprintln(m.Stdout, "label cleared")
+	} else {
+		fmt.Fprintf(m.Stdout, "label: %s\n", status.Label)
+	}
+	return nil
+}
+
+// RunSwitchProfile replaces the commands of the running daemon with those of
+// a [profile.NAME] section of its config. The default commands are restored
+// if no profile is specified.
+func (m *Main) RunSwitchProfile(args []string) error {
+	fs := flag.NewFlagSet("boxer-switch-profile", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 1 {
+		return fmt.Errorf("usage: boxer switch-profile [NAME]")
+	}
+	name := fs.Arg(0)
+
+	var status boxer.Status
+	if err := NewClient(*socketPath).Do("POST", "/profile?"+url.Values{"profile": {name}}.Encode(), &status); err != nil {
+		return err
+	}
+
+	if name == "" {
+		fmt.Fprintln(m.Stdout, "switched to default commands")
+	} else {
+		fmt.Fprintf(m.Stdout, "switched to profile %s\n", name)
+	}
+	return nil
+}
+
+// RunReload reloads the running daemon's commands from its config file.
+func (m *Main) RunReload(args []string) error {
+	fs := flag.NewFlagSet("boxer-reload", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("usage: boxer reload")
+	}
+
+	var status boxer.Status
+	if err := NewClient(*socketPath).Do("POST", "/reload", &status); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.Stdout, "reloaded config with %d commands\n", len(status.Commands))
+	return nil
+}
+
+// RunStatus prints the status of the running daemon.
+func (m *Main) RunStatus(args []string) error {
+	fs := flag.NewFlagSet("boxer-status", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	tickerName := fs.String("ticker", "", "name of the ticker to control")
+	asJSON := fs.Bool("json", false, "print status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var status boxer.Status
+	if err := NewTickerClient(*socketPath, *tickerName).Do("GET", "/status", &status); err != nil {
+		return err
+	}
+
+	// Print the raw status, if requested.
+	if *asJSON {
+		enc := json.NewEncoder(m.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	switch {
+	case status.Paused:
+		fmt.Fprintln(m.Stdout, "boxer is paused")
+	case !status.Active:
+		fmt.Fprintln(m.Stdout, "boxer is idle")
+	default:
+		fmt.Fprintln(m.Stdout, "boxer is running")
+	}
+	if status.Label != "" {
+		fmt.Fprintf(m.Stdout, "label: %s\n", status.Label)
+	}
+	if status.SnoozedUntil != nil {
+		fmt.Fprintf(m.Stdout, "snoozed until %s\n", status.SnoozedUntil.Local().Format("3:04pm"))
+	}
+	if status.Anchor != nil {
+		fmt.Fprintf(m.Stdout, "intervals started at %s\n", status.Anchor.Local().Format("3:04pm"))
+	}
+	fmt.Fprintln(m.Stdout, "")
+
+	// Print the position of each command in a table.
+	tw := tabwriter.NewWriter(m.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTEP\tINTERVAL START\tELAPSED\tREMAINING\tLAST ERROR")
+	for _, cs := range status.Commands {
+		name := cs.Name
+		if cs.Disabled {
+			name += " (disabled)"
+		} else if cs.Overtime > 0 {
+			name += fmt.Sprintf(" (%d step(s) over)", cs.Overtime)
+		} else if cs.QuarantinedUntil != nil {
+			name += fmt.Sprintf(" (quarantined until %s)", cs.QuarantinedUntil.Local().Format("3:04pm"))
+		}
+
+		var lastErr string
+		if cs.LastError != "" && cs.LastErrorTime != nil {
+			lastErr = fmt.Sprintf("%s (%s)", cs.LastError, cs.LastErrorTime.Local().Format("3:04pm"))
+		}
+		fmt.Fprintf(tw, "%s\t%d/%d\t%s\t%s\t%s\t%s\n",
+			name,
+			cs.Step+1, cs.Steps,
+			cs.IntervalStart.Local().Format("3:04pm"),
+			formatSeconds(cs.Elapsed),
+			formatSeconds(cs.Remaining),
+			lastErr,
+		)
+	}
+	return tw.Flush()
+}
+
+// ControlPath returns the request path for a control command, such as "skip"
+// or "snooze 5m". The commands are "pause", "resume", "skip", "start",
+// "snooze DURATION", and "label TEXT".
+func ControlPath(command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("control command required")
+	}
+
+	switch name, args := fields[0], fields[1:]; name {
+	case "pause", "resume", "skip", "start":
+		if len(args) > 0 {
+			return "", fmt.Errorf("%s takes no arguments", name)
+		}
+		return "/" + name, nil
+	case "snooze":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: snooze DURATION")
+		} else if _, err := time.ParseDuration(args[0]); err != nil {
+			return "", fmt.Errorf("snooze: invalid duration: %q", args[0])
+		}
+		return "/snooze?" + url.Values{"duration": {args[0]}}.Encode(), nil
+	case "label":
+		return "/label?" + url.Values{"label": {strings.Join(args, " ")}}.Encode(), nil
+	default:
+		return "", fmt.Errorf("unknown control command: %q", name)
+	}
+}
+
+// formatSeconds formats a number of seconds as a rounded duration.
+func formatSeconds(v float64) string {
+	return (time.Duration(v) * time.Second).String()
+}
+
+// DefaultSocketPath returns the default control socket path.
+// The default path is the ".boxer.sock" file in the user's home directory.
+func DefaultSocketPath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, ".boxer.sock"), nil
+}
+
+// ListenSocket opens a Unix socket listener at path.
+// Any stale socket left behind by a previous process is removed.
+func ListenSocket(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("boxer is already running: %s", path)
+		} else if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// Client represents a client for the daemon's control socket.
+type Client struct {
+	path string
+	http *http.Client
+
+	// If set, requests are sent to the named ticker.
+	Ticker string
+}
+
+// NewClient returns a new client for the control socket at path.
+// If path is blank then the default socket path is used.
+func NewClient(path string) *Client {
+	c := &Client{path: path}
+	c.http = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", c.path)
+			},
+		},
+	}
+	return c
+}
+
+// NewTickerClient returns a new client for the named ticker of the daemon
+// at path. If name is blank then requests are sent to the main ticker.
+func NewTickerClient(path, name string) *Client {
+	c := NewClient(path)
+	c.Ticker = name
+	return c
+}
+
+// Control sends a control command, such as "skip", to the daemon.
+func (c *Client) Control(command string) error {
+	path, err := ControlPath(command)
+	if err != nil {
+		return err
+	}
+	var status boxer.Status
+	return c.Do("POST", path, &status)
+}
+
+// Do sends a request to the daemon and decodes the JSON response into v.
+func (c *Client) Do(method, path string, v interface{}) error {
+	if c.path == "" {
+		p, err := DefaultSocketPath()
+		if err != nil {
+			return fmt.Errorf("default socket path: %s", err)
+		}
+		c.path = p
+	}
+
+	// Select the named ticker, if set.
+	if c.Ticker != "" {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + url.Values{"ticker": {c.Ticker}}.Encode()
+	}
+
+	req, err := http.NewRequest(method, "http://boxer"+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot connect to boxer, is it running? %s", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		if b, _ := ioutil.ReadAll(resp.Body); len(bytes.TrimSpace(b)) > 0 {
+			return fmt.Errorf("%s", bytes.TrimSpace(b))
+		}
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	} else if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// DoctorCheck represents a single diagnostic run by "boxer doctor".
+type DoctorCheck struct {
+	Name string
+	Run  func() error
+
+	// Returns the instructions to fix a failed check.
+	Fix func(err error) string
+}
+
+// RunDoctor checks the config, work directory, & desktop permissions and
+// prints how to fix each problem found. Most problems on macOS are missing
+// automation permissions for the Finder & System Events.
+func (m *Main) RunDoctor(args []string) error {
+	fs := flag.NewFlagSet("boxer-doctor", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *configPath
+	if path == "" {
+		str, err := DefaultConfigPath()
+		if err != nil {
+			return fmt.Errorf("default config path: %s", err)
+		}
+		path = str
+	}
+
+	// Continue with the default config if the config cannot be read so the
+	// remaining checks still run.
+	config := NewConfig()
+	if c, err := m.ReadConfig(path); err == nil {
+		config = c
+	}
+	checks := DoctorChecks(path, config, boxer.NewCommandExecutor(m.Executor))
+
+	var failed int
+	for _, c := range checks {
+		err := c.Run()
+		if err == nil {
+			fmt.Fprintf(m.Stdout, "ok    %s\n", c.Name)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(m.Stdout, "FAIL  %s: %s\n", c.Name, strings.TrimSpace(err.Error()))
+		if c.Fix != nil {
+			fmt.Fprintf(m.Stdout, "      fix: %s\n", c.Fix(err))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// DoctorChecks returns the checks for the config at path. The desktop checks
+// depend on the configured desktop.
+func DoctorChecks(path string, config *Config, exec boxer.CommandExecutor) []*DoctorCheck {
+	// The default work directory is used if none is set.
+	workDir := config.WorkDir
+	if workDir == "" {
+		workDir, _ = DefaultWorkDir()
+	}
+
+	checks := []*DoctorCheck{
+		{
+			Name: "config " + path,
+			Run: func() error {
+				buf, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				} else if problems := CheckConfig(string(buf)); len(problems) > 0 {
+					return fmt.Errorf("%d problem(s) found", len(problems))
+				}
+				return nil
+			},
+			Fix: func(err error) string {
+				if os.IsNotExist(err) {
+					return "run \"boxer init\" to create a config"
+				}
+				return fmt.Sprintf("run \"boxer check-config %s\" to list the problems", path)
+			},
+		},
+		{
+			Name: "work directory " + workDir,
+			Run:  func() error { return checkWritableDir(workDir) },
+			Fix: func(err error) string {
+				return "set \"work_dir\" to a directory you can write to"
+			},
+		},
+	}
+
+	if name := desktopName(config); name != "macos" {
+		return append(checks, &DoctorCheck{
+			Name: "desktop " + name,
+			Run: func() error {
+				desktop, err := boxer.NewDesktop(name, exec)
+				if err != nil {
+					return err
+				}
+				_, _, err = desktop.Size()
+				return err
+			},
+			Fix: func(err error) string {
+				return "set \"desktop\" to \"gnome\", \"kde\", or \"sway\" and ensure its tools are installed"
+			},
+		})
+	}
+
+	checks = append(checks,
+		&DoctorCheck{
+			Name: "finder automation",
+			Run: func() error {
+				_, _, err := boxer.DesktopSize(exec)
+				return err
+			},
+			Fix: automationFix("Finder"),
+		},
+		&DoctorCheck{
+			Name: "system events automation",
+			Run: func() error {
+				_, err := boxer.DarkMode(exec)
+				return err
+			},
+			Fix: automationFix("System Events"),
+		},
+	)
+
+	// Set the wallpaper to the current wallpaper so nothing visibly changes.
+	if config.Wallpaper.Enabled {
+		checks = append(checks, &DoctorCheck{
+			Name: "wallpaper set & restore",
+			Run: func() error {
+				setter, err := boxer.ParseWallpaperSetter(config.Wallpaper.Backend)
+				if err != nil {
+					return err
+				}
+				current, err := boxer.Wallpaper(exec)
+				if err != nil {
+					return fmt.Errorf("get: %s", err)
+				} else if err := setter(exec, current); err != nil {
+					return fmt.Errorf("set: %s", err)
+				}
+				return nil
+			},
+			Fix: func(err error) string {
+				if strings.Contains(err.Error(), "-1743") {
+					return automationFix("Finder")(err)
+				}
+				return "set \"backend\" in [wallpaper] to \"osascript\", which only needs Finder automation"
+			},
+		})
+	}
+	return checks
+}
+
+// automationFix returns the fix for a failed osascript call to app.
+func automationFix(app string) func(error) string {
+	return func(err error) string {
+		if strings.Contains(err.Error(), "-1743") || strings.Contains(err.Error(), "Not authorized") {
+			return fmt.Sprintf("allow your terminal to control %s in System Settings > Privacy & Security > Automation", app)
+		}
+		return fmt.Sprintf("approve the prompt to control %s, or run \"tccutil reset AppleEvents\" to be asked again", app)
+	}
+}
+
+// checkWritableDir returns an error if a file cannot be created in dir. The
+// directory is created if it doesn't exist.
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, ".boxer-doctor-")
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunExport writes the intervals recorded in the journal as CSV or JSON so
+// they can be imported into invoicing or analytics tools.
+func (m *Main) RunExport(args []string) error {
+	fs := flag.NewFlagSet("boxer-export", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	journalPath := fs.String("journal", "", "journal path")
+	format := fs.String("format", "csv", "output format, \"csv\" or \"json\"")
+	from := fs.String("from", "", "only export intervals starting at or after, such as \"today\" or \"2006-01-02\"")
+	to := fs.String("to", "", "only export intervals starting before, such as \"+7d\" or \"2006-01-02\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *format != "csv" && *format != "json" {
+		return fmt.Errorf("invalid format: %q", *format)
+	}
+
+	// Determine the range to export. The end is relative to the start, if set.
+	now := time.Now()
+	var start, end time.Time
+	if *from != "" {
+		t, err := ParseExportTime(*from, now, now)
+		if err != nil {
+			return fmt.Errorf("parse from: %s", err)
+		}
+		start = t
+	}
+	if *to != "" {
+		base := start
+		if base.IsZero() {
+			base = now
+		}
+		t, err := ParseExportTime(*to, base, now)
+		if err != nil {
+			return fmt.Errorf("parse to: %s", err)
+		} else if !start.IsZero() && !t.After(start) {
+			return fmt.Errorf("end of export must be after the start")
+		}
+		end = t
+	}
+
+	// Read the journal path from the config, if not specified.
+	if *journalPath == "" {
+		config, err := m.ReadConfig(*configPath)
+		if err != nil {
+			return fmt.Errorf("read config: %s", err)
+		} else if *journalPath, err = config.JournalPath(); err != nil {
+			return fmt.Errorf("journal path: %s", err)
+		}
+	}
+
+	records, err := boxer.NewFileJournal(*journalPath).Records()
+	if err != nil {
+		return fmt.Errorf("read journal: %s", err)
+	}
+	rows := ExportRecords(records, start, end)
+
+	if *format == "json" {
+		return WriteExportJSON(m.Stdout, rows)
+	}
+	return WriteExportCSV(m.Stdout, rows)
+}
+
+// ExportRecord represents a recorded interval in an export.
+type ExportRecord struct {
+	Command string    `json:"command"`
+	Label   string    `json:"label"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Hours   float64   `json:"hours"`
+	Paused  bool      `json:"paused"`
+	Skipped bool      `json:"skipped"`
+}
+
+// ExportRecords converts the records that started within the range to export
+// records, ordered by start time. A zero start or end leaves the range open.
+func ExportRecords(records []*boxer.IntervalRecord, start, end time.Time) []*ExportRecord {
+	a := make([]*ExportRecord, 0, len(records))
+	for _, r := range records {
+		if !start.IsZero() && r.Start.Before(start) {
+			continue
+		} else if !end.IsZero() && !r.Start.Before(end) {
+			continue
+		}
+
+		a = append(a, &ExportRecord{
+			Command: r.Command,
+			Label:   r.Label,
+			Start:   r.Start,
+			End:     r.End,
+			Hours:   r.Duration().Hours(),
+			Paused:  r.Paused,
+			Skipped: r.Skipped,
+		})
+	}
+	sort.SliceStable(a, func(i, j int) bool { return a[i].Start.Before(a[j].Start) })
+	return a
+}
+
+// WriteExportCSV writes rows to w as CSV with a header row. Times use the
+// RFC 3339 format.
+func WriteExportCSV(w io.Writer, rows []*ExportRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"command", "label", "start", "end", "hours", "paused", "skipped"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write([]string{
+			row.Command,
+			row.Label,
+			row.Start.Format(time.RFC3339),
+			row.End.Format(time.RFC3339),
+			strconv.FormatFloat(row.Hours, 'f', -1, 64),
+			strconv.FormatBool(row.Paused),
+			strconv.FormatBool(row.Skipped),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteExportJSON writes rows to w as an indented JSON array.
+func WriteExportJSON(w io.Writer, rows []*ExportRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+// RunExportICS writes the intervals planned by the configured schedule as
+// an iCalendar file so they can be overlaid on another calendar. Intervals
+// shared by several commands are only written once.
+func (m *Main) RunExportICS(args []string) error {
+	fs := flag.NewFlagSet("boxer-export-ics", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	from := fs.String("from", "today", "start of the export, such as \"today\" or \"2006-01-02\"")
+	to := fs.String("to", "+7d", "end of the export, such as \"+7d\" or \"2006-01-02\"")
+	name := fs.String("command", "", "only export the intervals of the named command")
+	summary := fs.String("summary", "Timebox", "title of each event")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Determine the range to export. The end is relative to the start.
+	now := time.Now()
+	start, err := ParseExportTime(*from, now, now)
+	if err != nil {
+		return fmt.Errorf("parse from: %s", err)
+	}
+	end, err := ParseExportTime(*to, start, now)
+	if err != nil {
+		return fmt.Errorf("parse to: %s", err)
+	} else if !end.After(start) {
+		return fmt.Errorf("end of export must be after the start")
+	}
+
+	config, err := m.ReadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("read config: %s", err)
+	}
+	config.Journal.Enabled = false
+	config.Presentation.Enabled = false
+
+	// Generate any files into a temporary work directory.
+	workDir, err := ioutil.TempDir("", "boxer-export-")
+	if err != nil {
+		return fmt.Errorf("temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	config.WorkDir = workDir
+
+	ticker, err := NewTicker(config, boxer.CommandExecutor(NopCommandExecutor))
+	if err != nil {
+		return fmt.Errorf("cannot create ticker: %s", err)
+	}
+
+	// Align intervals to the start set by "boxer start", if any.
+	anchorPath := config.AnchorPath
+	if anchorPath == "" {
+		if anchorPath, err = DefaultAnchorPath(); err != nil {
+			return fmt.Errorf("anchor path: %s", err)
+		}
+	}
+	ticker.AnchorStore = boxer.NewFileAnchorStore(anchorPath)
+	if err := ticker.RestoreAnchor(); err != nil {
+		return fmt.Errorf("restore anchor: %s", err)
+	}
+
+	// Ensure the command exists so a typo does not export an empty calendar.
+	if *name != "" {
+		var found bool
+		for _, cmd := range ticker.Commands {
+			found = found || cmd.Name == *name
+		}
+		if !found {
+			return fmt.Errorf("command not found: %q", *name)
+		}
+	}
+
+	return boxer.WriteICS(m.Stdout, PlanEvents(ticker.Plan(start, end), *name, *summary), now)
+}
+
+// PlanEvents converts planned intervals to calendar events titled summary.
+// If name is set then only the intervals of that command are included.
+// Intervals with the same start & end are only included once.
+func PlanEvents(records []*boxer.IntervalRecord, name, summary string) []boxer.CalendarEvent {
+	type key struct{ start, end int64 }
+
+	var events []boxer.CalendarEvent
+	m := make(map[key]bool)
+	for _, r := range records {
+		if name != "" && r.Command != name {
+			continue
+		}
+
+		k := key{start: r.Start.UnixNano(), end: r.End.UnixNano()}
+		if m[k] {
+			continue
+		}
+		m[k] = true
+
+		events = append(events, boxer.CalendarEvent{Summary: summary, Start: r.Start, End: r.End})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+// ParseExportTime parses a time as "now", "today", "tomorrow", a date in
+// the "2006-01-02" format, or an offset from base such as "+7d" or "+4h".
+// Dates are midnight in the local time zone.
+func ParseExportTime(s string, base, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+	switch {
+	case s == "now":
+		return now, nil
+	case s == "today":
+		return today, nil
+	case s == "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case strings.HasPrefix(s, "+") && strings.HasSuffix(s, "d"):
+		n, err := strconv.Atoi(strings.TrimSuffix(s[1:], "d"))
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid time: %q", s)
+		}
+		return base.AddDate(0, 0, n), nil
+	case strings.HasPrefix(s, "+"):
+		v, err := time.ParseDuration(s[1:])
+		if err != nil || v < 0 {
+			return time.Time{}, fmt.Errorf("invalid time: %q", s)
+		}
+		return base.Add(v), nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %q", s)
+	}
+	return t, nil
+}
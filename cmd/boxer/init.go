@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunInit writes a starter config file. The work hours & wallpaper palette
+// can be chosen with flags and the rest of the settings use defaults.
+func (m *Main) RunInit(args []string) error {
+	fs := flag.NewFlagSet("boxer-init", flag.ContinueOnError)
+	path := fs.String("path", "", "config path")
+	start := fs.String("start", "9:00am", "start of work hours")
+	end := fs.String("end", "5:00pm", "end of work hours")
+	palette := fs.String("palette", "default", "wallpaper palette ("+strings.Join(boxer.PaletteNames(), ", ")+")")
+	force := fs.Bool("force", false, "overwrite an existing config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		str, err := DefaultConfigPath()
+		if err != nil {
+			return fmt.Errorf("default config path: %s", err)
+		}
+		*path = str
+	}
+
+	// Refuse to clobber an existing config unless forced.
+	if _, err := os.Stat(*path); err == nil && !*force {
+		return fmt.Errorf("config already exists, use -force to overwrite: %s", *path)
+	}
+
+	buf, err := StarterConfig(*start, *end, *palette)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*path), 0777); err != nil {
+		return fmt.Errorf("mkdir: %s", err)
+	} else if err := ioutil.WriteFile(*path, buf, 0666); err != nil {
+		return fmt.Errorf("write config: %s", err)
+	}
+
+	fmt.Fprintf(m.Stdout, "wrote config: %s\n", *path)
+	return nil
+}
+
+// StarterConfig returns a commented config using the given work hours and
+// palette. The generated config is validated before it is returned.
+func StarterConfig(start, end, palette string) ([]byte, error) {
+	if _, err := boxer.LoadPalette(palette); err != nil {
+		return nil, fmt.Errorf("invalid palette: %q", palette)
+	}
+
+	var buf bytes.Buffer
+	if err := starterConfigTemplate.Execute(&buf, map[string]interface{}{
+		"Start":   start,
+		"End":     end,
+		"Palette": palette,
+	}); err != nil {
+		return nil, err
+	}
+
+	if problems := CheckConfig(buf.String()); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config: %s", problems[0].Message)
+	}
+	return buf.Bytes(), nil
+}
+
+var starterConfigTemplate = template.Must(template.New("boxer.conf").Parse(`# Boxer configuration. Run "boxer check-config" after editing this file
+# to catch mistakes. See boxer.sample.conf for every available setting.
+
+# Every command only runs during your work hours.
+[active_hours]
+start = "{{.Start}}"
+end   = "{{.End}}"
+
+# The wallpaper updates every step and restarts every interval. The
+# foreground shifts between the palette's two colors over the course of the
+# work day. Set "foregrounds" & "backgrounds" to use your own colors.
+[wallpaper]
+enabled  = true
+step     = "1m"
+interval = "15m"
+style    = "solid"
+times    = ["{{.Start}}", "{{.End}}"]
+palette  = "{{.Palette}}"
+
+# The menu bar flashes at the start of every interval.
+[menu_bar]
+enabled  = true
+interval = "30m"
+
+# A notification is displayed at the start of every interval.
+[announcement]
+enabled  = false
+interval = "30m"
+
+# Bundled sounds are "chime", "bell", and "tick".
+[sound]
+enabled        = false
+step           = "5m"
+interval       = "30m"
+interval_sound = "chime"
+volume         = 0.5
+
+# Completed intervals are recorded so "boxer report" can summarize them.
+[journal]
+enabled = true
+`))
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"image/color"
+	"io"
 	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -17,77 +28,447 @@ import (
 
 func main() {
 	m := NewMain()
+
+	// Shutdown gracefully when interrupted or terminated.
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		m.Logger.Printf("Received %s, shutting down...", sig)
+		m.Close()
+	}()
+
 	if err := m.Run(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
 }
 
-// DefaultTickInterval is the time between ticks on the ticker.
-const DefaultTickInterval = 1 * time.Second
+// DefaultRetryBackoff is the wait before retrying a failed handler.
+const DefaultRetryBackoff = 1 * time.Second
 
 // Main represents the program execution.
 type Main struct {
-	// The time between tick execution on the Ticker.
-	TickInterval time.Duration
-
-	// The function used to execute OS commands.
-	Executor boxer.CommandExecutor
+	// The executor used to execute OS commands.
+	Executor boxer.Executor
 
 	// The logger passed to the ticker during execution.
 	Logger *log.Logger
 
+	// The writer used for subcommand output.
+	Stdout io.Writer
+
 	closing chan struct{}
 }
 
 // NewMain returns a new instance of Main with default settings.
 func NewMain() *Main {
 	return &Main{
-		TickInterval: DefaultTickInterval,
-		Executor:     boxer.DefaultCommandExecutor,
-		Logger:       log.New(os.Stderr, "", 0),
+		Executor: boxer.DefaultExecutor,
+		Logger:   log.New(os.Stderr, "", 0),
+		Stdout:   os.Stdout,
 
 		closing: make(chan struct{}, 0),
 	}
 }
 
+// Close signals a running program to stop. Run returns once the current
+// tick has completed.
+func (m *Main) Close() error {
+	select {
+	case <-m.closing:
+	default:
+		close(m.closing)
+	}
+	return nil
+}
+
 // Run excutes the program.
 func (m *Main) Run(args []string) error {
+	// Execute a subcommand, if one is specified.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "pause", "resume", "skip":
+			return m.RunControl(args[0], args[1:])
+		case "enable", "disable":
+			return m.RunCommandControl(args[0], args[1:])
+		case "snooze":
+			return m.RunSnooze(args[1:])
+		case "reload":
+			return m.RunReload(args[1:])
+		case "switch-profile":
+			return m.RunSwitchProfile(args[1:])
+		case "start":
+			return m.RunStart(args[1:])
+		case "status":
+			return m.RunStatus(args[1:])
+		case "label":
+			return m.RunLabel(args[1:])
+		case "tui":
+			return m.RunTUI(args[1:])
+		case "menubar":
+			return m.RunMenuBar(args[1:])
+		case "report":
+			return m.RunReport(args[1:])
+		case "export":
+			return m.RunExport(args[1:])
+		case "export-ics":
+			return m.RunExportICS(args[1:])
+		case "init":
+			return m.RunInit(args[1:])
+		case "simulate":
+			return m.RunSimulate(args[1:])
+		case "preview":
+			return m.RunPreview(args[1:])
+		case "check-config":
+			return m.RunCheckConfig(args[1:])
+		case "doctor":
+			return m.RunDoctor(args[1:])
+		case "install-service":
+			return m.RunInstallService(args[1:])
+		case "uninstall-service":
+			return m.RunUninstallService(args[1:])
+		default:
+			return fmt.Errorf("unknown command: %s", args[0])
+		}
+	}
+
 	// Parse CLI arguments.
 	fs := flag.NewFlagSet("boxer", flag.ContinueOnError)
 	configPath := fs.String("config", "", "config path")
+	dryRun := fs.Bool("dry-run", false, "log commands instead of executing them")
+	profile := fs.String("profile", "", "name of the [profile.NAME] commands to run")
+	var overrides ConfigOverrides
+	fs.Var(&overrides, "set", "override a config value, such as wallpaper.interval=50m (repeatable)")
+	verbose := fs.Bool("v", false, "log why each command does or does not execute on every tick")
+	veryVerbose := fs.Bool("vv", false, "like -v & also log the arguments, input & output of every executed command")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
-	// Read configuration file.
-	config, err := m.ReadConfig(*configPath)
+	// Read configuration file & apply any overrides.
+	base, err := m.ReadConfig(*configPath)
 	if err != nil {
 		return fmt.Errorf("read config: %s", err)
+	} else if err := overrides.Apply(base); err != nil {
+		return err
+	}
+
+	// Use the default work directory if none is set.
+	if base.WorkDir == "" {
+		if base.WorkDir, err = DefaultWorkDir(); err != nil {
+			return fmt.Errorf("default work dir: %s", err)
+		}
+	}
+	if err := os.MkdirAll(base.WorkDir, 0777); err != nil {
+		return fmt.Errorf("work dir: %s", err)
 	}
 
-	// Use a temp directory if no work directory is set.
-	if config.WorkDir == "" {
-		str, err := ioutil.TempDir("", "boxer-")
+	// Log commands instead of executing them in a dry run.
+	exec := m.Executor
+	if *dryRun {
+		exec = &boxer.RecordingExecutor{Logger: m.Logger}
+	}
+
+	// Trace every executed command at the highest verbosity.
+	if *veryVerbose {
+		exec = &boxer.TracingExecutor{Executor: exec, Logger: m.Logger}
+	}
+
+	// Apply a profile's commands over the config. Profiles can be switched
+	// at runtime with "boxer switch-profile".
+	profileConfig := func(name string) (*Config, error) {
+		c, err := base.WithProfile(name)
 		if err != nil {
-			return fmt.Errorf("temp dir: %s", err)
+			return nil, err
+		} else if *dryRun {
+			if c.Wallpaper.Backend == "native" {
+				c.Wallpaper.Backend = "osascript" // native calls bypass the executor
+			}
+			c.Toggl.Enabled = false // API requests bypass the executor
+		}
+		return c, nil
+	}
+	config, err := profileConfig(*profile)
+	if err == boxer.ErrProfileNotFound {
+		return fmt.Errorf("profile not found: %q", *profile)
+	} else if err != nil {
+		return fmt.Errorf("profile %q: %s", *profile, err)
+	}
+
+	// Restrict the executables that can be run & audit every execution.
+	if config.Exec.Allow != nil || config.Exec.Audit {
+		x := boxer.NewAllowlistExecutor(exec, config.Exec.Allow)
+		if config.Exec.Audit {
+			x.Logger = m.Logger
+		}
+		exec = x
+	}
+
+	// Trigger the macOS automation prompts in a fixed order before any
+	// command sends Apple events so a denied permission fails immediately.
+	if apps := AutomationApps(config); len(apps) > 0 && !*dryRun {
+		path := config.AutomationPath
+		if path == "" {
+			if path, err = DefaultAutomationPath(); err != nil {
+				return fmt.Errorf("default automation path: %s", err)
+			}
+		}
+		if err := m.PreflightAutomation(boxer.NewCommandExecutor(exec), path, apps); err != nil {
+			return err
 		}
-		config.WorkDir = str
 	}
 
 	// Create a new ticker based on the config.
-	ticker, err := NewTicker(config, m.Executor)
+	ticker, err := NewTicker(config, exec)
 	if err != nil {
 		return fmt.Errorf("cannot create ticker: %s", err)
 	}
 
+	// Restore the interval start set by "boxer start" before a restart.
+	anchorPath := config.AnchorPath
+	if anchorPath == "" {
+		if anchorPath, err = DefaultAnchorPath(); err != nil {
+			return fmt.Errorf("default anchor path: %s", err)
+		}
+	}
+	ticker.AnchorStore = boxer.NewFileAnchorStore(anchorPath)
+	if err := ticker.RestoreAnchor(); err != nil {
+		return err
+	}
+
+	// Attach the logger in the configured format.
+	switch config.LogFormat {
+	case "", "text":
+		ticker.Logger = boxer.NewTextLogger(m.Logger)
+	case "json":
+		ticker.Logger = boxer.NewJSONLogger(os.Stderr)
+	default:
+		return fmt.Errorf("invalid log format: %q", config.LogFormat)
+	}
+	if *verbose || *veryVerbose {
+		ticker.Debug = log.New(m.Logger.Writer(), "debug: ", 0)
+	}
+
+	// Create the named tickers. These share the logger & journal of the
+	// main ticker.
+	tickers := make(map[string]*boxer.Ticker)
+	for i := range config.Tickers {
+		tc := &config.Tickers[i]
+		if tc.Name == "" {
+			return fmt.Errorf("ticker %d: name required", i)
+		} else if tickers[tc.Name] != nil {
+			return fmt.Errorf("ticker %d: duplicate name: %q", i, tc.Name)
+		}
+
+		t, err := NewNamedTicker(config, tc, exec)
+		if err != nil {
+			return fmt.Errorf("cannot create ticker %q: %s", tc.Name, err)
+		}
+		t.Logger, t.Journal = ticker.Logger, ticker.Journal
+		if ticker.Debug != nil {
+			t.Debug = log.New(m.Logger.Writer(), fmt.Sprintf("debug: ticker %s: ", tc.Name), 0)
+		}
+		tickers[tc.Name] = t
+	}
+	handler := &boxer.HTTPHandler{Ticker: ticker, Tickers: tickers}
+
+	// Replace the main ticker's commands when switching profiles.
+	var mu sync.Mutex
+	activeProfile := *profile
+	replaceCommands := func(name string) (int, error) {
+		c, err := profileConfig(name)
+		if err != nil {
+			return 0, err
+		}
+		commands, err := newCommands(c, ticker, c.CommandConfigs(), exec, newPresentationSuppressor(c, exec))
+		if err != nil {
+			return 0, err
+		}
+		ticker.ReplaceCommands(commands)
+		activeProfile = name
+		return len(commands), nil
+	}
+	handler.SwitchProfile = func(name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		n, err := replaceCommands(name)
+		if err != nil {
+			return err
+		}
+		m.Logger.Printf("switched to profile %q with %d commands", name, n)
+		return nil
+	}
+
+	// Reload the main ticker's commands from the config file on SIGHUP or
+	// "boxer reload". A broken config is logged & displayed as a notification
+	// while the active config keeps running.
+	path := *configPath
+	if path == "" {
+		if path, err = DefaultConfigPath(); err != nil {
+			return fmt.Errorf("default config path: %s", err)
+		}
+	}
+	var notifier boxer.Notifier
+	if desktop, err := boxer.NewDesktop(config.Desktop, boxer.NewCommandExecutor(exec)); err == nil {
+		notifier = desktop
+	}
+	startup := base
+	reload := func() (int, error) {
+		c, err := m.ReloadConfig(path, overrides)
+		if err != nil {
+			return 0, err
+		} else if c.WorkDir == "" {
+			c.WorkDir = base.WorkDir
+		}
+
+		prev := base
+		base = c
+		n, err := replaceCommands(activeProfile)
+		if err != nil {
+			base = prev
+			return n, err
+		}
+
+		// Settings outside the commands keep the values read at startup.
+		if keys := StaticSettings(startup, c); len(keys) > 0 {
+			m.Logger.Printf("reload: changes to %s require a restart", strings.Join(keys, ", "))
+		}
+		return n, nil
+	}
+	handler.Reload = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		n, err := reload()
+		if err != nil {
+			rerr := &ReloadError{Path: path, Profile: activeProfile, Err: err}
+			if err := ReportReloadError(ticker.Logger, notifier, rerr); err != nil {
+				m.Logger.Printf("notify: %s", err)
+			}
+			return rerr
+		}
+		m.Logger.Printf("reloaded %s with %d commands", path, n)
+		return nil
+	}
+	// Summarize the day from the journal at the end of the workday.
+	var summarizer *boxer.Summarizer
+	if config.Summary.Enabled {
+		if summarizer, err = NewSummarizer(config, exec); err != nil {
+			return fmt.Errorf("summary: %s", err)
+		}
+		summarizer.Logger = ticker.Logger
+	}
+
+	// Serve ticker status over HTTP, if enabled. Control requests are only
+	// served over the socket since any process or web page can reach TCP.
+	if config.HTTP.Listen != "" {
+		ln, err := net.Listen("tcp", config.HTTP.Listen)
+		if err != nil {
+			return fmt.Errorf("listen: %s", err)
+		}
+		defer func() { _ = ln.Close() }()
+		readOnly := *handler
+		readOnly.ReadOnly = true
+		go func() { _ = http.Serve(ln, &readOnly) }()
+	}
+
+	// Serve control requests over a Unix socket.
+	socketPath := config.Socket
+	if socketPath == "" {
+		if socketPath, err = DefaultSocketPath(); err != nil {
+			return fmt.Errorf("default socket path: %s", err)
+		}
+	}
+	ln, err := ListenSocket(socketPath)
+	if err != nil {
+		return fmt.Errorf("listen socket: %s", err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() { _ = http.Serve(ln, handler) }()
+
+	// Save the original wallpaper so it can be restored on shutdown. It is
+	// restored with the same backend so every Space is restored, if needed.
+	if config.Wallpaper.Enabled && !*dryRun {
+		setter, err := boxer.ParseWallpaperSetter(config.Wallpaper.Backend)
+		if err != nil {
+			return fmt.Errorf("wallpaper: %s", err)
+		}
+		path, err := boxer.Wallpaper(boxer.NewCommandExecutor(exec))
+		if err != nil {
+			return fmt.Errorf("wallpaper: %s", err)
+		}
+		defer func() {
+			if err := setter(boxer.NewCommandExecutor(exec), path); err != nil {
+				m.Logger.Printf("restore wallpaper: %s", err)
+			}
+		}()
+	}
+
 	// Notify user of the current settings.
-	log.Printf("Boxer running with %d commands...", len(ticker.Commands))
+	if len(tickers) > 0 {
+		log.Printf("Boxer running with %d commands & %d named tickers...", len(ticker.Commands), len(tickers))
+	} else {
+		log.Printf("Boxer running with %d commands...", len(ticker.Commands))
+	}
+
+	// Stop ticking once the program is closed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { <-m.closing; cancel() }()
+
+	// Reload on SIGHUP until the program is closed.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				_ = handler.Reload()
+			}
+		}
+	}()
 
-	// Begin ticking.
-	for {
-		ticker.Tick()
-		time.Sleep(m.TickInterval)
+	// Wake each ticker at step boundaries, delayed by the jitter.
+	opts := boxer.RunOptions{Align: true, Jitter: config.Jitter.Duration}
+
+	// Begin ticking the named tickers & summarizing in the background. The
+	// first error stops everything.
+	background := make(map[string]func(context.Context) error)
+	for name, t := range tickers {
+		t := t
+		background[fmt.Sprintf("ticker %q", name)] = func(ctx context.Context) error { return boxer.Run(ctx, t, opts) }
+	}
+	if summarizer != nil {
+		background["summary"] = summarizer.Run
+	}
+	errs := make(chan error, len(background))
+	for name, run := range background {
+		go func(name string, run func(context.Context) error) {
+			err := run(ctx)
+			if err != nil && err != context.Canceled {
+				err = fmt.Errorf("%s: %s", name, err)
+				cancel()
+			}
+			errs <- err
+		}(name, run)
+	}
+
+	// Begin ticking the main ticker & wait for the background work to stop.
+	err = boxer.Run(ctx, ticker, opts)
+	cancel()
+	for range background {
+		if e := <-errs; err == nil || err == context.Canceled {
+			err = e
+		}
+	}
+	if err != nil && err != context.Canceled {
+		return err
 	}
+	return nil
 }
 
 // ReadConfig reads the configuration from a path.
@@ -102,144 +483,2033 @@ func (m *Main) ReadConfig(path string) (*Config, error) {
 		path = str
 	}
 
-	// Decode file into config.
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand environment variables & decode the file into config.
+	src, err := ExpandConfig(string(buf), os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
 	config := NewConfig()
-	if _, err := toml.DecodeFile(path, &config); err != nil {
+	md, err := toml.Decode(src, &config)
+	if err != nil {
 		return nil, err
 	}
+	config.md = md
 	return config, nil
 }
 
 // DefaultConfigPath returns the default configuration path.
-// The default path is the "boxer.conf" file in the user's home directory.
+// The default path is the "config.toml" file in the config directory. A
+// "boxer.conf" file in the user's home directory is moved there first.
 func DefaultConfigPath() (string, error) {
-	u, err := user.Current()
+	dir, err := ConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(u.HomeDir, "boxer.conf"), nil
+	return defaultPath(dir, "config.toml", "boxer.conf")
 }
 
-// NewTicker creates a new ticker from configuration.
-func NewTicker(c *Config, exec boxer.CommandExecutor) (*boxer.Ticker, error) {
-	t := boxer.NewTicker()
+// ExpandConfig replaces each "${NAME}" in the TOML config in src with the
+// value of the environment variable NAME, and "~" at the start of a quoted
+// string with the user's home directory, so one config can be shared across
+// machines. Comments are left unchanged. Unquoted "$NAME" is not expanded
+// so shell commands can still use their own variables.
+//
+// Values are escaped for the string they are in so they cannot end the
+// string early. Values that cannot be escaped, such as a quote in a literal
+// string or anything but a number or word outside a string, are rejected.
+//
+// Returns an *ExpandError if a variable is not set or cannot be used.
+func ExpandConfig(src string, lookupEnv func(string) (string, bool)) (string, error) {
+	var state tomlState
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		var buf strings.Builder
+		for j := 0; j < len(line); {
+			// Expand variables in place of the original text.
+			if m := configEnvRegexp.FindString(line[j:]); m != "" {
+				name := m[2 : len(m)-1]
+				v, ok := lookupEnv(name)
+				if !ok {
+					return "", &ExpandError{Line: i + 1, Name: name}
+				}
+				if v, ok = state.escape(v); !ok {
+					return "", &ExpandError{Line: i + 1, Name: name, Reason: state.invalid()}
+				}
+				buf.WriteString(v)
+				j += len(m)
+				continue
+			}
 
-	if c.Wallpaper.Enabled {
-		// Parse times from config.
-		var times []time.Time
-		for _, s := range c.Wallpaper.Times {
-			t, err := time.Parse("3:04pm", s)
-			if err != nil {
-				return nil, fmt.Errorf("parse wallpaper time: %s", err)
+			// Copy the rest of the line unchanged if it is a comment.
+			if state == tomlBare && line[j] == '#' {
+				buf.WriteString(line[j:])
+				break
 			}
-			times = append(times, t)
-		}
 
-		// Parse foreground color from config.
-		var foregrounds []color.RGBA
-		for _, s := range c.Wallpaper.Foregrounds {
-			c, err := boxer.ParseColor(s)
-			if err != nil {
-				return nil, fmt.Errorf("parse wallpaper foreground: %s", err)
+			prev := state
+			n := state.next(line[j:])
+			buf.WriteString(line[j : j+n])
+			j += n
+
+			// Expand "~" at the start of a single-line string.
+			if prev == tomlBare && (state == tomlBasic || state == tomlLiteral) && configHomeRegexp.MatchString(line[j-1:]) {
+				home, ok := lookupEnv("HOME")
+				if !ok {
+					return "", &ExpandError{Line: i + 1, Name: "HOME"}
+				}
+				if home, ok = state.escape(home); !ok {
+					return "", &ExpandError{Line: i + 1, Name: "HOME", Reason: state.invalid()}
+				}
+				buf.WriteString(home)
+				j++
 			}
-			foregrounds = append(foregrounds, c)
 		}
 
-		// Parse backgroun color from config.
-		var backgrounds []color.RGBA
-		for _, s := range c.Wallpaper.Backgrounds {
-			c, err := boxer.ParseColor(s)
-			if err != nil {
-				return nil, fmt.Errorf("parse wallpaper background: %s", err)
+		// Single-line strings cannot continue onto the next line.
+		if state == tomlBasic || state == tomlLiteral {
+			state = tomlBare
+		}
+		lines[i] = buf.String()
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+var (
+	configEnvRegexp  = regexp.MustCompile(`^\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+	configHomeRegexp = regexp.MustCompile(`^(["'])~([/"'])`)
+	configBareRegexp = regexp.MustCompile(`^[A-Za-z0-9_.:+-]*$`)
+)
+
+// tomlState represents the kind of string being scanned in a TOML document.
+type tomlState int
+
+const (
+	tomlBare tomlState = iota
+	tomlBasic
+	tomlLiteral
+	tomlMultiBasic
+	tomlMultiLiteral
+)
+
+// next advances the state over the start of s & returns the number of bytes
+// consumed, which is more than one for escapes & multi-line delimiters.
+func (st *tomlState) next(s string) int {
+	switch *st {
+	case tomlBare:
+		switch {
+		case strings.HasPrefix(s, `"""`):
+			*st = tomlMultiBasic
+			return 3
+		case strings.HasPrefix(s, "'''"):
+			*st = tomlMultiLiteral
+			return 3
+		case s[0] == '"':
+			*st = tomlBasic
+		case s[0] == '\'':
+			*st = tomlLiteral
+		}
+	case tomlBasic, tomlMultiBasic:
+		switch {
+		case s[0] == '\\' && len(s) > 1:
+			return 2
+		case *st == tomlMultiBasic && strings.HasPrefix(s, `"""`):
+			*st = tomlBare
+			return 3
+		case *st == tomlBasic && s[0] == '"':
+			*st = tomlBare
+		}
+	case tomlLiteral:
+		if s[0] == '\'' {
+			*st = tomlBare
+		}
+	case tomlMultiLiteral:
+		if strings.HasPrefix(s, "'''") {
+			*st = tomlBare
+			return 3
+		}
+	}
+	return 1
+}
+
+// escape returns v escaped for the current string. Returns false if v cannot
+// be represented without changing the structure of the document.
+func (st tomlState) escape(v string) (string, bool) {
+	switch st {
+	case tomlBasic, tomlMultiBasic:
+		var buf strings.Builder
+		for _, r := range v {
+			switch r {
+			case '"':
+				buf.WriteString(`\"`)
+			case '\\':
+				buf.WriteString(`\\`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				if r < 0x20 || r == 0x7F {
+					fmt.Fprintf(&buf, `\u%04X`, r)
+				} else {
+					buf.WriteRune(r)
+				}
 			}
-			backgrounds = append(backgrounds, c)
 		}
+		return buf.String(), true
+	case tomlLiteral:
+		return v, !strings.Contains(v, "'") && !hasControl(v, "\t")
+	case tomlMultiLiteral:
+		return v, !strings.Contains(v, "'") && !hasControl(v, "\t\n")
+	default:
+		return v, configBareRegexp.MatchString(v)
+	}
+}
+
+// invalid returns the reason a value could not be escaped for the state.
+func (st tomlState) invalid() string {
+	switch st {
+	case tomlLiteral, tomlMultiLiteral:
+		return "cannot contain quotes or control characters in a literal string"
+	default:
+		return "must be quoted unless it is a number or word"
+	}
+}
+
+// hasControl returns true if s has a control character not in allowed.
+func hasControl(s, allowed string) bool {
+	for _, r := range s {
+		if (r < 0x20 || r == 0x7F) && !strings.ContainsRune(allowed, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandError is returned when a config references an environment variable
+// that is not set or whose value cannot be used where it is referenced.
+type ExpandError struct {
+	Line   int
+	Name   string
+	Reason string // empty if the variable is not set
+}
+
+// Error returns the error message.
+func (e *ExpandError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message())
+}
+
+// Message returns the error message without the line number.
+func (e *ExpandError) Message() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("environment variable not set: %s", e.Name)
+	}
+	return fmt.Sprintf("environment variable %s %s", e.Name, e.Reason)
+}
+
+func init() {
+	boxer.RegisterHandlerFactory("wallpaper", NewWallpaperCommand)
+	boxer.RegisterHandlerFactory("announcement", NewAnnouncementCommand)
+	boxer.RegisterHandlerFactory("menu_bar", NewMenuBarCommand)
+	boxer.RegisterHandlerFactory("shell", NewShellCommand)
+	boxer.RegisterHandlerFactory("sound", NewSoundCommand)
+	boxer.RegisterHandlerFactory("dock", NewDockCommand)
+	boxer.RegisterHandlerFactory("overlay", NewOverlayCommand)
+	boxer.RegisterHandlerFactory("terminal", NewTerminalCommand)
+	boxer.RegisterHandlerFactory("status_file", NewStatusFileCommand)
+	boxer.RegisterHandlerFactory("touch_bar", NewTouchBarCommand)
+	boxer.RegisterHandlerFactory("enforcer", NewEnforcerCommand)
+	boxer.RegisterHandlerFactory("warning", NewWarningCommand)
+	boxer.RegisterHandlerFactory("toggl", NewTogglCommand)
+	boxer.RegisterHandlerFactory("issue_label", NewIssueLabelCommand)
+}
+
+// NewTicker creates a new ticker from configuration.
+func NewTicker(c *Config, exec boxer.Executor) (*boxer.Ticker, error) {
+	return newTicker(c, c.CommandConfigs(), exec)
+}
 
-		// Create a wallpaper generator.
-		generator, err := boxer.NewWallpaperGenerator(time.Now, times, foregrounds, backgrounds)
+// NewNamedTicker creates a ticker for a [[ticker]] section of the config.
+// Its commands generate files in a separate work directory for the ticker.
+func NewNamedTicker(c *Config, tc *TickerConfig, exec boxer.Executor) (*boxer.Ticker, error) {
+	other := *c
+	other.WorkDir = filepath.Join(c.WorkDir, "ticker", tc.Name)
+	other.tickerName = tc.Name
+	return newTicker(&other, tc.CommandConfigs(), exec)
+}
+
+// newTicker creates a new ticker with the given commands & the settings of c.
+func newTicker(c *Config, configs []CommandConfig, exec boxer.Executor) (*boxer.Ticker, error) {
+	t := boxer.NewTicker()
+
+	// Suppress intrusive commands & wallpaper changes while presenting.
+	presentation := newPresentationSuppressor(c, exec)
+	if presentation != nil {
+		t.Suppressors = append(t.Suppressors, presentation)
+	}
+
+	// Record completed intervals to the journal.
+	if c.Journal.Enabled {
+		path, err := c.JournalPath()
 		if err != nil {
-			return nil, fmt.Errorf("wallpaper generator: %s", err)
+			return nil, fmt.Errorf("journal path: %s", err)
 		}
+		t.Journal = boxer.NewFileJournal(path)
+	}
 
-		// Generate a new command.
-		t.Commands = append(t.Commands, boxer.Command{
-			Name:     "wallpaper",
-			Step:     c.Wallpaper.Step.Duration,
-			Interval: c.Wallpaper.Interval.Duration,
-			Handler: boxer.NewWallpaperHandler(
-				exec, boxer.DesktopSize, generator,
-				filepath.Join(c.WorkDir, "wallpaper"),
-			),
-		})
+	// Record to the journal through the goal tracker so it can celebrate
+	// once the goal is met. Commands share the tracker for their progress.
+	if tracker, err := c.GoalTracker(exec); err != nil {
+		return nil, err
+	} else if tracker != nil {
+		t.Journal = tracker
 	}
 
-	if c.Announcement.Enabled {
-		t.Commands = append(t.Commands, boxer.Command{
-			Name:     "announcement",
-			Interval: c.Announcement.Interval.Duration,
-			Handler:  boxer.NewAnnouncementHandler(exec),
-		})
+	// Create commands from the command sections.
+	commands, err := newCommands(c, t, configs, exec, presentation)
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range commands {
+		if cmd.Interval <= 0 {
+			return nil, fmt.Errorf("%s: %s", cmd.Name, boxer.ErrInvalidInterval)
+		} else if cmd.Step > 0 && cmd.Interval%cmd.Step != 0 {
+			return nil, fmt.Errorf("%s: step (%s) must divide interval (%s) evenly", cmd.Name, cmd.Step, cmd.Interval)
+		}
 	}
+	t.Commands = commands
 
-	if c.MenuBar.Enabled {
-		t.Commands = append(t.Commands, boxer.Command{
-			Name:     "menu_bar",
-			Interval: c.MenuBar.Interval.Duration,
-			Handler:  boxer.NewMenuBarHandler(exec),
+	// Parse active hours from config.
+	if c.ActiveHours.Start != "" || c.ActiveHours.End != "" {
+		start, err := ParseTimeOfDay(c.ActiveHours.Start)
+		if err != nil {
+			return nil, fmt.Errorf("active hours: start: %s", err)
+		}
+
+		end, err := ParseTimeOfDay(c.ActiveHours.End)
+		if err != nil {
+			return nil, fmt.Errorf("active hours: end: %s", err)
+		}
+
+		t.ActiveHours = &boxer.TimeRange{Start: start, End: end}
+	}
+
+	// Parse schedule rules from config.
+	for i, sc := range c.Schedule {
+		days, err := ParseWeekdays(sc.Days)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %d: %s", i, err)
+		}
+
+		start, err := ParseTimeOfDay(sc.Start)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %d: start: %s", i, err)
+		}
+
+		end, err := ParseTimeOfDay(sc.End)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %d: end: %s", i, err)
+		}
+
+		t.Schedule = append(t.Schedule, boxer.ScheduleRule{
+			Days:     days,
+			Start:    start,
+			End:      end,
+			Step:     sc.Step.Duration,
+			Interval: sc.Interval.Duration,
 		})
 	}
 
+	// Suppress intrusive commands during calendar events.
+	if c.Calendar.Enabled {
+		s := boxer.NewCalendarSuppressor()
+		s.RefreshInterval = c.Calendar.RefreshInterval.Duration
+		for _, u := range c.Calendar.URLs {
+			s.Fetchers = append(s.Fetchers, boxer.NewICSCalendarFetcher(u))
+		}
+		if len(c.Calendar.URLs) == 0 || len(c.Calendar.Calendars) > 0 {
+			s.Fetchers = append(s.Fetchers, boxer.NewCalendarAppFetcher(boxer.NewCommandExecutor(exec), c.Calendar.Calendars, time.Now))
+		}
+		t.Suppressors = append(t.Suppressors, s)
+	}
+
+	t.WakeThreshold = c.WakeThreshold.Duration
+	t.WakeIntervalHandlers = c.WakeIntervalHandlers
+	t.MaxFailures = c.MaxFailures
+	if c.Resolution.Duration > 0 {
+		t.MaxSleep = c.Resolution.Duration
+	}
+	if c.Timezone != "" {
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %s", err)
+		}
+		t.Location = loc
+	}
+	if t.Overtime, err = boxer.ParseOvertimePolicy(c.Overtime); err != nil {
+		return nil, fmt.Errorf("overtime: %s", err)
+	}
+
+	// Notify the user once a command is quarantined.
+	if c.Breaker.Failures > 0 && c.Breaker.Notify {
+		desktop, err := boxer.NewDesktop(c.Desktop, boxer.NewCommandExecutor(exec))
+		if err != nil {
+			return nil, err
+		}
+		notifier, n, err := c.Breaker.NotificationConfig.Notifier(boxer.NewCommandExecutor(exec), desktop)
+		if err != nil {
+			return nil, fmt.Errorf("breaker: %s", err)
+		}
+		t.QuarantineHandlers = append(t.QuarantineHandlers, boxer.NewQuarantineNotificationHandler(notifier, n))
+	}
+
+	// Suppress intrusive commands while Focus or Do Not Disturb is enabled.
+	if c.Focus.Enabled {
+		d, err := boxer.NewFocusDetector()
+		if err != nil {
+			return nil, fmt.Errorf("focus detector: %s", err)
+		}
+		t.Suppressors = append(t.Suppressors, boxer.NewFocusSuppressor(d))
+	}
+
 	return t, nil
 }
 
-// Config represnts the configuration file used to store command settings.
-type Config struct {
-	WorkDir string `toml:"work_dir"`
-
-	Wallpaper struct {
-		Enabled     bool     `toml:"enabled"`
-		Step        Duration `toml:"step"`
-		Interval    Duration `toml:"interval"`
-		Times       []string `toml:"times"`
-		Foregrounds []string `toml:"foregrounds"`
-		Backgrounds []string `toml:"backgrounds"`
-	} `toml:"wallpaper"`
-
-	MenuBar struct {
-		Enabled  bool     `toml:"enabled"`
-		Interval Duration `toml:"interval"`
-	} `toml:"menu_bar"`
-
-	Announcement struct {
-		Enabled  bool     `toml:"enabled"`
-		Interval Duration `toml:"interval"`
-		Voice    string   `toml:"voice"`
-		Source   string   `toml:"source"`
-	} `toml:"announcement"`
+// NewSummarizer creates a summarizer for the daily summary. The summary
+// defaults to one minute after the end of the active hours so the last
+// interval of the day has been recorded to the journal.
+func NewSummarizer(c *Config, exec boxer.Executor) (*boxer.Summarizer, error) {
+	sc := &c.Summary
+	if !c.Journal.Enabled {
+		return nil, fmt.Errorf("journal must be enabled")
+	}
+	path, err := c.JournalPath()
+	if err != nil {
+		return nil, fmt.Errorf("journal path: %s", err)
+	}
+
+	var at time.Duration
+	switch {
+	case sc.At != "":
+		if at, err = ParseTimeOfDay(sc.At); err != nil {
+			return nil, fmt.Errorf("at: %s", err)
+		}
+	case c.ActiveHours.End != "":
+		if at, err = ParseTimeOfDay(c.ActiveHours.End); err != nil {
+			return nil, fmt.Errorf("active hours: end: %s", err)
+		}
+		at += 1 * time.Minute
+	default:
+		return nil, fmt.Errorf("at required if active hours are not set")
+	}
+
+	s := boxer.NewSummarizer(boxer.NewFileJournal(path), at)
+	s.Command = sc.Command
+	if sc.Notify {
+		desktop, err := boxer.NewDesktop(c.Desktop, boxer.NewCommandExecutor(exec))
+		if err != nil {
+			return nil, err
+		}
+		notifier, n, err := sc.NotificationConfig.Notifier(boxer.NewCommandExecutor(exec), desktop)
+		if err != nil {
+			return nil, err
+		}
+		s.Handlers = append(s.Handlers, boxer.NewSummaryNotificationHandler(notifier, n))
+	}
+	if sc.Dir != "" {
+		s.Handlers = append(s.Handlers, boxer.NewSummaryFileHandler(sc.Dir))
+	}
+	return s, nil
 }
 
-// NewConfig returns an instance of Config with default settings.
-func NewConfig() *Config {
-	var c Config
+// GoalTracker creates a tracker for the goal that celebrates with a
+// notification once the goal is met. Returns nil if the goal is disabled.
+func (c *Config) GoalTracker(exec boxer.Executor) (*boxer.GoalTracker, error) {
+	gc := &c.Goal
+	if !gc.Enabled {
+		return nil, nil
+	} else if !c.Journal.Enabled {
+		return nil, fmt.Errorf("goal: journal must be enabled")
+	}
 
-	c.Wallpaper.Enabled = false
-	c.Wallpaper.Step = Duration{1 * time.Minute}
-	c.Wallpaper.Interval = Duration{15 * time.Minute}
+	goal, err := gc.Goal()
+	if err != nil {
+		return nil, fmt.Errorf("goal: %s", err)
+	}
+	path, err := c.JournalPath()
+	if err != nil {
+		return nil, fmt.Errorf("journal path: %s", err)
+	}
 
-	c.MenuBar.Enabled = false
-	c.MenuBar.Interval = Duration{15 * time.Minute}
+	t := boxer.NewGoalTracker(boxer.NewFileJournal(path), goal)
+	if gc.Notify {
+		desktop, err := boxer.NewDesktop(c.Desktop, boxer.NewCommandExecutor(exec))
+		if err != nil {
+			return nil, err
+		}
+		notifier, n, err := gc.NotificationConfig.Notifier(boxer.NewCommandExecutor(exec), desktop)
+		if err != nil {
+			return nil, fmt.Errorf("goal: %s", err)
+		}
+		t.Handlers = append(t.Handlers, boxer.NewGoalNotificationHandler(notifier, n))
+	}
+	return t, nil
+}
 
-	c.Announcement.Enabled = false
-	c.Announcement.Interval = Duration{30 * time.Minute}
+// newPresentationSuppressor returns a suppressor that is active while
+// presenting. Returns nil if presentation detection is disabled.
+func newPresentationSuppressor(c *Config, exec boxer.Executor) boxer.Suppressor {
+	if !c.Presentation.Enabled {
+		return nil
+	}
 
-	return &c
+	d := boxer.NewPresentationDetector(boxer.NewCommandExecutor(exec))
+	if c.Presentation.Apps != nil {
+		d.Apps = c.Presentation.Apps
+	}
+	return boxer.NewPresentationSuppressor(d)
 }
 
-// Duration is used by the TOML config to parse duration values.
-type Duration struct {
+// newCommands creates the commands for t from the command sections. Wallpaper
+// changes are also suppressed by presentation, if not nil.
+func newCommands(c *Config, t *boxer.Ticker, configs []CommandConfig, exec boxer.Executor, presentation boxer.Suppressor) ([]boxer.Command, error) {
+	var a []boxer.Command
+	for _, cc := range configs {
+		cmd, err := NewCommand(c, t, exec, &cc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", cc.Name, err)
+		}
+		if presentation != nil && cc.Type == "wallpaper" {
+			cmd.Suppressors = append(cmd.Suppressors, presentation)
+		}
+		a = append(a, cmd)
+	}
+	return a, nil
+}
+
+// NewCommand creates a command from a registered handler factory.
+// The options are passed to the factory's decoder so they can be decoded
+// into the factory's own configuration type.
+func NewCommand(c *Config, t *boxer.Ticker, exec boxer.Executor, cc *CommandConfig) (boxer.Command, error) {
+	desktop, err := boxer.NewDesktop(c.Desktop, boxer.NewCommandExecutor(exec))
+	if err != nil {
+		return boxer.Command{}, err
+	}
+
+	hc := &boxer.HandlerConfig{
+		Executor: boxer.NewCommandExecutor(exec),
+		Exec:     exec,
+		Desktop:  desktop,
+		WorkDir:  filepath.Join(c.WorkDir, cc.Name),
+		Step:     cc.Step.Duration,
+		Interval: cc.Interval.Duration,
+		Timeout:  cc.Timeout.Duration,
+		Label:    t.Label,
+		SetLabel: t.SetLabel,
+		Control:  NewTickerClient(c.Socket, c.tickerName).Control,
+		Decode:   func(v interface{}) error { return DecodeOptions(cc.Options, v) },
+	}
+
+	// Provide the progress toward the goal from the ticker's tracker.
+	if tracker, ok := t.Journal.(*boxer.GoalTracker); ok {
+		hc.Goal = tracker.Progress
+	}
+
+	cmd, err := boxer.NewCommand(cc.Type, cc.Name, hc)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	cmd.Timeout = cc.Timeout.Duration
+	if cmd.CatchUp, err = boxer.ParseCatchUpPolicy(cc.CatchUp); err != nil {
+		return boxer.Command{}, err
+	}
+
+	// Align steps & intervals to the time the ticker starts, by its clock,
+	// or to a time of day.
+	switch cc.Align {
+	case "":
+	case "start":
+		cmd.AlignStart = true
+	default:
+		if cmd.Offset, err = ParseTimeOfDay(cc.Align); err != nil {
+			return boxer.Command{}, fmt.Errorf("align: %s", err)
+		}
+	}
+	cmd.Retry = boxer.RetryPolicy{Attempts: cc.Retries, Backoff: cc.RetryBackoff.Duration}
+	if cmd.Retry.Backoff == 0 {
+		cmd.Retry.Backoff = DefaultRetryBackoff
+	}
+	cmd.Breaker = boxer.BreakerPolicy{Failures: c.Breaker.Failures, Interval: c.Breaker.Interval.Duration}
+	return cmd, nil
+}
+
+// DecodeOptions decodes options into v by re-encoding them as TOML.
+func DecodeOptions(options, v interface{}) error {
+	if options == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(options); err != nil {
+		return fmt.Errorf("encode options: %s", err)
+	} else if _, err := toml.Decode(buf.String(), v); err != nil {
+		return fmt.Errorf("decode options: %s", err)
+	}
+	return nil
+}
+
+// NewWallpaperCommand creates a command that updates the desktop wallpaper.
+func NewWallpaperCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	wc := WallpaperConfig{DesktopSizeTTL: Duration{boxer.DefaultDesktopSizeTTL}, BarOpacity: 1, Overdue: DefaultOverdueColor}
+	if err := c.Decode(&wc); err != nil {
+		return boxer.Command{}, err
+	}
+
+	// Determine the segment count for the segmented & grid styles.
+	var segments int
+	if wc.Style == "segmented" || wc.Style == "grid" {
+		if c.Step <= 0 {
+			return boxer.Command{}, fmt.Errorf("step required for %s style", wc.Style)
+		}
+		segments = int(c.Interval / c.Step)
+	}
+
+	enc, err := wc.ImageEncoder()
+	if err != nil {
+		return boxer.Command{}, err
+	}
+
+	darkMode := func() (bool, error) { return boxer.DarkMode(c.Executor) }
+	generator, err := NewWallpaperGenerator(&wc, c.Interval, segments, time.Now, darkMode, enc)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+
+	// Caption the wallpaper with the progress toward the goal.
+	if wc.GoalCaption {
+		if c.Goal == nil {
+			return boxer.Command{}, fmt.Errorf("goal must be enabled for goal caption")
+		}
+		generator = boxer.NewCaptionWallpaperGenerator(generator, enc, func() (string, error) {
+			p, err := c.Goal(time.Now())
+			if err != nil {
+				return "", err
+			}
+			return p.Message(), nil
+		})
+	}
+
+	// The backend & size fallback only apply to the macOS desktop.
+	setter, err := boxer.ParseWallpaperSetter(wc.Backend)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	desktop := c.Desktop
+	if d, ok := desktop.(*boxer.MacDesktop); ok {
+		other := *d
+		other.Setter = setter
+		if other.Sizer == nil {
+			other.Sizer = boxer.NewMacDesktopSizer(log.New(os.Stderr, "", 0), wc.DesktopWidth, wc.DesktopHeight)
+		}
+		desktop = &other
+	}
+
+	// Cache generated images in the work directory.
+	key, err := wc.CacheKey()
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	cache := boxer.NewWallpaperCache(c.WorkDir, generator)
+	cache.MaxSize = int64(wc.MaxCacheSize) * 1024 * 1024
+	cache.Ext = enc.Ext()
+	cache.Key = key
+	cache.Workers = wc.Workers
+	cache.Progress = newWallpaperProgressLogger(log.New(os.Stderr, "", 0), time.Now)
+
+	// Blend the overdue color over steps past the end of an interval held
+	// open by the overtime policy.
+	if wc.Overdue != "" {
+		overdue, err := boxer.ParseColor(wc.Overdue)
+		if err != nil {
+			return boxer.Command{}, fmt.Errorf("parse overdue: %s", err)
+		}
+		cache.Overdue = boxer.NewOverdueWallpaperGenerator(generator, overdue, enc)
+	}
+
+	return boxer.Command{
+		Handler: boxer.NewCachedWallpaperHandler(boxer.NewCachedDesktop(desktop, wc.DesktopSizeTTL.Duration, time.Now), cache),
+	}, nil
+}
+
+// newWallpaperProgressLogger returns a progress callback that logs each
+// quarter of the first pregeneration, such as at startup, which can take a
+// while on large displays. Later pregenerations are not logged.
+func newWallpaperProgressLogger(logger *log.Logger, now boxer.NowFunc) func(done, total int) {
+	var start time.Time
+	var finished bool
+
+	return func(done, total int) {
+		switch {
+		case finished || total == 0:
+		case done == 0:
+			start = now()
+			logger.Printf("wallpaper: generating %d images", total)
+		case done == total:
+			finished = true
+			logger.Printf("wallpaper: generated %d images in %s", total, now().Sub(start).Round(time.Millisecond))
+		case done*4/total != (done-1)*4/total:
+			logger.Printf("wallpaper: generated %d/%d images", done, total)
+		}
+	}
+}
+
+// NewWallpaperGenerator creates the wallpaper generator described by wc.
+// The interval is only used to rotate palettes, the segment count is only
+// used by the segmented & grid styles, and darkMode is only used if dark
+// colors are configured.
+func NewWallpaperGenerator(wc *WallpaperConfig, interval time.Duration, segments int, now boxer.NowFunc, darkMode boxer.DarkModeFunc, enc *boxer.ImageEncoder) (boxer.WallpaperGenerator, error) {
+	// Fill in colors from the bundled palette, if set.
+	wc, err := wc.WithPalette()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse times from config. Sunrise & sunset are calculated daily so
+	// they are left as zero times until then.
+	var times []time.Time
+	var sun bool
+	for _, s := range wc.Times {
+		if s == "sunrise" || s == "sunset" {
+			times, sun = append(times, time.Time{}), true
+			continue
+		}
+
+		t, err := time.Parse("3:04pm", s)
+		if err != nil {
+			return nil, fmt.Errorf("parse time: %s", err)
+		}
+		times = append(times, t)
+	}
+	if sun && (wc.Latitude == nil || wc.Longitude == nil) {
+		return nil, fmt.Errorf("latitude & longitude required for sunrise & sunset times")
+	}
+
+	// Parse colors from config.
+	foregrounds, err := parseColors(wc.Foregrounds)
+	if err != nil {
+		return nil, fmt.Errorf("parse foreground: %s", err)
+	}
+	backgrounds, err := parseColors(wc.Backgrounds)
+	if err != nil {
+		return nil, fmt.Errorf("parse background: %s", err)
+	}
+
+	// Parse the color space used for transitions.
+	space, err := boxer.ParseColorSpace(wc.ColorSpace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the progress bar's placement & size.
+	placement, err := boxer.ParseBarPlacement(wc.BarPlacement)
+	if err != nil {
+		return nil, err
+	}
+	fill, err := boxer.ParseFillDirection(wc.BarFill)
+	if err != nil {
+		return nil, err
+	}
+	bar := boxer.WallpaperBar{Placement: placement, Fill: fill, Thickness: wc.BarThickness, Margin: wc.BarMargin}
+	grid := boxer.WallpaperGrid{Rows: wc.GridRows, Columns: wc.GridColumns, Gap: wc.GridGap, Radius: wc.GridRadius}
+
+	// Read the background photo for the image style.
+	var img boxer.WallpaperImage
+	if wc.Style == "image" {
+		if wc.Image == "" {
+			return nil, fmt.Errorf("image required for image style")
+		}
+		if img.Image, err = boxer.ReadImage(wc.Image); err != nil {
+			return nil, fmt.Errorf("read image: %s", err)
+		}
+		img.Opacity = wc.BarOpacity
+	}
+
+	// Parse the colors used in dark mode.
+	var darkForegrounds, darkBackgrounds []color.RGBA
+	if wc.Dark != nil {
+		if darkForegrounds, err = parseColors(wc.Dark.Foregrounds); err != nil {
+			return nil, fmt.Errorf("dark: parse foreground: %s", err)
+		}
+		if darkBackgrounds, err = parseColors(wc.Dark.Backgrounds); err != nil {
+			return nil, fmt.Errorf("dark: parse background: %s", err)
+		}
+	}
+
+	// Parse the palettes to rotate through each interval.
+	rotation := make([][2][]color.RGBA, len(wc.Rotation))
+	for i, pc := range wc.Rotation {
+		if rotation[i][0], err = parseColors(pc.Foregrounds); err != nil {
+			return nil, fmt.Errorf("rotation %d: parse foreground: %s", i, err)
+		}
+		if rotation[i][1], err = parseColors(pc.Backgrounds); err != nil {
+			return nil, fmt.Errorf("rotation %d: parse background: %s", i, err)
+		}
+	}
+
+	// Create a wallpaper generator for the configured style that rotates
+	// palettes, if any, and uses the dark colors instead while the system is
+	// in dark mode.
+	create := func(times []time.Time) (boxer.WallpaperGenerator, error) {
+		var generator boxer.WallpaperGenerator
+		if len(rotation) > 0 {
+			generators := make([]boxer.WallpaperGenerator, len(rotation))
+			for i, colors := range rotation {
+				g, err := newWallpaperGenerator(now, wc.Style, times, colors[0], colors[1], space, segments, bar, grid, img, enc)
+				if err != nil {
+					return nil, fmt.Errorf("rotation %d: generator: %s", i, err)
+				}
+				generators[i] = g
+			}
+
+			g, err := boxer.NewRotatingWallpaperGenerator(now, interval, generators)
+			if err != nil {
+				return nil, err
+			}
+			generator = g
+		} else {
+			g, err := newWallpaperGenerator(now, wc.Style, times, foregrounds, backgrounds, space, segments, bar, grid, img, enc)
+			if err != nil {
+				return nil, fmt.Errorf("generator: %s", err)
+			}
+			generator = g
+		}
+		if wc.Dark == nil {
+			return generator, nil
+		}
+
+		dark, err := newWallpaperGenerator(now, wc.Style, times, darkForegrounds, darkBackgrounds, space, segments, bar, grid, img, enc)
+		if err != nil {
+			return nil, fmt.Errorf("dark: generator: %s", err)
+		}
+		return boxer.NewDarkModeWallpaperGenerator(darkMode, generator, dark), nil
+	}
+
+	// Recreate the generator every day if the times follow the sun. The
+	// generator is created for today first so errors are reported early.
+	var generator boxer.WallpaperGenerator
+	if sun {
+		sunTimes := func(day time.Time) []time.Time {
+			return resolveSunTimes(wc.Times, times, day, *wc.Latitude, *wc.Longitude)
+		}
+		if _, err := create(sunTimes(now())); err != nil {
+			return nil, err
+		}
+		generator = boxer.NewDailyWallpaperGenerator(now, func(day time.Time) (boxer.WallpaperGenerator, error) {
+			return create(sunTimes(day))
+		})
+	} else if generator, err = create(times); err != nil {
+		return nil, err
+	}
+
+	// Create a generator for each profile. The colors of a profile transition
+	// over its own window of time.
+	if len(wc.Profiles) > 0 {
+		profiles := make([]boxer.WallpaperProfile, len(wc.Profiles))
+		for i, pc := range wc.Profiles {
+			start, err := ParseTimeOfDay(pc.Start)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: start: %s", i, err)
+			}
+			end, err := ParseTimeOfDay(pc.End)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: end: %s", i, err)
+			}
+
+			foregrounds, err := parseColors(pc.Foregrounds)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: parse foreground: %s", i, err)
+			}
+			backgrounds, err := parseColors(pc.Backgrounds)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: parse background: %s", i, err)
+			}
+
+			// Windows that wrap past midnight use the colors for the whole day.
+			var times []time.Time
+			if start < end {
+				times = []time.Time{time.Time{}.Add(start), time.Time{}.Add(end)}
+			}
+
+			g, err := newWallpaperGenerator(now, wc.Style, times, foregrounds, backgrounds, space, segments, bar, grid, img, enc)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: generator: %s", i, err)
+			}
+			profiles[i] = boxer.WallpaperProfile{Hours: boxer.TimeRange{Start: start, End: end}, Generator: g}
+		}
+		generator = boxer.NewProfileWallpaperGenerator(now, profiles, generator)
+	}
+	return generator, nil
+}
+
+// newWallpaperGenerator returns a wallpaper generator for a style.
+func newWallpaperGenerator(now boxer.NowFunc, style string, times []time.Time, foregrounds, backgrounds []color.RGBA, space boxer.ColorSpace, segments int, bar boxer.WallpaperBar, grid boxer.WallpaperGrid, img boxer.WallpaperImage, enc *boxer.ImageEncoder) (boxer.WallpaperGenerator, error) {
+	switch style {
+	case "", "solid":
+		return boxer.NewBarWallpaperGenerator(now, times, foregrounds, backgrounds, space, bar, enc)
+	case "gradient":
+		return boxer.NewGradientWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc)
+	case "ring":
+		return boxer.NewRingWallpaperGenerator(now, times, foregrounds, backgrounds, space, enc)
+	case "segmented":
+		return boxer.NewSegmentedWallpaperGenerator(now, times, foregrounds, backgrounds, space, segments, enc)
+	case "grid":
+		return boxer.NewGridWallpaperGenerator(now, times, foregrounds, backgrounds, space, segments, grid, enc)
+	case "image":
+		return boxer.NewImageWallpaperGenerator(now, times, foregrounds, backgrounds, space, bar, img, enc)
+	default:
+		return nil, fmt.Errorf("invalid style: %q", style)
+	}
+}
+
+// resolveSunTimes returns a copy of times with the "sunrise" & "sunset" entries of
+// names replaced by the sunrise & sunset on day at the latitude & longitude.
+// If the sun does not rise & set on day then no times are returned so the
+// colors transition over the whole day.
+func resolveSunTimes(names []string, times []time.Time, day time.Time, lat, lon float64) []time.Time {
+	sunrise, sunset, ok := boxer.SunTimes(day, lat, lon)
+	if !ok {
+		return nil
+	}
+
+	other := make([]time.Time, len(times))
+	copy(other, times)
+	for i, name := range names {
+		switch name {
+		case "sunrise":
+			other[i] = sunrise
+		case "sunset":
+			other[i] = sunset
+		}
+	}
+	return other
+}
+
+// parseColors parses a list of colors.
+func parseColors(a []string) ([]color.RGBA, error) {
+	var colors []color.RGBA
+	for _, s := range a {
+		c, err := boxer.ParseColor(s)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+// NewAnnouncementCommand creates a command that displays a notification.
+func NewAnnouncementCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	var options struct {
+		NotificationConfig
+		Template string         `toml:"template"`
+		Source   string         `toml:"source"`
+		Actions  []ActionConfig `toml:"actions"`
+	}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+	notifier, n, err := options.NotificationConfig.Notifier(c.Executor, c.Desktop)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+
+	// Add buttons that send control commands to the ticker. An unanswered
+	// notification is dismissed once the next one is due.
+	var actions []boxer.NotificationAction
+	for _, ac := range options.Actions {
+		if _, err := ControlPath(ac.Command); err != nil {
+			return boxer.Command{}, fmt.Errorf("action %q: %s", ac.Label, err)
+		}
+		command, control := ac.Command, c.Control
+		actions = append(actions, boxer.NotificationAction{Label: ac.Label, Run: func() error { return control(command) }})
+	}
+	if x, ok := notifier.(*boxer.TerminalNotifier); ok && len(actions) > 0 {
+		x.Timeout = c.Interval
+		notifier = boxer.NewActionNotifier(x, actions)
+	}
+
+	// Read the announcement text from the source, if specified.
+	var source boxer.MessageSource
+	if options.Source != "" {
+		if source, err = boxer.NewMessageSource(options.Source, c.Executor); err != nil {
+			return boxer.Command{}, err
+		}
+	}
+
+	h, err := boxer.NewAnnouncementHandler(notifier, n, options.Template, source)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{IntervalHandler: h, Intrusive: true}, nil
+}
+
+// NewWarningCommand creates a command that displays a notification when the
+// end of the interval is approaching.
+func NewWarningCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	options := struct {
+		NotificationConfig
+		Before Duration `toml:"before"`
+	}{Before: Duration{5 * time.Minute}}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	} else if options.Before.Duration <= 0 || options.Before.Duration >= c.Interval {
+		return boxer.Command{}, fmt.Errorf("warning must be before the end of the interval")
+	}
+	notifier, n, err := options.NotificationConfig.Notifier(c.Executor, c.Desktop)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{WarningHandler: boxer.NewWarningHandler(notifier, n), Warning: options.Before.Duration, Intrusive: true}, nil
+}
+
+// NewMenuBarCommand creates a command that flashes the menu bar.
+func NewMenuBarCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	mc := MenuBarConfig{
+		Flashes: boxer.DefaultMenuBarFlashes,
+		Delay:   Duration{boxer.DefaultMenuBarDelay},
+	}
+	if err := c.Decode(&mc); err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{IntervalHandler: boxer.NewMenuBarHandler(c.Desktop, mc.Flashes, mc.Delay.Duration), Intrusive: true}, nil
+}
+
+// NewTogglCommand creates a command that tracks each interval as a time
+// entry in Toggl or Clockify, described by the interval's label.
+func NewTogglCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	var tc TogglConfig
+	if err := c.Decode(&tc); err != nil {
+		return boxer.Command{}, err
+	} else if c.Interval <= 0 {
+		return boxer.Command{}, fmt.Errorf("interval required")
+	}
+
+	tracker, err := boxer.NewTimeTracker(tc.Service, tc.Token, tc.Workspace)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{IntervalHandler: boxer.NewTimeTrackingHandler(tracker, c.Interval)}, nil
+}
+
+// NewIssueLabelCommand creates a command that labels each interval with the
+// title of the issue assigned to you that is in progress on GitHub or Jira.
+func NewIssueLabelCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	var options struct {
+		Service string `toml:"service"`
+		URL     string `toml:"url"`
+		User    string `toml:"user"`
+		Token   string `toml:"token"`
+		Query   string `toml:"query"`
+	}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+
+	var fetch boxer.IssueFetcher
+	switch options.Service {
+	case "", "github":
+		fetch = boxer.NewGitHubIssueFetcher(nil, options.URL, options.Token, options.Query)
+	case "jira":
+		var err error
+		if fetch, err = boxer.NewJiraIssueFetcher(nil, options.URL, options.User, options.Token, options.Query); err != nil {
+			return boxer.Command{}, err
+		}
+	default:
+		return boxer.Command{}, fmt.Errorf("invalid issue service: %q", options.Service)
+	}
+	return boxer.Command{IntervalHandler: boxer.NewIssueLabelHandler(fetch, c.Label, c.SetLabel), SetsLabel: true}, nil
+}
+
+// NewShellCommand creates a command that executes a templated shell command
+// on every step. The shell command is killed if it exceeds the command's
+// timeout.
+func NewShellCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	var options struct {
+		Command string `toml:"command"`
+		Dir     string `toml:"dir"`
+	}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	} else if options.Command == "" {
+		return boxer.Command{}, fmt.Errorf("shell command required")
+	}
+
+	h, err := boxer.NewShellHandler(c.RequestExecutor(), options.Command, options.Dir, c.Timeout)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{Handler: h}, nil
+}
+
+// NewSoundCommand creates a command that plays a sound every step and interval.
+// Sounds can either be the name of a bundled sound or a path to a sound file.
+func NewSoundCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	sc := SoundConfig{Volume: 1}
+	if err := c.Decode(&sc); err != nil {
+		return boxer.Command{}, err
+	} else if sc.Volume < 0 || sc.Volume > 1 {
+		return boxer.Command{}, fmt.Errorf("volume must be between 0 and 1")
+	}
+
+	cmd := boxer.Command{Intrusive: true}
+	if sc.StepSound != "" {
+		path, err := boxer.SoundPath(c.WorkDir, sc.StepSound)
+		if err != nil {
+			return boxer.Command{}, err
+		}
+		cmd.Handler = boxer.NewStepSoundHandler(c.Executor, path, sc.Volume)
+	}
+	if sc.IntervalSound != "" {
+		path, err := boxer.SoundPath(c.WorkDir, sc.IntervalSound)
+		if err != nil {
+			return boxer.Command{}, err
+		}
+		cmd.IntervalHandler = boxer.NewSoundHandler(c.Executor, path, sc.Volume)
+	}
+	return cmd, nil
+}
+
+// NewStatusFileCommand creates a command that writes the current step to a
+// JSON file for editor & statusline plugins.
+func NewStatusFileCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	var options struct {
+		Path string `toml:"path"`
+	}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+
+	h, err := boxer.NewStatusFileHandler(options.Path)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{Handler: h}, nil
+}
+
+// NewTouchBarCommand creates a command that shows progress on the Touch Bar
+// with a BetterTouchTool widget.
+func NewTouchBarCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	options := struct {
+		UUID  string `toml:"uuid"`
+		Cells int    `toml:"cells"`
+	}{Cells: boxer.DefaultTouchBarCells}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+
+	h, err := boxer.NewTouchBarHandler(c.Executor, options.UUID, options.Cells)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{Handler: h}, nil
+}
+
+// NewEnforcerCommand creates a command that enforces a break when the
+// interval ends. A notification is displayed "grace" before the end of the
+// interval so the break can be postponed with "boxer snooze".
+func NewEnforcerCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	options := struct {
+		NotificationConfig
+		Action   string   `toml:"action"`
+		Duration Duration `toml:"duration"`
+		Grace    Duration `toml:"grace"`
+		Message  string   `toml:"message"`
+		Escape   bool     `toml:"escape"`
+	}{
+		Duration: Duration{boxer.DefaultBreakDuration},
+		Grace:    Duration{1 * time.Minute},
+		Message:  "Time for a break",
+		Escape:   true,
+	}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	} else if options.Duration.Duration <= 0 {
+		return boxer.Command{}, fmt.Errorf("duration must be greater than zero")
+	} else if options.Grace.Duration < 0 || options.Grace.Duration >= c.Interval {
+		return boxer.Command{}, fmt.Errorf("grace must be less than the interval")
+	}
+
+	action, err := boxer.ParseEnforcerAction(options.Action)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	cmd := boxer.Command{
+		IntervalHandler: boxer.NewEnforcerHandler(c.Executor, action, options.Duration.Duration, options.Message, options.Escape),
+		Intrusive:       true,
+	}
+
+	// Warn before the break so it can be snoozed.
+	if options.Grace.Duration > 0 {
+		notifier, n, err := options.NotificationConfig.Notifier(c.Executor, c.Desktop)
+		if err != nil {
+			return boxer.Command{}, err
+		}
+		if n.Subtitle == "" {
+			n.Subtitle = "Break starts soon"
+		}
+		cmd.WarningHandler, cmd.Warning = boxer.NewWarningHandler(notifier, n), options.Grace.Duration
+	}
+	return cmd, nil
+}
+
+// NewDockCommand creates a command that shows progress as the icon of a
+// folder that can be kept in the Dock.
+func NewDockCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	options := struct {
+		Path       string `toml:"path"`
+		Foreground string `toml:"foreground"`
+		Track      string `toml:"track"`
+	}{Foreground: "#C97C7C", Track: "#534B4D"}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+
+	// Default to a "Boxer" folder in the home directory.
+	if options.Path == "" {
+		u, err := user.Current()
+		if err != nil {
+			return boxer.Command{}, err
+		}
+		options.Path = filepath.Join(u.HomeDir, "Boxer")
+	}
+
+	fg, err := boxer.ParseColor(options.Foreground)
+	if err != nil {
+		return boxer.Command{}, fmt.Errorf("parse foreground: %s", err)
+	}
+	track, err := boxer.ParseColor(options.Track)
+	if err != nil {
+		return boxer.Command{}, fmt.Errorf("parse track: %s", err)
+	}
+
+	return boxer.Command{Handler: boxer.NewDockIconHandler(c.Executor, c.WorkDir, options.Path, c.Step, fg, track)}, nil
+}
+
+// NewOverlayCommand creates a command that writes a progress image with a
+// transparent background for streaming overlays. The size defaults to a
+// 400x24 bar or a 128x128 ring.
+func NewOverlayCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	options := struct {
+		Path       string `toml:"path"`
+		Style      string `toml:"style"`
+		Width      int    `toml:"width"`
+		Height     int    `toml:"height"`
+		Foreground string `toml:"foreground"`
+		Track      string `toml:"track"`
+	}{Foreground: "#C97C7C", Track: "rgba(83, 75, 77, 0.5)"}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+
+	style, err := boxer.ParseOverlayStyle(options.Style)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	if options.Width == 0 && options.Height == 0 {
+		options.Width, options.Height = 400, 24
+		if style == boxer.OverlayRing {
+			options.Width, options.Height = 128, 128
+		}
+	}
+
+	fg, err := boxer.ParseColor(options.Foreground)
+	if err != nil {
+		return boxer.Command{}, fmt.Errorf("parse foreground: %s", err)
+	}
+	track, err := boxer.ParseColor(options.Track)
+	if err != nil {
+		return boxer.Command{}, fmt.Errorf("parse track: %s", err)
+	}
+
+	h, err := boxer.NewOverlayHandler(options.Path, style, options.Width, options.Height, fg, track)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	return boxer.Command{Handler: h}, nil
+}
+
+// NewTerminalCommand creates a command that shifts the terminal background
+// color as the interval progresses.
+func NewTerminalCommand(c *boxer.HandlerConfig) (boxer.Command, error) {
+	options := struct {
+		App        string `toml:"app"`
+		TTY        string `toml:"tty"`
+		From       string `toml:"from"`
+		To         string `toml:"to"`
+		ColorSpace string `toml:"color_space"`
+	}{From: "#1E1E1E", To: "#3A2424"}
+	if err := c.Decode(&options); err != nil {
+		return boxer.Command{}, err
+	}
+
+	app, err := boxer.ParseTerminalApp(options.App)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+	from, err := boxer.ParseColor(options.From)
+	if err != nil {
+		return boxer.Command{}, fmt.Errorf("parse from: %s", err)
+	}
+	to, err := boxer.ParseColor(options.To)
+	if err != nil {
+		return boxer.Command{}, fmt.Errorf("parse to: %s", err)
+	}
+	space, err := boxer.ParseColorSpace(options.ColorSpace)
+	if err != nil {
+		return boxer.Command{}, err
+	}
+
+	return boxer.Command{Handler: boxer.NewTerminalBackgroundHandler(c.Executor, app, options.TTY, from, to, space)}, nil
+}
+
+// ParseTimeOfDay parses a time in the "3:04pm" format and returns it as
+// an offset from midnight.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("3:04pm", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)), nil
+}
+
+// ParseWeekdays parses a list of weekday names. Names can be abbreviated
+// (e.g. "mon") and inclusive ranges are specified with a dash ("mon-fri").
+func ParseWeekdays(a []string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, s := range a {
+		// Parse a single day.
+		if !strings.Contains(s, "-") {
+			day, err := parseWeekday(s)
+			if err != nil {
+				return nil, err
+			}
+			days = append(days, day)
+			continue
+		}
+
+		// Parse a range of days, wrapping past the end of the week if needed.
+		parts := strings.SplitN(s, "-", 2)
+		first, err := parseWeekday(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		last, err := parseWeekday(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseWeekday parses a single full or abbreviated weekday name.
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday: %q", s)
+}
+
+// Config represnts the configuration file used to store command settings.
+type Config struct {
+	WorkDir   string `toml:"work_dir"`
+	Desktop   string `toml:"desktop"`
+	LogFormat string `toml:"log_format"`
+	Socket    string `toml:"socket"`
+
+	// Path to the file that saves the interval start set by "boxer start".
+	AnchorPath string `toml:"anchor_path"`
+
+	// Path to the file that records the apps boxer has been allowed to
+	// control on macOS.
+	AutomationPath string `toml:"automation_path"`
+
+	WakeThreshold        Duration `toml:"wake_threshold"`
+	WakeIntervalHandlers bool     `toml:"wake_interval_handlers"`
+
+	// Disables a command after its handlers fail this many times in a row.
+	MaxFailures int `toml:"max_failures"`
+
+	// The maximum time between ticks.
+	Resolution Duration `toml:"resolution"`
+
+	// Delays each tick by a random duration up to the jitter.
+	Jitter Duration `toml:"jitter"`
+
+	// The time zone steps & intervals are aligned to, such as "UTC".
+	// Defaults to the local time zone.
+	Timezone string `toml:"timezone"`
+
+	// How an interval begun by "boxer start" ends: "none" begins the next
+	// interval & "hold" runs over until the next "boxer start".
+	Overtime string `toml:"overtime"`
+
+	HTTP struct {
+		Listen string `toml:"listen"`
+	} `toml:"http"`
+
+	Exec struct {
+		Allow []string `toml:"allow"`
+		Audit bool     `toml:"audit"`
+	} `toml:"exec"`
+
+	ActiveHours struct {
+		Start string `toml:"start"`
+		End   string `toml:"end"`
+	} `toml:"active_hours"`
+
+	Schedule []ScheduleConfig `toml:"schedule"`
+
+	Calendar CalendarConfig `toml:"calendar"`
+
+	Focus struct {
+		Enabled bool `toml:"enabled"`
+	} `toml:"focus"`
+
+	Presentation struct {
+		Enabled bool     `toml:"enabled"`
+		Apps    []string `toml:"apps"`
+	} `toml:"presentation"`
+
+	Journal struct {
+		Enabled bool   `toml:"enabled"`
+		Path    string `toml:"path"`
+	} `toml:"journal"`
+
+	Wallpaper    WallpaperConfig    `toml:"wallpaper"`
+	MenuBar      MenuBarConfig      `toml:"menu_bar"`
+	Announcement AnnouncementConfig `toml:"announcement"`
+	Sound        SoundConfig        `toml:"sound"`
+	Toggl        TogglConfig        `toml:"toggl"`
+	Commands     []CommandConfig    `toml:"command"`
+	Tickers      []TickerConfig     `toml:"ticker"`
+	Summary      SummaryConfig      `toml:"summary"`
+	Breaker      BreakerConfig      `toml:"breaker"`
+	Goal         GoalConfig         `toml:"goal"`
+
+	// Named sets of commands, [profile.NAME], that replace the command
+	// sections above while the profile is active. See WithProfile().
+	Profiles map[string]toml.Primitive `toml:"profile"`
+
+	// Metadata used to decode the profiles.
+	md toml.MetaData
+
+	// The name of the ticker the config's commands run on, if not the main
+	// ticker. Control commands sent by handlers are routed to it.
+	tickerName string
+}
+
+// SummaryConfig represents the configuration for the daily summary of the
+// intervals in the journal.
+type SummaryConfig struct {
+	Enabled bool   `toml:"enabled"`
+	At      string `toml:"at"`
+	Command string `toml:"command"`
+	Notify  bool   `toml:"notify"`
+	Dir     string `toml:"dir"`
+	NotificationConfig
+}
+
+// BreakerConfig represents the configuration for quarantining commands that
+// fail persistently.
+type BreakerConfig struct {
+	Failures int      `toml:"failures"`
+	Interval Duration `toml:"interval"`
+	Notify   bool     `toml:"notify"`
+	NotificationConfig
+}
+
+// GoalConfig represents the configuration for the goal of completed
+// intervals per day or week.
+type GoalConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Command string `toml:"command"`
+	Period  string `toml:"period"`
+	Target  int    `toml:"target"`
+	Notify  bool   `toml:"notify"`
+	NotificationConfig
+}
+
+// Goal returns the goal described by the config.
+func (c *GoalConfig) Goal() (boxer.Goal, error) {
+	period, err := boxer.ParseGoalPeriod(c.Period)
+	if err != nil {
+		return boxer.Goal{}, err
+	} else if c.Target <= 0 {
+		return boxer.Goal{}, fmt.Errorf("target must be greater than zero")
+	}
+	return boxer.Goal{Command: c.Command, Period: period, Target: c.Target}, nil
+}
+
+// TickerConfig represents a named ticker that runs its own commands
+// independently of the main ticker. It shares the schedule, active hours,
+// suppressors, and journal of the main ticker.
+type TickerConfig struct {
+	Name     string          `toml:"name"`
+	Step     Duration        `toml:"step"`
+	Interval Duration        `toml:"interval"`
+	Commands []CommandConfig `toml:"command"`
+}
+
+// CommandConfigs returns the [[ticker.command]] sections. Commands without
+// a name are named after their type and commands without a step or interval
+// use the ticker's.
+func (c *TickerConfig) CommandConfigs() []CommandConfig {
+	var a []CommandConfig
+	for _, cc := range c.Commands {
+		if cc.Name == "" {
+			cc.Name = cc.Type
+		}
+		if cc.Step.Duration == 0 {
+			cc.Step = c.Step
+		}
+		if cc.Interval.Duration == 0 {
+			cc.Interval = c.Interval
+		}
+		a = append(a, cc)
+	}
+	return a
+}
+
+// JournalPath returns the configured journal path or the default path.
+func (c *Config) JournalPath() (string, error) {
+	if c.Journal.Path != "" {
+		return c.Journal.Path, nil
+	}
+	return DefaultJournalPath()
+}
+
+// DefaultAnchorPath returns the default anchor path.
+// The default path is the "anchor" file in the data directory. A
+// ".boxer_anchor" file in the user's home directory is moved there first.
+func DefaultAnchorPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return defaultPath(dir, "anchor", ".boxer_anchor")
+}
+
+// DefaultJournalPath returns the default journal path.
+// The default path is the "journal.jsonl" file in the data directory. A
+// ".boxer_journal.jsonl" file in the user's home directory is moved there first.
+func DefaultJournalPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return defaultPath(dir, "journal.jsonl", ".boxer_journal.jsonl")
+}
+
+// CommandConfigs returns the enabled built-in command sections followed by
+// the generic [[command]] sections. Generic commands without a name are
+// named after their type.
+func (c *Config) CommandConfigs() []CommandConfig {
+	var a []CommandConfig
+	if c.Wallpaper.Enabled {
+		a = append(a, CommandConfig{Type: "wallpaper", Name: "wallpaper", Step: c.Wallpaper.Step, Interval: c.Wallpaper.Interval, Timeout: c.Wallpaper.Timeout, Retries: c.Wallpaper.Retries, RetryBackoff: c.Wallpaper.RetryBackoff, CatchUp: c.Wallpaper.CatchUp, Align: c.Wallpaper.Align, Options: c.Wallpaper})
+	}
+	if c.Announcement.Enabled {
+		a = append(a, CommandConfig{Type: "announcement", Name: "announcement", Interval: c.Announcement.Interval, Timeout: c.Announcement.Timeout, Retries: c.Announcement.Retries, RetryBackoff: c.Announcement.RetryBackoff, CatchUp: c.Announcement.CatchUp, Align: c.Announcement.Align, Options: c.Announcement})
+	}
+	if c.MenuBar.Enabled {
+		a = append(a, CommandConfig{Type: "menu_bar", Name: "menu_bar", Interval: c.MenuBar.Interval, Timeout: c.MenuBar.Timeout, Retries: c.MenuBar.Retries, RetryBackoff: c.MenuBar.RetryBackoff, CatchUp: c.MenuBar.CatchUp, Align: c.MenuBar.Align, Options: c.MenuBar})
+	}
+	if c.Toggl.Enabled {
+		a = append(a, CommandConfig{Type: "toggl", Name: "toggl", Interval: c.Toggl.Interval, Timeout: c.Toggl.Timeout, Retries: c.Toggl.Retries, RetryBackoff: c.Toggl.RetryBackoff, CatchUp: c.Toggl.CatchUp, Align: c.Toggl.Align, Options: c.Toggl})
+	}
+	if c.Sound.Enabled {
+		a = append(a, CommandConfig{Type: "sound", Name: "sound", Step: c.Sound.Step, Interval: c.Sound.Interval, Timeout: c.Sound.Timeout, Retries: c.Sound.Retries, RetryBackoff: c.Sound.RetryBackoff, CatchUp: c.Sound.CatchUp, Align: c.Sound.Align, Options: c.Sound})
+	}
+
+	for _, cc := range c.Commands {
+		if cc.Name == "" {
+			cc.Name = cc.Type
+		}
+		a = append(a, cc)
+	}
+	return a
+}
+
+// Set overrides the config value at the dotted key, such as
+// "wallpaper.interval", with value. The value is parsed as a TOML value, such
+// as 25, true, or ["#FFFFFF"], or used as a string if that fails. Keys within
+// arrays of tables & profiles cannot be set.
+func (c *Config) Set(key, value string) error {
+	if !configSetKeyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid key: %q", key)
+	} else if key == "profile" || strings.HasPrefix(key, "profile.") {
+		return fmt.Errorf("profiles cannot be set")
+	}
+
+	// Place the key within its table.
+	var table string
+	if i := strings.LastIndex(key, "."); i != -1 {
+		table, key = "["+key[:i]+"]\n", key[i+1:]
+	}
+
+	md, err := toml.Decode(table+key+" = "+value, c)
+	if err != nil {
+		if md, err = toml.Decode(table+key+" = "+strconv.Quote(value), c); err != nil {
+			return err
+		}
+	}
+	if len(md.Undecoded()) > 0 {
+		return fmt.Errorf("unknown key")
+	}
+	return nil
+}
+
+var configSetKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// ConfigOverrides represents a repeatable "-set key=value" flag that
+// overrides values in the config file.
+type ConfigOverrides []string
+
+// String returns the overrides separated by commas.
+func (a *ConfigOverrides) String() string {
+	return strings.Join(*a, ",")
+}
+
+// Set adds a "key=value" override.
+func (a *ConfigOverrides) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("expected key=value: %q", s)
+	}
+	*a = append(*a, s)
+	return nil
+}
+
+// Apply sets each override on c, in order.
+func (a ConfigOverrides) Apply(c *Config) error {
+	for _, s := range a {
+		i := strings.Index(s, "=")
+		if err := c.Set(s[:i], s[i+1:]); err != nil {
+			return fmt.Errorf("set %s: %s", s[:i], err)
+		}
+	}
+	return nil
+}
+
+// ProfileConfig represents the command sections of a [profile.NAME] section.
+type ProfileConfig struct {
+	Wallpaper    WallpaperConfig    `toml:"wallpaper"`
+	MenuBar      MenuBarConfig      `toml:"menu_bar"`
+	Announcement AnnouncementConfig `toml:"announcement"`
+	Sound        SoundConfig        `toml:"sound"`
+	Toggl        TogglConfig        `toml:"toggl"`
+	Commands     []CommandConfig    `toml:"command"`
+}
+
+// WithProfile returns a copy of the config with the command sections of the
+// named profile applied over the top-level command sections. Keys that the
+// profile does not set keep their top-level values, except that [[command]]
+// & other arrays of tables set by the profile replace the top-level arrays.
+// A blank name returns a copy of the config unchanged.
+//
+// Returns boxer.ErrProfileNotFound if the config has no such profile.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	other := *c
+	if name == "" {
+		return &other, nil
+	}
+
+	prim, ok := c.Profiles[name]
+	if !ok {
+		return nil, boxer.ErrProfileNotFound
+	}
+
+	// Copy the top-level sections so decoding the profile over them cannot
+	// modify the slices they share with c.
+	var p ProfileConfig
+	if err := DecodeOptions(ProfileConfig{
+		Wallpaper:    c.Wallpaper,
+		MenuBar:      c.MenuBar,
+		Announcement: c.Announcement,
+		Sound:        c.Sound,
+		Toggl:        c.Toggl,
+		Commands:     c.Commands,
+	}, &p); err != nil {
+		return nil, err
+	}
+
+	md := &other.md
+	if md.IsDefined("profile", name, "command") {
+		p.Commands = nil
+	}
+	if md.IsDefined("profile", name, "wallpaper", "rotation") {
+		p.Wallpaper.Rotation = nil
+	}
+	if md.IsDefined("profile", name, "wallpaper", "profile") {
+		p.Wallpaper.Profiles = nil
+	}
+	if md.IsDefined("profile", name, "announcement", "actions") {
+		p.Announcement.Actions = nil
+	}
+	if err := md.PrimitiveDecode(prim, &p); err != nil {
+		return nil, err
+	}
+
+	other.Wallpaper, other.MenuBar, other.Announcement = p.Wallpaper, p.MenuBar, p.Announcement
+	other.Sound, other.Toggl, other.Commands = p.Sound, p.Toggl, p.Commands
+	return &other, nil
+}
+
+// WallpaperConfig represents the configuration for the wallpaper command.
+type WallpaperConfig struct {
+	Enabled      bool     `toml:"enabled"`
+	Step         Duration `toml:"step"`
+	Interval     Duration `toml:"interval"`
+	Timeout      Duration `toml:"timeout"`
+	Retries      int      `toml:"retries"`
+	RetryBackoff Duration `toml:"retry_backoff"`
+	CatchUp      string   `toml:"catch_up"`
+	Align        string   `toml:"align"`
+	Style        string   `toml:"style"`
+	Times        []string `toml:"times"`
+	Foregrounds  []string `toml:"foregrounds"`
+	Backgrounds  []string `toml:"backgrounds"`
+	ColorSpace   string   `toml:"color_space"`
+
+	// Bundled palette, such as "nord", used for any colors not set above.
+	Palette string `toml:"palette"`
+
+	// Color blended over the wallpaper once an interval runs over with
+	// overtime = "hold". If blank, the wallpaper stays on the last step.
+	Overdue string `toml:"overdue"`
+
+	// Location used to calculate "sunrise" & "sunset" times, in degrees.
+	Latitude  *float64 `toml:"latitude"`
+	Longitude *float64 `toml:"longitude"`
+
+	// Progress bar size & position for the solid & image styles.
+	BarPlacement string `toml:"bar_placement"`
+	BarFill      string `toml:"bar_fill"`
+	BarThickness int    `toml:"bar_thickness"`
+	BarMargin    int    `toml:"bar_margin"`
+
+	// Cell layout for the grid style.
+	GridRows    int `toml:"grid_rows"`
+	GridColumns int `toml:"grid_columns"`
+	GridGap     int `toml:"grid_gap"`
+	GridRadius  int `toml:"grid_radius"`
+
+	// Background photo & bar opacity for the image style.
+	Image      string  `toml:"image"`
+	BarOpacity float64 `toml:"bar_opacity"`
+
+	// Maximum size of the image cache, in megabytes.
+	MaxCacheSize int `toml:"max_cache_size"`
+
+	// Image format, "png" or "jpeg", & encoder settings. The quality only
+	// applies to JPEG & the compression only applies to PNG.
+	Format      string `toml:"format"`
+	Quality     int    `toml:"quality"`
+	Compression string `toml:"compression"`
+
+	// Number of images generated concurrently. Defaults to the number of CPUs.
+	Workers int `toml:"workers"`
+
+	// Time to cache the desktop size before querying it again.
+	DesktopSizeTTL Duration `toml:"desktop_size_ttl"`
+
+	// Size used if the desktop size cannot be queried from the Finder or
+	// system_profiler, such as over SSH.
+	DesktopWidth  int `toml:"desktop_width"`
+	DesktopHeight int `toml:"desktop_height"`
+
+	// Backend used to set the wallpaper: "osascript", "native", or "spaces".
+	Backend string `toml:"backend"`
+
+	// Draws the progress toward the goal in the corner of the wallpaper.
+	GoalCaption bool `toml:"goal_caption"`
+
+	// Colors used while the system is in dark mode.
+	Dark *WallpaperPaletteConfig `toml:"dark"`
+
+	// Colors cycled through one interval at a time instead of the colors above.
+	Rotation []WallpaperPaletteConfig `toml:"rotation"`
+
+	// Colors used during windows of the day instead of the colors above.
+	Profiles []WallpaperProfileConfig `toml:"profile"`
+}
+
+// ImageEncoder returns the encoder for the configured image format.
+func (wc *WallpaperConfig) ImageEncoder() (*boxer.ImageEncoder, error) {
+	format, err := boxer.ParseImageFormat(wc.Format)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := boxer.ParsePNGCompression(wc.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := &boxer.ImageEncoder{Format: format, Quality: wc.Quality, Compression: compression}
+	if err := enc.Validate(); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// WithPalette returns a copy of wc with the foreground, background, and dark
+// colors of its palette filled in where they are not set. Returns wc if no
+// palette is set.
+func (wc *WallpaperConfig) WithPalette() (*WallpaperConfig, error) {
+	if wc.Palette == "" {
+		return wc, nil
+	}
+	p, err := boxer.LoadPalette(wc.Palette)
+	if err != nil {
+		return nil, err
+	}
+
+	other := *wc
+	if len(other.Foregrounds) == 0 {
+		other.Foregrounds = p.Foregrounds
+	}
+	if len(other.Backgrounds) == 0 {
+		other.Backgrounds = p.Backgrounds
+	}
+	if other.Dark == nil && p.Dark != nil {
+		other.Dark = &WallpaperPaletteConfig{Foregrounds: p.Dark.Foregrounds, Backgrounds: p.Dark.Backgrounds}
+	}
+	return &other, nil
+}
+
+// CacheKey returns a hash of the settings that change the generated images
+// so images from a previous config are not reused.
+func (wc *WallpaperConfig) CacheKey() (string, error) {
+	other := *wc
+	other.Enabled, other.Timeout, other.Retries, other.RetryBackoff = false, Duration{}, 0, Duration{}
+	other.CatchUp, other.Align, other.Backend = "", "", ""
+	other.MaxCacheSize, other.Workers = 0, 0
+	other.DesktopSizeTTL, other.DesktopWidth, other.DesktopHeight = Duration{}, 0, 0
+	return boxer.WallpaperKey(&other)
+}
+
+// DefaultOverdueColor is the default color blended over the wallpaper once
+// an interval runs over.
+const DefaultOverdueColor = "#C0392B"
+
+// WallpaperPaletteConfig represents the [wallpaper.dark] block or a
+// [[wallpaper.rotation]] block in the config.
+type WallpaperPaletteConfig struct {
+	Foregrounds []string `toml:"foregrounds"`
+	Backgrounds []string `toml:"backgrounds"`
+}
+
+// WallpaperProfileConfig represents a [[wallpaper.profile]] block in the config.
+type WallpaperProfileConfig struct {
+	Start       string   `toml:"start"`
+	End         string   `toml:"end"`
+	Foregrounds []string `toml:"foregrounds"`
+	Backgrounds []string `toml:"backgrounds"`
+}
+
+// MenuBarConfig represents the configuration for the menu bar command.
+type MenuBarConfig struct {
+	Enabled      bool     `toml:"enabled"`
+	Interval     Duration `toml:"interval"`
+	Timeout      Duration `toml:"timeout"`
+	Retries      int      `toml:"retries"`
+	RetryBackoff Duration `toml:"retry_backoff"`
+	CatchUp      string   `toml:"catch_up"`
+	Align        string   `toml:"align"`
+	Flashes      int      `toml:"flashes"`
+	Delay        Duration `toml:"delay"`
+}
+
+// TogglConfig represents the configuration for tracking intervals as time
+// entries in Toggl or Clockify.
+type TogglConfig struct {
+	Enabled      bool     `toml:"enabled"`
+	Interval     Duration `toml:"interval"`
+	Timeout      Duration `toml:"timeout"`
+	Retries      int      `toml:"retries"`
+	RetryBackoff Duration `toml:"retry_backoff"`
+	CatchUp      string   `toml:"catch_up"`
+	Align        string   `toml:"align"`
+	Service      string   `toml:"service"`
+	Token        string   `toml:"token"`
+	Workspace    string   `toml:"workspace"`
+}
+
+// AnnouncementConfig represents the configuration for the announcement command.
+type AnnouncementConfig struct {
+	Enabled      bool     `toml:"enabled"`
+	Interval     Duration `toml:"interval"`
+	Timeout      Duration `toml:"timeout"`
+	Retries      int      `toml:"retries"`
+	RetryBackoff Duration `toml:"retry_backoff"`
+	CatchUp      string   `toml:"catch_up"`
+	Align        string   `toml:"align"`
+	Voice        string   `toml:"voice"`
+	Source       string   `toml:"source"`
+	Template     string   `toml:"template"`
+	NotificationConfig
+
+	Actions []ActionConfig `toml:"actions"`
+}
+
+// ActionConfig represents a notification button that sends a control
+// command, such as "snooze 5m" or "skip", to the ticker when clicked.
+type ActionConfig struct {
+	Label   string `toml:"label"`
+	Command string `toml:"command"`
+}
+
+// NotificationConfig represents the options of commands that display
+// notifications.
+type NotificationConfig struct {
+	Backend  string `toml:"notifier"`
+	Title    string `toml:"title"`
+	Subtitle string `toml:"subtitle"`
+	Sound    string `toml:"sound"`
+}
+
+// Notifier returns the configured notifier & a notification with the
+// configured title, subtitle & sound. The desktop displays notifications if
+// no notifier is configured.
+func (c *NotificationConfig) Notifier(exec boxer.CommandExecutor, desktop boxer.Desktop) (boxer.Notifier, boxer.Notification, error) {
+	var notifier boxer.Notifier = desktop
+	if c.Backend != "" || desktop == nil {
+		var err error
+		if notifier, err = boxer.NewNotifier(c.Backend, exec); err != nil {
+			return nil, boxer.Notification{}, err
+		}
+	}
+	return notifier, boxer.Notification{Title: c.Title, Subtitle: c.Subtitle, Sound: c.Sound}, nil
+}
+
+// CalendarConfig represents the configuration for suppressing intrusive
+// commands during calendar events.
+type CalendarConfig struct {
+	Enabled         bool     `toml:"enabled"`
+	URLs            []string `toml:"urls"`
+	Calendars       []string `toml:"calendars"`
+	RefreshInterval Duration `toml:"refresh_interval"`
+}
+
+// SoundConfig represents the configuration for the sound command.
+type SoundConfig struct {
+	Enabled       bool     `toml:"enabled"`
+	Step          Duration `toml:"step"`
+	Interval      Duration `toml:"interval"`
+	Timeout       Duration `toml:"timeout"`
+	Retries       int      `toml:"retries"`
+	RetryBackoff  Duration `toml:"retry_backoff"`
+	CatchUp       string   `toml:"catch_up"`
+	Align         string   `toml:"align"`
+	StepSound     string   `toml:"step_sound"`
+	IntervalSound string   `toml:"interval_sound"`
+	Volume        float64  `toml:"volume"`
+}
+
+// CommandConfig represents a generic [[command]] section in the config.
+// The type refers to a handler factory registered with the boxer package.
+type CommandConfig struct {
+	Type         string      `toml:"type"`
+	Name         string      `toml:"name"`
+	Step         Duration    `toml:"step"`
+	Interval     Duration    `toml:"interval"`
+	Timeout      Duration    `toml:"timeout"`
+	Retries      int         `toml:"retries"`
+	RetryBackoff Duration    `toml:"retry_backoff"`
+	CatchUp      string      `toml:"catch_up"`
+	Align        string      `toml:"align"`
+	Options      interface{} `toml:"options"`
+}
+
+// ScheduleConfig represents a single [[schedule]] rule in the config.
+type ScheduleConfig struct {
+	Days     []string `toml:"days"`
+	Start    string   `toml:"start"`
+	End      string   `toml:"end"`
+	Step     Duration `toml:"step"`
+	Interval Duration `toml:"interval"`
+}
+
+// NewConfig returns an instance of Config with default settings.
+func NewConfig() *Config {
+	var c Config
+
+	c.WakeThreshold = Duration{5 * time.Minute}
+
+	c.Breaker.Interval = Duration{boxer.DefaultBreakerInterval}
+	c.Breaker.Notify = true
+
+	c.Wallpaper.Enabled = false
+	c.Wallpaper.Step = Duration{1 * time.Minute}
+	c.Wallpaper.Interval = Duration{15 * time.Minute}
+	c.Wallpaper.DesktopSizeTTL = Duration{boxer.DefaultDesktopSizeTTL}
+	c.Wallpaper.BarOpacity = 1
+	c.Wallpaper.Overdue = DefaultOverdueColor
+
+	c.MenuBar.Enabled = false
+	c.MenuBar.Interval = Duration{15 * time.Minute}
+	c.MenuBar.Flashes = boxer.DefaultMenuBarFlashes
+	c.MenuBar.Delay = Duration{boxer.DefaultMenuBarDelay}
+
+	c.Toggl.Enabled = false
+	c.Toggl.Interval = Duration{15 * time.Minute}
+	c.Toggl.Timeout = Duration{30 * time.Second}
+
+	c.Announcement.Enabled = false
+	c.Announcement.Interval = Duration{30 * time.Minute}
+
+	c.Calendar.Enabled = false
+	c.Calendar.RefreshInterval = Duration{boxer.DefaultCalendarRefreshInterval}
+
+	c.Sound.Enabled = false
+	c.Sound.Step = Duration{5 * time.Minute}
+	c.Sound.Interval = Duration{30 * time.Minute}
+	c.Sound.IntervalSound = "chime"
+	c.Sound.Volume = 1
+
+	c.Summary.Enabled = false
+	c.Summary.Notify = true
+
+	c.Goal.Enabled = false
+	c.Goal.Notify = true
+
+	return &c
+}
+
+// Duration is used by the TOML config to parse duration values.
+type Duration struct {
 	time.Duration
 }
 
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
 func (d *Duration) UnmarshalText(text []byte) error {
 	v, err := time.ParseDuration(string(text))
 	if err != nil {
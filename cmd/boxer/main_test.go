@@ -1,10 +1,25 @@
 package main_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
 	"github.com/benbjohnson/boxer/cmd/boxer"
 )
 
@@ -30,3 +45,1613 @@ interval = "1h"
 		t.Fatalf("unexpected wallpaper.interval: %v", config.Wallpaper.Interval)
 	}
 }
+
+// Ensure [[schedule]] sections of the config can be parsed into ticker rules.
+func TestNewTicker_Schedule(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[[schedule]]
+days     = ["mon-wed", "sat"]
+start    = "9:00am"
+end      = "5:30pm"
+step     = "25m"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	ticker, err := main.NewTicker(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ticker.Schedule, boxer.Schedule{{
+		Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Saturday},
+		Start: 9 * time.Hour,
+		End:   17*time.Hour + 30*time.Minute,
+		Step:  25 * time.Minute,
+	}}) {
+		t.Fatalf("unexpected schedule: %#v", ticker.Schedule)
+	}
+}
+
+// Ensure weekday ranges can wrap past the end of the week.
+func TestParseWeekdays_Wrap(t *testing.T) {
+	if days, err := main.ParseWeekdays([]string{"Fri-Sun", "monday"}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(days, []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}) {
+		t.Fatalf("unexpected days: %v", days)
+	}
+}
+
+// Ensure invalid weekdays return an error.
+func TestParseWeekdays_ErrInvalid(t *testing.T) {
+	if _, err := main.ParseWeekdays([]string{"funday"}); err == nil || err.Error() != `invalid weekday: "funday"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the [active_hours] section of the config can be parsed.
+func TestNewTicker_ActiveHours(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[active_hours]
+start = "9:00am"
+end   = "6:15pm"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if ticker, err := main.NewTicker(config, nil); err != nil {
+		t.Fatal(err)
+	} else if *ticker.ActiveHours != (boxer.TimeRange{Start: 9 * time.Hour, End: 18*time.Hour + 15*time.Minute}) {
+		t.Fatalf("unexpected active hours: %#v", ticker.ActiveHours)
+	}
+}
+
+// Ensure the summary defaults to one minute after the end of the active hours.
+func TestNewSummarizer(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[active_hours]
+start = "9:00am"
+end   = "5:00pm"
+
+[journal]
+enabled = true
+path    = "/tmp/journal.jsonl"
+
+[summary]
+enabled = true
+notify  = false
+dir     = "/tmp/summaries"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := main.NewSummarizer(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if s.At != 17*time.Hour+1*time.Minute {
+		t.Fatalf("unexpected time: %s", s.At)
+	} else if s.Journal.Path() != "/tmp/journal.jsonl" {
+		t.Fatalf("unexpected journal path: %s", s.Journal.Path())
+	} else if len(s.Handlers) != 1 {
+		t.Fatalf("unexpected handler count: %d", len(s.Handlers))
+	}
+
+	// The journal is required.
+	config.Journal.Enabled = false
+	if _, err := main.NewSummarizer(config, nil); err == nil || err.Error() != `journal must be enabled` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the goal wraps the ticker's journal & requires the journal.
+func TestNewTicker_Goal(t *testing.T) {
+	var goal func(time.Time) (*boxer.GoalProgress, error)
+	boxer.RegisterHandlerFactory("test_goal_command", func(c *boxer.HandlerConfig) (boxer.Command, error) {
+		goal = c.Goal
+		return boxer.Command{IntervalHandler: func(ctx *boxer.Context) error { return nil }}, nil
+	})
+
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[journal]
+enabled = true
+path    = "/tmp/journal.jsonl"
+
+[goal]
+enabled = true
+period  = "week"
+target  = 40
+notify  = false
+
+[[command]]
+type     = "test_goal_command"
+interval = "25m"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	ticker, err := main.NewTicker(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if goal == nil {
+		t.Fatal("expected command to receive the goal progress")
+	} else if tracker, ok := ticker.Journal.(*boxer.GoalTracker); !ok {
+		t.Fatalf("unexpected journal: %T", ticker.Journal)
+	} else if tracker.Goal != (boxer.Goal{Period: boxer.GoalWeekly, Target: 40}) || tracker.Journal.Path() != "/tmp/journal.jsonl" {
+		t.Fatalf("unexpected tracker: %#v", tracker)
+	}
+
+	config.Journal.Enabled = false
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `goal: journal must be enabled` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure [[ticker]] sections create tickers whose commands default to the
+// ticker's step & interval.
+func TestNewNamedTicker(t *testing.T) {
+	boxer.RegisterHandlerFactory("test_ticker_command", func(c *boxer.HandlerConfig) (boxer.Command, error) {
+		return boxer.Command{IntervalHandler: func(ctx *boxer.Context) error { return nil }}, nil
+	})
+
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[[ticker]]
+name     = "stretch"
+interval = "1h"
+
+[[ticker.command]]
+type = "test_ticker_command"
+
+[[ticker.command]]
+type     = "test_ticker_command"
+name     = "reminder"
+step     = "5m"
+interval = "15m"
+`, &config); err != nil {
+		t.Fatal(err)
+	} else if len(config.Tickers) != 1 {
+		t.Fatalf("unexpected ticker count: %d", len(config.Tickers))
+	}
+
+	ticker, err := main.NewNamedTicker(config, &config.Tickers[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ticker.Commands) != 2 {
+		t.Fatalf("unexpected command count: %d", len(ticker.Commands))
+	} else if cmd := ticker.Commands[0]; cmd.Name != "test_ticker_command" || cmd.Interval != 1*time.Hour || cmd.Step != 0 {
+		t.Fatalf("unexpected command: %#v", cmd)
+	} else if cmd := ticker.Commands[1]; cmd.Name != "reminder" || cmd.Interval != 15*time.Minute || cmd.Step != 5*time.Minute {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+}
+
+// Ensure [[command]] sections create commands from registered factories.
+func TestNewTicker_Command(t *testing.T) {
+	var message string
+	boxer.RegisterHandlerFactory("test_command", func(c *boxer.HandlerConfig) (boxer.Command, error) {
+		var options struct {
+			Message string `toml:"message"`
+		}
+		if err := c.Decode(&options); err != nil {
+			return boxer.Command{}, err
+		}
+		message = options.Message
+		return boxer.Command{IntervalHandler: func(ctx *boxer.Context) error { return nil }}, nil
+	})
+
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[[command]]
+type     = "test_command"
+interval = "20m"
+catch_up = "all"
+align    = "9:05am"
+
+[command.options]
+message = "hello"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	ticker, err := main.NewTicker(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ticker.Commands) != 1 {
+		t.Fatalf("unexpected command count: %d", len(ticker.Commands))
+	} else if cmd := ticker.Commands[0]; cmd.Name != "test_command" || cmd.Interval != 20*time.Minute || cmd.IntervalHandler == nil || cmd.CatchUp != boxer.CatchUpAll || cmd.Offset != 9*time.Hour+5*time.Minute {
+		t.Fatalf("unexpected command: %#v", cmd)
+	} else if message != "hello" {
+		t.Fatalf("unexpected message option: %q", message)
+	}
+}
+
+// Ensure [[command]] sections with an unregistered type return an error.
+func TestNewTicker_Command_ErrUnknownType(t *testing.T) {
+	config := main.NewConfig()
+	config.Commands = []main.CommandConfig{{Type: "no_such_type"}}
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `no_such_type: unknown command type: "no_such_type"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure commands without an interval are rejected rather than panicking
+// when they tick.
+func TestNewTicker_Command_ErrInvalidInterval(t *testing.T) {
+	boxer.RegisterHandlerFactory("test_zero_interval", func(c *boxer.HandlerConfig) (boxer.Command, error) {
+		return boxer.Command{IntervalHandler: func(ctx *boxer.Context) error { return nil }}, nil
+	})
+
+	config := main.NewConfig()
+	config.Commands = []main.CommandConfig{{Type: "test_zero_interval"}}
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `test_zero_interval: interval must be greater than zero` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure commands whose step does not divide their interval are rejected.
+func TestNewTicker_Command_ErrStep(t *testing.T) {
+	boxer.RegisterHandlerFactory("test_step", func(c *boxer.HandlerConfig) (boxer.Command, error) {
+		return boxer.Command{Handler: func(ctx *boxer.Context) error { return nil }}, nil
+	})
+
+	config := main.NewConfig()
+	config.Commands = []main.CommandConfig{{Type: "test_step", Interval: main.Duration{1 * time.Minute}, Step: main.Duration{2 * time.Minute}}}
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `test_step: step (2m0s) must divide interval (1m0s) evenly` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the announcement's notifier is decoded from its section.
+func TestNewTicker_Announcement_ErrNotifier(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[announcement]
+enabled  = true
+notifier = "growl"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `announcement: invalid notifier: "growl"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure announcement actions are decoded & their commands validated.
+func TestNewTicker_Announcement_ErrAction(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[announcement]
+enabled = true
+actions = [
+  { label = "Extend 5m", command = "snooze 5m" },
+  { label = "Start break", command = "break" },
+]
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `announcement: action "Start break": unknown control command: "break"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure an enforcer's grace period must be within the interval.
+func TestNewTicker_Enforcer_ErrGrace(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[[command]]
+type     = "enforcer"
+interval = "30m"
+
+[command.options]
+grace = "45m"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `enforcer: grace must be less than the interval` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the toggl section requires an API token.
+func TestNewTicker_Toggl_ErrToken(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[toggl]
+enabled   = true
+workspace = "42"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `toggl: api token required` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure control commands are converted to control socket paths.
+func TestControlPath(t *testing.T) {
+	for command, exp := range map[string]string{
+		"skip":              "/skip",
+		"snooze 5m":         "/snooze?duration=5m",
+		"label code review": "/label?label=code+review",
+	} {
+		if path, err := main.ControlPath(command); err != nil {
+			t.Fatal(err)
+		} else if path != exp {
+			t.Fatalf("%s: unexpected path: %s", command, path)
+		}
+	}
+
+	if _, err := main.ControlPath("snooze soon"); err == nil || err.Error() != `snooze: invalid duration: "soon"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a "warning" command is created with the time before the interval ends.
+func TestNewTicker_Warning(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[[command]]
+type     = "warning"
+interval = "30m"
+
+[command.options]
+before = "2m"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	ticker, err := main.NewTicker(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if cmd := ticker.Commands[0]; cmd.Warning != 2*time.Minute || cmd.WarningHandler == nil {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+
+	// Warnings must occur within the interval.
+	config.Commands[0].Interval.Duration = 1 * time.Minute
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `warning: warning must be before the end of the interval` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the [wallpaper] section creates a wallpaper command.
+func TestNewTicker_Wallpaper(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[wallpaper]
+enabled     = true
+style       = "segmented"
+times       = ["9:00am"]
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#000000"]
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if ticker, err := main.NewTicker(config, nil); err != nil {
+		t.Fatal(err)
+	} else if cmd := ticker.Commands[0]; cmd.Name != "wallpaper" || cmd.Step != 1*time.Minute || cmd.Interval != 15*time.Minute || cmd.Handler == nil {
+		t.Fatalf("unexpected command: %#v", cmd)
+	}
+}
+
+// Ensure the wallpaper cache key only changes with settings that change the image.
+func TestWallpaperConfig_CacheKey(t *testing.T) {
+	wc := main.WallpaperConfig{Style: "solid", Foregrounds: []string{"#FFFFFF"}}
+	key, err := wc.CacheKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := wc
+	other.Workers, other.MaxCacheSize = 4, 100
+	if k, err := other.CacheKey(); err != nil {
+		t.Fatal(err)
+	} else if k != key {
+		t.Fatalf("unexpected key change: %s != %s", k, key)
+	}
+
+	other.Foregrounds = []string{"#000000"}
+	if k, err := other.CacheKey(); err != nil {
+		t.Fatal(err)
+	} else if k == key {
+		t.Fatalf("expected key to change: %s", k)
+	}
+}
+
+// Ensure a bundled palette fills in the colors that are not set.
+func TestWallpaperConfig_WithPalette(t *testing.T) {
+	wc := main.WallpaperConfig{Palette: "nord", Backgrounds: []string{"#000000"}}
+	if other, err := wc.WithPalette(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other.Foregrounds, []string{"#5E81AC", "#B48EAD"}) || !reflect.DeepEqual(other.Backgrounds, []string{"#000000"}) {
+		t.Fatalf("unexpected colors: %v %v", other.Foregrounds, other.Backgrounds)
+	} else if other.Dark == nil || !reflect.DeepEqual(other.Dark.Backgrounds, []string{"#2E3440"}) {
+		t.Fatalf("unexpected dark colors: %#v", other.Dark)
+	}
+
+	if problems := main.CheckConfig("[wallpaper]\nenabled = true\npalette = \"gruvbox\"\n"); len(problems) != 0 {
+		t.Fatalf("unexpected problem: %s", problems[0].Message)
+	} else if problems := main.CheckConfig("[wallpaper]\nenabled = true\npalette = \"neon\"\n"); len(problems) == 0 || problems[0].Message != `wallpaper.palette: unknown palette "neon", expected one of: default, gruvbox, high-contrast, muted, nord, solarized` {
+		t.Fatalf("unexpected problems: %d", len(problems))
+	}
+}
+
+// Ensure wallpaper times can follow the sun if a location is set.
+func TestNewTicker_WallpaperSunTimes(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[wallpaper]
+enabled     = true
+times       = ["sunrise", "sunset"]
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#000000"]
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `wallpaper: latitude & longitude required for sunrise & sunset times` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := toml.Decode(`
+[wallpaper]
+latitude  = 40.7128
+longitude = -74.0060
+`, &config); err != nil {
+		t.Fatal(err)
+	} else if _, err := main.NewTicker(config, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the pause & resume commands control a ticker over the socket.
+func TestMain_RunControl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Serve a ticker over a control socket.
+	path := filepath.Join(dir, "boxer.sock")
+	ln, err := main.ListenSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ticker := boxer.NewTicker()
+	go http.Serve(ln, boxer.NewHTTPHandler(ticker))
+
+	// Pause the ticker.
+	var buf bytes.Buffer
+	m := main.NewMain()
+	m.Stdout = &buf
+	if err := m.Run([]string{"pause", "-socket", path}); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "boxer is paused\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	} else if !ticker.Status().Paused {
+		t.Fatal("expected paused")
+	}
+
+	// Resume the ticker.
+	buf.Reset()
+	if err := m.Run([]string{"resume", "-socket", path}); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "boxer is running\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	} else if ticker.Status().Paused {
+		t.Fatal("expected resumed")
+	}
+}
+
+// Ensure control commands can be sent to a named ticker.
+func TestMain_RunControl_Ticker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "boxer.sock")
+	ln, err := main.ListenSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ticker, stretch := boxer.NewTicker(), boxer.NewTicker()
+	go http.Serve(ln, &boxer.HTTPHandler{Ticker: ticker, Tickers: map[string]*boxer.Ticker{"stretch": stretch}})
+
+	m := main.NewMain()
+	m.Stdout = ioutil.Discard
+	if err := m.Run([]string{"pause", "-socket", path, "-ticker", "stretch"}); err != nil {
+		t.Fatal(err)
+	} else if !stretch.Status().Paused || ticker.Status().Paused {
+		t.Fatal("expected only the named ticker to be paused")
+	}
+
+	if err := m.Run([]string{"snooze", "-socket", path, "-ticker", "no_such_ticker", "5m"}); err == nil || err.Error() != `ticker not found: "no_such_ticker"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a command can be disabled in the running daemon.
+func TestMain_RunCommandControl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "boxer.sock")
+	ln, err := main.ListenSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ticker := boxer.NewTicker()
+	ticker.Commands = []boxer.Command{{Name: "menu_bar", Interval: 30 * time.Minute}}
+	go http.Serve(ln, boxer.NewHTTPHandler(ticker))
+
+	var buf bytes.Buffer
+	m := main.NewMain()
+	m.Stdout = &buf
+	if err := m.Run([]string{"disable", "-socket", path, "menu_bar"}); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "menu_bar disabled\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	} else if !ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected disabled")
+	}
+
+	// Unknown commands return the daemon's error.
+	if err := m.Run([]string{"enable", "-socket", path, "wallpaper"}); err == nil || err.Error() != `command not found: "wallpaper"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the status command prints the position of each command.
+func TestMain_RunStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Serve a ticker over a control socket.
+	path := filepath.Join(dir, "boxer.sock")
+	ln, err := main.ListenSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ticker := boxer.NewTicker()
+	ticker.Clock = clock.NewMock(time.Date(2000, 1, 1, 0, 7, 30, 0, time.UTC))
+	ticker.Commands = []boxer.Command{{Name: "wallpaper", Step: 1 * time.Minute, Interval: 15 * time.Minute, Handler: func(ctx *boxer.Context) error { return nil }}}
+	ticker.Tick()
+	go http.Serve(ln, boxer.NewHTTPHandler(ticker))
+
+	// Print human-readable status.
+	var buf bytes.Buffer
+	m := main.NewMain()
+	m.Stdout = &buf
+	if err := m.Run([]string{"status", "-socket", path}); err != nil {
+		t.Fatal(err)
+	} else if s := buf.String(); !strings.HasPrefix(s, "boxer is running\n") || !strings.Contains(s, "wallpaper  8/15") || !strings.Contains(s, "7m30s") {
+		t.Fatalf("unexpected output: %s", s)
+	}
+
+	// Print status as JSON.
+	buf.Reset()
+	var status boxer.Status
+	if err := m.Run([]string{"status", "-socket", path, "-json"}); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(buf.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	} else if len(status.Commands) != 1 || status.Commands[0].Remaining != 450 {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+}
+
+// Ensure an unknown subcommand returns an error.
+func TestMain_Run_ErrUnknownCommand(t *testing.T) {
+	if err := main.NewMain().Run([]string{"no_such_command"}); err == nil || err.Error() != `unknown command: no_such_command` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the journal is exported as CSV within the range.
+func TestMain_Run_Export(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "journal.jsonl")
+	j := boxer.NewFileJournal(path)
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	for _, r := range []*boxer.IntervalRecord{
+		{Command: "wallpaper", Label: "invoice, acme", Start: start, End: start.Add(90 * time.Minute)},
+		{Command: "wallpaper", Start: start.AddDate(0, 0, 1), End: start.AddDate(0, 0, 1).Add(30 * time.Minute), Skipped: true},
+	} {
+		if err := j.Record(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	m := main.NewMain()
+	m.Stdout = &buf
+	if err := m.Run([]string{"export", "-journal", path}); err != nil {
+		t.Fatal(err)
+	} else if exp := "command,label,start,end,hours,paused,skipped\n" +
+		"wallpaper,\"invoice, acme\",2000-01-01T09:00:00Z,2000-01-01T10:30:00Z,1.5,false,false\n" +
+		"wallpaper,,2000-01-02T09:00:00Z,2000-01-02T09:30:00Z,0.5,false,true\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	var rows []main.ExportRecord
+	if err := m.Run([]string{"export", "-journal", path, "-format", "json"}); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatal(err)
+	} else if len(rows) != 2 || rows[0].Hours != 1.5 || !rows[1].Skipped {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+
+	// Only export the first day.
+	records, err := j.Records()
+	if err != nil {
+		t.Fatal(err)
+	} else if rows := main.ExportRecords(records, start, start.Add(24*time.Hour)); len(rows) != 1 || rows[0].Label != "invoice, acme" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+
+	if err := m.Run([]string{"export", "-journal", path, "-format", "xml"}); err == nil || err.Error() != `invalid format: "xml"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure journal records are summarized by day & command.
+func TestSummarize(t *testing.T) {
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.Local)
+	rows := main.Summarize([]*boxer.IntervalRecord{
+		{Command: "wallpaper", Start: start, End: start.Add(1 * time.Hour)},
+		{Command: "wallpaper", Start: start.Add(1 * time.Hour), End: start.Add(2 * time.Hour), Paused: true},
+		{Command: "wallpaper", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), Skipped: true},
+		{Command: "wallpaper", Start: start.AddDate(0, 0, 1), End: start.AddDate(0, 0, 1).Add(30 * time.Minute)},
+	}, false)
+
+	if len(rows) != 2 {
+		t.Fatalf("unexpected row count: %d", len(rows))
+	} else if r := rows[0]; r.Boxed != 1*time.Hour || r.Intervals != 3 || r.Paused != 1 || r.Skipped != 1 {
+		t.Fatalf("unexpected row: %#v", r)
+	} else if r := rows[1]; r.Boxed != 30*time.Minute || r.Intervals != 1 {
+		t.Fatalf("unexpected row: %#v", r)
+	}
+
+	// Group the same records by week.
+	if rows := main.Summarize([]*boxer.IntervalRecord{
+		{Command: "wallpaper", Start: start, End: start.Add(1 * time.Hour)},
+		{Command: "wallpaper", Start: start.AddDate(0, 0, 1), End: start.AddDate(0, 0, 1).Add(1 * time.Hour)},
+	}, true); len(rows) != 1 || rows[0].Boxed != 2*time.Hour || !rows[0].Period.Equal(time.Date(1999, time.December, 27, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+}
+
+// Ensure planned intervals are converted to events once per start & end.
+func TestPlanEvents(t *testing.T) {
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.Local)
+	events := main.PlanEvents([]*boxer.IntervalRecord{
+		{Command: "wallpaper", Start: start, End: start.Add(30 * time.Minute)},
+		{Command: "wallpaper", Start: start.Add(30 * time.Minute), End: start.Add(1 * time.Hour)},
+		{Command: "menu_bar", Start: start, End: start.Add(30 * time.Minute)},
+		{Command: "menu_bar", Start: start.Add(30 * time.Minute), End: start.Add(45 * time.Minute)},
+	}, "", "Timebox")
+
+	if len(events) != 3 {
+		t.Fatalf("unexpected event count: %d", len(events))
+	} else if e := events[0]; e.Summary != "Timebox" || !e.Start.Equal(start) || !e.End.Equal(start.Add(30*time.Minute)) {
+		t.Fatalf("unexpected event: %#v", e)
+	} else if e := events[2]; !e.End.Equal(start.Add(45 * time.Minute)) {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	// Filter by command name.
+	if events := main.PlanEvents([]*boxer.IntervalRecord{
+		{Command: "wallpaper", Start: start, End: start.Add(30 * time.Minute)},
+		{Command: "menu_bar", Start: start, End: start.Add(15 * time.Minute)},
+	}, "menu_bar", "Timebox"); len(events) != 1 || !events[0].End.Equal(start.Add(15*time.Minute)) {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+}
+
+// Ensure export times are parsed as names, dates, or offsets.
+func TestParseExportTime(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 14, 30, 0, 0, time.Local)
+	base := time.Date(2000, time.January, 3, 0, 0, 0, 0, time.Local)
+	for _, tt := range []struct {
+		s   string
+		exp time.Time
+	}{
+		{"now", now},
+		{"today", time.Date(2000, time.January, 1, 0, 0, 0, 0, time.Local)},
+		{"tomorrow", time.Date(2000, time.January, 2, 0, 0, 0, 0, time.Local)},
+		{"2000-02-01", time.Date(2000, time.February, 1, 0, 0, 0, 0, time.Local)},
+		{"+7d", time.Date(2000, time.January, 10, 0, 0, 0, 0, time.Local)},
+		{"+4h", time.Date(2000, time.January, 3, 4, 0, 0, 0, time.Local)},
+	} {
+		if v, err := main.ParseExportTime(tt.s, base, now); err != nil {
+			t.Fatal(err)
+		} else if !v.Equal(tt.exp) {
+			t.Fatalf("%s: unexpected time: %s", tt.s, v)
+		}
+	}
+
+	if _, err := main.ParseExportTime("next week", base, now); err == nil || err.Error() != `invalid time: "next week"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure all problems in a config are reported with their line.
+func TestCheckConfig(t *testing.T) {
+	problems := main.CheckConfig(`log_format = "text"
+
+[wallpaper]
+enabled     = true
+step        = "7m"
+interval    = "30m"
+foregrounds = ["#534B4D", "nocolor"]
+backgrounds = ["#9AC97C"]
+
+[[schedule]]
+days  = ["mon-fri"]
+start = "9:00am"
+end   = "6:00pm"
+
+[[schedule]]
+days  = ["sat"]
+start = "25:00"
+end   = "6:00pm"
+`)
+	var a []string
+	for _, p := range problems {
+		a = append(a, fmt.Sprintf("%d: %s", p.Line, p.Message))
+	}
+	if exp := []string{
+		`17: schedule.1.start: invalid time "25:00", expected a time such as "9:00am"`,
+		`5: wallpaper.step: step (7m0s) must divide interval (30m0s) evenly`,
+		`7: wallpaper.foregrounds: cannot parse color: "nocolor"`,
+	}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected problems: %#v", a)
+	}
+}
+
+// Ensure sub-second steps are allowed down to the minimum step.
+func TestCheckConfig_MinStep(t *testing.T) {
+	problems := main.CheckConfig(`resolution = "10ms"
+
+[sound]
+enabled  = true
+step     = "100ms"
+interval = "1s"
+
+[[command]]
+type     = "shell"
+step     = "50ms"
+interval = "1s"
+`)
+	var a []string
+	for _, p := range problems {
+		a = append(a, fmt.Sprintf("%d: %s", p.Line, p.Message))
+	}
+	if exp := []string{
+		`1: resolution: must be at least 100ms`,
+		`10: command.0.step: must be at least 100ms`,
+	}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected problems: %#v", a)
+	}
+}
+
+// Ensure problems in [[ticker]] sections are reported with their line.
+func TestCheckConfig_Ticker(t *testing.T) {
+	problems := main.CheckConfig(`[[ticker]]
+name     = "stretch"
+interval = "1h"
+
+[[ticker.command]]
+type = "announcement"
+
+[[ticker]]
+name = "stretch"
+
+[[ticker.command]]
+type = "shell"
+
+[[ticker.command]]
+type     = "no_such_type"
+interval = "10m"
+`)
+	var a []string
+	for _, p := range problems {
+		a = append(a, fmt.Sprintf("%d: %s", p.Line, p.Message))
+	}
+	if exp := []string{
+		`9: ticker.1.name: duplicate ticker name "stretch"`,
+		`11: ticker.1.command.0.interval: must be greater than zero`,
+		`15: ticker.1.command.1.type: unknown command type "no_such_type"`,
+	}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected problems: %#v", a)
+	}
+}
+
+// Ensure allowed executables must be absolute paths.
+func TestCheckConfig_ExecAllow(t *testing.T) {
+	problems := main.CheckConfig(`[exec]
+allow = ["/usr/bin/osascript", "afplay"]
+`)
+	if len(problems) != 1 || problems[0].Line != 2 || problems[0].Message != `exec.allow: path "afplay" must be absolute` {
+		t.Fatalf("unexpected problems: %#v", problems)
+	}
+}
+
+// Ensure the overtime policy & overdue color are validated.
+func TestCheckConfig_Overtime(t *testing.T) {
+	problems := main.CheckConfig(`overtime = "forever"
+
+[wallpaper]
+enabled     = true
+foregrounds = ["#534B4D"]
+backgrounds = ["#9AC97C"]
+overdue     = "nocolor"
+`)
+	var a []string
+	for _, p := range problems {
+		a = append(a, fmt.Sprintf("%d: %s", p.Line, p.Message))
+	}
+	if exp := []string{
+		`1: overtime: invalid overtime policy "forever", expected "none" or "hold"`,
+		`7: wallpaper.overdue: cannot parse color: "nocolor"`,
+	}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected problems: %#v", a)
+	}
+}
+
+// Ensure problems in [profile.NAME] sections are reported with their line.
+func TestCheckConfig_Profile(t *testing.T) {
+	problems := main.CheckConfig(`[wallpaper]
+enabled     = true
+foregrounds = ["#534B4D"]
+backgrounds = ["#9AC97C"]
+
+[profile.deepwork.wallpaper]
+step  = "7m"
+shade = "dark"
+`)
+	var a []string
+	for _, p := range problems {
+		a = append(a, fmt.Sprintf("%d: %s", p.Line, p.Message))
+	}
+	if exp := []string{
+		`8: profile.deepwork.wallpaper.shade: unknown key`,
+		`7: profile.deepwork.wallpaper.step: step (7m0s) must divide interval (15m0s) evenly`,
+	}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected problems: %#v", a)
+	}
+}
+
+// Ensure a profile overrides the command sections it sets & replaces the
+// generic commands without changing the config.
+func TestConfig_WithProfile(t *testing.T) {
+	f, err := ioutil.TempFile("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(`
+[wallpaper]
+enabled     = true
+style       = "bar"
+foregrounds = ["#FFFFFF", "#000000"]
+backgrounds = ["#000000", "#FFFFFF"]
+
+[announcement]
+enabled = true
+
+[[command]]
+type = "shell"
+name = "standup"
+
+[profile.deepwork.wallpaper]
+style       = "ring"
+foregrounds = ["#FF0000"]
+
+[profile.deepwork.announcement]
+enabled = false
+
+[[profile.deepwork.command]]
+type = "sound"
+`); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := main.NewMain().ReadConfig(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := config.WithProfile("deepwork")
+	if err != nil {
+		t.Fatal(err)
+	} else if wc := other.Wallpaper; wc.Style != "ring" || !reflect.DeepEqual(wc.Foregrounds, []string{"#FF0000"}) || !reflect.DeepEqual(wc.Backgrounds, []string{"#000000", "#FFFFFF"}) || wc.Interval.Duration != 15*time.Minute {
+		t.Fatalf("unexpected wallpaper: %#v", wc)
+	} else if other.Announcement.Enabled {
+		t.Fatal("expected announcement to be disabled")
+	} else if len(other.Commands) != 1 || other.Commands[0].Type != "sound" || other.Commands[0].Name != "" {
+		t.Fatalf("unexpected commands: %#v", other.Commands)
+	}
+
+	// The original config is unchanged.
+	if wc := config.Wallpaper; wc.Style != "bar" || !reflect.DeepEqual(wc.Foregrounds, []string{"#FFFFFF", "#000000"}) {
+		t.Fatalf("unexpected wallpaper: %#v", wc)
+	} else if !config.Announcement.Enabled || len(config.Commands) != 1 || config.Commands[0].Name != "standup" {
+		t.Fatal("expected config to be unchanged")
+	}
+
+	if _, err := config.WithProfile("meetings"); err != boxer.ErrProfileNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure individual config values can be overridden.
+func TestConfigOverrides_Apply(t *testing.T) {
+	config := main.NewConfig()
+	config.Wallpaper.Foregrounds = []string{"#000000"}
+
+	overrides := main.ConfigOverrides{
+		"wallpaper.interval=50m",
+		"wallpaper.enabled=true",
+		"wallpaper.style=5",
+		`wallpaper.backgrounds=["#FFFFFF", "#EEEEEE"]`,
+		"work_dir=/tmp/boxer",
+	}
+	if err := overrides.Apply(config); err != nil {
+		t.Fatal(err)
+	} else if wc := config.Wallpaper; wc.Interval.Duration != 50*time.Minute || !wc.Enabled || wc.Style != "5" || wc.Step.Duration != time.Minute {
+		t.Fatalf("unexpected wallpaper: %#v", wc)
+	} else if !reflect.DeepEqual(config.Wallpaper.Backgrounds, []string{"#FFFFFF", "#EEEEEE"}) || !reflect.DeepEqual(config.Wallpaper.Foregrounds, []string{"#000000"}) {
+		t.Fatalf("unexpected colors: %#v", config.Wallpaper)
+	} else if config.WorkDir != "/tmp/boxer" {
+		t.Fatalf("unexpected work dir: %s", config.WorkDir)
+	}
+
+	if err := (main.ConfigOverrides{"wallpaper.no_such_key=1"}).Apply(config); err == nil || err.Error() != `set wallpaper.no_such_key: unknown key` {
+		t.Fatal(err)
+	} else if err := (main.ConfigOverrides{"wallpaper.step=soon"}).Apply(config); err == nil {
+		t.Fatal("expected error")
+	} else if err := (main.ConfigOverrides{"profile.deepwork.wallpaper.style=ring"}).Apply(config); err == nil || err.Error() != `set profile.deepwork.wallpaper.style: profiles cannot be set` {
+		t.Fatal(err)
+	}
+
+	var a main.ConfigOverrides
+	if err := a.Set("wallpaper.step"); err == nil || err.Error() != `expected key=value: "wallpaper.step"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure environment variables & "~" are expanded in config values.
+func TestExpandConfig(t *testing.T) {
+	env := map[string]string{"HOME": "/Users/me", "HOOK": "https://example.com/hook?a=$1"}
+	lookupEnv := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+
+	if src, err := main.ExpandConfig(`work_dir = "~/.boxer"
+# socket = "${NO_SUCH_VAR}"
+journal = '~'
+url     = "${HOOK}"
+command = "echo $HOME ~/x"
+`, lookupEnv); err != nil {
+		t.Fatal(err)
+	} else if exp := `work_dir = "/Users/me/.boxer"
+# socket = "${NO_SUCH_VAR}"
+journal = '/Users/me'
+url     = "https://example.com/hook?a=$1"
+command = "echo $HOME ~/x"
+`; src != exp {
+		t.Fatalf("unexpected config:\n%s", src)
+	}
+
+	if _, err := main.ExpandConfig("\ndir = \"${NO_SUCH_VAR}/x\"\n", lookupEnv); err == nil || err.Error() != `line 2: environment variable not set: NO_SUCH_VAR` {
+		t.Fatal(err)
+	}
+
+}
+
+// Ensure expanded values are escaped so they cannot end a string or add keys.
+func TestExpandConfig_Escape(t *testing.T) {
+	env := map[string]string{"HOME": `C:\Users\me`, "TOKEN": "a\"\nsocket = \"/tmp/x", "PORT": "7171", "QUOTE": "it's"}
+	lookupEnv := func(k string) (string, bool) { v, ok := env[k]; return v, ok }
+
+	src, err := main.ExpandConfig(`dir   = "~/boxer"
+token = "${TOKEN}" # ${QUOTE}
+body  = """
+${TOKEN}"""
+port  = ${PORT}
+`, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := `dir   = "C:\\Users\\me/boxer"
+token = "a\"\nsocket = \"/tmp/x" # ${QUOTE}
+body  = """
+a\"\nsocket = \"/tmp/x"""
+port  = 7171
+`; src != exp {
+		t.Fatalf("unexpected config:\n%s", src)
+	}
+
+	var v struct {
+		Dir, Token, Body, Socket string
+		Port                     int
+	}
+	if md, err := toml.Decode(src, &v); err != nil {
+		t.Fatal(err)
+	} else if len(md.Undecoded()) != 0 {
+		t.Fatalf("unexpected keys: %v", md.Undecoded())
+	} else if v.Dir != `C:\Users\me/boxer` || v.Token != env["TOKEN"] || v.Body != env["TOKEN"] || v.Socket != "" || v.Port != 7171 {
+		t.Fatalf("unexpected values: %#v", v)
+	}
+
+	if _, err := main.ExpandConfig("dir = '${QUOTE}'\n", lookupEnv); err == nil || err.Error() != `line 1: environment variable QUOTE cannot contain quotes or control characters in a literal string` {
+		t.Fatal(err)
+	} else if _, err := main.ExpandConfig("\nport = ${TOKEN}\n", lookupEnv); err == nil || err.Error() != `line 2: environment variable TOKEN must be quoted unless it is a number or word` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure wallpaper colors that are hard to tell apart are warned about.
+func TestCheckConfigContrast(t *testing.T) {
+	problems := main.CheckConfigContrast(`[wallpaper]
+enabled     = true
+foregrounds = ["#FF0000", "#FFFFFF"]
+backgrounds = ["#00A000", "#000000"]
+
+[wallpaper.dark]
+foregrounds = ["#202020"]
+backgrounds = ["#101010"]
+`)
+	var a []string
+	for _, p := range problems {
+		a = append(a, fmt.Sprintf("%d: %s", p.Line, p.Message))
+	}
+	if exp := []string{
+		`3: wallpaper.foregrounds: "#FF0000" on "#00A000" is hard to tell apart with deuteranopia (contrast 1.2:1), try "#850000"`,
+		`7: wallpaper.dark.foregrounds: "#202020" on "#101010" has too little contrast (1.2:1), try "#606060"`,
+	}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected problems: %#v", a)
+	}
+
+	// Bundled palettes have enough contrast.
+	for _, name := range boxer.PaletteNames() {
+		wc := &main.WallpaperConfig{Palette: name}
+		if a := wc.ContrastProblems(); len(a) != 0 {
+			t.Fatalf("%s: unexpected problem: %s", name, a[0].Message)
+		}
+	}
+}
+
+// Ensure syntax errors are reported with their line.
+func TestCheckConfig_ErrSyntax(t *testing.T) {
+	problems := main.CheckConfig("[wallpaper]\nenabled = true\nstep = \n")
+	if len(problems) != 1 || problems[0].Line != 3 {
+		t.Fatalf("unexpected problems: %#v", problems)
+	}
+}
+
+// Ensure wallpaper profiles are validated when the ticker is created.
+func TestNewTicker_WallpaperProfile(t *testing.T) {
+	config := main.NewConfig()
+	if _, err := toml.Decode(`
+[wallpaper]
+enabled     = true
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#000000"]
+
+[[wallpaper.profile]]
+start       = "6:00am"
+end         = "12:00pm"
+foregrounds = ["#000000", "#111111"]
+backgrounds = ["#FFD400"]
+
+[[wallpaper.profile]]
+start       = "6:00pm"
+end         = "11:00pm"
+foregrounds = ["#000000"]
+backgrounds = ["nocolor"]
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := main.NewTicker(config, nil); err == nil || err.Error() != `wallpaper: profile 1: parse background: cannot parse color: "nocolor"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.Wallpaper.Profiles[1].Backgrounds = []string{"#C9D3DB"}
+	if _, err := main.NewTicker(config, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure init writes a valid config & does not overwrite an existing one.
+func TestMain_RunInit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "boxer.conf")
+	m := main.NewMain()
+	m.Stdout = ioutil.Discard
+	if err := m.Run([]string{"init", "-path", path, "-start", "8:30am", "-palette", "muted"}); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := m.ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if config.ActiveHours.Start != "8:30am" || config.ActiveHours.End != "5:00pm" {
+		t.Fatalf("unexpected active hours: %#v", config.ActiveHours)
+	} else if config.Wallpaper.Palette != "muted" {
+		t.Fatalf("unexpected palette: %q", config.Wallpaper.Palette)
+	}
+
+	if err := m.Run([]string{"init", "-path", path}); err == nil || !strings.Contains(err.Error(), "config already exists") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure init rejects invalid work hours.
+func TestStarterConfig_ErrInvalidTime(t *testing.T) {
+	if _, err := main.StarterConfig("9am", "5:00pm", "default"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure preview writes a wallpaper image of the requested size.
+func TestMain_RunPreview(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "boxer.conf")
+	if err := ioutil.WriteFile(configPath, []byte(`
+[wallpaper]
+style       = "segmented"
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#000000"]
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "preview.png")
+	m := main.NewMain()
+	m.Stdout = ioutil.Discard
+	m.Executor = boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		t.Fatal("unexpected exec")
+		return nil, nil
+	})
+	if err := m.Run([]string{"preview", "-config", configPath, "-step", "7", "-of", "12", "-width", "40", "-height", "30", "-at", "9:00am", "-out", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if cfg, err := png.DecodeConfig(f); err != nil {
+		t.Fatal(err)
+	} else if cfg.Width != 40 || cfg.Height != 30 {
+		t.Fatalf("unexpected size: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// Steps outside of the interval are rejected.
+	if err := m.Run([]string{"preview", "-config", configPath, "-step", "12", "-of", "12", "-width", "40", "-height", "30"}); err == nil || err.Error() != "step must be between 0 and 11" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure preview uses the dark colors for the dark appearance.
+func TestMain_RunPreview_Appearance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "boxer.conf")
+	if err := ioutil.WriteFile(configPath, []byte(`
+[wallpaper]
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#000000"]
+
+[wallpaper.dark]
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#333333"]
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "preview.png")
+	m := main.NewMain()
+	m.Stdout = ioutil.Discard
+	if err := m.Run([]string{"preview", "-config", configPath, "-of", "2", "-width", "4", "-height", "4", "-appearance", "dark", "-out", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if img, err := png.Decode(f); err != nil {
+		t.Fatal(err)
+	} else if c := img.At(0, 0); c != (color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xFF}) {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+}
+
+// Ensure simulate prints a timeline of events from an accelerated clock.
+func TestMain_RunSimulate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "boxer.conf")
+	if err := ioutil.WriteFile(configPath, []byte(`
+[[command]]
+type     = "shell"
+step     = "10m"
+interval = "30m"
+
+[command.options]
+command = "exit 1"
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	m := main.NewMain()
+	m.Stdout = &buf
+	if err := m.Run([]string{"simulate", "-config", configPath, "-speed", "3600x", "-duration", "30m", "-start", "9:00am"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Handlers are not executed so no failures should be reported.
+	if s := buf.String(); !strings.Contains(s, "9:00am  shell: step 1/3\n") || !strings.Contains(s, "9:20am  shell: step 3/3\n") || strings.Contains(s, "failed") {
+		t.Fatalf("unexpected output: %s", s)
+	}
+}
+
+// Ensure clock speeds can be parsed with or without a suffix.
+func TestParseSpeed(t *testing.T) {
+	if v, err := main.ParseSpeed("60x"); err != nil || v != 60 {
+		t.Fatalf("unexpected speed: %v, %v", v, err)
+	} else if v, err := main.ParseSpeed("2.5"); err != nil || v != 2.5 {
+		t.Fatalf("unexpected speed: %v, %v", v, err)
+	} else if _, err := main.ParseSpeed("0x"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure the menu bar plugin output shows the current step & controls.
+func TestWriteMenuBar(t *testing.T) {
+	var buf bytes.Buffer
+	main.WriteMenuBar(&buf, &boxer.Status{
+		Active: true,
+		Label:  "write design doc",
+		Commands: []boxer.CommandStatus{
+			{Name: "wallpaper", Step: 7, Steps: 15, Elapsed: 450, Remaining: 450},
+			{Name: "menu_bar", Step: 0, Steps: 1, Elapsed: 450, Remaining: 1350, Disabled: true},
+		},
+	}, "", "/usr/local/bin/boxer", "")
+
+	if exp := "" +
+		"1m ▰▰▱▱▱\n" +
+		"---\n" +
+		"write design doc\n" +
+		"wallpaper: step 8/15, 8m left in interval\n" +
+		"menu_bar: disabled | color=gray\n" +
+		"---\n" +
+		"Pause | bash=\"/usr/local/bin/boxer\" param1=pause terminal=false refresh=true\n" +
+		"Skip interval | bash=\"/usr/local/bin/boxer\" param1=skip terminal=false refresh=true\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// Ensure the menu bar shows steps of overtime in a deepening overdue color.
+func TestWriteMenuBar_Overtime(t *testing.T) {
+	var buf bytes.Buffer
+	main.WriteMenuBar(&buf, &boxer.Status{
+		Active: true,
+		Commands: []boxer.CommandStatus{
+			{Name: "wallpaper", Step: 14, Steps: 15, Elapsed: 1020, Overtime: 2},
+		},
+	}, "", "/usr/local/bin/boxer", "")
+
+	if exp := "" +
+		"+2 ▰▰▰▰▰ | color=#d35b26\n" +
+		"---\n" +
+		"wallpaper: 2 step(s) past the end of the interval\n" +
+		"---\n" +
+		"Pause | bash=\"/usr/local/bin/boxer\" param1=pause terminal=false refresh=true\n" +
+		"Skip interval | bash=\"/usr/local/bin/boxer\" param1=skip terminal=false refresh=true\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// Ensure the TUI draws a bar per command with step markers.
+func TestTUIFrame(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 7, 30, 0, time.UTC)
+	lines := main.TUIFrame(&boxer.Status{
+		Time:   now.Add(-30 * time.Second),
+		Active: true,
+		Label:  "write design doc",
+		Commands: []boxer.CommandStatus{
+			{Name: "wallpaper", Step: 1, Steps: 4, Elapsed: 420, Remaining: 480},
+		},
+	}, now, 30)
+
+	if exp := []string{
+		"boxer is running: write design doc",
+		"",
+		"wallpaper      2/4  7m30s left",
+		"███████████████│░░░░░░│░░░░░░░",
+	}; !reflect.DeepEqual(lines, exp) {
+		t.Fatalf("unexpected lines: %#v", lines)
+	}
+}
+
+// Ensure doctor reports a missing automation permission with its fix.
+func TestMain_RunDoctor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "boxer.conf")
+	if err := ioutil.WriteFile(configPath, []byte(`
+work_dir = "`+filepath.Join(dir, "work")+`"
+desktop  = "macos"
+
+[wallpaper]
+enabled     = true
+foregrounds = ["#FFFFFF"]
+backgrounds = ["#000000"]
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var set bool
+	var buf bytes.Buffer
+	m := main.NewMain()
+	m.Stdout = &buf
+	m.Executor = boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		switch src := string(b); {
+		case strings.Contains(src, "bounds of window of desktop"):
+			return []byte("0, 0, 1440, 900\n"), nil
+		case strings.Contains(src, "dark mode"):
+			return []byte("Not authorized to send Apple events to System Events. (-1743)"), fmt.Errorf("exit status 1")
+		case strings.Contains(src, "get POSIX path"):
+			return []byte("/Library/Desktop Pictures/Sonoma.heic\n"), nil
+		case strings.Contains(src, `set desktop picture to POSIX file "/Library/Desktop Pictures/Sonoma.heic"`):
+			set = true
+			return nil, nil
+		}
+		t.Fatalf("unexpected exec: %s %v\n%s", name, args, b)
+		return nil, nil
+	})
+
+	if err := m.Run([]string{"doctor", "-config", configPath}); err == nil || err.Error() != "1 check(s) failed" {
+		t.Fatalf("unexpected error: %v\n%s", err, buf.String())
+	} else if !set {
+		t.Fatal("expected wallpaper to be restored")
+	} else if exp := "ok    config " + configPath + "\n" +
+		"ok    work directory " + filepath.Join(dir, "work") + "\n" +
+		"ok    finder automation\n" +
+		"FAIL  system events automation: exec: Not authorized to send Apple events to System Events. (-1743)\n" +
+		"      fix: allow your terminal to control System Events in System Settings > Privacy & Security > Automation\n" +
+		"ok    wallpaper set & restore\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// Ensure the automation preflight prompts in order, records granted apps, and
+// stops at the first denied app.
+func TestMain_PreflightAutomation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "automation")
+
+	var apps []string
+	var logs bytes.Buffer
+	m := main.NewMain()
+	m.Logger = log.New(&logs, "", 0)
+	exec := boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		apps = append(apps, string(b))
+		if strings.Contains(string(b), "System Events") {
+			return []byte("Not authorized to send Apple events to System Events. (-1743)"), fmt.Errorf("exit status 1")
+		}
+		return nil, nil
+	})
+
+	if err := m.PreflightAutomation(exec, path, []string{"Finder", "System Events", "Calendar"}); err == nil || err.Error() != `not authorized to control System Events: allow it in System Settings > Privacy & Security > Automation, then restart boxer` {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(apps) != 2 || apps[0] != `tell application "Finder" to get name` {
+		t.Fatalf("unexpected scripts: %#v", apps)
+	} else if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected nothing recorded after a denial")
+	}
+
+	// Only unrecorded apps are announced once permission is granted.
+	if err := ioutil.WriteFile(path, []byte("Finder\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	logs.Reset()
+	if err := m.PreflightAutomation(exec, path, []string{"Finder", "Calendar"}); err != nil {
+		t.Fatal(err)
+	} else if logs.String() != "Requesting permission to control Calendar, click \"OK\" if macOS asks...\n" {
+		t.Fatalf("unexpected logs: %s", logs.String())
+	} else if b, _ := ioutil.ReadFile(path); string(b) != "Calendar\nFinder\n" {
+		t.Fatalf("unexpected automation file: %q", b)
+	}
+}
+
+// Ensure XDG directories are used when set.
+func TestConfigDir_XDG(t *testing.T) {
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", "/home/me/.xdg/config")
+	os.Setenv("XDG_DATA_HOME", "/home/me/.xdg/data")
+
+	if dir, err := main.ConfigDir(); err != nil {
+		t.Fatal(err)
+	} else if dir != "/home/me/.xdg/config/boxer" {
+		t.Fatalf("unexpected config dir: %s", dir)
+	} else if dir, err := main.DefaultWorkDir(); err != nil {
+		t.Fatal(err)
+	} else if dir != "/home/me/.xdg/data/boxer/work" {
+		t.Fatalf("unexpected work dir: %s", dir)
+	}
+}
+
+// Ensure a legacy file is moved to its new path unless one exists already.
+func TestMigratePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	legacy, path := filepath.Join(dir, ".boxer_anchor"), filepath.Join(dir, "data", "boxer", "anchor")
+	if err := ioutil.WriteFile(legacy, []byte("1"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := main.MigratePath(legacy, path); p != path {
+		t.Fatalf("unexpected path: %s", p)
+	} else if b, _ := ioutil.ReadFile(path); string(b) != "1" {
+		t.Fatalf("unexpected contents: %q", b)
+	} else if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatal("expected legacy file to be moved")
+	}
+
+	// An existing file is preferred over the legacy file.
+	if err := ioutil.WriteFile(legacy, []byte("2"), 0666); err != nil {
+		t.Fatal(err)
+	} else if p := main.MigratePath(legacy, path); p != path {
+		t.Fatalf("unexpected path: %s", p)
+	} else if b, _ := ioutil.ReadFile(path); string(b) != "1" {
+		t.Fatalf("unexpected contents: %q", b)
+	}
+}
+
+// Ensure a broken config is rejected on reload & reported with the config
+// that stays active.
+func TestMain_ReloadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("[wallpaper]\nenabled = true\ninterval = \"fast\"\n"); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := main.NewMain()
+	_, err = m.ReloadConfig(f.Name(), nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	var entries []*boxer.LogEntry
+	var notifications []*boxer.Notification
+	rerr := &main.ReloadError{Path: "boxer.toml", Profile: "deepwork", Err: err}
+	if err := main.ReportReloadError(
+		LoggerFunc(func(e *boxer.LogEntry) { entries = append(entries, e) }),
+		NotifierFunc(func(n *boxer.Notification) error { notifications = append(notifications, n); return nil }),
+		rerr,
+	); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 || entries[0].Command != "config" || entries[0].Err != rerr {
+		t.Fatalf("unexpected log entries: %#v", entries)
+	} else if len(notifications) != 1 || notifications[0].Message != `reload boxer.toml: time: invalid duration "fast"; still running the previous config with profile "deepwork"` {
+		t.Fatalf("unexpected notifications: %#v", notifications)
+	}
+}
+
+// Ensure settings that are only read at startup are reported when they change.
+func TestStaticSettings(t *testing.T) {
+	prev := main.NewConfig()
+	if keys := main.StaticSettings(prev, main.NewConfig()); len(keys) != 0 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	next := main.NewConfig()
+	if _, err := toml.Decode(`
+resolution = "5s"
+
+[http]
+listen = "127.0.0.1:7171"
+
+[journal]
+enabled = true
+
+[wallpaper]
+interval = "30m"
+`, &next); err != nil {
+		t.Fatal(err)
+	} else if keys := main.StaticSettings(prev, next); !reflect.DeepEqual(keys, []string{"resolution", "http", "journal"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+// LoggerFunc is a function that implements boxer.Logger.
+type LoggerFunc func(e *boxer.LogEntry)
+
+func (fn LoggerFunc) Log(e *boxer.LogEntry) { fn(e) }
+
+// NotifierFunc is a function that implements boxer.Notifier.
+type NotifierFunc func(n *boxer.Notification) error
+
+func (fn NotifierFunc) Notify(n *boxer.Notification) error { return fn(n) }
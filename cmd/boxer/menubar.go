@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunMenuBar prints the status of the running daemon as an xbar/SwiftBar
+// plugin. The menu bar item shows the minutes remaining in the current step
+// along with a progress glyph and the menu can pause, resume, or skip.
+func (m *Main) RunMenuBar(args []string) error {
+	fs := flag.NewFlagSet("boxer-menubar", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	name := fs.String("command", "", "command to display, defaults to the first command")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	program, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("executable: %s", err)
+	}
+
+	// Show an inactive item if the daemon cannot be reached so the plugin
+	// does not display an error.
+	var status boxer.Status
+	if err := NewClient(*socketPath).Do("GET", "/status", &status); err != nil {
+		fmt.Fprintln(m.Stdout, "boxer | color=gray")
+		fmt.Fprintln(m.Stdout, "---")
+		fmt.Fprintln(m.Stdout, "boxer is not running")
+		return nil
+	}
+
+	WriteMenuBar(m.Stdout, &status, *name, program, *socketPath)
+	return nil
+}
+
+// WriteMenuBar writes status to w in the xbar/SwiftBar plugin format. The
+// title displays the command with the given name, or the first command if
+// blank. Menu items execute program to control the daemon on socketPath.
+func WriteMenuBar(w io.Writer, status *boxer.Status, name, program, socketPath string) {
+	// Find the command to display in the title.
+	var cs *boxer.CommandStatus
+	for i := range status.Commands {
+		if name == "" || status.Commands[i].Name == name {
+			cs = &status.Commands[i]
+			break
+		}
+	}
+
+	switch {
+	case status.Paused:
+		fmt.Fprintln(w, "⏸ paused")
+	case !status.Active || cs == nil:
+		fmt.Fprintln(w, "boxer | color=gray")
+	case cs.Overtime > 0:
+		fmt.Fprintf(w, "+%d %s | color=%s\n", cs.Overtime, progressGlyph(cs.Steps, cs.Steps), overdueColor(cs.Overtime))
+	default:
+		fmt.Fprintf(w, "%s %s\n", formatMinutes(stepRemaining(cs)), progressGlyph(cs.Step, cs.Steps))
+	}
+
+	// List every command & its progress.
+	fmt.Fprintln(w, "---")
+	if status.Label != "" {
+		fmt.Fprintf(w, "%s\n", status.Label)
+	}
+	for _, cs := range status.Commands {
+		if cs.Disabled {
+			fmt.Fprintf(w, "%s: disabled | color=gray\n", cs.Name)
+			continue
+		} else if cs.Overtime > 0 {
+			fmt.Fprintf(w, "%s: %d step(s) past the end of the interval\n", cs.Name, cs.Overtime)
+			continue
+		}
+		fmt.Fprintf(w, "%s: step %d/%d, %s left in interval\n", cs.Name, cs.Step+1, cs.Steps, formatMinutes(cs.Remaining))
+	}
+
+	// Add control actions. Arguments are passed as separate params so paths
+	// with spaces do not need to be escaped.
+	fmt.Fprintln(w, "---")
+	action := func(title, command string) {
+		line := fmt.Sprintf("%s | bash=%q param1=%s", title, program, command)
+		if socketPath != "" {
+			line += fmt.Sprintf(" param2=-socket param3=%q", socketPath)
+		}
+		fmt.Fprintln(w, line+" terminal=false refresh=true")
+	}
+	if status.Paused {
+		action("Resume", "resume")
+	} else {
+		action("Pause", "pause")
+	}
+	action("Skip interval", "skip")
+}
+
+// stepRemaining returns the seconds remaining in the command's current step.
+func stepRemaining(cs *boxer.CommandStatus) float64 {
+	if cs.Steps <= 0 {
+		return cs.Remaining
+	}
+	step := (cs.Elapsed + cs.Remaining) / float64(cs.Steps)
+	return float64(cs.Step+1)*step - cs.Elapsed
+}
+
+// overdueColor returns the title color after the given steps of overtime,
+// which deepens from amber to red.
+func overdueColor(overtime int) string {
+	amber, red := color.RGBA{R: 0xE6, G: 0x7E, B: 0x22, A: 0xFF}, color.RGBA{R: 0xC0, G: 0x39, B: 0x2B, A: 0xFF}
+	return boxer.FormatColor(boxer.TransposeColor(amber, red, boxer.OverdueIntensity(overtime)))
+}
+
+// formatMinutes formats seconds as whole minutes, rounded up.
+func formatMinutes(v float64) string {
+	return fmt.Sprintf("%dm", int(math.Ceil(v/time.Minute.Seconds())))
+}
+
+// progressGlyph returns a five cell bar showing the steps completed in an interval.
+func progressGlyph(i, n int) string {
+	const cells = 5
+	if n <= 0 {
+		return ""
+	}
+	filled := (i * cells) / n
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", cells-filled)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+// ConfigDir returns the directory of the default config. This is
+// "$XDG_CONFIG_HOME/boxer" if XDG_CONFIG_HOME is set, or the platform's
+// config directory otherwise.
+func ConfigDir() (string, error) {
+	return userDir("XDG_CONFIG_HOME", defaultConfigHome)
+}
+
+// DataDir returns the directory of boxer's state & cached files, such as the
+// journal & generated wallpapers. This is "$XDG_DATA_HOME/boxer" if
+// XDG_DATA_HOME is set, or the platform's data directory otherwise.
+func DataDir() (string, error) {
+	return userDir("XDG_DATA_HOME", defaultDataHome)
+}
+
+// userDir returns the "boxer" directory within the directory in the env
+// variable. Relative paths are ignored, per the XDG spec, and the default
+// directory within the user's home directory is used instead.
+func userDir(env string, defaultHome func(home string) string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "boxer"), nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(defaultHome(u.HomeDir), "boxer"), nil
+}
+
+// DefaultWorkDir returns the default work directory for generated files.
+// The default path is the "work" directory in the data directory.
+func DefaultWorkDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "work"), nil
+}
+
+// defaultPath returns the path of name within dir. A file left at the legacy
+// path in the user's home directory, from before boxer used XDG paths, is
+// moved there first.
+func defaultPath(dir, name, legacy string) (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return MigratePath(filepath.Join(u.HomeDir, legacy), filepath.Join(dir, name)), nil
+}
+
+// MigratePath moves the file at legacy to path unless a file exists at path
+// already. Returns the path to use, which is legacy if it cannot be moved.
+func MigratePath(legacy, path string) string {
+	if _, err := os.Lstat(path); err == nil {
+		return path
+	} else if _, err := os.Lstat(legacy); err != nil {
+		return path
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		warnf("cannot move %s to %s: %s", legacy, path, err)
+		return legacy
+	} else if err := os.Rename(legacy, path); err != nil {
+		warnf("cannot move %s to %s: %s", legacy, path, err)
+		return legacy
+	}
+	warnf("moved %s to %s", legacy, path)
+	return path
+}
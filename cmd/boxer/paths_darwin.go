@@ -0,0 +1,14 @@
+package main
+
+import "path/filepath"
+
+// defaultConfigHome returns the base config directory if XDG_CONFIG_HOME is
+// not set.
+func defaultConfigHome(home string) string {
+	return filepath.Join(home, "Library", "Application Support")
+}
+
+// defaultDataHome returns the base data directory if XDG_DATA_HOME is not set.
+func defaultDataHome(home string) string {
+	return filepath.Join(home, "Library", "Application Support")
+}
@@ -0,0 +1,14 @@
+package main
+
+import "path/filepath"
+
+// defaultConfigHome returns the base config directory if XDG_CONFIG_HOME is
+// not set.
+func defaultConfigHome(home string) string {
+	return filepath.Join(home, ".config")
+}
+
+// defaultDataHome returns the base data directory if XDG_DATA_HOME is not set.
+func defaultDataHome(home string) string {
+	return filepath.Join(home, ".local", "share")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunPreview generates the configured wallpaper for an arbitrary step and
+// writes it to a file. The desktop is not changed.
+func (m *Main) RunPreview(args []string) error {
+	fs := flag.NewFlagSet("boxer-preview", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	step := fs.Int("step", 0, "step index within the interval")
+	of := fs.Int("of", 0, "total steps per interval, defaults to the configured steps")
+	width := fs.Int("width", 0, "image width, defaults to the desktop width")
+	height := fs.Int("height", 0, "image height, defaults to the desktop height")
+	scale := fs.Float64("scale", 0, "pixels per point, defaults to the desktop scale when sized by the desktop, otherwise 1")
+	at := fs.String("at", "", "time of day used for colors, such as \"3:00pm\"")
+	appearance := fs.String("appearance", "", "\"light\" or \"dark\", defaults to the system appearance")
+	out := fs.String("out", "preview.png", "output path")
+	var overrides ConfigOverrides
+	fs.Var(&overrides, "set", "override a config value, such as wallpaper.interval=50m (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := m.ReadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("read config: %s", err)
+	} else if err := overrides.Apply(config); err != nil {
+		return err
+	}
+	wc := &config.Wallpaper
+
+	// Default to the number of steps in the configured interval.
+	if *of == 0 && wc.Step.Duration > 0 {
+		*of = int(wc.Interval.Duration / wc.Step.Duration)
+	}
+	if *of <= 0 {
+		return fmt.Errorf("step count must be greater than zero")
+	} else if *step < 0 || *step >= *of {
+		return fmt.Errorf("step must be between 0 and %d", *of-1)
+	}
+
+	// Use the desktop size unless a size is specified.
+	if *width == 0 || *height == 0 {
+		desktop, err := boxer.NewDesktop(config.Desktop, boxer.NewCommandExecutor(m.Executor))
+		if err != nil {
+			return err
+		}
+		w, h, err := desktop.Size()
+		if err != nil {
+			return fmt.Errorf("desktop size: %s", err)
+		}
+		if *width == 0 {
+			*width = w
+		}
+		if *height == 0 {
+			*height = h
+		}
+		if *scale == 0 {
+			if *scale, err = boxer.DesktopScale(desktop); err != nil {
+				return fmt.Errorf("desktop scale: %s", err)
+			}
+		}
+	}
+	if *scale == 0 {
+		*scale = 1
+	} else if *scale < 0 {
+		return fmt.Errorf("scale must be greater than zero")
+	}
+
+	// Generate colors for a specific time of day, if specified.
+	now := time.Now
+	if *at != "" {
+		offset, err := ParseTimeOfDay(*at)
+		if err != nil {
+			return fmt.Errorf("parse time: %s", err)
+		}
+		y, mo, d := time.Now().Date()
+		t := time.Date(y, mo, d, 0, 0, 0, 0, time.Local).Add(offset)
+		now = func() time.Time { return t }
+	}
+
+	// Use a specific appearance, if specified.
+	darkMode := func() (bool, error) { return boxer.DarkMode(boxer.NewCommandExecutor(m.Executor)) }
+	switch *appearance {
+	case "":
+	case "light", "dark":
+		v := *appearance == "dark"
+		darkMode = func() (bool, error) { return v, nil }
+	default:
+		return fmt.Errorf("invalid appearance: %q", *appearance)
+	}
+
+	// Encode with the configured settings in the format of the output path.
+	enc, err := wc.ImageEncoder()
+	if err != nil {
+		return err
+	}
+	if format, err := boxer.ParseImageFormat(strings.TrimPrefix(filepath.Ext(*out), ".")); err == nil {
+		enc.Format = format
+	}
+
+	generator, err := NewWallpaperGenerator(wc, wc.Interval.Duration, *of, now, darkMode, enc)
+	if err != nil {
+		return err
+	}
+
+	path, err := filepath.Abs(*out)
+	if err != nil {
+		return err
+	} else if err := generator(path, *width, *height, *scale, float64(*step)/float64(*of)); err != nil {
+		return fmt.Errorf("generate wallpaper: %s", err)
+	}
+
+	fmt.Fprintf(m.Stdout, "wrote preview: %s\n", path)
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// ReloadError represents a config file that could not be reloaded. The
+// previously loaded config remains active.
+type ReloadError struct {
+	Path    string
+	Profile string // active profile, if any
+	Err     error
+}
+
+// Error returns the reason the reload failed & the config that is still active.
+func (e *ReloadError) Error() string {
+	active := "default commands"
+	if e.Profile != "" {
+		active = fmt.Sprintf("profile %q", e.Profile)
+	}
+	return fmt.Sprintf("reload %s: %s; still running the previous config with %s", e.Path, e.Err, active)
+}
+
+// ReloadConfig reads & validates the config at path and applies overrides.
+// The first problem found is returned as the error so a broken edit never
+// replaces a working config.
+func (m *Main) ReloadConfig(path string, overrides ConfigOverrides) (*Config, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if problems := CheckConfig(string(buf)); len(problems) > 0 {
+		p := problems[0]
+		if p.Line > 0 {
+			return nil, fmt.Errorf("line %d: %s", p.Line, p.Message)
+		}
+		return nil, fmt.Errorf("%s", p.Message)
+	}
+
+	config, err := m.ReadConfig(path)
+	if err != nil {
+		return nil, err
+	} else if err := overrides.Apply(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// StaticSettings returns the keys of the settings that differ between prev &
+// next but are only read when boxer starts. A reload only replaces the
+// commands so these are reported instead of silently keeping their values.
+func StaticSettings(prev, next *Config) []string {
+	settings := []struct {
+		key        string
+		prev, next interface{}
+	}{
+		{"work_dir", prev.WorkDir, next.WorkDir},
+		{"desktop", prev.Desktop, next.Desktop},
+		{"log_format", prev.LogFormat, next.LogFormat},
+		{"socket", prev.Socket, next.Socket},
+		{"anchor_path", prev.AnchorPath, next.AnchorPath},
+		{"automation_path", prev.AutomationPath, next.AutomationPath},
+		{"wake_threshold", prev.WakeThreshold, next.WakeThreshold},
+		{"wake_interval_handlers", prev.WakeIntervalHandlers, next.WakeIntervalHandlers},
+		{"max_failures", prev.MaxFailures, next.MaxFailures},
+		{"resolution", prev.Resolution, next.Resolution},
+		{"jitter", prev.Jitter, next.Jitter},
+		{"timezone", prev.Timezone, next.Timezone},
+		{"overtime", prev.Overtime, next.Overtime},
+		{"http", prev.HTTP, next.HTTP},
+		{"exec", prev.Exec, next.Exec},
+		{"active_hours", prev.ActiveHours, next.ActiveHours},
+		{"schedule", prev.Schedule, next.Schedule},
+		{"calendar", prev.Calendar, next.Calendar},
+		{"focus", prev.Focus, next.Focus},
+		{"presentation", prev.Presentation, next.Presentation},
+		{"journal", prev.Journal, next.Journal},
+		{"breaker.notify", []interface{}{prev.Breaker.Notify, prev.Breaker.NotificationConfig}, []interface{}{next.Breaker.Notify, next.Breaker.NotificationConfig}},
+		{"goal", prev.Goal, next.Goal},
+		{"summary", prev.Summary, next.Summary},
+		{"ticker", prev.Tickers, next.Tickers},
+	}
+
+	var keys []string
+	for _, s := range settings {
+		if !reflect.DeepEqual(s.prev, s.next) {
+			keys = append(keys, s.key)
+		}
+	}
+	return keys
+}
+
+// ReportReloadError writes err to logger as a failed "config" entry so it is
+// structured like every other log entry, and displays it as a notification
+// if notifier is set.
+func ReportReloadError(logger boxer.Logger, notifier boxer.Notifier, err *ReloadError) error {
+	logger.Log(&boxer.LogEntry{Time: time.Now(), Command: "config", Err: err})
+
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Notify(&boxer.Notification{
+		Title:    boxer.DefaultNotificationTitle,
+		Subtitle: "Config reload failed",
+		Message:  err.Error(),
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunReport prints a summary of the hours boxed from the journal.
+func (m *Main) RunReport(args []string) error {
+	fs := flag.NewFlagSet("boxer-report", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	journalPath := fs.String("journal", "", "journal path")
+	by := fs.String("by", "day", "group by day or week")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *by != "day" && *by != "week" {
+		return fmt.Errorf("invalid grouping: %q", *by)
+	}
+
+	// Read the journal path from the config, if not specified, along with
+	// the goal, if one is enabled.
+	var goal *boxer.Goal
+	if *journalPath == "" || *configPath != "" {
+		config, err := m.ReadConfig(*configPath)
+		if err != nil {
+			return fmt.Errorf("read config: %s", err)
+		}
+		if *journalPath == "" {
+			if *journalPath, err = config.JournalPath(); err != nil {
+				return fmt.Errorf("journal path: %s", err)
+			}
+		}
+		if config.Goal.Enabled {
+			g, err := config.Goal.Goal()
+			if err != nil {
+				return fmt.Errorf("goal: %s", err)
+			}
+			goal = &g
+		}
+	}
+
+	// Read all records from the journal.
+	f, err := os.Open(*journalPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	records, err := boxer.ReadIntervalRecords(f)
+	if err != nil {
+		return fmt.Errorf("read journal: %s", err)
+	}
+
+	if err := WriteReport(m.Stdout, Summarize(records, *by == "week")); err != nil {
+		return err
+	}
+
+	// Report the progress toward the goal for the current day or week.
+	if goal != nil {
+		p := goal.Progress(records, time.Now())
+		fmt.Fprintf(m.Stdout, "\nGoal: %s (%.0f%%)\n", p.Message(), 100*float64(p.Completed)/float64(p.Target))
+	}
+	return nil
+}
+
+// ReportRow represents the summary of a command's intervals over a period.
+type ReportRow struct {
+	Period    time.Time
+	Command   string
+	Boxed     time.Duration
+	Intervals int
+	Paused    int
+	Skipped   int
+}
+
+// Summarize groups records by command & the day or week that they started.
+// Only intervals that were not paused or skipped count toward boxed time.
+func Summarize(records []*boxer.IntervalRecord, weekly bool) []*ReportRow {
+	type key struct {
+		period  time.Time
+		command string
+	}
+
+	m := make(map[key]*ReportRow)
+	for _, r := range records {
+		// Truncate start to the beginning of the day or week.
+		t := r.Start.Local()
+		period := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		if weekly {
+			period = period.AddDate(0, 0, -((int(period.Weekday()) + 6) % 7))
+		}
+
+		k := key{period: period, command: r.Command}
+		row := m[k]
+		if row == nil {
+			row = &ReportRow{Period: period, Command: r.Command}
+			m[k] = row
+		}
+
+		row.Intervals++
+		switch {
+		case r.Skipped:
+			row.Skipped++
+		case r.Paused:
+			row.Paused++
+		default:
+			row.Boxed += r.Duration()
+		}
+	}
+
+	// Sort by period & then by command.
+	a := make([]*ReportRow, 0, len(m))
+	for _, row := range m {
+		a = append(a, row)
+	}
+	sort.Slice(a, func(i, j int) bool {
+		if !a[i].Period.Equal(a[j].Period) {
+			return a[i].Period.Before(a[j].Period)
+		}
+		return a[i].Command < a[j].Command
+	})
+	return a
+}
+
+// WriteReport writes rows to w as a table.
+func WriteReport(w io.Writer, rows []*ReportRow) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PERIOD\tCOMMAND\tHOURS\tINTERVALS\tPAUSED\tSKIPPED")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\t%d\t%d\t%d\n",
+			row.Period.Format("2006-01-02"),
+			row.Command,
+			row.Boxed.Hours(),
+			row.Intervals,
+			row.Paused,
+			row.Skipped,
+		)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// RunInstallService installs boxer as a service that starts at login.
+// The service runs the current binary with the given config path.
+func (m *Main) RunInstallService(args []string) error {
+	fs := flag.NewFlagSet("boxer-install-service", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Resolve the absolute paths to the binary & config.
+	program, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("executable: %s", err)
+	} else if program, err = filepath.EvalSymlinks(program); err != nil {
+		return fmt.Errorf("executable: %s", err)
+	}
+
+	if *configPath == "" {
+		if *configPath, err = DefaultConfigPath(); err != nil {
+			return fmt.Errorf("default config path: %s", err)
+		}
+	}
+	config, err := filepath.Abs(*configPath)
+	if err != nil {
+		return fmt.Errorf("config path: %s", err)
+	}
+
+	// Write the service file.
+	path, err := ServicePath()
+	if err != nil {
+		return fmt.Errorf("service path: %s", err)
+	}
+	buf, err := ServiceFile(program, config)
+	if err != nil {
+		return fmt.Errorf("service file: %s", err)
+	} else if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("mkdir: %s", err)
+	} else if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+		return fmt.Errorf("write service file: %s", err)
+	}
+
+	// Start the service and enable it at login.
+	if err := loadService(boxer.NewCommandExecutor(m.Executor), path); err != nil {
+		return fmt.Errorf("load service: %s", err)
+	}
+
+	fmt.Fprintf(m.Stdout, "installed service: %s\n", path)
+	return nil
+}
+
+// RunUninstallService stops the boxer service and removes its service file.
+func (m *Main) RunUninstallService(args []string) error {
+	fs := flag.NewFlagSet("boxer-uninstall-service", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path, err := ServicePath()
+	if err != nil {
+		return fmt.Errorf("service path: %s", err)
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("service not installed: %s", path)
+	}
+
+	// Stop the service and remove it.
+	if err := unloadService(boxer.NewCommandExecutor(m.Executor), path); err != nil {
+		return fmt.Errorf("unload service: %s", err)
+	} else if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove service file: %s", err)
+	}
+
+	fmt.Fprintf(m.Stdout, "uninstalled service: %s\n", path)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// ServiceLabel is the launchd label for the boxer service.
+const ServiceLabel = "com.github.benbjohnson.boxer"
+
+// LaunchctlPath is the path to the "launchctl" binary.
+const LaunchctlPath = `/bin/launchctl`
+
+// ServicePath returns the path to the user's launchd agent plist.
+func ServicePath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, "Library", "LaunchAgents", ServiceLabel+".plist"), nil
+}
+
+// ServiceFile returns a launchd plist that runs program with a config path.
+func ServiceFile(program, config string) ([]byte, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := serviceTemplate.Execute(&buf, map[string]string{
+		"Label":   ServiceLabel,
+		"Program": program,
+		"Config":  config,
+		"LogPath": filepath.Join(u.HomeDir, "Library", "Logs", "boxer.log"),
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadService loads the plist at path into launchd and enables it at login.
+func loadService(exec boxer.CommandExecutor, path string) error {
+	if b, err := exec(LaunchctlPath, []string{"load", "-w", path}, strings.NewReader("")); err != nil {
+		return fmt.Errorf("exec launchctl: %s", b)
+	}
+	return nil
+}
+
+// unloadService stops the service and disables it at login.
+func unloadService(exec boxer.CommandExecutor, path string) error {
+	if b, err := exec(LaunchctlPath, []string{"unload", "-w", path}, strings.NewReader("")); err != nil {
+		return fmt.Errorf("exec launchctl: %s", b)
+	}
+	return nil
+}
+
+var serviceTemplate = template.Must(template.New("service").Funcs(template.FuncMap{
+	"xml": func(s string) string {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	},
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>{{.Label | xml}}</string>
+  <key>ProgramArguments</key>
+  <array>
+    <string>{{.Program | xml}}</string>
+    <string>-config</string>
+    <string>{{.Config | xml}}</string>
+  </array>
+  <key>KeepAlive</key>
+  <true/>
+  <key>RunAtLoad</key>
+  <true/>
+  <key>StandardErrorPath</key>
+  <string>{{.LogPath | xml}}</string>
+</dict>
+</plist>
+`))
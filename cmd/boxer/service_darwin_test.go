@@ -0,0 +1,23 @@
+package main_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benbjohnson/boxer/cmd/boxer"
+)
+
+// Ensure the launchd plist runs the program with the config path.
+func TestServiceFile(t *testing.T) {
+	buf, err := main.ServiceFile("/usr/local/bin/boxer", "/Users/me/Boxer & Co/boxer.conf")
+	if err != nil {
+		t.Fatal(err)
+	} else if s := string(buf); !strings.Contains(s, `
+  <array>
+    <string>/usr/local/bin/boxer</string>
+    <string>-config</string>
+    <string>/Users/me/Boxer &amp; Co/boxer.conf</string>
+  </array>`) {
+		t.Fatalf("unexpected plist: %s", s)
+	}
+}
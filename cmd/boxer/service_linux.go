@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// ServiceName is the systemd unit name for the boxer service.
+const ServiceName = "boxer.service"
+
+// SystemctlPath is the path to the "systemctl" binary.
+const SystemctlPath = `/usr/bin/systemctl`
+
+// ServicePath returns the path to the user's systemd unit file.
+func ServicePath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, ".config", "systemd", "user", ServiceName), nil
+}
+
+// ServiceFile returns a systemd user unit that runs program with a config path.
+func ServiceFile(program, config string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := serviceTemplate.Execute(&buf, map[string]string{
+		"Program": program,
+		"Config":  config,
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadService reloads systemd and starts the service at path.
+// The service is also enabled so it starts at login.
+func loadService(exec boxer.CommandExecutor, path string) error {
+	if err := systemctl(exec, "daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl(exec, "enable", "--now", ServiceName)
+}
+
+// unloadService stops the service and disables it at login.
+func unloadService(exec boxer.CommandExecutor, path string) error {
+	return systemctl(exec, "disable", "--now", ServiceName)
+}
+
+// systemctl executes a systemctl command for the user's service manager.
+func systemctl(exec boxer.CommandExecutor, args ...string) error {
+	if b, err := exec(SystemctlPath, append([]string{"--user"}, args...), strings.NewReader("")); err != nil {
+		return fmt.Errorf("exec systemctl: %s", b)
+	}
+	return nil
+}
+
+var serviceTemplate = template.Must(template.New("service").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+}).Parse(`[Unit]
+Description=boxer
+
+[Service]
+ExecStart={{.Program | quote}} -config {{.Config | quote}}
+Restart=always
+
+[Install]
+WantedBy=default.target
+`))
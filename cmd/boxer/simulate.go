@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// RunSimulate runs the configured commands against an accelerated clock and
+// prints a timeline of the ticker's events. Handlers are replaced with no-ops
+// and OS commands are not executed so the desktop is not changed. The
+// journal is disabled during the simulation.
+func (m *Main) RunSimulate(args []string) error {
+	fs := flag.NewFlagSet("boxer-simulate", flag.ContinueOnError)
+	configPath := fs.String("config", "", "config path")
+	speed := fs.String("speed", "60x", "clock speed, such as \"60x\"")
+	duration := fs.Duration("duration", 8*time.Hour, "simulated duration")
+	start := fs.String("start", "", "simulated start time of day, such as \"9:00am\"")
+	var overrides ConfigOverrides
+	fs.Var(&overrides, "set", "override a config value, such as wallpaper.interval=50m (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	multiplier, err := ParseSpeed(*speed)
+	if err != nil {
+		return err
+	}
+
+	// Begin at a specific time of day today, if specified.
+	now := time.Now()
+	if *start != "" {
+		offset, err := ParseTimeOfDay(*start)
+		if err != nil {
+			return fmt.Errorf("parse start: %s", err)
+		}
+		y, mo, d := now.Date()
+		now = time.Date(y, mo, d, 0, 0, 0, 0, time.Local).Add(offset)
+	}
+
+	config, err := m.ReadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("read config: %s", err)
+	} else if err := overrides.Apply(config); err != nil {
+		return err
+	}
+	config.Journal.Enabled = false
+	config.Presentation.Enabled = false
+
+	// Generate any files into a temporary work directory.
+	workDir, err := ioutil.TempDir("", "boxer-simulate-")
+	if err != nil {
+		return fmt.Errorf("temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	config.WorkDir = workDir
+
+	ticker, err := NewTicker(config, boxer.CommandExecutor(NopCommandExecutor))
+	if err != nil {
+		return fmt.Errorf("cannot create ticker: %s", err)
+	}
+	for i := range ticker.Commands {
+		cmd := &ticker.Commands[i]
+		if cmd.Handler != nil {
+			cmd.Handler = func(ctx *boxer.Context) error { return nil }
+		}
+		if cmd.IntervalHandler != nil {
+			cmd.IntervalHandler = func(ctx *boxer.Context) error { return nil }
+		}
+		if cmd.WarningHandler != nil {
+			cmd.WarningHandler = func(time.Duration) error { return nil }
+		}
+	}
+	clk := clock.NewScaled(now, multiplier)
+	ticker.Clock = clk
+	ticker.Logger = boxer.NewTextLogger(m.Logger)
+
+	// Print events as they occur.
+	ch, unsubscribe := ticker.Subscribe(1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range ch {
+			WriteEvent(m.Stdout, e)
+		}
+	}()
+
+	// Stop once the simulated duration has elapsed or the program is closed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-clk.After(*duration):
+		case <-m.closing:
+		}
+		cancel()
+	}()
+
+	err = ticker.Run(ctx)
+	unsubscribe()
+	<-done
+
+	if err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+// ParseSpeed parses a clock speed multiplier, such as "60x" or "60".
+func ParseSpeed(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "x"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid speed: %q", s)
+	}
+	return v, nil
+}
+
+// WriteEvent writes a single line describing a ticker event to w.
+func WriteEvent(w io.Writer, e boxer.Event) {
+	const layout = "Mon 3:04pm"
+	switch e := e.(type) {
+	case *boxer.IntervalStartedEvent:
+		fmt.Fprintf(w, "%s  %s: interval started (%d steps)\n", e.Time.Format(layout), e.Command, e.Steps)
+	case *boxer.StepStartedEvent:
+		if e.Overtime > 0 {
+			fmt.Fprintf(w, "%s  %s: step %d/%d, %d step(s) over\n", e.Time.Format(layout), e.Command, e.Step+1, e.Steps, e.Overtime)
+		} else {
+			fmt.Fprintf(w, "%s  %s: step %d/%d\n", e.Time.Format(layout), e.Command, e.Step+1, e.Steps)
+		}
+	case *boxer.WarningEvent:
+		fmt.Fprintf(w, "%s  %s: %s left in interval\n", e.Time.Format(layout), e.Command, e.Remaining)
+	case *boxer.HandlerFailedEvent:
+		fmt.Fprintf(w, "%s  %s: failed: %s\n", e.Time.Format(layout), e.Command, e.Err)
+	case *boxer.WakeEvent:
+		fmt.Fprintf(w, "%s  woke after %s\n", e.Time.Format(layout), e.Time.Sub(e.Prev))
+	case *boxer.ZoneChangedEvent:
+		fmt.Fprintf(w, "%s  utc offset changed from %s to %s\n", e.Time.Format(layout), e.Prev.Format("-07:00"), e.Time.Format("-07:00"))
+	case *boxer.PauseEvent:
+		if e.Paused {
+			fmt.Fprintf(w, "%s  paused\n", e.Time.Format(layout))
+		} else {
+			fmt.Fprintf(w, "%s  resumed\n", e.Time.Format(layout))
+		}
+	case *boxer.CommandQuarantinedEvent:
+		fmt.Fprintf(w, "%s  %s: quarantined until %s after %d failure(s): %s\n", e.Time.Format(layout), e.Command, e.Until.Format(layout), e.Failures, e.Err)
+	case *boxer.CommandDisabledEvent:
+		fmt.Fprintf(w, "%s  %s: disabled after %d failure(s): %s\n", e.Time.Format(layout), e.Command, e.Failures, e.Err)
+	}
+}
+
+// NopCommandExecutor is a command executor that does nothing.
+func NopCommandExecutor(name string, args []string, stdin io.Reader) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// DefaultTUIWidth is the terminal width used if it cannot be determined.
+const DefaultTUIWidth = 80
+
+// RunTUI renders the progress of each command in the running daemon as a
+// full-width bar in the terminal. The bars are redrawn in place until the
+// program is closed.
+func (m *Main) RunTUI(args []string) error {
+	fs := flag.NewFlagSet("boxer-tui", flag.ContinueOnError)
+	socketPath := fs.String("socket", "", "control socket path")
+	width := fs.Int("width", 0, "terminal width, defaults to $COLUMNS")
+	refresh := fs.Duration("refresh", 1*time.Second, "time between redraws")
+	once := fs.Bool("once", false, "draw once and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Determine the width from the environment if not specified.
+	if *width <= 0 {
+		*width = DefaultTUIWidth
+		if v, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && v > 0 {
+			*width = v
+		}
+	}
+
+	client := NewClient(*socketPath)
+	var prev int
+	for {
+		var status boxer.Status
+		if err := client.Do("GET", "/status", &status); err != nil {
+			return err
+		}
+
+		// Move the cursor back over the previous frame & redraw it.
+		lines := TUIFrame(&status, time.Now(), *width)
+		if prev > 0 {
+			fmt.Fprintf(m.Stdout, "\x1b[%dA", prev)
+		}
+		for _, line := range lines {
+			fmt.Fprintf(m.Stdout, "\x1b[2K%s\n", line)
+		}
+		prev = len(lines)
+
+		if *once {
+			return nil
+		}
+
+		select {
+		case <-m.closing:
+			return nil
+		case <-time.After(*refresh):
+		}
+	}
+}
+
+// TUIFrame returns the lines that display status at now. Each command is
+// drawn as a bar of the given width with a marker at every step boundary.
+// Progress is extrapolated from the time of the status so bars move
+// smoothly between steps.
+func TUIFrame(status *boxer.Status, now time.Time, width int) []string {
+	var lines []string
+	switch {
+	case status.Paused:
+		lines = append(lines, "boxer is paused")
+	case !status.Active:
+		lines = append(lines, "boxer is idle")
+	default:
+		lines = append(lines, "boxer is running")
+	}
+	if status.Label != "" {
+		lines[0] += ": " + status.Label
+	}
+
+	// Only extrapolate progress while the commands are advancing.
+	var offset float64
+	if status.Active && !status.Paused && !status.Time.IsZero() {
+		offset = now.Sub(status.Time).Seconds()
+	}
+
+	for _, cs := range status.Commands {
+		interval := cs.Elapsed + cs.Remaining
+		elapsed := cs.Elapsed + offset
+		if elapsed > interval {
+			elapsed = interval
+		}
+
+		var info string
+		if cs.Disabled {
+			info = "disabled"
+		} else if cs.Overtime > 0 {
+			info = fmt.Sprintf("%d/%d  %d step(s) over", cs.Step+1, cs.Steps, cs.Overtime)
+		} else {
+			info = fmt.Sprintf("%d/%d  %s left", cs.Step+1, cs.Steps, formatSeconds(interval-elapsed))
+		}
+
+		pad := width - len(cs.Name) - len(info)
+		if pad < 1 {
+			pad = 1
+		}
+		lines = append(lines, "", cs.Name+strings.Repeat(" ", pad)+info)
+
+		var pct float64
+		if interval > 0 {
+			pct = elapsed / interval
+		}
+		lines = append(lines, progressBar(width, cs.Steps, pct))
+	}
+	return lines
+}
+
+// progressBar returns a bar of width cells filled to pct with a marker at
+// each of the n step boundaries.
+func progressBar(width, n int, pct float64) string {
+	if width <= 0 {
+		return ""
+	}
+	filled := int(pct * float64(width))
+
+	cells := make([]rune, width)
+	for i := range cells {
+		if i < filled {
+			cells[i] = '█'
+		} else {
+			cells[i] = '░'
+		}
+	}
+
+	// Only draw markers if there is room between them.
+	if n > 1 && width/n >= 2 {
+		for k := 1; k < n; k++ {
+			if i := k * width / n; i >= filled {
+				cells[i] = '│'
+			}
+		}
+	}
+	return string(cells)
+}
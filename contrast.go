@@ -0,0 +1,196 @@
+package boxer
+
+import (
+	"image/color"
+	"math"
+)
+
+// MinContrastRatio is the minimum contrast ratio between colors that must be
+// told apart at a glance. This is the WCAG minimum for graphics.
+const MinContrastRatio = 3.0
+
+// MinColorDifference is the minimum CIE76 color difference between colors
+// of similar lightness that must be told apart at a glance.
+const MinColorDifference = 20.0
+
+// ColorVision represents a type of color vision used to check that colors
+// can be told apart.
+type ColorVision int
+
+const (
+	NormalVision ColorVision = iota
+	Protanopia               // no red cones
+	Deuteranopia             // no green cones
+	Tritanopia               // no blue cones
+)
+
+// ColorVisions is every type of color vision checked by CheckContrast.
+var ColorVisions = []ColorVision{NormalVision, Protanopia, Deuteranopia, Tritanopia}
+
+// String returns the name of the color vision.
+func (v ColorVision) String() string {
+	switch v {
+	case Protanopia:
+		return "protanopia"
+	case Deuteranopia:
+		return "deuteranopia"
+	case Tritanopia:
+		return "tritanopia"
+	default:
+		return "normal vision"
+	}
+}
+
+// colorVisionMatrices simulate each color vision deficiency in linear RGB.
+// From Machado, Oliveira & Fernandes (2009) at full severity.
+var colorVisionMatrices = map[ColorVision][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// SimulateColorVision returns c as it is seen with color vision v. The alpha
+// of c is ignored and the returned color is opaque.
+func SimulateColorVision(c color.Color, v ColorVision) color.RGBA {
+	r, g, b := linearRGB(c)
+	if m, ok := colorVisionMatrices[v]; ok {
+		r, g, b = m[0][0]*r+m[0][1]*g+m[0][2]*b, m[1][0]*r+m[1][1]*g+m[1][2]*b, m[2][0]*r+m[2][1]*g+m[2][2]*b
+	}
+	return color.RGBA{R: encodeSRGB(r), G: encodeSRGB(g), B: encodeSRGB(b), A: 0xFF}
+}
+
+// ContrastRatio returns the WCAG contrast ratio between the relative
+// luminance of a & b, from 1 to 21.
+func ContrastRatio(a, b color.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// ColorDifference returns the CIE76 difference between a & b. A difference
+// of about 2 is just noticeable.
+func ColorDifference(a, b color.Color) float64 {
+	al, aa, ab := lab(a)
+	bl, ba, bb := lab(b)
+	return math.Sqrt((al-bl)*(al-bl) + (aa-ba)*(aa-ba) + (ab-bb)*(ab-bb))
+}
+
+// ContrastWarning represents a foreground & background that are hard to tell
+// apart with a color vision.
+type ContrastWarning struct {
+	Vision     ColorVision
+	Ratio      float64    // contrast ratio as seen with the color vision
+	Suggestion color.RGBA // foreground that can be told apart by every color vision
+}
+
+// CheckContrast returns a warning for each color vision with which fg & bg
+// have neither the minimum contrast ratio nor the minimum color difference.
+// Alpha is ignored.
+func CheckContrast(fg, bg color.Color) []*ContrastWarning {
+	var a []*ContrastWarning
+	for _, v := range ColorVisions {
+		sfg, sbg := SimulateColorVision(fg, v), SimulateColorVision(bg, v)
+		if !distinguishable(sfg, sbg) {
+			a = append(a, &ContrastWarning{Vision: v, Ratio: ContrastRatio(sfg, sbg)})
+		}
+	}
+	if len(a) > 0 {
+		suggestion := SuggestForeground(fg, bg)
+		for _, w := range a {
+			w.Suggestion = suggestion
+		}
+	}
+	return a
+}
+
+// SuggestForeground returns the color closest in lightness to fg, with the
+// same hue & saturation, that has the minimum contrast ratio against bg.
+// Lightness is moved away from the background so the result can be told
+// apart by every color vision.
+func SuggestForeground(fg, bg color.Color) color.RGBA {
+	h, s, l, _ := rgbToHSL(fg)
+	dir := 0.01
+	if luminance(bg) > 0.18 {
+		dir = -0.01
+	}
+
+	for ; l >= 0 && l <= 1; l += dir {
+		r, g, b := hslToRGB(h, s, l)
+		c := color.RGBA{R: r, G: g, B: b, A: 0xFF}
+		if ContrastRatio(c, bg) >= MinContrastRatio {
+			return c
+		}
+	}
+	if dir < 0 {
+		return color.RGBA{A: 0xFF}
+	}
+	return color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+}
+
+// distinguishable returns true if a & b differ enough in lightness or color.
+func distinguishable(a, b color.Color) bool {
+	return ContrastRatio(a, b) >= MinContrastRatio || ColorDifference(a, b) >= MinColorDifference
+}
+
+// luminance returns the WCAG relative luminance of c, from 0 to 1.
+func luminance(c color.Color) float64 {
+	r, g, b := linearRGB(c)
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// lab converts c to CIE L*a*b* with a D65 white point.
+func lab(c color.Color) (l, a, b float64) {
+	r, g, bl := linearRGB(c)
+	x := (0.4124*r + 0.3576*g + 0.1805*bl) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*bl
+	z := (0.0193*r + 0.1192*g + 0.9505*bl) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// linearRGB returns the linear red, green & blue of c, from 0 to 1, without
+// its alpha premultiplied.
+func linearRGB(c color.Color) (r, g, b float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return decodeSRGB(n.R), decodeSRGB(n.G), decodeSRGB(n.B)
+}
+
+// decodeSRGB converts an sRGB component to linear light.
+func decodeSRGB(v uint8) float64 {
+	f := float64(v) / 0xFF
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+// encodeSRGB converts linear light to an sRGB component.
+func encodeSRGB(f float64) uint8 {
+	if f <= 0.0031308 {
+		f *= 12.92
+	} else {
+		f = 1.055*math.Pow(f, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Max(0, math.Min(1, f)) * 0xFF))
+}
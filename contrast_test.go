@@ -0,0 +1,96 @@
+package boxer_test
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the contrast ratio between colors is calculated.
+func TestContrastRatio(t *testing.T) {
+	black, white := color.RGBA{A: 0xFF}, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	if r := boxer.ContrastRatio(black, white); r != 21 {
+		t.Fatalf("unexpected ratio: %v", r)
+	} else if r := boxer.ContrastRatio(white, black); r != 21 {
+		t.Fatalf("unexpected ratio: %v", r)
+	} else if r := boxer.ContrastRatio(white, white); r != 1 {
+		t.Fatalf("unexpected ratio: %v", r)
+	}
+
+	// Mid gray on white is just above the WCAG minimum for text.
+	if r := boxer.ContrastRatio(color.RGBA{R: 0x76, G: 0x76, B: 0x76, A: 0xFF}, white); math.Abs(r-4.54) > 0.01 {
+		t.Fatalf("unexpected ratio: %v", r)
+	}
+}
+
+// Ensure colors are simulated as seen with each color vision.
+func TestSimulateColorVision(t *testing.T) {
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	if c := boxer.SimulateColorVision(red, boxer.NormalVision); c != red {
+		t.Fatalf("unexpected color: %#v", c)
+	}
+
+	// Red & green look alike without green cones.
+	green := color.RGBA{G: 0xA0, A: 0xFF}
+	if d := boxer.ColorDifference(boxer.SimulateColorVision(red, boxer.Deuteranopia), boxer.SimulateColorVision(green, boxer.Deuteranopia)); d >= boxer.MinColorDifference {
+		t.Fatalf("unexpected difference: %v", d)
+	} else if d := boxer.ColorDifference(boxer.SimulateColorVision(red, boxer.Tritanopia), boxer.SimulateColorVision(green, boxer.Tritanopia)); d < boxer.MinColorDifference {
+		t.Fatalf("unexpected difference: %v", d)
+	}
+
+	// Grays are unchanged.
+	gray := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF}
+	for _, v := range boxer.ColorVisions {
+		if c := boxer.SimulateColorVision(gray, v); boxer.ColorDifference(c, gray) > 1 {
+			t.Fatalf("%s: unexpected color: %#v", v, c)
+		}
+	}
+}
+
+// Ensure colors that are hard to tell apart with red-green color blindness
+// are warned about with a suggested foreground.
+func TestCheckContrast(t *testing.T) {
+	red, green := color.RGBA{R: 0xFF, A: 0xFF}, color.RGBA{G: 0xA0, A: 0xFF}
+	warnings := boxer.CheckContrast(red, green)
+	if len(warnings) != 1 {
+		t.Fatalf("unexpected warnings: %d", len(warnings))
+	} else if w := warnings[0]; w.Vision != boxer.Deuteranopia || w.Ratio >= boxer.MinContrastRatio {
+		t.Fatalf("unexpected warning: %#v", w)
+	}
+
+	// The suggestion can be told apart by every color vision.
+	if s := warnings[0].Suggestion; boxer.ContrastRatio(s, green) < boxer.MinContrastRatio {
+		t.Fatalf("unexpected suggestion: %#v", s)
+	} else if a := boxer.CheckContrast(s, green); len(a) != 0 {
+		t.Fatalf("unexpected suggestion warnings: %d", len(a))
+	}
+}
+
+// Ensure colors with enough contrast are not warned about.
+func TestCheckContrast_OK(t *testing.T) {
+	if a := boxer.CheckContrast(color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, color.RGBA{A: 0xFF}); len(a) != 0 {
+		t.Fatalf("unexpected warnings: %d", len(a))
+	}
+
+	// Blue & yellow are told apart by color instead of lightness.
+	if a := boxer.CheckContrast(color.RGBA{R: 0x40, G: 0x70, B: 0xE0, A: 0xFF}, color.RGBA{R: 0xA0, G: 0x90, B: 0x30, A: 0xFF}); len(a) != 0 {
+		t.Fatalf("unexpected warnings: %d", len(a))
+	}
+}
+
+// Ensure a foreground is suggested on a dark & a light background.
+func TestSuggestForeground(t *testing.T) {
+	fg := color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xFF}
+	dark := color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xFF}
+	if s := boxer.SuggestForeground(fg, dark); boxer.ContrastRatio(s, dark) < boxer.MinContrastRatio || boxer.ContrastRatio(s, dark) > boxer.MinContrastRatio+0.5 {
+		t.Fatalf("unexpected suggestion: %#v", s)
+	}
+
+	light := color.RGBA{R: 0xE0, G: 0xE0, B: 0xE0, A: 0xFF}
+	fg = color.RGBA{R: 0xC0, G: 0xC0, B: 0xF0, A: 0xFF}
+	if s := boxer.SuggestForeground(fg, light); boxer.ContrastRatio(s, light) < boxer.MinContrastRatio || s.B <= s.R {
+		t.Fatalf("unexpected suggestion: %#v", s)
+	}
+}
@@ -0,0 +1,528 @@
+package boxer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Desktop represents the desktop environment that handlers update. Handlers
+// only use this interface so supporting a new platform only requires a new
+// implementation.
+type Desktop interface {
+	Notifier
+
+	// SetWallpaper sets the desktop background to the image at path.
+	SetWallpaper(path string) error
+
+	// Size returns the size of the desktop screen.
+	Size() (w, h int, err error)
+
+	// FlashAttention flashes the desktop n times, waiting delay between each
+	// toggle, to get the user's attention. It blocks until the flash is done.
+	FlashAttention(n int, delay time.Duration) error
+}
+
+// DesktopScaler is implemented by desktops whose size is in points rather
+// than pixels, such as Retina displays on macOS.
+type DesktopScaler interface {
+	// Scale returns the number of pixels per point of the desktop size.
+	Scale() (float64, error)
+}
+
+// DesktopScale returns the scale factor of d. Returns 1 if d does not
+// implement DesktopScaler or reports an invalid scale.
+func DesktopScale(d Desktop) (float64, error) {
+	s, ok := d.(DesktopScaler)
+	if !ok {
+		return 1, nil
+	}
+
+	scale, err := s.Scale()
+	if err != nil {
+		return 0, err
+	} else if scale <= 0 {
+		return 1, nil
+	}
+	return scale, nil
+}
+
+// NewDesktop returns the desktop with the given name. The names are "macos",
+// "gnome", "kde", and "sway". If name is blank then the desktop is detected
+// from the environment. Returns an error if no desktop is supported on the
+// platform.
+func NewDesktop(name string, exec CommandExecutor) (Desktop, error) {
+	if name == "" {
+		name = DetectDesktop(os.Getenv)
+	}
+
+	switch name {
+	case "":
+		return nil, fmt.Errorf("unsupported desktop on %s", runtime.GOOS)
+	case "macos":
+		return newMacDesktop(exec)
+	case "gnome":
+		return &GNOMEDesktop{Exec: exec}, nil
+	case "kde":
+		return &KDEDesktop{Exec: exec}, nil
+	case "sway":
+		return &SwayDesktop{Exec: exec}, nil
+	default:
+		return nil, fmt.Errorf("invalid desktop: %q", name)
+	}
+}
+
+// DetectDesktop returns the name of the current desktop based on the
+// environment variables set by the session. Returns DefaultDesktop if the
+// desktop cannot be detected.
+func DetectDesktop(getenv func(string) string) string {
+	if getenv("SWAYSOCK") != "" {
+		return "sway"
+	}
+	for _, name := range strings.Split(getenv("XDG_CURRENT_DESKTOP"), ":") {
+		switch strings.ToUpper(name) {
+		case "GNOME":
+			return "gnome"
+		case "KDE":
+			return "kde"
+		case "SWAY":
+			return "sway"
+		}
+	}
+	return DefaultDesktop
+}
+
+// DesktopSizer returns the size of the desktop screen.
+type DesktopSizer func(exec CommandExecutor) (w, h int, err error)
+
+// DefaultDesktopSizeTTL is the default time to cache the desktop size.
+const DefaultDesktopSizeTTL = 5 * time.Minute
+
+// NewCachedDesktopSizer returns a sizer that caches the size returned by
+// sizer for ttl so the desktop is not queried on every step. Errors are not
+// cached. If ttl is zero then sizer is returned unchanged.
+func NewCachedDesktopSizer(sizer DesktopSizer, ttl time.Duration, now NowFunc) DesktopSizer {
+	if ttl <= 0 {
+		return sizer
+	}
+
+	var mu sync.Mutex
+	var cw, ch int
+	var expiry time.Time
+
+	return func(exec CommandExecutor) (w, h int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Return the cached size if it hasn't expired yet.
+		t := now()
+		if t.Before(expiry) {
+			return cw, ch, nil
+		}
+
+		// Otherwise query the desktop and cache the size.
+		if w, h, err = sizer(exec); err != nil {
+			return 0, 0, err
+		}
+		cw, ch, expiry = w, h, t.Add(ttl)
+		return w, h, nil
+	}
+}
+
+// StaticDesktopSizer returns a sizer that always returns the given size.
+func StaticDesktopSizer(w, h int) DesktopSizer {
+	return func(exec CommandExecutor) (int, int, error) { return w, h, nil }
+}
+
+// DesktopSizeSource represents a named sizer within a fallback chain.
+type DesktopSizeSource struct {
+	Name  string
+	Sizer DesktopSizer
+}
+
+// NewFallbackDesktopSizer returns a sizer that tries each source in order and
+// returns the first size found. If every source fails then their errors are
+// combined. Whenever a different source is used than on the previous call,
+// it is written to logger, if set, along with the errors of the sources
+// before it. The first source succeeding on the first call is not logged.
+func NewFallbackDesktopSizer(logger *log.Logger, sources ...DesktopSizeSource) DesktopSizer {
+	var mu sync.Mutex
+	prev := 0
+
+	return func(exec CommandExecutor) (w, h int, err error) {
+		var errs []string
+		for i, src := range sources {
+			if w, h, err = src.Sizer(exec); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", src.Name, err))
+				continue
+			}
+
+			mu.Lock()
+			if i != prev && logger != nil {
+				if len(errs) > 0 {
+					logger.Printf("desktop size: using %s (%s)", src.Name, strings.Join(errs, "; "))
+				} else {
+					logger.Printf("desktop size: using %s", src.Name)
+				}
+			}
+			prev = i
+			mu.Unlock()
+			return w, h, nil
+		}
+		return 0, 0, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+}
+
+// NewCachedDesktop returns a desktop that caches the size & scale of d for
+// ttl. If ttl is zero then d is returned unchanged.
+func NewCachedDesktop(d Desktop, ttl time.Duration, now NowFunc) Desktop {
+	if ttl <= 0 {
+		return d
+	}
+	sizer := func(exec CommandExecutor) (w, h int, err error) { return d.Size() }
+	return &cachedDesktop{Desktop: d, sizer: NewCachedDesktopSizer(sizer, ttl, now), ttl: ttl, now: now}
+}
+
+// cachedDesktop wraps a desktop & caches its size & scale.
+type cachedDesktop struct {
+	Desktop
+	sizer DesktopSizer
+	ttl   time.Duration
+	now   NowFunc
+
+	mu          sync.Mutex
+	scale       float64
+	scaleExpiry time.Time
+}
+
+// Size returns the cached size of the desktop.
+func (d *cachedDesktop) Size() (w, h int, err error) { return d.sizer(nil) }
+
+// Scale returns the cached scale of the desktop. Errors are not cached.
+func (d *cachedDesktop) Scale() (float64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t := d.now()
+	if t.Before(d.scaleExpiry) {
+		return d.scale, nil
+	}
+
+	scale, err := DesktopScale(d.Desktop)
+	if err != nil {
+		return 0, err
+	}
+	d.scale, d.scaleExpiry = scale, t.Add(d.ttl)
+	return scale, nil
+}
+
+// DefaultMenuBarFlashes is the default number of times the menu bar flashes.
+const DefaultMenuBarFlashes = 30
+
+// DefaultMenuBarDelay is the default time between toggling the menu bar.
+const DefaultMenuBarDelay = 500 * time.Millisecond
+
+// NewMenuBarHandler returns a handler for flashing the menu bar. The desktop
+// flashes n times, waiting delay between each toggle. On macOS, the menu bar
+// toggles dark mode on and off.
+//
+// The flash runs in the background so it does not block the ticker. If a
+// previous flash is still running then the new flash is quietly skipped.
+// Errors from a background flash are returned on the next call to the handler.
+func NewMenuBarHandler(desktop Desktop, n int, delay time.Duration) IntervalHandler {
+	var mu sync.Mutex
+	var running bool
+	var lastErr error
+
+	return func(ctx *Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Skip if the previous flash is still running. This is not a failure
+		// so it doesn't count toward disabling or quarantining the command.
+		if running {
+			return nil
+		}
+
+		// Pull the error from the previous flash, if any.
+		err := lastErr
+		lastErr = nil
+
+		// Flash menu bar in the background.
+		running = true
+		go func() {
+			err := desktop.FlashAttention(n, delay)
+
+			mu.Lock()
+			defer mu.Unlock()
+			lastErr = err
+			running = false
+		}()
+
+		return err
+	}
+}
+
+// GSettingsPath is the path to the "gsettings" binary.
+const GSettingsPath = `/usr/bin/gsettings`
+
+// XRandRPath is the path to the "xrandr" binary.
+const XRandRPath = `/usr/bin/xrandr`
+
+// QDBusPath is the path to the "qdbus" binary.
+const QDBusPath = `/usr/bin/qdbus`
+
+// SwayMsgPath is the path to the "swaymsg" binary.
+const SwayMsgPath = `/usr/bin/swaymsg`
+
+// GNOMEDesktop is the GNOME desktop, controlled with gsettings. The desktop
+// flashes by toggling the dark color scheme.
+type GNOMEDesktop struct {
+	Exec CommandExecutor
+}
+
+// SetWallpaper sets the background for both the light & dark color schemes.
+func (d *GNOMEDesktop) SetWallpaper(path string) error {
+	uri, err := fileURI(path)
+	if err != nil {
+		return err
+	}
+
+	// Older versions of GNOME have no dark background so its error is ignored.
+	_, _ = d.Exec(GSettingsPath, []string{"set", "org.gnome.desktop.background", "picture-uri-dark", uri}, nil)
+	return d.gsettings("set", "org.gnome.desktop.background", "picture-uri", uri)
+}
+
+// Size returns the size of the screen reported by xrandr.
+func (d *GNOMEDesktop) Size() (w, h int, err error) { return xrandrSize(d.Exec) }
+
+// Notify displays n with notify-send.
+func (d *GNOMEDesktop) Notify(n *Notification) error {
+	return (&NotifySendNotifier{Exec: d.Exec}).Notify(n)
+}
+
+// FlashAttention toggles between the dark & current color schemes.
+func (d *GNOMEDesktop) FlashAttention(n int, delay time.Duration) error {
+	b, err := d.Exec(GSettingsPath, []string{"get", "org.gnome.desktop.interface", "color-scheme"}, nil)
+	if err != nil {
+		return fmt.Errorf("exec gsettings: %s", b)
+	}
+	scheme := strings.Trim(strings.TrimSpace(string(b)), "'")
+
+	flash := "prefer-dark"
+	if scheme == flash {
+		flash = "prefer-light"
+	}
+
+	for i := 0; i < n; i++ {
+		if err := d.gsettings("set", "org.gnome.desktop.interface", "color-scheme", flash); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+		if err := d.gsettings("set", "org.gnome.desktop.interface", "color-scheme", scheme); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// gsettings executes gsettings with args.
+func (d *GNOMEDesktop) gsettings(args ...string) error {
+	if b, err := d.Exec(GSettingsPath, args, nil); err != nil {
+		return fmt.Errorf("exec gsettings: %s", b)
+	}
+	return nil
+}
+
+// KDEDesktop is the KDE Plasma desktop, controlled with qdbus. The desktop
+// flashes by toggling KWin's "Invert" effect, which must be enabled.
+type KDEDesktop struct {
+	Exec CommandExecutor
+}
+
+// SetWallpaper sets the background of every desktop with a Plasma script.
+func (d *KDEDesktop) SetWallpaper(path string) error {
+	uri, err := fileURI(path)
+	if err != nil {
+		return err
+	}
+	src, err := renderScript(plasmaWallpaperScript, uri)
+	if err != nil {
+		return err
+	}
+	return d.qdbus("org.kde.plasmashell", "/PlasmaShell", "org.kde.PlasmaShell.evaluateScript", src)
+}
+
+var plasmaWallpaperScript = newScriptTemplate("plasma wallpaper", `
+desktops().forEach(function(d) {
+  d.wallpaperPlugin = "org.kde.image";
+  d.currentConfigGroup = ["Wallpaper", "org.kde.image", "General"];
+  d.writeConfig("Image", {{js .}});
+});
+`)
+
+// Size returns the size of the screen reported by xrandr.
+func (d *KDEDesktop) Size() (w, h int, err error) { return xrandrSize(d.Exec) }
+
+// Notify displays n with notify-send.
+func (d *KDEDesktop) Notify(n *Notification) error {
+	return (&NotifySendNotifier{Exec: d.Exec}).Notify(n)
+}
+
+// FlashAttention inverts the screen colors on and off.
+func (d *KDEDesktop) FlashAttention(n int, delay time.Duration) error {
+	for i := 0; i < n*2; i++ {
+		if err := d.qdbus("org.kde.kglobalaccel", "/component/kwin", "invokeShortcut", "Invert"); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// qdbus executes qdbus with args.
+func (d *KDEDesktop) qdbus(args ...string) error {
+	if b, err := d.Exec(QDBusPath, args, nil); err != nil {
+		return fmt.Errorf("exec qdbus: %s", b)
+	}
+	return nil
+}
+
+// SwayDesktop is the sway Wayland compositor, controlled with swaymsg. The
+// desktop flashes by dimming every window.
+type SwayDesktop struct {
+	Exec CommandExecutor
+}
+
+// SetWallpaper sets the background of every output.
+func (d *SwayDesktop) SetWallpaper(path string) error {
+	path, err := ScriptPath(path)
+	if err != nil {
+		return err
+	}
+	return d.swaymsg("output * bg " + swayString(path) + " fill")
+}
+
+// Size returns the size of the focused output, or the first active output
+// if none is focused. The size is in logical pixels when the output is scaled.
+func (d *SwayDesktop) Size() (w, h int, err error) {
+	o, err := d.output()
+	if err != nil {
+		return 0, 0, err
+	}
+	return o.Rect.Width, o.Rect.Height, nil
+}
+
+// Scale returns the scale factor of the focused output.
+func (d *SwayDesktop) Scale() (float64, error) {
+	o, err := d.output()
+	if err != nil {
+		return 0, err
+	}
+	return o.Scale, nil
+}
+
+// swayOutput represents an output returned by "swaymsg -t get_outputs".
+type swayOutput struct {
+	Active  bool    `json:"active"`
+	Focused bool    `json:"focused"`
+	Scale   float64 `json:"scale"`
+	Rect    struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"rect"`
+}
+
+// output returns the focused output, or the first active output if none is focused.
+func (d *SwayDesktop) output() (*swayOutput, error) {
+	b, err := d.Exec(SwayMsgPath, []string{"-t", "get_outputs", "-r"}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("exec swaymsg: %s", b)
+	}
+
+	var outputs []*swayOutput
+	if err := json.Unmarshal(b, &outputs); err != nil {
+		return nil, fmt.Errorf("unexpected exec output: %s", b)
+	}
+
+	var output *swayOutput
+	for _, o := range outputs {
+		if o.Active && (o.Focused || output == nil) {
+			output = o
+		}
+	}
+	if output == nil || output.Rect.Width == 0 || output.Rect.Height == 0 {
+		return nil, fmt.Errorf("no active outputs")
+	}
+	return output, nil
+}
+
+// Notify displays n with notify-send.
+func (d *SwayDesktop) Notify(n *Notification) error {
+	return (&NotifySendNotifier{Exec: d.Exec}).Notify(n)
+}
+
+// FlashAttention dims every window on and off.
+func (d *SwayDesktop) FlashAttention(n int, delay time.Duration) error {
+	for i := 0; i < n; i++ {
+		if err := d.swaymsg("[all] opacity 0.5"); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+		if err := d.swaymsg("[all] opacity 1"); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// swaymsg executes a sway command.
+func (d *SwayDesktop) swaymsg(command string) error {
+	if b, err := d.Exec(SwayMsgPath, []string{command}, nil); err != nil {
+		return fmt.Errorf("exec swaymsg: %s", b)
+	}
+	return nil
+}
+
+// swayString returns s as a quoted string argument for a sway command.
+func swayString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// xrandrSize returns the size of the X screen reported by xrandr.
+func xrandrSize(exec CommandExecutor) (w, h int, err error) {
+	b, err := exec(XRandRPath, []string{"--current"}, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("exec xrandr: %s", b)
+	}
+	m := xrandrSizeRegex.FindSubmatch(b)
+	if m == nil {
+		return 0, 0, fmt.Errorf("unexpected exec output: %s", b)
+	}
+	w, _ = strconv.Atoi(string(m[1]))
+	h, _ = strconv.Atoi(string(m[2]))
+	return w, h, nil
+}
+
+// xrandrSizeRegex matches the current screen size in xrandr's output.
+var xrandrSizeRegex = regexp.MustCompile(`current (\d+) x (\d+)`)
+
+// fileURI returns a file URI for the absolute path of path.
+func fileURI(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String(), nil
+}
@@ -0,0 +1,10 @@
+package boxer
+
+// DefaultDesktop is the name of the desktop used if none is configured or
+// detected.
+const DefaultDesktop = "macos"
+
+// newMacDesktop returns the macOS desktop.
+func newMacDesktop(exec CommandExecutor) (Desktop, error) {
+	return &MacDesktop{Exec: exec}, nil
+}
@@ -0,0 +1,12 @@
+package boxer
+
+import "errors"
+
+// DefaultDesktop is the name of the desktop used if none is configured or
+// detected.
+const DefaultDesktop = "gnome"
+
+// newMacDesktop returns an error since macOS is not available on Linux.
+func newMacDesktop(exec CommandExecutor) (Desktop, error) {
+	return nil, errors.New("macos desktop unavailable on linux")
+}
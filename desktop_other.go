@@ -0,0 +1,17 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package boxer
+
+import (
+	"errors"
+	"runtime"
+)
+
+// DefaultDesktop is blank since there is no known desktop on this platform.
+const DefaultDesktop = ""
+
+// newMacDesktop returns an error since macOS is not available.
+func newMacDesktop(exec CommandExecutor) (Desktop, error) {
+	return nil, errors.New("macos desktop unavailable on " + runtime.GOOS)
+}
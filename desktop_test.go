@@ -0,0 +1,156 @@
+package boxer_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the desktop is detected from the session's environment variables.
+func TestDetectDesktop(t *testing.T) {
+	for env, exp := range map[string]string{
+		"XDG_CURRENT_DESKTOP=ubuntu:GNOME":  "gnome",
+		"XDG_CURRENT_DESKTOP=KDE":           "kde",
+		"SWAYSOCK=/run/user/1000/sway.sock": "sway",
+		"":                                  boxer.DefaultDesktop,
+	} {
+		getenv := func(key string) string {
+			if kv := strings.SplitN(env, "=", 2); kv[0] == key {
+				return kv[1]
+			}
+			return ""
+		}
+		if name := boxer.DetectDesktop(getenv); name != exp {
+			t.Fatalf("%s: unexpected desktop: %s", env, name)
+		}
+	}
+}
+
+// Ensure an unknown desktop returns an error.
+func TestNewDesktop_ErrInvalid(t *testing.T) {
+	if _, err := boxer.NewDesktop("windows", nil); err == nil || err.Error() != `invalid desktop: "windows"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the GNOME desktop sets the light & dark backgrounds & reads the size from xrandr.
+func TestGNOMEDesktop(t *testing.T) {
+	var calls [][]string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		if name == boxer.XRandRPath {
+			return []byte("Screen 0: minimum 320 x 200, current 2560 x 1440, maximum 16384 x 16384\n"), nil
+		}
+		return nil, nil
+	}
+
+	d := &boxer.GNOMEDesktop{Exec: exec}
+	if err := d.SetWallpaper("/my path/wallpaper.png"); err != nil {
+		t.Fatal(err)
+	} else if exp := [][]string{
+		{boxer.GSettingsPath, "set", "org.gnome.desktop.background", "picture-uri-dark", "file:///my%20path/wallpaper.png"},
+		{boxer.GSettingsPath, "set", "org.gnome.desktop.background", "picture-uri", "file:///my%20path/wallpaper.png"},
+	}; !reflect.DeepEqual(calls, exp) {
+		t.Fatalf("unexpected calls: %#v", calls)
+	}
+
+	if w, h, err := d.Size(); err != nil {
+		t.Fatal(err)
+	} else if w != 2560 || h != 1440 {
+		t.Fatalf("unexpected size: %dx%d", w, h)
+	}
+}
+
+// Ensure the KDE desktop sets the background of every desktop with a Plasma script.
+func TestKDEDesktop_SetWallpaper(t *testing.T) {
+	var args []string
+	exec := func(name string, a []string, stdin io.Reader) ([]byte, error) {
+		args = a
+		return nil, nil
+	}
+
+	if err := (&boxer.KDEDesktop{Exec: exec}).SetWallpaper(`/my/"work"/wallpaper.png`); err != nil {
+		t.Fatal(err)
+	} else if len(args) != 4 || args[2] != "org.kde.PlasmaShell.evaluateScript" {
+		t.Fatalf("unexpected args: %#v", args)
+	} else if !strings.Contains(args[3], `d.writeConfig("Image", "file:///my/%22work%22/wallpaper.png");`) {
+		t.Fatalf("unexpected script:\n%s", args[3])
+	}
+}
+
+// Ensure the sway desktop sets the background of every output & sizes & scales the focused output.
+func TestSwayDesktop(t *testing.T) {
+	var commands []string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if len(args) > 1 {
+			return []byte(`[{"active":true,"focused":false,"rect":{"width":1920,"height":1080}},{"active":true,"focused":true,"scale":2,"rect":{"width":2560,"height":1440}}]`), nil
+		}
+		commands = append(commands, args...)
+		return nil, nil
+	}
+
+	d := &boxer.SwayDesktop{Exec: exec}
+	if err := d.SetWallpaper(`/my/"work"/wallpaper.png`); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{`output * bg "/my/\"work\"/wallpaper.png" fill`}; !reflect.DeepEqual(commands, exp) {
+		t.Fatalf("unexpected commands: %#v", commands)
+	}
+
+	if w, h, err := d.Size(); err != nil {
+		t.Fatal(err)
+	} else if w != 2560 || h != 1440 {
+		t.Fatalf("unexpected size: %dx%d", w, h)
+	}
+
+	if scale, err := boxer.DesktopScale(d); err != nil {
+		t.Fatal(err)
+	} else if scale != 2 {
+		t.Fatalf("unexpected scale: %f", scale)
+	}
+}
+
+// Ensure desktops that don't report a scale default to 1.
+func TestDesktopScale_Default(t *testing.T) {
+	if scale, err := boxer.DesktopScale(&boxer.GNOMEDesktop{}); err != nil {
+		t.Fatal(err)
+	} else if scale != 1 {
+		t.Fatalf("unexpected scale: %f", scale)
+	}
+}
+
+// Ensure the sway desktop returns the output of a failed flash.
+func TestSwayDesktop_FlashAttention_ErrExec(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte("no windows"), errors.New("")
+	}
+	if err := (&boxer.SwayDesktop{Exec: exec}).FlashAttention(1, 0); err == nil || err.Error() != `exec swaymsg: no windows` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the cached desktop only queries the size again after the ttl.
+func TestNewCachedDesktop(t *testing.T) {
+	var n int
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		n++
+		return []byte("current 100 x 200,"), nil
+	}
+
+	now := time.Unix(0, 0)
+	d := boxer.NewCachedDesktop(&boxer.GNOMEDesktop{Exec: exec}, time.Minute, func() time.Time { return now })
+	d.Size()
+	d.Size()
+	now = now.Add(time.Minute)
+	if w, _, err := d.Size(); err != nil {
+		t.Fatal(err)
+	} else if w != 100 {
+		t.Fatalf("unexpected width: %d", w)
+	} else if n != 2 {
+		t.Fatalf("unexpected query count: %d", n)
+	}
+}
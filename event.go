@@ -0,0 +1,193 @@
+package boxer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event represents a notification emitted by the ticker. Events are one of
+// StepStartedEvent, IntervalStartedEvent, HandlerFailedEvent, WarningEvent,
+// WakeEvent, PauseEvent, ZoneChangedEvent, CommandDisabledEvent, or
+// CommandQuarantinedEvent.
+type Event interface {
+	event()
+}
+
+// IntervalStartedEvent is emitted when a command's interval handler executes.
+type IntervalStartedEvent struct {
+	Time    time.Time
+	Command string
+	Steps   int
+}
+
+// StepStartedEvent is emitted when a command's step handler executes.
+type StepStartedEvent struct {
+	Time     time.Time
+	Command  string
+	Step     int
+	Steps    int
+	Overtime int // steps past the end of an interval held open, if any
+}
+
+// HandlerFailedEvent is emitted when a command's handler returns an error
+// or times out.
+type HandlerFailedEvent struct {
+	Time     time.Time
+	Command  string
+	Step     int
+	Steps    int
+	Interval bool
+	Warning  bool
+	Err      error
+}
+
+// WarningEvent is emitted when a command's warning handler executes.
+type WarningEvent struct {
+	Time      time.Time
+	Command   string
+	Remaining time.Duration
+}
+
+// WakeEvent is emitted when the ticker detects a wake from sleep.
+// Missed holds the number of whole intervals missed by command name.
+type WakeEvent struct {
+	Time   time.Time
+	Prev   time.Time
+	Missed map[string]int
+}
+
+// PauseEvent is emitted when the ticker is paused or resumed by the user.
+type PauseEvent struct {
+	Time   time.Time
+	Paused bool
+}
+
+// ZoneChangedEvent is emitted when the UTC offset changes between ticks, such
+// as at a DST transition. Prev is the previous tick in its own offset.
+type ZoneChangedEvent struct {
+	Time time.Time
+	Prev time.Time
+}
+
+// CommandDisabledEvent is emitted when a command is disabled because its
+// handler returned a fatal error or failed too many times in a row.
+type CommandDisabledEvent struct {
+	Time     time.Time
+	Command  string
+	Failures int
+	Err      error
+}
+
+// CommandQuarantinedEvent is emitted once when a command is quarantined by
+// its breaker. The command is retried at Until.
+type CommandQuarantinedEvent struct {
+	Time     time.Time
+	Command  string
+	Failures int
+	Until    time.Time
+	Err      error
+}
+
+func (*IntervalStartedEvent) event()    {}
+func (*StepStartedEvent) event()        {}
+func (*HandlerFailedEvent) event()      {}
+func (*WarningEvent) event()            {}
+func (*WakeEvent) event()               {}
+func (*PauseEvent) event()              {}
+func (*ZoneChangedEvent) event()        {}
+func (*CommandDisabledEvent) event()    {}
+func (*CommandQuarantinedEvent) event() {}
+
+// MarshalEvent encodes e as a JSON object. The "type" field identifies the
+// event, such as "step_started", and durations are encoded in seconds.
+func MarshalEvent(e Event) ([]byte, error) {
+	var v jsonEvent
+	switch e := e.(type) {
+	case *IntervalStartedEvent:
+		v = jsonEvent{Type: "interval_started", Time: e.Time, Command: e.Command, Steps: e.Steps}
+	case *StepStartedEvent:
+		v = jsonEvent{Type: "step_started", Time: e.Time, Command: e.Command, Step: &e.Step, Steps: e.Steps, Overtime: e.Overtime}
+	case *HandlerFailedEvent:
+		v = jsonEvent{Type: "handler_failed", Time: e.Time, Command: e.Command, Step: &e.Step, Steps: e.Steps, Interval: e.Interval, Warning: e.Warning}
+		if e.Err != nil {
+			v.Error = e.Err.Error()
+		}
+	case *WarningEvent:
+		v = jsonEvent{Type: "warning", Time: e.Time, Command: e.Command, Remaining: e.Remaining.Seconds()}
+	case *WakeEvent:
+		v = jsonEvent{Type: "wake", Time: e.Time, Prev: &e.Prev, Missed: e.Missed}
+	case *PauseEvent:
+		v = jsonEvent{Type: "resumed", Time: e.Time}
+		if e.Paused {
+			v.Type = "paused"
+		}
+	case *ZoneChangedEvent:
+		v = jsonEvent{Type: "zone_changed", Time: e.Time, Prev: &e.Prev}
+	case *CommandQuarantinedEvent:
+		v = jsonEvent{Type: "command_quarantined", Time: e.Time, Command: e.Command, Failures: e.Failures, Until: &e.Until}
+		if e.Err != nil {
+			v.Error = e.Err.Error()
+		}
+	case *CommandDisabledEvent:
+		v = jsonEvent{Type: "command_disabled", Time: e.Time, Command: e.Command, Failures: e.Failures}
+		if e.Err != nil {
+			v.Error = e.Err.Error()
+		}
+	}
+	return json.Marshal(&v)
+}
+
+// jsonEvent represents the JSON encoding of every type of event.
+type jsonEvent struct {
+	Type      string         `json:"type"`
+	Time      time.Time      `json:"time"`
+	Command   string         `json:"command,omitempty"`
+	Step      *int           `json:"step,omitempty"`
+	Steps     int            `json:"steps,omitempty"`
+	Overtime  int            `json:"overtime,omitempty"`
+	Interval  bool           `json:"interval,omitempty"`
+	Warning   bool           `json:"warning,omitempty"`
+	Remaining float64        `json:"remaining,omitempty"`
+	Prev      *time.Time     `json:"prev,omitempty"`
+	Missed    map[string]int `json:"missed,omitempty"`
+	Failures  int            `json:"failures,omitempty"`
+	Until     *time.Time     `json:"until,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// Subscribe returns a channel that receives events emitted by the ticker.
+// The channel is buffered with size n and events are dropped if the buffer
+// is full so slow subscribers cannot block the ticker. Call the returned
+// function to unsubscribe and close the channel.
+func (t *Ticker) Subscribe(n int) (<-chan Event, func()) {
+	ch := make(chan Event, n)
+
+	t.mu.Lock()
+	if t.subscribers == nil {
+		t.subscribers = make(map[chan Event]struct{})
+	}
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	return ch, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// emit sends e to every subscriber without blocking.
+func (t *Ticker) emit(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ch := range t.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package boxer_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// Ensure the ticker emits events for started & failed handlers.
+func TestTicker_Subscribe(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Logger = boxer.NewTextLogger(log.New(ioutil.Discard, "", 0))
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	ticker.Commands = []boxer.Command{{
+		Name:            "foo",
+		Step:            5 * time.Minute,
+		Interval:        15 * time.Minute,
+		IntervalHandler: func(ctx *boxer.Context) error { return nil },
+		Handler:         func(ctx *boxer.Context) error { return errors.New("marker") },
+	}}
+
+	ch, unsubscribe := ticker.Subscribe(10)
+	ticker.Tick()
+	unsubscribe()
+
+	var events []boxer.Event
+	for e := range ch {
+		events = append(events, e)
+	}
+
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if !reflect.DeepEqual(events, []boxer.Event{
+		&boxer.IntervalStartedEvent{Time: now, Command: "foo", Steps: 3},
+		&boxer.StepStartedEvent{Time: now, Command: "foo", Step: 0, Steps: 3},
+		&boxer.HandlerFailedEvent{Time: now, Command: "foo", Step: 0, Steps: 3, Err: errors.New("marker")},
+	}) {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+}
+
+// Ensure events are encoded as JSON with a type & durations in seconds.
+func TestMarshalEvent(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if buf, err := boxer.MarshalEvent(&boxer.StepStartedEvent{Time: now, Command: "foo", Step: 0, Steps: 3}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"step_started","time":"2000-01-01T00:00:00Z","command":"foo","step":0,"steps":3}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+	if buf, err := boxer.MarshalEvent(&boxer.StepStartedEvent{Time: now, Command: "foo", Step: 2, Steps: 3, Overtime: 1}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"step_started","time":"2000-01-01T00:00:00Z","command":"foo","step":2,"steps":3,"overtime":1}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+
+	if buf, err := boxer.MarshalEvent(&boxer.WarningEvent{Time: now, Command: "foo", Remaining: 2 * time.Minute}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"warning","time":"2000-01-01T00:00:00Z","command":"foo","remaining":120}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+
+	if buf, err := boxer.MarshalEvent(&boxer.HandlerFailedEvent{Time: now, Command: "foo", Step: 1, Steps: 3, Err: errors.New("marker")}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"handler_failed","time":"2000-01-01T00:00:00Z","command":"foo","step":1,"steps":3,"error":"marker"}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+
+	if buf, err := boxer.MarshalEvent(&boxer.ZoneChangedEvent{Time: now, Prev: now.Add(-time.Minute).In(time.FixedZone("EST", -5*60*60))}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"zone_changed","time":"2000-01-01T00:00:00Z","prev":"1999-12-31T18:59:00-05:00"}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+
+	if buf, err := boxer.MarshalEvent(&boxer.CommandQuarantinedEvent{Time: now, Command: "foo", Failures: 3, Until: now.Add(30 * time.Minute), Err: errors.New("marker")}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"command_quarantined","time":"2000-01-01T00:00:00Z","command":"foo","failures":3,"until":"2000-01-01T00:30:00Z","error":"marker"}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+
+	if buf, err := boxer.MarshalEvent(&boxer.CommandDisabledEvent{Time: now, Command: "foo", Failures: 3, Err: errors.New("marker")}); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != `{"type":"command_disabled","time":"2000-01-01T00:00:00Z","command":"foo","failures":3,"error":"marker"}` {
+		t.Fatalf("unexpected json: %s", buf)
+	}
+}
@@ -0,0 +1,303 @@
+package boxer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecRequest represents a request to execute an OS command.
+type ExecRequest struct {
+	Name  string
+	Args  []string
+	Stdin io.Reader
+
+	// Additional environment variables, as "KEY=value", added to the
+	// environment of the current process.
+	Env []string
+
+	// The working directory. Defaults to the current directory if blank.
+	Dir string
+
+	// The command is killed if it runs longer than the timeout, if set,
+	// or once the context is done.
+	Timeout time.Duration
+	Context context.Context
+}
+
+// Executor executes OS commands.
+type Executor interface {
+	Exec(req *ExecRequest) ([]byte, error)
+}
+
+// Exec implements Executor so a CommandExecutor can be used wherever an
+// Executor is expected. The function cannot receive the environment or
+// working directory so they are ignored. If the timeout elapses or the
+// context is done then the function is abandoned & the context's error is
+// returned.
+func (fn CommandExecutor) Exec(req *ExecRequest) ([]byte, error) {
+	ctx, cancel := req.context()
+	defer cancel()
+
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		b, err := fn(req.Name, req.Args, req.Stdin)
+		ch <- result{b, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.b, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// context returns the request's context with its timeout applied.
+func (req *ExecRequest) context() (context.Context, context.CancelFunc) {
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Timeout > 0 {
+		return context.WithTimeout(ctx, req.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// NewCommandExecutor returns a CommandExecutor that executes commands with e.
+// Returns nil if e is nil.
+func NewCommandExecutor(e Executor) CommandExecutor {
+	if e == nil {
+		return nil
+	}
+	return func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return e.Exec(&ExecRequest{Name: name, Args: args, Stdin: stdin})
+	}
+}
+
+// OSExecutor executes commands with os/exec. The combined output of stdout &
+// stderr is returned.
+type OSExecutor struct{}
+
+// DefaultExecutor is the executor used by DefaultCommandExecutor.
+var DefaultExecutor Executor = &OSExecutor{}
+
+// Exec executes req & waits for it to complete. If the request can time out
+// then, on Unix, the command runs in its own process group so any processes
+// started by the command, such as by a shell script, are killed along with it.
+func (e *OSExecutor) Exec(req *ExecRequest) ([]byte, error) {
+	cmd := exec.Command(req.Name, req.Args...)
+	cmd.Stdin = req.Stdin
+	cmd.Dir = req.Dir
+	if len(req.Env) > 0 {
+		cmd.Env = append(os.Environ(), req.Env...)
+	}
+
+	if req.Timeout <= 0 && req.Context == nil {
+		return cmd.CombinedOutput()
+	}
+
+	ctx, cancel := req.context()
+	defer cancel()
+
+	var buf bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &buf, &buf
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return buf.Bytes(), err
+	case <-ctx.Done():
+		_ = killProcessGroup(cmd)
+		<-done
+		return buf.Bytes(), ctx.Err()
+	}
+}
+
+// AllowlistExecutor wraps an executor & only executes commands whose
+// executable resolves to one of the allowed absolute paths. Commands that are
+// not absolute are resolved with the PATH. Every attempt is written to the
+// audit log, if set.
+//
+// Allowing a shell, such as /bin/sh, allows any command the shell can run.
+type AllowlistExecutor struct {
+	Executor Executor
+	Logger   *log.Logger
+
+	allow map[string]bool
+}
+
+// NewAllowlistExecutor returns an executor that only executes the paths in
+// allow with e. If allow is nil then every path is allowed and the executor
+// only writes the audit log.
+func NewAllowlistExecutor(e Executor, allow []string) *AllowlistExecutor {
+	x := &AllowlistExecutor{Executor: e}
+	if allow != nil {
+		x.allow = make(map[string]bool)
+		for _, path := range allow {
+			x.allow[filepath.Clean(path)] = true
+		}
+	}
+	return x
+}
+
+// Exec executes req with the underlying executor if its executable is
+// allowed. The resolved path is executed so the PATH cannot change between
+// the check & the execution.
+func (x *AllowlistExecutor) Exec(req *ExecRequest) ([]byte, error) {
+	path, err := resolveExecutable(req.Name)
+	if err == nil && x.allow != nil && !x.allow[path] {
+		err = fmt.Errorf("executable not allowed: %s", path)
+	}
+
+	if x.Logger != nil {
+		args := make([]string, len(req.Args))
+		for i, arg := range req.Args {
+			args[i] = fmt.Sprintf("%q", arg)
+		}
+		if err != nil {
+			x.Logger.Printf("exec denied: %s %s: %s", req.Name, strings.Join(args, " "), err)
+		} else {
+			x.Logger.Printf("exec: %s %s", path, strings.Join(args, " "))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	other := *req
+	other.Name = path
+	return x.Executor.Exec(&other)
+}
+
+// TracingExecutor wraps an executor & writes every command's arguments,
+// stdin, output, & duration to the logger. It is useful for diagnosing
+// failed scripts, such as AppleScript errors.
+type TracingExecutor struct {
+	Executor Executor
+	Logger   *log.Logger
+}
+
+// Exec executes req with the underlying executor & logs it.
+func (x *TracingExecutor) Exec(req *ExecRequest) ([]byte, error) {
+	args := make([]string, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = fmt.Sprintf("%q", arg)
+	}
+	msg := strings.TrimSpace(fmt.Sprintf("exec: %s %s", req.Name, strings.Join(args, " ")))
+
+	// Buffer stdin so it can be logged & still passed to the command.
+	other := *req
+	if req.Stdin != nil {
+		b, err := ioutil.ReadAll(req.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %s", err)
+		}
+		other.Stdin = bytes.NewReader(b)
+		if len(b) > 0 {
+			msg += "\n" + strings.TrimRight(string(b), "\n")
+		}
+	}
+	x.Logger.Print(msg)
+
+	t := time.Now()
+	b, err := x.Executor.Exec(&other)
+	msg = fmt.Sprintf("exec: %s finished in %s", req.Name, time.Since(t).Round(time.Millisecond))
+	if err != nil {
+		msg += fmt.Sprintf(": %s", err)
+	}
+	if out := strings.TrimRight(string(b), "\n"); out != "" {
+		msg += "\n" + out
+	}
+	x.Logger.Print(msg)
+	return b, err
+}
+
+// resolveExecutable returns the absolute path of the executable name.
+func resolveExecutable(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name), nil
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("executable not found: %s", name)
+	}
+	return filepath.Abs(path)
+}
+
+// ExecRecord represents a command recorded by RecordingExecutor.
+type ExecRecord struct {
+	Name  string
+	Args  []string
+	Stdin string
+	Env   []string
+	Dir   string
+}
+
+// RecordingExecutor records every command instead of executing it. It is
+// useful for debugging templated scripts & for tests. Commands return no
+// output & succeed.
+type RecordingExecutor struct {
+	mu      sync.Mutex
+	records []*ExecRecord
+
+	// If set, every command & its stdin are written to the logger.
+	Logger *log.Logger
+}
+
+// Exec records req without executing it.
+func (x *RecordingExecutor) Exec(req *ExecRequest) ([]byte, error) {
+	r := &ExecRecord{Name: req.Name, Args: req.Args, Env: req.Env, Dir: req.Dir}
+	if req.Stdin != nil {
+		b, err := ioutil.ReadAll(req.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %s", err)
+		}
+		r.Stdin = string(b)
+	}
+
+	x.mu.Lock()
+	x.records = append(x.records, r)
+	x.mu.Unlock()
+
+	if x.Logger != nil {
+		args := make([]string, len(r.Args))
+		for i, arg := range r.Args {
+			args[i] = fmt.Sprintf("%q", arg)
+		}
+		msg := strings.TrimSpace(fmt.Sprintf("dry run: %s %s", r.Name, strings.Join(args, " ")))
+		if r.Stdin != "" {
+			msg += "\n" + strings.TrimRight(r.Stdin, "\n")
+		}
+		x.Logger.Print(msg)
+	}
+	return nil, nil
+}
+
+// Records returns the commands recorded so far.
+func (x *RecordingExecutor) Records() []*ExecRecord {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return append([]*ExecRecord(nil), x.records...)
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package boxer
+
+import "os/exec"
+
+// setProcessGroup does nothing since process groups are not supported.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills only the process of cmd. Processes that it started
+// are left running.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,151 @@
+package boxer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the OS executor runs the command with the environment & directory.
+func TestOSExecutor_Exec(t *testing.T) {
+	b, err := boxer.DefaultExecutor.Exec(&boxer.ExecRequest{
+		Name: "/bin/sh",
+		Args: []string{"-c", `echo "$FOO $PWD"`},
+		Env:  []string{"FOO=bar"},
+		Dir:  "/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if string(b) != "bar /\n" {
+		t.Fatalf("unexpected output: %q", b)
+	}
+}
+
+// Ensure the OS executor kills a command once its timeout elapses.
+func TestOSExecutor_Exec_ErrTimeout(t *testing.T) {
+	if _, err := boxer.DefaultExecutor.Exec(&boxer.ExecRequest{Name: "sleep", Args: []string{"5"}, Timeout: 10 * time.Millisecond}); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+}
+
+// Ensure a command executor function can be used as an executor.
+func TestCommandExecutor_Exec(t *testing.T) {
+	fn := boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte(name + " " + strings.Join(args, " ")), nil
+	})
+	if b, err := fn.Exec(&boxer.ExecRequest{Name: "echo", Args: []string{"foo"}}); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "echo foo" {
+		t.Fatalf("unexpected output: %q", b)
+	}
+}
+
+// Ensure a command executor function is abandoned once the context is done.
+func TestCommandExecutor_Exec_ErrCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	fn := boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fn.Exec(&boxer.ExecRequest{Name: "echo", Context: ctx}); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the allowlist executor only executes allowed paths & audits every attempt.
+func TestAllowlistExecutor_Exec(t *testing.T) {
+	var names []string
+	fn := boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		names = append(names, name)
+		return nil, nil
+	})
+
+	var buf bytes.Buffer
+	x := boxer.NewAllowlistExecutor(fn, []string{"/usr/bin/osascript"})
+	x.Logger = log.New(&buf, "", 0)
+
+	if _, err := x.Exec(&boxer.ExecRequest{Name: "/usr/bin/osascript", Args: []string{"-l", "JavaScript"}}); err != nil {
+		t.Fatal(err)
+	} else if _, err := x.Exec(&boxer.ExecRequest{Name: "/bin/rm", Args: []string{"-rf", "/"}}); err == nil || err.Error() != `executable not allowed: /bin/rm` {
+		t.Fatal(err)
+	}
+
+	if len(names) != 1 || names[0] != "/usr/bin/osascript" {
+		t.Fatalf("unexpected executions: %v", names)
+	} else if buf.String() != `exec: /usr/bin/osascript "-l" "JavaScript"`+"\n"+`exec denied: /bin/rm "-rf" "/": executable not allowed: /bin/rm`+"\n" {
+		t.Fatalf("unexpected audit log: %s", buf.String())
+	}
+}
+
+// Ensure the allowlist executor resolves relative names with the PATH.
+func TestAllowlistExecutor_Exec_LookPath(t *testing.T) {
+	var name string
+	fn := boxer.CommandExecutor(func(n string, args []string, stdin io.Reader) ([]byte, error) {
+		name = n
+		return nil, nil
+	})
+
+	x := boxer.NewAllowlistExecutor(fn, []string{"/bin/sh"})
+	if _, err := x.Exec(&boxer.ExecRequest{Name: "sh"}); err != nil && !strings.HasPrefix(err.Error(), "executable not allowed: ") {
+		t.Fatal(err)
+	} else if err == nil && name != "/bin/sh" {
+		t.Fatalf("unexpected name: %s", name)
+	}
+
+	if _, err := x.Exec(&boxer.ExecRequest{Name: "no_such_executable"}); err == nil || err.Error() != `executable not found: no_such_executable` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the recording executor records commands & their stdin without executing them.
+func TestRecordingExecutor_Exec(t *testing.T) {
+	var buf bytes.Buffer
+	x := &boxer.RecordingExecutor{Logger: log.New(&buf, "", 0)}
+	if b, err := x.Exec(&boxer.ExecRequest{Name: "/usr/bin/osascript", Args: []string{"-l", "JavaScript"}, Stdin: strings.NewReader("beep\n"), Env: []string{"FOO=bar"}}); err != nil {
+		t.Fatal(err)
+	} else if b != nil {
+		t.Fatalf("unexpected output: %q", b)
+	}
+
+	if !reflect.DeepEqual(x.Records(), []*boxer.ExecRecord{
+		{Name: "/usr/bin/osascript", Args: []string{"-l", "JavaScript"}, Stdin: "beep\n", Env: []string{"FOO=bar"}},
+	}) {
+		t.Fatalf("unexpected records: %#v", x.Records())
+	} else if buf.String() != "dry run: /usr/bin/osascript \"-l\" \"JavaScript\"\nbeep\n" {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+}
+
+// Ensure the tracing executor logs each command's input & output and still
+// passes stdin to the command.
+func TestTracingExecutor_Exec(t *testing.T) {
+	var buf bytes.Buffer
+	x := &boxer.TracingExecutor{
+		Executor: boxer.CommandExecutor(func(name string, args []string, stdin io.Reader) ([]byte, error) {
+			b, _ := ioutil.ReadAll(stdin)
+			return []byte("execution error: " + string(b)), errors.New("exit status 1")
+		}),
+		Logger: log.New(&buf, "", 0),
+	}
+
+	if b, err := x.Exec(&boxer.ExecRequest{Name: "/usr/bin/osascript", Args: []string{"-"}, Stdin: strings.NewReader("beep\n")}); err == nil || err.Error() != "exit status 1" {
+		t.Fatal(err)
+	} else if string(b) != "execution error: beep\n" {
+		t.Fatalf("unexpected output: %q", b)
+	} else if exp := "exec: /usr/bin/osascript \"-\"\nbeep\nexec: /usr/bin/osascript finished in 0s: exit status 1\nexecution error: beep\n"; buf.String() != exp {
+		t.Fatalf("unexpected log: %q", buf.String())
+	}
+}
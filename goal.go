@@ -0,0 +1,199 @@
+package boxer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GoalPeriod represents the period a goal's completed intervals are counted over.
+type GoalPeriod string
+
+// Goal periods.
+const (
+	GoalDaily  GoalPeriod = "day"
+	GoalWeekly GoalPeriod = "week"
+)
+
+// ParseGoalPeriod parses a goal period. Defaults to GoalDaily if blank.
+func ParseGoalPeriod(s string) (GoalPeriod, error) {
+	switch period := GoalPeriod(s); period {
+	case "":
+		return GoalDaily, nil
+	case GoalDaily, GoalWeekly:
+		return period, nil
+	default:
+		return "", fmt.Errorf("invalid goal period: %q", s)
+	}
+}
+
+// start returns midnight at the start of t's day or week. Weeks start on Monday.
+func (p GoalPeriod) start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	if p == GoalWeekly {
+		d -= (int(t.Weekday()) + 6) % 7
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// prev returns the start of the period before the one starting at t.
+func (p GoalPeriod) prev(t time.Time) time.Time {
+	if p == GoalWeekly {
+		return p.start(t.AddDate(0, 0, -7))
+	}
+	return p.start(t.AddDate(0, 0, -1))
+}
+
+// Goal represents a target number of completed intervals per day or week.
+type Goal struct {
+	// The command whose intervals are counted. If blank, the command of the
+	// latest record is used.
+	Command string
+
+	Period GoalPeriod
+	Target int
+}
+
+// GoalProgress represents the progress toward a goal in the current period.
+type GoalProgress struct {
+	Command   string
+	Period    GoalPeriod
+	Start     time.Time // start of the current period
+	Completed int       // intervals completed during the period
+	Target    int
+	Streak    int // consecutive periods the goal was met
+}
+
+// Met returns true if the target was reached during the period.
+func (p *GoalProgress) Met() bool {
+	return p.Completed >= p.Target
+}
+
+// Message returns a one line description of the progress, such as
+// "7 of 10 boxes today, 3 day streak".
+func (p *GoalProgress) Message() string {
+	when := "today"
+	if p.Period == GoalWeekly {
+		when = "this week"
+	}
+	msg := fmt.Sprintf("%d of %d boxes %s", p.Completed, p.Target, when)
+	if p.Streak > 0 {
+		msg += fmt.Sprintf(", %d %s streak", p.Streak, p.Period)
+	}
+	return msg
+}
+
+// Progress counts the intervals completed during now's period. The streak
+// includes the current period once its goal is met so an unfinished day
+// does not break the streak.
+func (g *Goal) Progress(records []*IntervalRecord, now time.Time) *GoalProgress {
+	command := g.Command
+	if command == "" {
+		var latest time.Time
+		for _, r := range records {
+			if !r.Start.Before(latest) {
+				command, latest = r.Command, r.Start
+			}
+		}
+	}
+
+	// Tally completed intervals by the start of their period.
+	counts := make(map[int64]int)
+	for _, r := range records {
+		if r.Command != command || r.Paused || r.Skipped {
+			continue
+		}
+		counts[g.Period.start(r.Start.In(now.Location())).Unix()]++
+	}
+
+	start := g.Period.start(now)
+	p := &GoalProgress{
+		Command:   command,
+		Period:    g.Period,
+		Start:     start,
+		Completed: counts[start.Unix()],
+		Target:    g.Target,
+	}
+	if g.Target <= 0 {
+		return p
+	}
+
+	t := start
+	if !p.Met() {
+		t = g.Period.prev(t)
+	}
+	for ; counts[t.Unix()] >= g.Target; t = g.Period.prev(t) {
+		p.Streak++
+	}
+	return p
+}
+
+// GoalHandler is called with the progress when a goal is met.
+type GoalHandler func(p *GoalProgress) error
+
+// NewGoalNotificationHandler returns a handler that celebrates a met goal
+// with a notification. The title, subtitle & sound are copied from n.
+func NewGoalNotificationHandler(notifier Notifier, n Notification) GoalHandler {
+	if n.Title == "" {
+		n.Title = DefaultNotificationTitle
+	}
+
+	return func(p *GoalProgress) error {
+		n := n
+		n.Message = "Goal met! " + p.Message()
+		return notifier.Notify(&n)
+	}
+}
+
+// GoalTracker is a journal that records intervals to a file journal and calls
+// its handlers when a recorded interval meets the goal for its period.
+type GoalTracker struct {
+	Journal  *FileJournal
+	Goal     Goal
+	Handlers []GoalHandler
+}
+
+// NewGoalTracker returns a new goal tracker for the journal.
+func NewGoalTracker(journal *FileJournal, goal Goal) *GoalTracker {
+	return &GoalTracker{Journal: journal, Goal: goal}
+}
+
+// Record appends r to the journal. If r is the interval that reaches the goal
+// then the progress is passed to every handler. Handler errors are combined
+// into the returned error.
+func (t *GoalTracker) Record(r *IntervalRecord) error {
+	if err := t.Journal.Record(r); err != nil {
+		return err
+	} else if r.Paused || r.Skipped {
+		return nil
+	}
+
+	records, err := t.Journal.Records()
+	if err != nil {
+		return fmt.Errorf("read journal: %s", err)
+	}
+	p := t.Goal.Progress(records, r.Start)
+	if p.Command != r.Command || p.Completed != p.Target {
+		return nil
+	}
+
+	var errs []string
+	for _, h := range t.Handlers {
+		if err := h(p); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("goal: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Progress returns the progress toward the goal during now's period.
+func (t *GoalTracker) Progress(now time.Time) (*GoalProgress, error) {
+	records, err := t.Journal.Records()
+	if err != nil {
+		return nil, fmt.Errorf("read journal: %s", err)
+	}
+	return t.Goal.Progress(records, now), nil
+}
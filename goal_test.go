@@ -0,0 +1,94 @@
+package boxer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure progress counts the period's completed intervals & the streak of
+// previous periods that met the goal.
+func TestGoal_Progress(t *testing.T) {
+	day := time.Date(2000, time.January, 5, 9, 0, 0, 0, time.UTC)
+	var records []*boxer.IntervalRecord
+	add := func(t time.Time, n int) {
+		for i := 0; i < n; i++ {
+			s := t.Add(time.Duration(i) * 30 * time.Minute)
+			records = append(records, &boxer.IntervalRecord{Command: "wallpaper", Start: s, End: s.Add(30 * time.Minute)})
+		}
+	}
+	add(day.AddDate(0, 0, -4), 3)
+	add(day.AddDate(0, 0, -3), 1)
+	add(day.AddDate(0, 0, -2), 3)
+	add(day.AddDate(0, 0, -1), 4)
+	add(day, 2)
+	records = append(records, &boxer.IntervalRecord{Command: "wallpaper", Start: day.Add(-time.Hour), End: day, Skipped: true})
+
+	g := &boxer.Goal{Period: boxer.GoalDaily, Target: 3}
+	if p := g.Progress(records, day.Add(8*time.Hour)); p.Command != "wallpaper" || p.Completed != 2 || p.Streak != 2 || p.Met() {
+		t.Fatalf("unexpected progress: %#v", p)
+	} else if msg := p.Message(); msg != "2 of 3 boxes today, 2 day streak" {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+
+	// The current day counts toward the streak once it's met.
+	add(day.Add(2*time.Hour), 1)
+	if p := g.Progress(records, day.Add(8*time.Hour)); p.Completed != 3 || p.Streak != 3 || !p.Met() {
+		t.Fatalf("unexpected progress: %#v", p)
+	}
+
+	// Weeks start on Monday, January 3rd.
+	g = &boxer.Goal{Period: boxer.GoalWeekly, Target: 10}
+	if p := g.Progress(records, day); !p.Start.Equal(time.Date(2000, time.January, 3, 0, 0, 0, 0, time.UTC)) || p.Completed != 10 || p.Streak != 1 {
+		t.Fatalf("unexpected progress: %#v", p)
+	} else if msg := p.Message(); msg != "10 of 10 boxes this week, 1 week streak" {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+}
+
+// Ensure an invalid goal period returns an error.
+func TestParseGoalPeriod_ErrInvalid(t *testing.T) {
+	if _, err := boxer.ParseGoalPeriod("month"); err == nil || err.Error() != `invalid goal period: "month"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the tracker only calls its handlers for the interval that meets the goal.
+func TestGoalTracker_Record(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var met []*boxer.GoalProgress
+	tracker := boxer.NewGoalTracker(boxer.NewFileJournal(filepath.Join(dir, "journal.jsonl")), boxer.Goal{Period: boxer.GoalDaily, Target: 2})
+	tracker.Handlers = []boxer.GoalHandler{func(p *boxer.GoalProgress) error { met = append(met, p); return nil }}
+
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	for i, r := range []*boxer.IntervalRecord{
+		{Command: "wallpaper", Start: start, End: start.Add(30 * time.Minute)},
+		{Command: "wallpaper", Start: start.Add(30 * time.Minute), End: start.Add(60 * time.Minute), Skipped: true},
+		{Command: "wallpaper", Start: start.Add(60 * time.Minute), End: start.Add(90 * time.Minute)},
+		{Command: "wallpaper", Start: start.Add(90 * time.Minute), End: start.Add(120 * time.Minute)},
+	} {
+		if err := tracker.Record(r); err != nil {
+			t.Fatal(err)
+		} else if exp := map[bool]int{true: 1, false: 0}[i >= 2]; len(met) != exp {
+			t.Fatalf("%d: unexpected handler calls: %d", i, len(met))
+		}
+	}
+	if met[0].Completed != 2 || met[0].Streak != 1 {
+		t.Fatalf("unexpected progress: %#v", met[0])
+	}
+
+	if p, err := tracker.Progress(start.Add(8 * time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if p.Completed != 3 {
+		t.Fatalf("unexpected progress: %#v", p)
+	}
+}
@@ -0,0 +1,143 @@
+package boxer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ShellPath is the path to the shell used to execute shell commands.
+const ShellPath = `/bin/sh`
+
+// NewShellHandler returns a handler that executes a templated shell command.
+// The template has access to the fields of ShellData and the command's
+// environment includes them as BOXER_STEP, BOXER_TOTAL, BOXER_PERCENT,
+// BOXER_REMAINING & BOXER_INTERVAL_INDEX. The label is only available as
+// BOXER_LABEL since it is set by the user & could be run by the shell if it
+// was templated. The command runs in dir, if set, and is killed if it runs
+// longer than timeout, if set.
+func NewShellHandler(exec Executor, cmdTemplate, dir string, timeout time.Duration) (Handler, error) {
+	tmpl, err := template.New("shell").Parse(cmdTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %s", err)
+	}
+
+	// Report unknown fields, such as the label, before the first step.
+	if err := tmpl.Execute(ioutil.Discard, &ShellData{Total: 1}); err != nil {
+		return nil, fmt.Errorf("execute template: %s", err)
+	}
+
+	return func(ctx *Context) error {
+		data := &ShellData{
+			Step:          ctx.Step,
+			Total:         ctx.Total,
+			Percent:       percent(ctx.Step, ctx.Total),
+			IntervalStart: ctx.IntervalStart,
+			IntervalIndex: ctx.IntervalIndex,
+		}
+
+		// Render the command with the current step information.
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("execute template: %s", err)
+		}
+
+		// Execute the command through the shell.
+		if b, err := exec.Exec(&ExecRequest{
+			Name:  ShellPath,
+			Args:  []string{"-c", buf.String()},
+			Stdin: strings.NewReader(""),
+			Env: []string{
+				"BOXER_STEP=" + strconv.Itoa(data.Step),
+				"BOXER_TOTAL=" + strconv.Itoa(data.Total),
+				"BOXER_PERCENT=" + strconv.Itoa(data.Percent),
+				"BOXER_REMAINING=" + strconv.Itoa(int(ctx.Remaining.Round(time.Second)/time.Second)),
+				"BOXER_INTERVAL_INDEX=" + strconv.Itoa(data.IntervalIndex),
+				"BOXER_LABEL=" + ctx.Label,
+			},
+			Dir:     dir,
+			Timeout: timeout,
+		}); err == context.DeadlineExceeded {
+			return fmt.Errorf("exec: timed out after %s", timeout)
+		} else if err != nil {
+			return fmt.Errorf("exec: %s", b)
+		}
+		return nil
+	}, nil
+}
+
+// percent returns step as a whole percentage of total. Returns zero if there
+// are no steps, such as when the step is longer than the interval.
+func percent(step, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	return step * 100 / total
+}
+
+// ShellData represents the data available to shell command templates. Only
+// values that are safe to insert into a shell command are included.
+type ShellData struct {
+	Step          int       // current step index
+	Total         int       // total steps per interval
+	Percent       int       // percent through the interval, from 0 to 100
+	IntervalStart time.Time // start time of the current interval
+	IntervalIndex int       // index of the current interval within the day
+}
+
+// StatusFileVersion is the version of the status file format. It is only
+// incremented if a field is removed or its meaning changes.
+const StatusFileVersion = 1
+
+// StatusFile represents the JSON written by the status file handler. The
+// format is a stable contract for editor & statusline plugins.
+type StatusFile struct {
+	Version   int       `json:"version"`   // format version, see StatusFileVersion
+	Time      time.Time `json:"time"`      // time the file was written
+	Step      int       `json:"step"`      // zero-based step index
+	Total     int       `json:"total"`     // total steps per interval
+	Percent   int       `json:"percent"`   // percent through the interval, from 0 to 100
+	Remaining int       `json:"remaining"` // seconds remaining in the interval
+	Label     string    `json:"label"`     // current label, if any
+}
+
+// NewStatusFileHandler returns a handler that writes the current step as a
+// StatusFile to path. The file is written to a temporary file & renamed so
+// readers never see a partially written file.
+func NewStatusFileHandler(path string) (Handler, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path required")
+	}
+
+	return func(ctx *Context) error {
+		buf, err := json.Marshal(&StatusFile{
+			Version:   StatusFileVersion,
+			Time:      ctx.Now,
+			Step:      ctx.Step,
+			Total:     ctx.Total,
+			Percent:   percent(ctx.Step, ctx.Total),
+			Remaining: int(ctx.Remaining.Round(time.Second) / time.Second),
+			Label:     ctx.Label,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return fmt.Errorf("mkdir: %s", err)
+		} else if err := ioutil.WriteFile(path+".tmp", append(buf, '\n'), 0666); err != nil {
+			return fmt.Errorf("write status file: %s", err)
+		} else if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("rename: %s", err)
+		}
+		return nil
+	}, nil
+}
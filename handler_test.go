@@ -0,0 +1,170 @@
+package boxer_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the shell handler renders its template and executes it with the shell.
+func TestShellHandler(t *testing.T) {
+	var called bool
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != "/bin/sh" {
+			t.Fatalf("unexpected name: %s", name)
+		} else if !reflect.DeepEqual(args, []string{"-c", `echo "3/12 25%"`}) {
+			t.Fatalf("unexpected args: %#v", args)
+		}
+		called = true
+		return nil, nil
+	}
+
+	h, err := boxer.NewShellHandler(boxer.CommandExecutor(exec), `echo "{{.Step}}/{{.Total}} {{.Percent}}%"`, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Step: 3, Total: 12}); err != nil {
+		t.Fatal(err)
+	} else if !called {
+		t.Fatal("exec not called")
+	}
+}
+
+// Ensure the shell handler reports zero percent when there are no steps.
+func TestShellHandler_ZeroTotal(t *testing.T) {
+	var args []string
+	exec := func(name string, a []string, stdin io.Reader) ([]byte, error) {
+		args = a
+		return nil, nil
+	}
+
+	h, err := boxer.NewShellHandler(boxer.CommandExecutor(exec), `echo {{.Percent}}`, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(args, []string{"-c", `echo 0`}) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+// Ensure the shell handler returns the command output on failure.
+func TestShellHandler_ErrExec(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		return []byte("command not found"), errors.New("exit status 127")
+	}
+
+	h, _ := boxer.NewShellHandler(boxer.CommandExecutor(exec), `no_such_command`, "", 0)
+	if err := h(&boxer.Context{Step: 0, Total: 1}); err == nil || err.Error() != `exec: command not found` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the shell handler passes the step as environment variables & runs in the directory.
+func TestShellHandler_Env(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := boxer.NewShellHandler(boxer.DefaultExecutor, `echo "$BOXER_STEP/$BOXER_TOTAL $BOXER_PERCENT $BOXER_REMAINING $BOXER_LABEL" > out`, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Step: 3, Total: 12, Remaining: 90 * time.Second, Label: "docs $(echo injected)"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "out")); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "3/12 25 90 docs $(echo injected)\n" {
+		t.Fatalf("unexpected output: %q", b)
+	}
+}
+
+// Ensure the shell handler kills a command that runs past its timeout.
+func TestShellHandler_ErrTimeout(t *testing.T) {
+	h, _ := boxer.NewShellHandler(boxer.DefaultExecutor, `sleep 5`, "", 10*time.Millisecond)
+	if err := h(&boxer.Context{Step: 0, Total: 1}); err == nil || err.Error() != `exec: timed out after 10ms` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure an invalid template returns an error.
+func TestNewShellHandler_ErrTemplate(t *testing.T) {
+	if _, err := boxer.NewShellHandler(nil, `{{.Step`, "", 0); err == nil || err.Error() != `parse template: template: shell:1: unclosed action` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the label cannot be templated into the shell command.
+func TestNewShellHandler_ErrLabel(t *testing.T) {
+	if _, err := boxer.NewShellHandler(nil, `say '{{.Label}}'`, "", 0); err == nil || !strings.Contains(err.Error(), `can't evaluate field Label`) {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the status file handler writes the current step as JSON.
+func TestStatusFileHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "status", "boxer.json")
+	h, err := boxer.NewStatusFileHandler(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{
+		Step:      3,
+		Total:     12,
+		Remaining: 1350 * time.Second,
+		Now:       time.Date(2000, time.January, 1, 0, 7, 30, 0, time.UTC),
+		Label:     "write docs",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(b) != `{"version":1,"time":"2000-01-01T00:07:30Z","step":3,"total":12,"percent":25,"remaining":1350,"label":"write docs"}`+"\n" {
+		t.Fatalf("unexpected file: %s", b)
+	}
+}
+
+// Ensure the status file reports zero percent when there are no steps.
+func TestStatusFileHandler_ZeroTotal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "boxer.json")
+	if h, err := boxer.NewStatusFileHandler(path); err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Now: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(b) != `{"version":1,"time":"2000-01-01T00:00:00Z","step":0,"total":0,"percent":0,"remaining":0,"label":""}`+"\n" {
+		t.Fatalf("unexpected file: %s", b)
+	}
+}
+
+// Ensure the status file handler requires a path.
+func TestNewStatusFileHandler_ErrPathRequired(t *testing.T) {
+	if _, err := boxer.NewStatusFileHandler(""); err == nil || err.Error() != `path required` {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,249 @@
+package boxer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPHandler represents an HTTP handler for inspecting a running ticker.
+// Requests apply to Ticker unless a named ticker is selected with the
+// "ticker" form value.
+type HTTPHandler struct {
+	Ticker  *Ticker
+	Tickers map[string]*Ticker
+
+	// Replaces the commands of Ticker with those of the named profile. A
+	// blank name restores the default commands. If nil, switching profiles
+	// is not supported.
+	SwitchProfile func(name string) error
+
+	// Reloads the commands of Ticker from the config file. If nil,
+	// reloading is not supported.
+	Reload func() error
+
+	// If true, only the status & events are served and control requests
+	// are forbidden, such as on a TCP listener other processes can reach.
+	ReadOnly bool
+}
+
+// NewHTTPHandler returns a new instance of HTTPHandler for a ticker.
+func NewHTTPHandler(t *Ticker) *HTTPHandler {
+	return &HTTPHandler{Ticker: t}
+}
+
+// ServeHTTP routes requests to the appropriate endpoint.
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.ReadOnly && r.URL.Path != "/status" && r.URL.Path != "/events" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Select the named ticker, if specified.
+	t := h.Ticker
+	if name := r.FormValue("ticker"); name != "" {
+		if t = h.Tickers[name]; t == nil {
+			http.Error(w, fmt.Sprintf("ticker not found: %q", name), http.StatusNotFound)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/status":
+		h.serveStatus(w, r, t)
+	case "/events":
+		h.serveEvents(w, r, t)
+	case "/pause":
+		h.serveControl(w, r, t, t.Pause)
+	case "/resume":
+		h.serveControl(w, r, t, t.Resume)
+	case "/skip":
+		h.serveControl(w, r, t, t.Skip)
+	case "/label":
+		h.serveControl(w, r, t, func() { t.SetLabel(strings.TrimSpace(r.FormValue("label"))) })
+	case "/snooze":
+		h.serveSnooze(w, r, t)
+	case "/start":
+		h.serveStart(w, r, t)
+	case "/enable":
+		h.serveCommandControl(w, r, t, t.Enable)
+	case "/disable":
+		h.serveCommandControl(w, r, t, t.Disable)
+	case "/profile":
+		h.serveProfile(w, r)
+	case "/reload":
+		h.serveReload(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveStatus writes the current ticker status as JSON.
+func (h *HTTPHandler) serveStatus(w http.ResponseWriter, r *http.Request, t *Ticker) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t.Status())
+}
+
+// serveEvents upgrades the request to a WebSocket connection & streams the
+// ticker's events as JSON text messages until the client disconnects. The
+// current status is sent first as a "status" message.
+func (h *HTTPHandler) serveEvents(w http.ResponseWriter, r *http.Request, t *Ticker) {
+	ch, unsubscribe := t.Subscribe(100)
+	defer unsubscribe()
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	buf, err := json.Marshal(&statusMessage{Type: "status", Status: t.Status()})
+	if err != nil {
+		return
+	} else if err := conn.WriteText(buf); err != nil {
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- conn.readLoop() }()
+
+	for {
+		select {
+		case <-done:
+			return
+		case e := <-ch:
+			buf, err := MarshalEvent(e)
+			if err != nil {
+				return
+			} else if err := conn.WriteText(buf); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// statusMessage is the first message sent on the event stream.
+type statusMessage struct {
+	Type   string  `json:"type"`
+	Status *Status `json:"status"`
+}
+
+// serveControl executes fn and writes the resulting ticker status as JSON.
+func (h *HTTPHandler) serveControl(w http.ResponseWriter, r *http.Request, t *Ticker, fn func()) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fn()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t.Status())
+}
+
+// serveSnooze snoozes intrusive commands for the duration in the "duration"
+// form value and writes the resulting ticker status as JSON.
+func (h *HTTPHandler) serveSnooze(w http.ResponseWriter, r *http.Request, t *Ticker) {
+	d, err := time.ParseDuration(r.FormValue("duration"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid duration: %q", r.FormValue("duration")), http.StatusBadRequest)
+		return
+	}
+	h.serveControl(w, r, t, func() { t.Snooze(d) })
+}
+
+// serveStart begins a new interval now, or realigns intervals to the clock
+// if the "reset" form value is "true", and writes the resulting ticker status
+// as JSON.
+func (h *HTTPHandler) serveStart(w http.ResponseWriter, r *http.Request, t *Ticker) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var err error
+	if r.FormValue("reset") == "true" {
+		err = t.SetAnchor(time.Time{})
+	} else {
+		err = t.Start()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t.Status())
+}
+
+// serveCommandControl executes fn for the command named by the "command"
+// form value and writes the resulting ticker status as JSON.
+func (h *HTTPHandler) serveCommandControl(w http.ResponseWriter, r *http.Request, t *Ticker, fn func(name string) error) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.FormValue("command")
+	if err := fn(name); err == ErrCommandNotFound {
+		http.Error(w, fmt.Sprintf("command not found: %q", name), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t.Status())
+}
+
+// serveProfile switches the main ticker to the profile named by the "profile"
+// form value and writes the resulting ticker status as JSON.
+func (h *HTTPHandler) serveProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	} else if h.SwitchProfile == nil {
+		http.Error(w, "profiles not supported", http.StatusNotImplemented)
+		return
+	}
+
+	name := r.FormValue("profile")
+	if err := h.SwitchProfile(name); err == ErrProfileNotFound {
+		http.Error(w, fmt.Sprintf("profile not found: %q", name), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.Ticker.Status())
+}
+
+// serveReload reloads the main ticker's commands and writes the resulting
+// ticker status as JSON. The error is returned if the config is invalid.
+func (h *HTTPHandler) serveReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	} else if h.Reload == nil {
+		http.Error(w, "reload not supported", http.StatusNotImplemented)
+		return
+	}
+
+	if err := h.Reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.Ticker.Status())
+}
@@ -0,0 +1,367 @@
+package boxer_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// Ensure the status endpoint returns the ticker's progress as JSON.
+func TestHTTPHandler_Status(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 7, 30, 0, time.UTC))
+	ticker.Commands = []boxer.Command{{
+		Name:     "wallpaper",
+		Step:     1 * time.Minute,
+		Interval: 15 * time.Minute,
+		Handler:  func(ctx *boxer.Context) error { return nil },
+	}}
+	ticker.Tick()
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/status", nil)
+	boxer.NewHTTPHandler(ticker).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if w.Body.String() != `{"time":"2000-01-01T00:07:30Z","active":true,"paused":false,"commands":[{"name":"wallpaper","interval_start":"2000-01-01T00:00:00Z","elapsed":450,"remaining":450,"step":7,"steps":15}]}`+"\n" {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the events endpoint streams the status & ticker events over a WebSocket.
+func TestHTTPHandler_Events(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Clock = clock.NewMock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	s := httptest.NewServer(boxer.NewHTTPHandler(ticker))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Perform the opening handshake with the sample key from RFC 6455.
+	fmt.Fprintf(conn, "GET /events HTTP/1.1\r\nHost: localhost\r\nOrigin: http://localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	} else if v := resp.Header.Get("Sec-WebSocket-Accept"); v != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key: %s", v)
+	}
+
+	// The current status is sent first.
+	if msg := readWebSocketFrame(t, br); !strings.HasPrefix(msg, `{"type":"status","status":{`) {
+		t.Fatalf("unexpected message: %s", msg)
+	}
+
+	// Events follow as they are emitted.
+	ticker.Pause()
+	if msg := readWebSocketFrame(t, br); msg != `{"type":"paused","time":"2000-01-01T00:00:00Z"}` {
+		t.Fatalf("unexpected message: %s", msg)
+	}
+
+	// Closing the connection is acknowledged with a close frame.
+	if _, err := conn.Write([]byte{0x88, 0x82, 1, 2, 3, 4, 0x03 ^ 1, 0xE8 ^ 2}); err != nil {
+		t.Fatal(err)
+	} else if b, err := br.ReadByte(); err != nil {
+		t.Fatal(err)
+	} else if b != 0x88 {
+		t.Fatalf("unexpected frame: %x", b)
+	}
+}
+
+// Ensure the events endpoint requires a WebSocket upgrade.
+func TestHTTPHandler_Events_ErrUpgradeRequired(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/events", nil)
+	boxer.NewHTTPHandler(boxer.NewTicker()).ServeHTTP(w, r)
+	if w.Code != http.StatusUpgradeRequired {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+}
+
+// Ensure the events endpoint rejects handshakes from other origins.
+func TestHTTPHandler_Events_ErrCrossOrigin(t *testing.T) {
+	for _, origin := range []string{"http://evil.example", "http://localhost.evil.example", "null"} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "http://localhost:7171/events", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		boxer.NewHTTPHandler(boxer.NewTicker()).ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s: unexpected status code: %d", origin, w.Code)
+		}
+	}
+}
+
+// readWebSocketFrame reads an unmasked text frame sent by the server.
+func readWebSocketFrame(tb testing.TB, r *bufio.Reader) string {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		tb.Fatal(err)
+	} else if hdr[0] != 0x81 {
+		tb.Fatalf("unexpected frame: %x", hdr[0])
+	}
+
+	n := int(hdr[1])
+	if n == 126 {
+		var buf [2]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			tb.Fatal(err)
+		}
+		n = int(binary.BigEndian.Uint16(buf[:]))
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		tb.Fatal(err)
+	}
+	return string(buf)
+}
+
+// Ensure unknown paths return a 404.
+func TestHTTPHandler_NotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/no_such_path", nil)
+	boxer.NewHTTPHandler(boxer.NewTicker()).ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+}
+
+// Ensure a read-only handler serves the status but forbids control requests.
+func TestHTTPHandler_ReadOnly(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+	h.ReadOnly = true
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/pause", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if ticker.Status().Paused {
+		t.Fatal("expected ticker to not be paused")
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/status", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+}
+
+// Ensure requests are sent to the named ticker selected by the "ticker" value.
+func TestHTTPHandler_Ticker(t *testing.T) {
+	ticker, stretch := boxer.NewTicker(), boxer.NewTicker()
+	h := &boxer.HTTPHandler{Ticker: ticker, Tickers: map[string]*boxer.Ticker{"stretch": stretch}}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/pause?ticker=stretch", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if !stretch.Status().Paused {
+		t.Fatal("expected named ticker to be paused")
+	} else if ticker.Status().Paused {
+		t.Fatal("expected main ticker to be running")
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/status?ticker=no_such_ticker", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if w.Body.String() != "ticker not found: \"no_such_ticker\"\n" {
+		t.Fatalf("unexpected body: %q", w.Body.String())
+	}
+}
+
+// Ensure the ticker can be paused & resumed over HTTP.
+func TestHTTPHandler_PauseResume(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/pause", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if !ticker.Status().Paused {
+		t.Fatal("expected ticker to be paused")
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/resume", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if ticker.Status().Paused {
+		t.Fatal("expected ticker to be resumed")
+	}
+}
+
+// Ensure the label can be set & cleared over HTTP.
+func TestHTTPHandler_Label(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/label?label=write+design+doc", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if label := ticker.Label(); label != "write design doc" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/label", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if label := ticker.Label(); label != "" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+}
+
+// Ensure commands can be disabled over HTTP.
+func TestHTTPHandler_Disable(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Commands = []boxer.Command{{Name: "wallpaper", Interval: 15 * time.Minute}}
+	h := boxer.NewHTTPHandler(ticker)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/disable?command=wallpaper", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if !ticker.Status().Commands[0].Disabled {
+		t.Fatal("expected command to be disabled")
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/enable?command=menu_bar", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `command not found: "menu_bar"` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the main ticker's profile can be switched over HTTP.
+func TestHTTPHandler_Profile(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+	h.SwitchProfile = func(name string) error {
+		if name != "deepwork" {
+			return boxer.ErrProfileNotFound
+		}
+		ticker.ReplaceCommands([]boxer.Command{{Name: "focus", Interval: 15 * time.Minute}})
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/profile?profile=deepwork", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if status := ticker.Status(); len(status.Commands) != 1 || status.Commands[0].Name != "focus" {
+		t.Fatalf("unexpected commands: %#v", status.Commands)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/profile?profile=meetings", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `profile not found: "meetings"` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure a failed reload returns the error.
+func TestHTTPHandler_Reload(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/reload", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusNotImplemented {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+
+	h.Reload = func() error { return errors.New("marker") }
+	w = httptest.NewRecorder()
+	if h.ServeHTTP(w, r); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != "marker" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure intrusive commands can be snoozed over HTTP.
+func TestHTTPHandler_Snooze(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/snooze?duration=10m", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if ticker.Status().SnoozedUntil == nil {
+		t.Fatal("expected snooze")
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/snooze?duration=soon", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `invalid duration: "soon"` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the start endpoint anchors intervals to now & can reset them.
+func TestHTTPHandler_Start(t *testing.T) {
+	ticker := boxer.NewTicker()
+	h := boxer.NewHTTPHandler(ticker)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/start", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if ticker.Status().Anchor == nil {
+		t.Fatal("expected anchor")
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/start?reset=true", nil)
+	if h.ServeHTTP(w, r); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	} else if ticker.Status().Anchor != nil {
+		t.Fatal("expected no anchor")
+	}
+}
+
+// Ensure control endpoints require a POST.
+func TestHTTPHandler_ErrMethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/skip", nil)
+	boxer.NewHTTPHandler(boxer.NewTicker()).ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+}
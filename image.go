@@ -0,0 +1,184 @@
+package boxer
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ImageFormat represents the file format of generated images.
+type ImageFormat string
+
+// Image formats.
+const (
+	ImagePNG  ImageFormat = "png"
+	ImageJPEG ImageFormat = "jpeg"
+)
+
+// ParseImageFormat parses an image format. Defaults to ImagePNG if blank.
+func ParseImageFormat(s string) (ImageFormat, error) {
+	switch s {
+	case "", "png":
+		return ImagePNG, nil
+	case "jpeg", "jpg":
+		return ImageJPEG, nil
+	default:
+		return "", fmt.Errorf("invalid image format: %q", s)
+	}
+}
+
+// Ext returns the filename extension of the format, including the dot.
+func (f ImageFormat) Ext() string {
+	if f == ImageJPEG {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// ParsePNGCompression parses a PNG compression level: "default", "none",
+// "fast", or "best". Defaults to png.DefaultCompression if blank.
+func ParsePNGCompression(s string) (png.CompressionLevel, error) {
+	switch s {
+	case "", "default":
+		return png.DefaultCompression, nil
+	case "none":
+		return png.NoCompression, nil
+	case "fast":
+		return png.BestSpeed, nil
+	case "best":
+		return png.BestCompression, nil
+	default:
+		return 0, fmt.Errorf("invalid png compression: %q", s)
+	}
+}
+
+// ImageEncoder writes generated images to files. Encoded images & PNG
+// encoding buffers are pooled so they are reused between images. The zero
+// value encodes PNG images with the default compression.
+type ImageEncoder struct {
+	Format ImageFormat
+
+	// JPEG quality from 1 to 100. Defaults to jpeg.DefaultQuality.
+	Quality int
+
+	// PNG compression level.
+	Compression png.CompressionLevel
+
+	pool pngBufferPool
+	bufs sync.Pool
+}
+
+// Ext returns the filename extension of the encoder's format.
+func (e *ImageEncoder) Ext() string {
+	if e == nil {
+		return ImagePNG.Ext()
+	}
+	return e.Format.Ext()
+}
+
+// Validate returns an error if the quality is out of range.
+func (e *ImageEncoder) Validate() error {
+	if e.Quality < 0 || e.Quality > 100 {
+		return fmt.Errorf("jpeg quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// Encode writes m to w in the encoder's format. If e is nil then m is
+// encoded as a PNG with the default compression.
+func (e *ImageEncoder) Encode(w io.Writer, m image.Image) error {
+	if e == nil {
+		e = &ImageEncoder{}
+	}
+
+	switch e.Format {
+	case "", ImagePNG:
+		enc := &png.Encoder{CompressionLevel: e.Compression, BufferPool: &e.pool}
+		if err := enc.Encode(w, m); err != nil {
+			return fmt.Errorf("png encode: %s", err)
+		}
+	case ImageJPEG:
+		quality := e.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		if err := jpeg.Encode(w, m, &jpeg.Options{Quality: quality}); err != nil {
+			return fmt.Errorf("jpeg encode: %s", err)
+		}
+	default:
+		return fmt.Errorf("invalid image format: %q", e.Format)
+	}
+	return nil
+}
+
+// WriteFile encodes m in memory and then atomically replaces the file at
+// path. A crash mid-encode leaves the previous image in place, or no image at
+// all, rather than a truncated image.
+func (e *ImageEncoder) WriteFile(path string, m image.Image) error {
+	if e == nil {
+		e = &ImageEncoder{}
+	}
+
+	buf, _ := e.bufs.Get().(*bytes.Buffer)
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+	defer e.bufs.Put(buf)
+	buf.Reset()
+
+	if err := e.Encode(buf, m); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// writeFileAtomic writes b to a uniquely named temporary file beside path,
+// syncs it, and renames it over path. Concurrent writes to the same path are
+// safe & the last rename wins.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+
+	if err := f.Chmod(0644); err != nil {
+		_ = f.Close()
+		return err
+	} else if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	} else if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename: %s", err)
+	}
+	return nil
+}
+
+// pngBufferPool reuses PNG encoder buffers between images.
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+// Get returns a buffer from the pool, or nil if it is empty.
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	b, _ := p.pool.Get().(*png.EncoderBuffer)
+	return b
+}
+
+// Put returns a buffer to the pool.
+func (p *pngBufferPool) Put(b *png.EncoderBuffer) {
+	p.pool.Put(b)
+}
@@ -0,0 +1,79 @@
+package boxer_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the encoder writes images in the configured format.
+func TestImageEncoder_Encode(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for i := range m.Pix {
+		m.Pix[i] = 0xFF
+	}
+
+	for _, enc := range []*boxer.ImageEncoder{
+		nil,
+		{Format: boxer.ImagePNG, Compression: png.BestSpeed},
+		{Format: boxer.ImageJPEG, Quality: 80},
+	} {
+		// Encode twice to reuse pooled buffers.
+		for i := 0; i < 2; i++ {
+			var buf bytes.Buffer
+			if err := enc.Encode(&buf, m); err != nil {
+				t.Fatal(err)
+			}
+
+			other, format, err := image.Decode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			} else if exp := enc.Ext()[1:]; (exp == "jpg" && format != "jpeg") || (exp == "png" && format != "png") {
+				t.Fatalf("unexpected format: %s", format)
+			} else if other.Bounds() != m.Bounds() {
+				t.Fatalf("unexpected bounds: %v", other.Bounds())
+			} else if r, _, _, _ := other.At(10, 10).RGBA(); r>>8 < 0xF0 {
+				t.Fatalf("unexpected color: %v", other.At(10, 10))
+			}
+		}
+	}
+}
+
+// Ensure a failed write leaves the previous image in place.
+func TestImageEncoder_WriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wallpaper.png")
+	m := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	if err := (&boxer.ImageEncoder{}).WriteFile(path, m); err != nil {
+		t.Fatal(err)
+	}
+	prev, _ := ioutil.ReadFile(path)
+
+	if err := (&boxer.ImageEncoder{Format: "tiff"}).WriteFile(path, m); err == nil || err.Error() != `invalid image format: "tiff"` {
+		t.Fatal(err)
+	} else if b, _ := ioutil.ReadFile(path); !bytes.Equal(b, prev) {
+		t.Fatal("expected previous image to be unchanged")
+	} else if fis, _ := ioutil.ReadDir(dir); len(fis) != 1 {
+		t.Fatalf("unexpected file count: %d", len(fis))
+	}
+}
+
+// Ensure unknown formats & compression levels return an error.
+func TestParseImageFormat_ErrInvalid(t *testing.T) {
+	if _, err := boxer.ParseImageFormat("heic"); err == nil || err.Error() != `invalid image format: "heic"` {
+		t.Fatal(err)
+	} else if _, err := boxer.ParsePNGCompression("max"); err == nil || err.Error() != `invalid png compression: "max"` {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,119 @@
+package boxer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// IssueFetcher returns the title of the issue currently being worked on.
+// Returns a blank title if there is no such issue.
+type IssueFetcher func() (string, error)
+
+// GitHubURL is the base URL of the GitHub API.
+const GitHubURL = "https://api.github.com"
+
+// DefaultGitHubIssueQuery finds open issues assigned to the token's user that
+// are labeled as in progress.
+const DefaultGitHubIssueQuery = `is:open is:issue assignee:@me label:"in progress"`
+
+// NewGitHubIssueFetcher returns a fetcher for the most recently updated
+// GitHub issue matching the search query. If baseURL is blank then GitHubURL
+// is used. If query is blank then DefaultGitHubIssueQuery is used.
+func NewGitHubIssueFetcher(client *http.Client, baseURL, token, query string) IssueFetcher {
+	if query == "" {
+		query = DefaultGitHubIssueQuery
+	}
+
+	return func() (string, error) {
+		v := url.Values{"q": {query}, "sort": {"updated"}, "order": {"desc"}, "per_page": {"1"}}
+		req, err := newJSONRequest("GET", urlOrDefault(baseURL, GitHubURL)+"/search/issues?"+v.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		var result struct {
+			Items []struct {
+				Title string `json:"title"`
+			} `json:"items"`
+		}
+		if err := doJSONRequest(client, req, &result); err != nil {
+			return "", err
+		} else if len(result.Items) == 0 {
+			return "", nil
+		}
+		return result.Items[0].Title, nil
+	}
+}
+
+// DefaultJiraIssueQuery finds issues assigned to the current user that are
+// in progress.
+const DefaultJiraIssueQuery = `assignee = currentUser() AND statusCategory = "In Progress" ORDER BY updated DESC`
+
+// NewJiraIssueFetcher returns a fetcher for the first Jira issue matching the
+// JQL query on the site at baseURL, such as "https://example.atlassian.net".
+// Requests are authenticated with the user's email & API token. If query is
+// blank then DefaultJiraIssueQuery is used.
+func NewJiraIssueFetcher(client *http.Client, baseURL, user, token, query string) (IssueFetcher, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("jira url required")
+	} else if query == "" {
+		query = DefaultJiraIssueQuery
+	}
+
+	return func() (string, error) {
+		v := url.Values{"jql": {query}, "fields": {"summary"}, "maxResults": {"1"}}
+		req, err := newJSONRequest("GET", urlOrDefault(baseURL, "")+"/rest/api/2/search?"+v.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.SetBasicAuth(user, token)
+
+		var result struct {
+			Issues []struct {
+				Fields struct {
+					Summary string `json:"summary"`
+				} `json:"fields"`
+			} `json:"issues"`
+		}
+		if err := doJSONRequest(client, req, &result); err != nil {
+			return "", err
+		} else if len(result.Issues) == 0 {
+			return "", nil
+		}
+		return result.Issues[0].Fields.Summary, nil
+	}, nil
+}
+
+// NewIssueLabelHandler returns a handler that sets the label to the title of
+// the current issue at the start of every interval. If there is no current
+// issue then a label set by the handler is cleared but a label set by the
+// user is kept.
+func NewIssueLabelHandler(fetch IssueFetcher, label func() string, setLabel func(string)) IntervalHandler {
+	var mu sync.Mutex
+	var prev string
+
+	return func(ctx *Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		title, err := fetch()
+		if err != nil {
+			return fmt.Errorf("fetch issue: %s", err)
+		}
+
+		switch {
+		case title != "":
+			setLabel(title)
+		case prev != "" && label() == prev:
+			setLabel("")
+		}
+		prev = title
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package boxer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the GitHub fetcher searches for the in progress issue.
+func TestGitHubIssueFetcher(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/issues" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		} else if q := r.URL.Query().Get("q"); q != boxer.DefaultGitHubIssueQuery {
+			t.Errorf("unexpected query: %s", q)
+		} else if auth := r.Header.Get("Authorization"); auth != "Bearer TOKEN" {
+			t.Errorf("unexpected auth: %s", auth)
+		}
+		w.Write([]byte(`{"total_count":1,"items":[{"number":12,"title":"Fix login redirect"}]}`))
+	}))
+	defer s.Close()
+
+	if title, err := boxer.NewGitHubIssueFetcher(nil, s.URL, "TOKEN", "")(); err != nil {
+		t.Fatal(err)
+	} else if title != "Fix login redirect" {
+		t.Fatalf("unexpected title: %q", title)
+	}
+}
+
+// Ensure the Jira fetcher returns the summary of the first issue.
+func TestJiraIssueFetcher(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me@example.com" || pass != "TOKEN" {
+			t.Errorf("unexpected auth: %s:%s", user, pass)
+		} else if jql := r.URL.Query().Get("jql"); jql != "project = APP" {
+			t.Errorf("unexpected jql: %s", jql)
+		}
+		w.Write([]byte(`{"issues":[{"key":"APP-7","fields":{"summary":"Add export"}}]}`))
+	}))
+	defer s.Close()
+
+	fetch, err := boxer.NewJiraIssueFetcher(nil, s.URL, "me@example.com", "TOKEN", "project = APP")
+	if err != nil {
+		t.Fatal(err)
+	} else if title, err := fetch(); err != nil {
+		t.Fatal(err)
+	} else if title != "Add export" {
+		t.Fatalf("unexpected title: %q", title)
+	}
+}
+
+// Ensure the handler only clears labels that it set.
+func TestIssueLabelHandler(t *testing.T) {
+	var title, label string
+	fetch := func() (string, error) { return title, nil }
+	h := boxer.NewIssueLabelHandler(fetch, func() string { return label }, func(s string) { label = s })
+
+	title = "Fix login redirect"
+	if err := h(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	} else if label != "Fix login redirect" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+
+	title = ""
+	if err := h(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	} else if label != "" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+
+	label = "code review"
+	if err := h(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	} else if label != "code review" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+}
@@ -0,0 +1,164 @@
+package boxer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Journal represents a persistent record of completed intervals.
+type Journal interface {
+	Record(r *IntervalRecord) error
+}
+
+// IntervalRecord represents a single completed interval of a command.
+type IntervalRecord struct {
+	Command string    `json:"command"`
+	Label   string    `json:"label,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Paused  bool      `json:"paused,omitempty"`
+	Skipped bool      `json:"skipped,omitempty"`
+}
+
+// Duration returns the length of the interval.
+func (r *IntervalRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// contains returns true if t is within the interval.
+func (r *IntervalRecord) contains(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
+
+// FileJournal is a journal that appends records as JSON lines to a file.
+type FileJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileJournal returns a new journal that writes to path.
+func NewFileJournal(path string) *FileJournal {
+	return &FileJournal{path: path}
+}
+
+// Path returns the path to the journal file.
+func (j *FileJournal) Path() string { return j.path }
+
+// Record appends r to the journal file.
+func (j *FileJournal) Record(r *IntervalRecord) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0777); err != nil {
+		return fmt.Errorf("mkdir: %s", err)
+	}
+
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Records returns all records in the journal file. Returns no records if
+// the file does not exist.
+func (j *FileJournal) Records() ([]*IntervalRecord, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return ReadIntervalRecords(f)
+}
+
+// ReadIntervalRecords reads all JSON line records from r.
+func ReadIntervalRecords(r io.Reader) ([]*IntervalRecord, error) {
+	var a []*IntervalRecord
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec IntervalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		a = append(a, &rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AnchorStore represents persistent storage for the ticker's anchor.
+type AnchorStore interface {
+	LoadAnchor() (time.Time, error)
+	SaveAnchor(anchor time.Time) error
+}
+
+// FileAnchorStore is an anchor store that saves the anchor to a file.
+type FileAnchorStore struct {
+	path string
+}
+
+// NewFileAnchorStore returns a new anchor store that saves to path.
+func NewFileAnchorStore(path string) *FileAnchorStore {
+	return &FileAnchorStore{path: path}
+}
+
+// Path returns the path to the anchor file.
+func (s *FileAnchorStore) Path() string { return s.path }
+
+// LoadAnchor reads the anchor from the file. Returns a zero time if the file
+// does not exist.
+func (s *FileAnchorStore) LoadAnchor() (time.Time, error) {
+	buf, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(buf)))
+}
+
+// SaveAnchor writes the anchor to the file. The file is removed if the anchor
+// is zero.
+func (s *FileAnchorStore) SaveAnchor(anchor time.Time) error {
+	if anchor.IsZero() {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0777); err != nil {
+		return fmt.Errorf("mkdir: %s", err)
+	}
+	return ioutil.WriteFile(s.path, []byte(anchor.Format(time.RFC3339Nano)+"\n"), 0666)
+}
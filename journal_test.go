@@ -0,0 +1,128 @@
+package boxer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// Ensure the ticker records completed intervals to the journal.
+func TestTicker_Tick_Journal(t *testing.T) {
+	ticker := boxer.NewTicker()
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var j TestJournal
+	ticker.Journal = &j
+	ticker.Commands = []boxer.Command{{
+		Name:     "wallpaper",
+		Step:     5 * time.Minute,
+		Interval: 15 * time.Minute,
+		Handler:  func(ctx *boxer.Context) error { return nil },
+	}}
+
+	// Pause during the first interval, skip during the second, and
+	// label the third.
+	ticker.Tick()
+	ticker.Pause()
+	clk.Add(5 * time.Minute)
+	ticker.Tick()
+	ticker.Resume()
+	clk.Add(10 * time.Minute)
+	ticker.Tick()
+	ticker.Skip()
+	clk.Add(15 * time.Minute)
+	ticker.Tick()
+	ticker.SetLabel("write design doc")
+	clk.Add(15 * time.Minute)
+	ticker.Tick()
+
+	if !reflect.DeepEqual(j, TestJournal{
+		{Command: "wallpaper", Start: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2000, time.January, 1, 0, 15, 0, 0, time.UTC), Paused: true},
+		{Command: "wallpaper", Start: time.Date(2000, time.January, 1, 0, 15, 0, 0, time.UTC), End: time.Date(2000, time.January, 1, 0, 30, 0, 0, time.UTC), Skipped: true},
+		{Command: "wallpaper", Start: time.Date(2000, time.January, 1, 0, 30, 0, 0, time.UTC), End: time.Date(2000, time.January, 1, 0, 45, 0, 0, time.UTC), Label: "write design doc"},
+	}) {
+		t.Fatalf("unexpected records: %#v", j)
+	}
+}
+
+// Ensure records can be written to and read from a journal file.
+func TestFileJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j := boxer.NewFileJournal(filepath.Join(dir, "journal.jsonl"))
+	records := []*boxer.IntervalRecord{
+		{Command: "foo", Start: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2000, time.January, 1, 0, 15, 0, 0, time.UTC)},
+		{Command: "foo", Start: time.Date(2000, time.January, 1, 0, 15, 0, 0, time.UTC), End: time.Date(2000, time.January, 1, 0, 30, 0, 0, time.UTC), Skipped: true},
+	}
+	for _, r := range records {
+		if err := j.Record(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(j.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if other, err := boxer.ReadIntervalRecords(f); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(records, other) {
+		t.Fatalf("unexpected records: %#v", other)
+	}
+}
+
+// Ensure the anchor is saved to a file and restored by a ticker.
+func TestFileAnchorStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2000, time.January, 1, 10, 7, 30, 500, time.UTC)
+	ticker := boxer.NewTicker()
+	ticker.Clock = clock.NewMock(now)
+	ticker.AnchorStore = boxer.NewFileAnchorStore(filepath.Join(dir, "anchor"))
+	if err := ticker.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	other := boxer.NewTicker()
+	other.AnchorStore = ticker.AnchorStore
+	if err := other.RestoreAnchor(); err != nil {
+		t.Fatal(err)
+	} else if anchor := other.Anchor(); !anchor.Equal(now) {
+		t.Fatalf("unexpected anchor: %s", anchor)
+	}
+
+	// Clearing the anchor removes the file.
+	if err := ticker.SetAnchor(time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if anchor, err := ticker.AnchorStore.LoadAnchor(); err != nil {
+		t.Fatal(err)
+	} else if !anchor.IsZero() {
+		t.Fatalf("unexpected anchor: %s", anchor)
+	}
+}
+
+// TestJournal is a journal that stores records in memory.
+type TestJournal []boxer.IntervalRecord
+
+func (j *TestJournal) Record(r *boxer.IntervalRecord) error {
+	*j = append(*j, *r)
+	return nil
+}
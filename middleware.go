@@ -0,0 +1,107 @@
+package boxer
+
+import (
+	"time"
+)
+
+// Invocation represents a single execution of one of a command's handlers.
+type Invocation struct {
+	Command  string
+	Step     int
+	Steps    int
+	Overtime int  // steps past the end of an interval held open, if any
+	Interval bool // true if executing the interval handler
+	Warning  bool // true if executing the warning handler
+	Progress bool // true if executing the progress handler
+}
+
+// HandlerFunc executes a handler for an invocation.
+type HandlerFunc func(inv *Invocation) error
+
+// Middleware wraps a HandlerFunc to add behavior around handler execution.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain returns a middleware that applies mws in order. The first
+// middleware is the outermost wrapper.
+func Chain(mws ...Middleware) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// WithRetry returns a middleware that retries a failed handler up to n
+// additional times. The wait between attempts starts at backoff and doubles
+// after every attempt. The last error is returned if all attempts fail.
+// Fatal errors are not retried.
+func WithRetry(n int, backoff time.Duration) Middleware {
+	return RetryPolicy{Attempts: n, Backoff: backoff}.middleware(time.Time{})
+}
+
+// RetryPolicy represents how a command's failed handlers are retried.
+type RetryPolicy struct {
+	// The number of times to retry after the first failure.
+	Attempts int
+
+	// The wait before the first retry. The wait doubles after every retry.
+	Backoff time.Duration
+}
+
+// middleware returns a middleware that retries using the policy. If deadline
+// is set then no retry is started after the deadline.
+func (p RetryPolicy) middleware(deadline time.Time) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			err := next(inv)
+			for i, d := 0, p.Backoff; err != nil && !IsFatal(err) && i < p.Attempts; i, d = i+1, d*2 {
+				if !deadline.IsZero() && time.Now().Add(d).After(deadline) {
+					break
+				}
+				time.Sleep(d)
+				err = next(inv)
+			}
+			return err
+		}
+	}
+}
+
+// WithTimeout returns a middleware that returns ErrHandlerTimeout if the
+// handler does not complete within d. The handler continues to run in the
+// background after the timeout.
+func WithTimeout(d time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			ch := make(chan error, 1)
+			go func() { ch <- next(inv) }()
+
+			select {
+			case err := <-ch:
+				return err
+			case <-time.After(d):
+				return ErrHandlerTimeout
+			}
+		}
+	}
+}
+
+// WithLogging returns a middleware that writes every invocation to logger.
+func WithLogging(logger Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(inv *Invocation) error {
+			start := time.Now()
+			err := next(inv)
+			logger.Log(&LogEntry{
+				Time:     start,
+				Command:  inv.Command,
+				Step:     inv.Step,
+				Steps:    inv.Steps,
+				Interval: inv.Interval,
+				Duration: time.Since(start),
+				Err:      err,
+			})
+			return err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package boxer_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure middleware is applied with the first middleware as the outermost wrapper.
+func TestChain(t *testing.T) {
+	var calls []string
+	mw := func(name string) boxer.Middleware {
+		return func(next boxer.HandlerFunc) boxer.HandlerFunc {
+			return func(inv *boxer.Invocation) error {
+				calls = append(calls, name)
+				return next(inv)
+			}
+		}
+	}
+
+	fn := boxer.Chain(mw("a"), mw("b"))(func(inv *boxer.Invocation) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	if err := fn(&boxer.Invocation{}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(calls, []string{"a", "b", "handler"}) {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+}
+
+// Ensure a failed handler is retried until it succeeds.
+func TestWithRetry(t *testing.T) {
+	var n int
+	fn := boxer.WithRetry(3, time.Millisecond)(func(inv *boxer.Invocation) error {
+		if n++; n < 3 {
+			return errors.New("marker")
+		}
+		return nil
+	})
+	if err := fn(&boxer.Invocation{}); err != nil {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+}
+
+// Ensure the last error is returned after all retries fail.
+func TestWithRetry_ErrExhausted(t *testing.T) {
+	var n int
+	fn := boxer.WithRetry(2, time.Millisecond)(func(inv *boxer.Invocation) error {
+		n++
+		return errors.New("marker")
+	})
+	if err := fn(&boxer.Invocation{}); err == nil || err.Error() != `marker` {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+}
+
+// Ensure a slow handler returns a timeout error.
+func TestWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	fn := boxer.WithTimeout(10 * time.Millisecond)(func(inv *boxer.Invocation) error {
+		<-release
+		return nil
+	})
+	if err := fn(&boxer.Invocation{}); err != boxer.ErrHandlerTimeout {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+//go:build cgo
+// +build cgo
+
+package boxer
+
+/*
+#cgo CFLAGS: -x objective-c -Wno-deprecated-declarations
+#cgo LDFLAGS: -framework AppKit -framework Foundation
+#include <stdlib.h>
+#import <AppKit/AppKit.h>
+
+// boxer_set_desktop_image sets the desktop image of every screen to path.
+static int boxer_set_desktop_image(const char *path, char **errmsg) {
+	@autoreleasepool {
+		NSURL *url = [NSURL fileURLWithPath:[NSString stringWithUTF8String:path]];
+		NSWorkspace *ws = [NSWorkspace sharedWorkspace];
+		for (NSScreen *screen in [NSScreen screens]) {
+			NSError *err = nil;
+			NSDictionary *opts = [ws desktopImageOptionsForScreen:screen];
+			if (![ws setDesktopImageURL:url forScreen:screen options:opts error:&err]) {
+				*errmsg = strdup([[err localizedDescription] UTF8String]);
+				return -1;
+			}
+		}
+		return 0;
+	}
+}
+
+// boxer_notify delivers a notification. The notification center is only
+// available to bundled apps so this fails when run as a bare binary.
+static int boxer_notify(const char *title, const char *subtitle, const char *message, const char *sound, char **errmsg) {
+	@autoreleasepool {
+		NSUserNotificationCenter *center = [NSUserNotificationCenter defaultUserNotificationCenter];
+		if (center == nil) {
+			*errmsg = strdup("notification center unavailable");
+			return -1;
+		}
+
+		NSUserNotification *n = [[NSUserNotification alloc] init];
+		n.title = [NSString stringWithUTF8String:title];
+		n.informativeText = [NSString stringWithUTF8String:message];
+		if (subtitle[0] != 0) {
+			n.subtitle = [NSString stringWithUTF8String:subtitle];
+		}
+		if (sound[0] != 0) {
+			n.soundName = [NSString stringWithUTF8String:sound];
+		}
+		[center deliverNotification:n];
+		[n release];
+		return 0;
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// NativeAvailable is true if boxer was built with the native macOS backend.
+const NativeAvailable = true
+
+// nativeSetWallpaper sets the desktop image of every screen with NSWorkspace.
+func nativeSetWallpaper(path string) error {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	var errmsg *C.char
+	if C.boxer_set_desktop_image(cpath, &errmsg) != 0 {
+		defer C.free(unsafe.Pointer(errmsg))
+		return fmt.Errorf("set desktop image: %s", C.GoString(errmsg))
+	}
+	return nil
+}
+
+// nativeNotify delivers n with NSUserNotificationCenter.
+func nativeNotify(n *Notification) error {
+	title, subtitle := C.CString(n.Title), C.CString(n.Subtitle)
+	message, sound := C.CString(n.Message), C.CString(n.Sound)
+	defer func() {
+		C.free(unsafe.Pointer(title))
+		C.free(unsafe.Pointer(subtitle))
+		C.free(unsafe.Pointer(message))
+		C.free(unsafe.Pointer(sound))
+	}()
+
+	var errmsg *C.char
+	if C.boxer_notify(title, subtitle, message, sound, &errmsg) != 0 {
+		defer C.free(unsafe.Pointer(errmsg))
+		return fmt.Errorf("deliver notification: %s", C.GoString(errmsg))
+	}
+	return nil
+}
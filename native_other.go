@@ -0,0 +1,14 @@
+//go:build !darwin || !cgo
+// +build !darwin !cgo
+
+package boxer
+
+// NativeAvailable is true if boxer was built with the native macOS backend.
+// The backend requires cgo on macOS.
+const NativeAvailable = false
+
+// nativeSetWallpaper always returns ErrNativeUnavailable.
+func nativeSetWallpaper(path string) error { return ErrNativeUnavailable }
+
+// nativeNotify always returns ErrNativeUnavailable.
+func nativeNotify(n *Notification) error { return ErrNativeUnavailable }
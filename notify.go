@@ -0,0 +1,420 @@
+package boxer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// OSAScriptPath is the path to the "osascript" binary.
+const OSAScriptPath = `/usr/bin/osascript`
+
+// TerminalNotifierPath is the name of the "terminal-notifier" binary. It is
+// looked up in the PATH since it is usually installed with Homebrew.
+const TerminalNotifierPath = `terminal-notifier`
+
+// NotifySendPath is the path to the "notify-send" binary.
+const NotifySendPath = `/usr/bin/notify-send`
+
+// DefaultNotificationTitle is the title used if a notification has none.
+const DefaultNotificationTitle = "Boxer"
+
+// Notification represents a message displayed to the user.
+type Notification struct {
+	Title    string
+	Subtitle string
+	Message  string
+	Sound    string // name of the sound played with the notification, if any
+}
+
+// NotificationAction represents a button on a notification.
+type NotificationAction struct {
+	Label string       // button text, such as "Extend 5m"
+	Run   func() error // called when the button is clicked
+}
+
+// Notifier represents an object that displays notifications.
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+// ActionNotifier represents a notifier that can display buttons.
+type ActionNotifier interface {
+	// NotifyAction displays n with a button for each label & blocks until the
+	// user responds. Returns the clicked label or blank if none was clicked.
+	NotifyAction(n *Notification, labels []string) (string, error)
+}
+
+// NewActionNotifier returns a notifier that displays actions with notifier.
+// Each notification waits for a response in the background & runs the
+// clicked action. Errors from the action are returned by the next call to
+// Notify.
+func NewActionNotifier(notifier ActionNotifier, actions []NotificationAction) Notifier {
+	return &actionNotifier{notifier: notifier, actions: actions}
+}
+
+// actionNotifier displays notifications with actions.
+type actionNotifier struct {
+	mu       sync.Mutex
+	notifier ActionNotifier
+	actions  []NotificationAction
+	lastErr  error
+}
+
+// Notify displays n in the background & returns the error from a previous
+// action, if any.
+func (x *actionNotifier) Notify(n *Notification) error {
+	other := *n
+	go func() {
+		if err := x.run(&other); err != nil {
+			x.mu.Lock()
+			x.lastErr = err
+			x.mu.Unlock()
+		}
+	}()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	err := x.lastErr
+	x.lastErr = nil
+	return err
+}
+
+// run displays n & runs the action clicked by the user.
+func (x *actionNotifier) run(n *Notification) error {
+	labels := make([]string, len(x.actions))
+	for i, a := range x.actions {
+		labels[i] = a.Label
+	}
+
+	label, err := x.notifier.NotifyAction(n, labels)
+	if err != nil {
+		return err
+	}
+	for _, a := range x.actions {
+		if a.Label == label {
+			if err := a.Run(); err != nil {
+				return fmt.Errorf("action %q: %s", label, err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// NewNotifier returns the notifier with the given name. The names are
+// "osascript", "native", "terminal-notifier", "notify-send", and "none". If
+// name is blank then the default notifier for the platform is used.
+func NewNotifier(name string, exec CommandExecutor) (Notifier, error) {
+	if name == "" {
+		name = DefaultNotifier
+	}
+
+	switch name {
+	case "osascript":
+		return &OSAScriptNotifier{Exec: exec}, nil
+	case "native":
+		fallback, err := NewNotifier(DefaultNotifier, exec)
+		if err != nil {
+			return nil, err
+		}
+		return &NativeNotifier{Fallback: fallback}, nil
+	case "terminal-notifier":
+		return &TerminalNotifier{Exec: exec}, nil
+	case "notify-send":
+		return &NotifySendNotifier{Exec: exec}, nil
+	case "none":
+		return NopNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("invalid notifier: %q", name)
+	}
+}
+
+// OSAScriptNotifier displays notifications with AppleScript.
+type OSAScriptNotifier struct {
+	Exec CommandExecutor
+}
+
+// Notify displays n with "display notification".
+func (x *OSAScriptNotifier) Notify(n *Notification) error {
+	src, err := renderScript(displayNotificationScript, n)
+	if err != nil {
+		return err
+	}
+	if b, err := x.Exec(OSAScriptPath, nil, strings.NewReader(src)); err != nil {
+		return fmt.Errorf("exec display notification: %s", b)
+	}
+	return nil
+}
+
+var displayNotificationScript = newScriptTemplate("display notification",
+	`display notification {{str .Message}} with title {{str .Title}}`+
+		`{{with .Subtitle}} subtitle {{str .}}{{end}}`+
+		`{{with .Sound}} sound name {{str .}}{{end}}`,
+)
+
+// ErrNativeUnavailable is returned if boxer was built without the native
+// macOS backend.
+var ErrNativeUnavailable = errors.New("native backend unavailable")
+
+// NativeNotifier displays notifications with the macOS notification center
+// without spawning a process. If the native backend is unavailable or fails,
+// such as when boxer is not run from an app bundle, the fallback is used.
+type NativeNotifier struct {
+	Fallback Notifier
+}
+
+// Notify displays n natively or with the fallback notifier.
+func (x *NativeNotifier) Notify(n *Notification) error {
+	if err := nativeNotify(n); err == nil || x.Fallback == nil {
+		return err
+	}
+	return x.Fallback.Notify(n)
+}
+
+// TerminalNotifier displays notifications with terminal-notifier.
+type TerminalNotifier struct {
+	Exec CommandExecutor
+
+	// Time to wait for the user to click an action, if set. Otherwise
+	// actionable notifications wait until they are dismissed.
+	Timeout time.Duration
+}
+
+// Notify displays n with terminal-notifier.
+func (x *TerminalNotifier) Notify(n *Notification) error {
+	if b, err := x.Exec(TerminalNotifierPath, x.args(n), strings.NewReader("")); err != nil {
+		return fmt.Errorf("exec terminal-notifier: %s", b)
+	}
+	return nil
+}
+
+// NotifyAction displays n with its actions & waits for the user to respond.
+// Labels cannot contain commas since terminal-notifier separates them with
+// commas.
+func (x *TerminalNotifier) NotifyAction(n *Notification, labels []string) (string, error) {
+	for _, label := range labels {
+		if strings.Contains(label, ",") {
+			return "", fmt.Errorf("invalid action label: %q", label)
+		}
+	}
+
+	args := append(x.args(n), "-actions", strings.Join(labels, ","))
+	if x.Timeout > 0 {
+		args = append(args, "-timeout", strconv.Itoa(int(x.Timeout.Seconds())))
+	}
+
+	b, err := x.Exec(TerminalNotifierPath, args, strings.NewReader(""))
+	if err != nil {
+		return "", fmt.Errorf("exec terminal-notifier: %s", b)
+	}
+
+	// A single action is reported as "@ACTIONCLICKED" instead of its label.
+	// Other responses, such as "@TIMEOUT" or "@CLOSED", are not actions.
+	switch out := strings.TrimSpace(string(b)); {
+	case out == "@ACTIONCLICKED" && len(labels) == 1:
+		return labels[0], nil
+	case strings.HasPrefix(out, "@"):
+		return "", nil
+	default:
+		return out, nil
+	}
+}
+
+// args returns the terminal-notifier arguments for n.
+func (x *TerminalNotifier) args(n *Notification) []string {
+	args := []string{"-title", n.Title, "-message", n.Message}
+	if n.Subtitle != "" {
+		args = append(args, "-subtitle", n.Subtitle)
+	}
+	if n.Sound != "" {
+		args = append(args, "-sound", n.Sound)
+	}
+	return args
+}
+
+// NotifySendNotifier displays notifications with notify-send on Linux. The
+// subtitle is shown on the first line of the body since there is no subtitle.
+type NotifySendNotifier struct {
+	Exec CommandExecutor
+}
+
+// Notify displays n with notify-send.
+func (x *NotifySendNotifier) Notify(n *Notification) error {
+	body := n.Message
+	if n.Subtitle != "" {
+		body = n.Subtitle + "\n" + body
+	}
+
+	var args []string
+	if n.Sound != "" {
+		args = append(args, "--hint=string:sound-name:"+n.Sound)
+	}
+	args = append(args, n.Title, body)
+
+	if b, err := x.Exec(NotifySendPath, args, strings.NewReader("")); err != nil {
+		return fmt.Errorf("exec notify-send: %s", b)
+	}
+	return nil
+}
+
+// NopNotifier is a notifier that does nothing.
+type NopNotifier struct{}
+
+// Notify does nothing.
+func (NopNotifier) Notify(n *Notification) error { return nil }
+
+// DefaultAnnouncementTemplate is the message template used by announcements
+// if no template is specified. It displays the time & the current label.
+const DefaultAnnouncementTemplate = `{{.Time.Format "3:04pm"}}{{with .Label}} - {{.}}{{end}}`
+
+// DefaultSourceAnnouncementTemplate is the message template used by
+// announcements with a source if no template is specified.
+const DefaultSourceAnnouncementTemplate = `{{.Text}}`
+
+// NewAnnouncementHandler returns a handler for announcing the current time.
+// The title, subtitle & sound are copied from n. The message is rendered from
+// msgTemplate which has access to the fields of AnnouncementData. If source
+// is not nil then it is read at every interval into the Text field.
+//
+// If msgTemplate is blank, DefaultAnnouncementTemplate is used or, if there
+// is a source, DefaultSourceAnnouncementTemplate.
+func NewAnnouncementHandler(notifier Notifier, n Notification, msgTemplate string, source MessageSource) (IntervalHandler, error) {
+	if n.Title == "" {
+		n.Title = DefaultNotificationTitle
+	}
+	if msgTemplate == "" {
+		msgTemplate = DefaultAnnouncementTemplate
+		if source != nil {
+			msgTemplate = DefaultSourceAnnouncementTemplate
+		}
+	}
+
+	tmpl, err := template.New("announcement").Parse(msgTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %s", err)
+	}
+
+	return func(ctx *Context) error {
+		data := &AnnouncementData{
+			Step:          ctx.Step,
+			Total:         ctx.Total,
+			Remaining:     ctx.Remaining.Round(time.Second),
+			IntervalIndex: ctx.IntervalIndex,
+			Label:         ctx.Label,
+			Time:          ctx.Now,
+		}
+
+		// Refresh the text from the source.
+		if source != nil {
+			text, err := source()
+			if err != nil {
+				return fmt.Errorf("read source: %s", err)
+			}
+			data.Text = text
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("execute template: %s", err)
+		}
+
+		n := n
+		n.Message = buf.String()
+		return notifier.Notify(&n)
+	}, nil
+}
+
+// AnnouncementData represents the data available to announcement templates.
+type AnnouncementData struct {
+	Step          int           // current step index
+	Total         int           // total steps per interval
+	Remaining     time.Duration // time remaining in the interval, to the second
+	IntervalIndex int           // index of the current interval within the day
+	Label         string        // current label, if any
+	Time          time.Time     // time of the announcement
+	Text          string        // text read from the source, if any
+}
+
+// MessageSource returns the text of a message.
+type MessageSource func() (string, error)
+
+// NewMessageSource returns a source that reads text from an HTTP URL, the
+// output of a shell command, or a file. Sources beginning with "http://" or
+// "https://" are fetched, sources beginning with "!" are executed with the
+// shell, and all other sources are read as a file path. Surrounding
+// whitespace is trimmed from the text.
+func NewMessageSource(source string, exec CommandExecutor) (MessageSource, error) {
+	switch {
+	case source == "":
+		return nil, fmt.Errorf("source required")
+
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		return func() (string, error) {
+			resp, err := http.Get(source)
+			if err != nil {
+				return "", err
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != http.StatusOK {
+				return "", fmt.Errorf("unexpected status: %s", resp.Status)
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(b)), nil
+		}, nil
+
+	case strings.HasPrefix(source, "!"):
+		command := strings.TrimSpace(strings.TrimPrefix(source, "!"))
+		if command == "" {
+			return nil, fmt.Errorf("source command required")
+		}
+		return func() (string, error) {
+			b, err := exec(ShellPath, []string{"-c", command}, strings.NewReader(""))
+			if err != nil {
+				return "", fmt.Errorf("exec: %s", b)
+			}
+			return strings.TrimSpace(string(b)), nil
+		}, nil
+
+	default:
+		return func() (string, error) {
+			b, err := ioutil.ReadFile(source)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(b)), nil
+		}, nil
+	}
+}
+
+// NewWarningHandler returns a handler that displays a notification with the
+// minutes remaining in the interval, such as "5 minutes left". The title,
+// subtitle & sound are copied from n.
+func NewWarningHandler(notifier Notifier, n Notification) WarningHandler {
+	if n.Title == "" {
+		n.Title = DefaultNotificationTitle
+	}
+
+	return func(remaining time.Duration) error {
+		n := n
+
+		// Round up so a late tick does not under-report the time left.
+		minutes := int((remaining + time.Minute - 1) / time.Minute)
+		n.Message = fmt.Sprintf("%d minutes left", minutes)
+		if minutes == 1 {
+			n.Message = "1 minute left"
+		}
+		return notifier.Notify(&n)
+	}
+}
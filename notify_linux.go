@@ -0,0 +1,4 @@
+package boxer
+
+// DefaultNotifier is the name of the notifier used if none is configured.
+const DefaultNotifier = "notify-send"
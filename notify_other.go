@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package boxer
+
+// DefaultNotifier is the name of the notifier used if none is configured.
+// Notifications are skipped since there is no known notifier.
+const DefaultNotifier = "none"
@@ -0,0 +1,313 @@
+package boxer_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the osascript notifier displays a notification with AppleScript.
+func TestOSAScriptNotifier_Notify(t *testing.T) {
+	var src string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.OSAScriptPath {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		b, _ := ioutil.ReadAll(stdin)
+		src = string(b)
+		return nil, nil
+	}
+
+	n := &boxer.Notification{Title: "Boxer", Subtitle: "Time check", Message: "9:00am", Sound: "Glass"}
+	if err := (&boxer.OSAScriptNotifier{Exec: exec}).Notify(n); err != nil {
+		t.Fatal(err)
+	} else if exp := `display notification "9:00am" with title "Boxer" subtitle "Time check" sound name "Glass"`; src != exp {
+		t.Fatalf("unexpected script: %s", src)
+	}
+}
+
+// Ensure quotes & line breaks in the notification are escaped in the script.
+func TestOSAScriptNotifier_Notify_Escape(t *testing.T) {
+	var src string
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		b, _ := ioutil.ReadAll(stdin)
+		src = string(b)
+		return nil, nil
+	}
+
+	n := &boxer.Notification{Title: "Boxer", Message: "say \"hi\"\nnow"}
+	if err := (&boxer.OSAScriptNotifier{Exec: exec}).Notify(n); err != nil {
+		t.Fatal(err)
+	} else if exp := `display notification "say \"hi\"\nnow" with title "Boxer"`; src != exp {
+		t.Fatalf("unexpected script: %s", src)
+	}
+}
+
+// Ensure terminal-notifier is passed the notification as arguments.
+func TestTerminalNotifier_Notify(t *testing.T) {
+	var args []string
+	exec := func(name string, a []string, stdin io.Reader) ([]byte, error) {
+		args = a
+		return nil, nil
+	}
+
+	n := &boxer.Notification{Title: "Boxer", Message: "9:00am", Sound: "Glass"}
+	if err := (&boxer.TerminalNotifier{Exec: exec}).Notify(n); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"-title", "Boxer", "-message", "9:00am", "-sound", "Glass"}; !reflect.DeepEqual(args, exp) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+// Ensure terminal-notifier displays actions & returns the clicked label.
+func TestTerminalNotifier_NotifyAction(t *testing.T) {
+	var args []string
+	exec := func(name string, a []string, stdin io.Reader) ([]byte, error) {
+		args = a
+		return []byte("Start break\n"), nil
+	}
+
+	n := &boxer.Notification{Title: "Boxer", Message: "9:00am"}
+	if label, err := (&boxer.TerminalNotifier{Exec: exec, Timeout: 30 * time.Minute}).NotifyAction(n, []string{"Extend 5m", "Start break"}); err != nil {
+		t.Fatal(err)
+	} else if label != "Start break" {
+		t.Fatalf("unexpected label: %q", label)
+	} else if exp := []string{"-title", "Boxer", "-message", "9:00am", "-actions", "Extend 5m,Start break", "-timeout", "1800"}; !reflect.DeepEqual(args, exp) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+// Ensure a dismissed notification returns no action.
+func TestTerminalNotifier_NotifyAction_Closed(t *testing.T) {
+	exec := func(name string, a []string, stdin io.Reader) ([]byte, error) { return []byte("@CLOSED"), nil }
+	if label, err := (&boxer.TerminalNotifier{Exec: exec}).NotifyAction(&boxer.Notification{}, []string{"Extend 5m"}); err != nil {
+		t.Fatal(err)
+	} else if label != "" {
+		t.Fatalf("unexpected label: %q", label)
+	}
+}
+
+// Ensure notify-send shows the subtitle in the body & returns its output on failure.
+func TestNotifySendNotifier_Notify(t *testing.T) {
+	var args []string
+	exec := func(name string, a []string, stdin io.Reader) ([]byte, error) {
+		args = a
+		return []byte("no server"), errors.New("exit status 1")
+	}
+
+	n := &boxer.Notification{Title: "Boxer", Subtitle: "Time check", Message: "9:00am"}
+	if err := (&boxer.NotifySendNotifier{Exec: exec}).Notify(n); err == nil || err.Error() != `exec notify-send: no server` {
+		t.Fatal(err)
+	} else if exp := []string{"Boxer", "Time check\n9:00am"}; !reflect.DeepEqual(args, exp) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+// Ensure an unknown notifier returns an error.
+func TestNewNotifier_ErrInvalid(t *testing.T) {
+	if _, err := boxer.NewNotifier("growl", nil); err == nil || err.Error() != `invalid notifier: "growl"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the native notifier uses the fallback when the native backend is unavailable.
+func TestNativeNotifier_Fallback(t *testing.T) {
+	if boxer.NativeAvailable {
+		t.Skip("native backend available")
+	}
+
+	var got boxer.Notification
+	n := &boxer.NativeNotifier{Fallback: NotifierFunc(func(n *boxer.Notification) error { got = *n; return nil })}
+	if err := n.Notify(&boxer.Notification{Title: "Boxer", Message: "9:00am"}); err != nil {
+		t.Fatal(err)
+	} else if got.Title != "Boxer" || got.Message != "9:00am" {
+		t.Fatalf("unexpected notification: %#v", got)
+	}
+}
+
+// Ensure the warning handler notifies with the minutes left & default title.
+func TestNewWarningHandler(t *testing.T) {
+	var got boxer.Notification
+	notifier := NotifierFunc(func(n *boxer.Notification) error { got = *n; return nil })
+
+	h := boxer.NewWarningHandler(notifier, boxer.Notification{Sound: "Glass"})
+	if err := h(4*time.Minute + 10*time.Second); err != nil {
+		t.Fatal(err)
+	} else if exp := (boxer.Notification{Title: "Boxer", Message: "5 minutes left", Sound: "Glass"}); got != exp {
+		t.Fatalf("unexpected notification: %#v", got)
+	}
+}
+
+// Ensure the announcement handler displays the time & label by default.
+func TestNewAnnouncementHandler(t *testing.T) {
+	var got boxer.Notification
+	notifier := NotifierFunc(func(n *boxer.Notification) error { got = *n; return nil })
+
+	h, err := boxer.NewAnnouncementHandler(notifier, boxer.Notification{}, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Now: time.Date(2000, time.January, 1, 14, 30, 0, 0, time.UTC), Label: "write report"}); err != nil {
+		t.Fatal(err)
+	} else if exp := (boxer.Notification{Title: "Boxer", Message: "2:30pm - write report"}); got != exp {
+		t.Fatalf("unexpected notification: %#v", got)
+	}
+}
+
+// Ensure the announcement handler renders the message from a template.
+func TestNewAnnouncementHandler_Template(t *testing.T) {
+	var got boxer.Notification
+	notifier := NotifierFunc(func(n *boxer.Notification) error { got = *n; return nil })
+
+	h, err := boxer.NewAnnouncementHandler(notifier, boxer.Notification{}, "Box {{.IntervalIndex}}: {{.Remaining}} left, step {{.Step}}/{{.Total}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := h(&boxer.Context{Step: 1, Total: 6, IntervalIndex: 29, Remaining: 25*time.Minute + 400*time.Millisecond}); err != nil {
+		t.Fatal(err)
+	} else if got.Message != "Box 29: 25m0s left, step 1/6" {
+		t.Fatalf("unexpected message: %q", got.Message)
+	}
+}
+
+// Ensure an invalid announcement template returns an error.
+func TestNewAnnouncementHandler_ErrTemplate(t *testing.T) {
+	if _, err := boxer.NewAnnouncementHandler(boxer.NopNotifier{}, boxer.Notification{}, "{{.Remaining", nil); err == nil || !strings.HasPrefix(err.Error(), "parse template: ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the announcement handler reads the message from its source at every interval.
+func TestNewAnnouncementHandler_Source(t *testing.T) {
+	var got boxer.Notification
+	notifier := NotifierFunc(func(n *boxer.Notification) error { got = *n; return nil })
+
+	var i int
+	source := func() (string, error) { i++; return fmt.Sprintf("quote #%d", i), nil }
+	h, err := boxer.NewAnnouncementHandler(notifier, boxer.Notification{}, "", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"quote #1", "quote #2"} {
+		if err := h(&boxer.Context{}); err != nil {
+			t.Fatal(err)
+		} else if got.Message != exp {
+			t.Fatalf("unexpected message: %q", got.Message)
+		}
+	}
+}
+
+// Ensure a message source reads text from a file.
+func TestNewMessageSource_File(t *testing.T) {
+	f, err := ioutil.TempFile("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.WriteString("stay focused\n"); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if source, err := boxer.NewMessageSource(f.Name(), nil); err != nil {
+		t.Fatal(err)
+	} else if text, err := source(); err != nil {
+		t.Fatal(err)
+	} else if text != "stay focused" {
+		t.Fatalf("unexpected text: %q", text)
+	}
+}
+
+// Ensure a message source fetches text from a URL.
+func TestNewMessageSource_URL(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(" drink water "))
+	}))
+	defer s.Close()
+
+	if source, err := boxer.NewMessageSource(s.URL, nil); err != nil {
+		t.Fatal(err)
+	} else if text, err := source(); err != nil {
+		t.Fatal(err)
+	} else if text != "drink water" {
+		t.Fatalf("unexpected text: %q", text)
+	}
+}
+
+// Ensure a message source executes a shell command prefixed with "!".
+func TestNewMessageSource_Command(t *testing.T) {
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.ShellPath || !reflect.DeepEqual(args, []string{"-c", "fortune -s"}) {
+			t.Fatalf("unexpected command: %s %#v", name, args)
+		}
+		return []byte("take a walk\n"), nil
+	}
+
+	if source, err := boxer.NewMessageSource("!fortune -s", exec); err != nil {
+		t.Fatal(err)
+	} else if text, err := source(); err != nil {
+		t.Fatal(err)
+	} else if text != "take a walk" {
+		t.Fatalf("unexpected text: %q", text)
+	}
+}
+
+// Ensure a message source requires a command after the "!" prefix.
+func TestNewMessageSource_ErrCommandRequired(t *testing.T) {
+	if _, err := boxer.NewMessageSource("! ", nil); err == nil || err.Error() != `source command required` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the action notifier runs the clicked action in the background & returns its error on the next call.
+func TestActionNotifier(t *testing.T) {
+	clicked := make(chan struct{}, 1)
+	notifier := boxer.NewActionNotifier(ActionNotifierFunc(func(n *boxer.Notification, labels []string) (string, error) {
+		return labels[1], nil
+	}), []boxer.NotificationAction{
+		{Label: "Extend 5m", Run: func() error { t.Fatal("unexpected action"); return nil }},
+		{Label: "Skip", Run: func() error {
+			select {
+			case clicked <- struct{}{}:
+			default:
+			}
+			return errors.New("not running")
+		}},
+	})
+
+	if err := notifier.Notify(&boxer.Notification{}); err != nil {
+		t.Fatal(err)
+	}
+	<-clicked
+
+	for {
+		if err := notifier.Notify(&boxer.Notification{}); err == nil {
+			time.Sleep(time.Millisecond)
+			continue
+		} else if err.Error() != `action "Skip": not running` {
+			t.Fatal(err)
+		}
+		break
+	}
+}
+
+// NotifierFunc is a function that implements boxer.Notifier.
+type NotifierFunc func(n *boxer.Notification) error
+
+func (fn NotifierFunc) Notify(n *boxer.Notification) error { return fn(n) }
+
+// ActionNotifierFunc is a function that implements boxer.ActionNotifier.
+type ActionNotifierFunc func(n *boxer.Notification, labels []string) (string, error)
+
+func (fn ActionNotifierFunc) NotifyAction(n *boxer.Notification, labels []string) (string, error) {
+	return fn(n, labels)
+}
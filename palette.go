@@ -0,0 +1,46 @@
+package boxer
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed etc/palettes/*.json
+var palettes embed.FS
+
+// Palette represents a named set of wallpaper colors. If set, the dark
+// colors are used while the system is in dark mode.
+type Palette struct {
+	Foregrounds []string `json:"foregrounds"`
+	Backgrounds []string `json:"backgrounds"`
+	Dark        *Palette `json:"dark,omitempty"`
+}
+
+// PaletteNames returns the names of the bundled palettes.
+func PaletteNames() []string {
+	entries, _ := palettes.ReadDir("etc/palettes")
+
+	a := make([]string, 0, len(entries))
+	for _, e := range entries {
+		a = append(a, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(a)
+	return a
+}
+
+// LoadPalette returns the bundled palette with the given name, such as "nord".
+func LoadPalette(name string) (*Palette, error) {
+	buf, err := palettes.ReadFile("etc/palettes/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown palette: %q", name)
+	}
+
+	var p Palette
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return nil, fmt.Errorf("palette %s: %s", name, err)
+	}
+	return &p, nil
+}
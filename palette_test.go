@@ -0,0 +1,42 @@
+package boxer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure every bundled palette can be loaded & has valid colors.
+func TestLoadPalette(t *testing.T) {
+	names := boxer.PaletteNames()
+	if !reflect.DeepEqual(names, []string{"default", "gruvbox", "high-contrast", "muted", "nord", "solarized"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	for _, name := range names {
+		p, err := boxer.LoadPalette(name)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(p.Foregrounds) == 0 || len(p.Backgrounds) == 0 {
+			t.Fatalf("%s: colors required", name)
+		}
+
+		colors := append(append([]string{}, p.Foregrounds...), p.Backgrounds...)
+		if p.Dark != nil {
+			colors = append(append(colors, p.Dark.Foregrounds...), p.Dark.Backgrounds...)
+		}
+		for _, s := range colors {
+			if _, err := boxer.ParseColor(s); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+	}
+}
+
+// Ensure loading an unknown palette returns an error.
+func TestLoadPalette_ErrUnknown(t *testing.T) {
+	if _, err := boxer.LoadPalette("no_such_palette"); err == nil || err.Error() != `unknown palette: "no_such_palette"` {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,115 @@
+package boxer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]HandlerFactory)
+)
+
+// HandlerFactory creates a command's handlers from its configuration.
+// The name, step, and interval of the returned command are set by NewCommand.
+type HandlerFactory func(c *HandlerConfig) (Command, error)
+
+// HandlerConfig represents the configuration passed to a HandlerFactory.
+type HandlerConfig struct {
+	// The function used to execute OS commands.
+	Executor CommandExecutor
+
+	// The executor used for commands that need an environment, working
+	// directory, or timeout. If nil, Executor is used & those are ignored.
+	Exec Executor
+
+	// The desktop environment that handlers update.
+	Desktop Desktop
+
+	// The directory available for storing generated files.
+	WorkDir string
+
+	// The step & interval the command will run on.
+	Step     time.Duration
+	Interval time.Duration
+
+	// The command's timeout, if any.
+	Timeout time.Duration
+
+	// Returns the label for the current work, if any.
+	Label func() string
+
+	// Sets the label for the current work on the command's ticker.
+	SetLabel func(label string)
+
+	// Sends a control command, such as "skip", to the command's ticker.
+	Control func(command string) error
+
+	// Returns the progress toward the goal of completed intervals.
+	// This is nil if no goal is configured.
+	Goal func(now time.Time) (*GoalProgress, error)
+
+	// Decodes the command's type-specific options into v.
+	// This is nil if the command has no options.
+	Decode func(v interface{}) error
+}
+
+// RequestExecutor returns Exec, or Executor if Exec is nil.
+func (c *HandlerConfig) RequestExecutor() Executor {
+	if c.Exec != nil {
+		return c.Exec
+	}
+	return c.Executor
+}
+
+// RegisterHandlerFactory makes a handler factory available by name.
+// Panics if fn is nil or if a factory is registered twice under the same name.
+func RegisterHandlerFactory(name string, fn HandlerFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if fn == nil {
+		panic("boxer: register handler factory is nil")
+	} else if _, ok := factories[name]; ok {
+		panic("boxer: register handler factory called twice for " + name)
+	}
+	factories[name] = fn
+}
+
+// HandlerFactoryNames returns a sorted list of registered factory names.
+func HandlerFactoryNames() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	a := make([]string, 0, len(factories))
+	for name := range factories {
+		a = append(a, name)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// NewCommand creates a named command using the factory registered under typ.
+func NewCommand(typ, name string, c *HandlerConfig) (Command, error) {
+	factoriesMu.RLock()
+	fn := factories[typ]
+	factoriesMu.RUnlock()
+
+	if fn == nil {
+		return Command{}, fmt.Errorf("unknown command type: %q", typ)
+	}
+
+	// Ensure a decoder always exists so factories can decode unconditionally.
+	if c.Decode == nil {
+		c.Decode = func(v interface{}) error { return nil }
+	}
+
+	cmd, err := fn(c)
+	if err != nil {
+		return Command{}, err
+	}
+	cmd.Name, cmd.Step, cmd.Interval = name, c.Step, c.Interval
+	return cmd, nil
+}
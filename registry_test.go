@@ -0,0 +1,70 @@
+package boxer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+func init() {
+	boxer.RegisterHandlerFactory("test_registry", func(c *boxer.HandlerConfig) (boxer.Command, error) {
+		var options struct{ Fail bool }
+		if err := c.Decode(&options); err != nil {
+			return boxer.Command{}, err
+		} else if options.Fail {
+			return boxer.Command{}, errors.New("marker")
+		}
+		return boxer.Command{
+			Name:     "overwritten",
+			Interval: 1 * time.Second,
+			Handler:  func(ctx *boxer.Context) error { return nil },
+		}, nil
+	})
+}
+
+// Ensure a command can be created from a registered factory.
+func TestNewCommand(t *testing.T) {
+	cmd, err := boxer.NewCommand("test_registry", "foo", &boxer.HandlerConfig{
+		Step:     1 * time.Minute,
+		Interval: 10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if cmd.Name != "foo" || cmd.Step != 1*time.Minute || cmd.Interval != 10*time.Minute {
+		t.Fatalf("unexpected command: %#v", cmd)
+	} else if cmd.Handler == nil {
+		t.Fatal("expected handler")
+	}
+}
+
+// Ensure factory errors are returned from NewCommand.
+func TestNewCommand_ErrFactory(t *testing.T) {
+	_, err := boxer.NewCommand("test_registry", "foo", &boxer.HandlerConfig{
+		Decode: func(v interface{}) error {
+			v.(*struct{ Fail bool }).Fail = true
+			return nil
+		},
+	})
+	if err == nil || err.Error() != `marker` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure an error is returned for unregistered command types.
+func TestNewCommand_ErrUnknownType(t *testing.T) {
+	if _, err := boxer.NewCommand("no_such_type", "foo", &boxer.HandlerConfig{}); err == nil || err.Error() != `unknown command type: "no_such_type"` {
+		t.Fatal(err)
+	}
+}
+
+// Ensure registering a factory twice panics.
+func TestRegisterHandlerFactory_ErrDuplicate(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "boxer: register handler factory called twice for test_registry" {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	boxer.RegisterHandlerFactory("test_registry", func(c *boxer.HandlerConfig) (boxer.Command, error) { return boxer.Command{}, nil })
+}
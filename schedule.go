@@ -0,0 +1,88 @@
+package boxer
+
+import (
+	"time"
+)
+
+// Schedule represents a set of rules that determine when the ticker is active.
+type Schedule []ScheduleRule
+
+// Rule returns the first rule that is active at t.
+// Returns nil if no rules are active.
+func (s Schedule) Rule(t time.Time) *ScheduleRule {
+	for i := range s {
+		if s[i].Contains(t) {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+// ScheduleRule represents a window of time that is active on certain days
+// of the week. Start and End are offsets from midnight. If End is before
+// Start then the window wraps past midnight.
+type ScheduleRule struct {
+	Days  []time.Weekday
+	Start time.Duration
+	End   time.Duration
+
+	// If set, these override the step and interval of every command
+	// while the rule is active.
+	Step     time.Duration
+	Interval time.Duration
+}
+
+// Contains returns true if t falls within the rule's window.
+func (r *ScheduleRule) Contains(t time.Time) bool {
+	// Ensure the rule applies to the day of the week.
+	if !r.hasDay(t.Weekday()) {
+		return false
+	}
+
+	return (&TimeRange{Start: r.Start, End: r.End}).Contains(t)
+}
+
+// hasDay returns true if the rule applies to the given weekday.
+// A rule with no days applies to every day.
+func (r *ScheduleRule) hasDay(day time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, d := range r.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns a copy of cmd with the rule's step & interval overrides.
+func (r *ScheduleRule) apply(cmd Command) Command {
+	if r.Interval > 0 {
+		cmd.Interval = r.Interval
+	}
+	if r.Step > 0 {
+		cmd.Step = r.Step
+	}
+	return cmd
+}
+
+// TimeRange represents a window of time within a day. Start and End are
+// offsets from midnight. If End is before Start then the range wraps past
+// midnight.
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains returns true if the time of day of t falls within the range.
+func (r *TimeRange) Contains(t time.Time) bool {
+	// Determine the offset since midnight in t's location.
+	y, m, d := t.Date()
+	offset := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+
+	if r.End < r.Start {
+		return offset >= r.Start || offset < r.End
+	}
+	return offset >= r.Start && offset < r.End
+}
@@ -0,0 +1,113 @@
+package boxer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// Ensure a schedule rule only contains times on its days & within its window.
+func TestScheduleRule_Contains(t *testing.T) {
+	r := &boxer.ScheduleRule{
+		Days:  []time.Weekday{time.Monday, time.Tuesday},
+		Start: 9 * time.Hour,
+		End:   18 * time.Hour,
+	}
+
+	for i, tt := range []struct {
+		t   time.Time
+		exp bool
+	}{
+		{t: time.Date(2000, time.January, 3, 9, 0, 0, 0, time.UTC), exp: true},   // monday, start
+		{t: time.Date(2000, time.January, 4, 17, 59, 0, 0, time.UTC), exp: true}, // tuesday, before end
+		{t: time.Date(2000, time.January, 3, 8, 59, 0, 0, time.UTC), exp: false}, // monday, before start
+		{t: time.Date(2000, time.January, 3, 18, 0, 0, 0, time.UTC), exp: false}, // monday, end
+		{t: time.Date(2000, time.January, 5, 12, 0, 0, 0, time.UTC), exp: false}, // wednesday
+		{t: time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC), exp: false}, // saturday
+	} {
+		if v := r.Contains(tt.t); v != tt.exp {
+			t.Errorf("%d. %s: unexpected result: %v", i, tt.t, v)
+		}
+	}
+}
+
+// Ensure a schedule rule can wrap past midnight.
+func TestScheduleRule_Contains_Overnight(t *testing.T) {
+	r := &boxer.ScheduleRule{Start: 22 * time.Hour, End: 2 * time.Hour}
+	if !r.Contains(time.Date(2000, time.January, 1, 23, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected 11pm to be contained")
+	} else if !r.Contains(time.Date(2000, time.January, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected 1am to be contained")
+	} else if r.Contains(time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected noon to not be contained")
+	}
+}
+
+// Ensure the ticker is silent outside the schedule and uses the rule's overrides inside it.
+func TestTicker_Tick_Schedule(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.Schedule = boxer.Schedule{{
+		Days:  []time.Weekday{time.Monday},
+		Start: 9 * time.Hour,
+		End:   10 * time.Hour,
+		Step:  5 * time.Minute,
+	}}
+
+	// Mock the current time, starting on a monday.
+	now := time.Date(2000, time.January, 3, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var steps []int
+	ticker.Commands = []boxer.Command{{
+		Step:     1 * time.Minute,
+		Interval: 15 * time.Minute,
+		Handler:  func(ctx *boxer.Context) error { steps = append(steps, ctx.Step); return nil },
+	}}
+
+	// Move forward a minute at a time for 3h.
+	start := now
+	for i := time.Duration(0); i < 3*time.Hour; i += time.Minute {
+		clk.Set(start.Add(i))
+		ticker.Tick()
+	}
+
+	// Only 5m steps between 9am & 10am should have fired.
+	if len(steps) != 12 {
+		t.Fatalf("unexpected step count: %d", len(steps))
+	} else if steps[0] != 0 || steps[1] != 1 || steps[3] != 0 {
+		t.Fatalf("unexpected steps: %v", steps)
+	} else if s := ticker.Status(); s.Active {
+		t.Fatal("expected inactive status")
+	}
+}
+
+// Ensure the ticker is silent outside of its active hours.
+func TestTicker_Tick_ActiveHours(t *testing.T) {
+	ticker := boxer.NewTicker()
+	ticker.ActiveHours = &boxer.TimeRange{Start: 9 * time.Hour, End: 17 * time.Hour}
+
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	ticker.Clock = clk
+
+	var n int
+	ticker.Commands = []boxer.Command{{
+		Interval:        1 * time.Hour,
+		IntervalHandler: func(ctx *boxer.Context) error { n++; return nil },
+	}}
+
+	// Move forward a minute at a time for a day.
+	start := now
+	for i := time.Duration(0); i < 24*time.Hour; i += time.Minute {
+		clk.Set(start.Add(i))
+		ticker.Tick()
+	}
+
+	// Only the intervals from 9am through 4pm should have fired.
+	if n != 8 {
+		t.Fatalf("unexpected interval count: %d", n)
+	}
+}
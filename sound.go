@@ -0,0 +1,72 @@
+package boxer
+
+import (
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed etc/sounds/*.wav
+var sounds embed.FS
+
+// SoundNames returns the names of the bundled sounds.
+func SoundNames() []string {
+	entries, _ := sounds.ReadDir("etc/sounds")
+
+	a := make([]string, 0, len(entries))
+	for _, e := range entries {
+		a = append(a, strings.TrimSuffix(e.Name(), ".wav"))
+	}
+	sort.Strings(a)
+	return a
+}
+
+// SoundPath returns the path to a sound file. If name refers to a bundled
+// sound, such as "chime", then the sound is written to dir and its path is
+// returned. Otherwise name is returned as a path to a sound file.
+func SoundPath(dir, name string) (string, error) {
+	buf, err := sounds.ReadFile("etc/sounds/" + name + ".wav")
+	if err != nil {
+		return name, nil
+	}
+
+	// Write bundled sound to the directory.
+	path := filepath.Join(dir, name+".wav")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", fmt.Errorf("mkdir: %s", err)
+	} else if err := ioutil.WriteFile(path, buf, 0666); err != nil {
+		return "", fmt.Errorf("write sound: %s", err)
+	}
+	return path, nil
+}
+
+// NewSoundHandler returns a handler that plays the sound file at path.
+// The volume is from 0 to 1.
+func NewSoundHandler(exec CommandExecutor, path string, volume float64) IntervalHandler {
+	return func(ctx *Context) error {
+		args, err := soundPlayerArgs(path, volume)
+		if err != nil {
+			return err
+		}
+		if b, err := exec(SoundPlayerPath, args, strings.NewReader("")); err != nil {
+			return fmt.Errorf("exec play sound: %s", b)
+		}
+		return nil
+	}
+}
+
+// NewStepSoundHandler returns a handler that plays the sound file at path on
+// every step except the first step of the interval.
+func NewStepSoundHandler(exec CommandExecutor, path string, volume float64) Handler {
+	h := NewSoundHandler(exec, path, volume)
+	return func(ctx *Context) error {
+		if ctx.Step == 0 {
+			return nil
+		}
+		return h(ctx)
+	}
+}
@@ -0,0 +1,11 @@
+package boxer
+
+import "strconv"
+
+// SoundPlayerPath is the path to the command used to play sounds.
+const SoundPlayerPath = `/usr/bin/afplay`
+
+// soundPlayerArgs returns the arguments for playing path at volume.
+func soundPlayerArgs(path string, volume float64) ([]string, error) {
+	return []string{"-v", strconv.FormatFloat(volume, 'f', -1, 64), path}, nil
+}
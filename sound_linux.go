@@ -0,0 +1,12 @@
+package boxer
+
+import "strconv"
+
+// SoundPlayerPath is the path to the command used to play sounds.
+const SoundPlayerPath = `/usr/bin/paplay`
+
+// soundPlayerArgs returns the arguments for playing path at volume.
+// The paplay volume ranges from 0 to 65536.
+func soundPlayerArgs(path string, volume float64) ([]string, error) {
+	return []string{"--volume=" + strconv.Itoa(int(volume*65536)), path}, nil
+}
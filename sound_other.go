@@ -0,0 +1,15 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package boxer
+
+import "errors"
+
+// SoundPlayerPath is empty since there is no known sound player.
+const SoundPlayerPath = ``
+
+// soundPlayerArgs returns an error since sounds cannot be played on this
+// platform.
+func soundPlayerArgs(path string, volume float64) ([]string, error) {
+	return nil, Fatal(errors.New("play sound: unsupported platform"))
+}
@@ -0,0 +1,78 @@
+package boxer_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the sound handler plays the sound file with the sound player.
+func TestSoundHandler(t *testing.T) {
+	var called bool
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		if name != boxer.SoundPlayerPath {
+			t.Fatalf("unexpected name: %s", name)
+		} else if args[len(args)-1] != "/tmp/chime.wav" {
+			t.Fatalf("unexpected args: %#v", args)
+		}
+		called = true
+		return nil, nil
+	}
+
+	if err := boxer.NewSoundHandler(exec, "/tmp/chime.wav", 0.5)(&boxer.Context{}); err != nil {
+		t.Fatal(err)
+	} else if !called {
+		t.Fatal("exec not called")
+	}
+}
+
+// Ensure the step sound handler does not play on the first step of the interval.
+func TestStepSoundHandler(t *testing.T) {
+	var n int
+	exec := func(name string, args []string, stdin io.Reader) ([]byte, error) {
+		n++
+		return nil, nil
+	}
+
+	h := boxer.NewStepSoundHandler(exec, "/tmp/tick.wav", 1)
+	for i := 0; i < 3; i++ {
+		if err := h(&boxer.Context{Step: i, Total: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n != 2 {
+		t.Fatalf("unexpected play count: %d", n)
+	}
+}
+
+// Ensure bundled sounds are written to the directory and other names are returned as paths.
+func TestSoundPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if names := boxer.SoundNames(); !reflect.DeepEqual(names, []string{"bell", "chime", "tick"}) {
+		t.Fatalf("unexpected names: %#v", names)
+	}
+
+	if path, err := boxer.SoundPath(dir, "chime"); err != nil {
+		t.Fatal(err)
+	} else if path != filepath.Join(dir, "chime.wav") {
+		t.Fatalf("unexpected path: %s", path)
+	} else if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if path, err := boxer.SoundPath(dir, "/path/to/custom.aiff"); err != nil {
+		t.Fatal(err)
+	} else if path != "/path/to/custom.aiff" {
+		t.Fatalf("unexpected path: %s", path)
+	}
+}
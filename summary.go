@@ -0,0 +1,283 @@
+package boxer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/boxer/clock"
+)
+
+// DaySummary represents the tally of a command's intervals over a day.
+type DaySummary struct {
+	Date      time.Time     // midnight at the start of the day
+	Command   string        // command the intervals were recorded for
+	Intervals int           // total intervals recorded
+	Completed int           // intervals that were not paused or skipped
+	Paused    int           // intervals that were paused
+	Skipped   int           // intervals that were skipped
+	Boxed     time.Duration // total time of completed intervals
+	Streak    int           // longest run of consecutive completed intervals
+	First     time.Time     // start of the first interval
+	Last      time.Time     // end of the last interval
+	Labels    []LabelSummary
+}
+
+// LabelSummary represents the time boxed under a single label.
+type LabelSummary struct {
+	Label     string
+	Completed int
+	Boxed     time.Duration
+}
+
+// Rate returns the fraction of intervals that were completed, from 0 to 1.
+func (s *DaySummary) Rate() float64 {
+	if s.Intervals == 0 {
+		return 0
+	}
+	return float64(s.Completed) / float64(s.Intervals)
+}
+
+// Message returns a one line description of the summary, such as
+// "8 of 10 intervals completed (80%), 4h0m boxed".
+func (s *DaySummary) Message() string {
+	if s.Intervals == 0 {
+		return "No intervals recorded today"
+	}
+	msg := fmt.Sprintf("%d of %d intervals completed (%.0f%%), %s boxed", s.Completed, s.Intervals, s.Rate()*100, formatHoursMinutes(s.Boxed))
+	if s.Streak > 1 {
+		msg += fmt.Sprintf(", longest streak %d", s.Streak)
+	}
+	return msg
+}
+
+// WriteMarkdown writes the summary to w as a Markdown document.
+func (s *DaySummary) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", s.Date.Format("Monday, January 2, 2006"))
+	fmt.Fprintf(&b, "%s.\n\n", s.Message())
+
+	if s.Intervals > 0 {
+		fmt.Fprintf(&b, "| | |\n|---|---|\n")
+		fmt.Fprintf(&b, "| Started | %s |\n", s.First.Format("3:04pm"))
+		fmt.Fprintf(&b, "| Ended | %s |\n", s.Last.Format("3:04pm"))
+		fmt.Fprintf(&b, "| Intervals | %d |\n", s.Intervals)
+		fmt.Fprintf(&b, "| Completed | %d |\n", s.Completed)
+		fmt.Fprintf(&b, "| Paused | %d |\n", s.Paused)
+		fmt.Fprintf(&b, "| Skipped | %d |\n", s.Skipped)
+		fmt.Fprintf(&b, "| Boxed | %s |\n", formatHoursMinutes(s.Boxed))
+		fmt.Fprintf(&b, "| Longest streak | %d |\n", s.Streak)
+	}
+
+	if len(s.Labels) > 0 {
+		fmt.Fprintf(&b, "\n## Labels\n\n| Label | Intervals | Boxed |\n|---|---|---|\n")
+		for _, l := range s.Labels {
+			label := l.Label
+			if label == "" {
+				label = "(none)"
+			}
+			fmt.Fprintf(&b, "| %s | %d | %s |\n", strings.Replace(label, "|", `\|`, -1), l.Completed, formatHoursMinutes(l.Boxed))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// SummarizeDay tallies the records of a command that started on the day of
+// t in t's location. If command is blank then the command of the first
+// record of the day is used.
+func SummarizeDay(records []*IntervalRecord, command string, t time.Time) *DaySummary {
+	y, m, d := t.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	end := start.AddDate(0, 0, 1)
+
+	// Filter the records to the command & day, ordered by start time.
+	var a []*IntervalRecord
+	for _, r := range records {
+		if r.Start.Before(start) || !r.Start.Before(end) {
+			continue
+		} else if command == "" {
+			command = r.Command
+		}
+		if r.Command == command {
+			a = append(a, r)
+		}
+	}
+	sort.SliceStable(a, func(i, j int) bool { return a[i].Start.Before(a[j].Start) })
+
+	s := &DaySummary{Date: start, Command: command, Intervals: len(a)}
+	labels := make(map[string]*LabelSummary)
+	var streak int
+	for i, r := range a {
+		if i == 0 {
+			s.First = r.Start.In(t.Location())
+		}
+		s.Last = r.End.In(t.Location())
+
+		switch {
+		case r.Skipped:
+			s.Skipped, streak = s.Skipped+1, 0
+			continue
+		case r.Paused:
+			s.Paused, streak = s.Paused+1, 0
+			continue
+		}
+
+		s.Completed++
+		s.Boxed += r.Duration()
+		if streak++; streak > s.Streak {
+			s.Streak = streak
+		}
+
+		l := labels[r.Label]
+		if l == nil {
+			l = &LabelSummary{Label: r.Label}
+			labels[r.Label] = l
+		}
+		l.Completed++
+		l.Boxed += r.Duration()
+	}
+
+	// Order labels by the most time boxed.
+	for _, l := range labels {
+		s.Labels = append(s.Labels, *l)
+	}
+	sort.Slice(s.Labels, func(i, j int) bool {
+		if s.Labels[i].Boxed != s.Labels[j].Boxed {
+			return s.Labels[i].Boxed > s.Labels[j].Boxed
+		}
+		return s.Labels[i].Label < s.Labels[j].Label
+	})
+	return s
+}
+
+// formatHoursMinutes formats d as hours & minutes, such as "4h10m".
+func formatHoursMinutes(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%dm", d/time.Hour, (d%time.Hour)/time.Minute)
+}
+
+// SummaryHandler is called with the summary of the day.
+type SummaryHandler func(s *DaySummary) error
+
+// NewSummaryNotificationHandler returns a handler that displays the summary
+// message as a notification. The title, subtitle & sound are copied from n.
+func NewSummaryNotificationHandler(notifier Notifier, n Notification) SummaryHandler {
+	if n.Title == "" {
+		n.Title = DefaultNotificationTitle
+	}
+
+	return func(s *DaySummary) error {
+		n := n
+		n.Message = s.Message()
+		return notifier.Notify(&n)
+	}
+}
+
+// NewSummaryFileHandler returns a handler that writes the summary as a
+// Markdown file named after the date, such as "2006-01-02.md", in dir.
+func NewSummaryFileHandler(dir string) SummaryHandler {
+	return func(s *DaySummary) error {
+		var b strings.Builder
+		if err := s.WriteMarkdown(&b); err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return fmt.Errorf("mkdir: %s", err)
+		} else if err := ioutil.WriteFile(filepath.Join(dir, s.Date.Format("2006-01-02")+".md"), []byte(b.String()), 0666); err != nil {
+			return fmt.Errorf("write summary: %s", err)
+		}
+		return nil
+	}
+}
+
+// Summarizer summarizes the day's intervals from the journal at a time of
+// day, such as the end of the workday.
+type Summarizer struct {
+	// The journal to read the day's intervals from.
+	Journal *FileJournal
+
+	// The command whose intervals are tallied. If blank, the command of the
+	// first interval of the day is used.
+	Command string
+
+	// The time of day to summarize, as an offset from midnight.
+	At time.Duration
+
+	// The handlers that receive the summary.
+	Handlers []SummaryHandler
+
+	// The logger used for reporting handler errors.
+	Logger Logger
+
+	// The clock used to determine the current time & to sleep.
+	Clock clock.Clock
+}
+
+// NewSummarizer returns a new summarizer for the journal.
+func NewSummarizer(journal *FileJournal, at time.Duration) *Summarizer {
+	return &Summarizer{
+		Journal: journal,
+		At:      at,
+		Logger:  NewTextLogger(log.New(os.Stderr, "", 0)),
+		Clock:   clock.New(),
+	}
+}
+
+// Run summarizes the day at the time of day until ctx is done.
+func (s *Summarizer) Run(ctx context.Context) error {
+	for {
+		now := s.Clock.Now()
+		timer := s.Clock.NewTimer(s.next(now).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case now = <-timer.C:
+		}
+
+		if err := s.Summarize(now); err != nil {
+			s.Logger.Log(&LogEntry{Time: now, Command: "summary", Err: err})
+		}
+	}
+}
+
+// next returns the next time of day to summarize after now.
+func (s *Summarizer) next(now time.Time) time.Time {
+	y, m, d := now.Date()
+	t := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(s.At)
+	if !t.After(now) {
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()).Add(s.At)
+	}
+	return t
+}
+
+// Summarize tallies the intervals of now's day & passes the summary to
+// every handler. Handler errors are combined into the returned error.
+func (s *Summarizer) Summarize(now time.Time) error {
+	records, err := s.Journal.Records()
+	if err != nil {
+		return fmt.Errorf("read journal: %s", err)
+	}
+	summary := SummarizeDay(records, s.Command, now)
+
+	var errs []string
+	for _, h := range s.Handlers {
+		if err := h(summary); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package boxer_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the day's intervals of a single command are tallied.
+func TestSummarizeDay(t *testing.T) {
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	interval := func(i int, label string) *boxer.IntervalRecord {
+		s := start.Add(time.Duration(i) * 30 * time.Minute)
+		return &boxer.IntervalRecord{Command: "wallpaper", Label: label, Start: s, End: s.Add(30 * time.Minute)}
+	}
+
+	records := []*boxer.IntervalRecord{
+		{Command: "wallpaper", Start: start.AddDate(0, 0, -1), End: start.AddDate(0, 0, -1).Add(30 * time.Minute)},
+		interval(0, "email"),
+		{Command: "announcement", Start: start, End: start.Add(30 * time.Minute)},
+		interval(1, "design doc"),
+		interval(2, "design doc"),
+		interval(3, "design doc"),
+		interval(4, ""),
+	}
+	records[5].Paused = true
+	records[6].Skipped = true
+
+	s := boxer.SummarizeDay(records, "", start.Add(8*time.Hour))
+	if s.Command != "wallpaper" || s.Intervals != 5 || s.Completed != 3 || s.Paused != 1 || s.Skipped != 1 {
+		t.Fatalf("unexpected summary: %#v", s)
+	} else if s.Boxed != 90*time.Minute || s.Streak != 3 || s.Rate() != 0.6 {
+		t.Fatalf("unexpected summary: %#v", s)
+	} else if !s.First.Equal(start) || !s.Last.Equal(start.Add(150*time.Minute)) {
+		t.Fatalf("unexpected range: %s - %s", s.First, s.Last)
+	} else if !reflect.DeepEqual(s.Labels, []boxer.LabelSummary{
+		{Label: "design doc", Completed: 2, Boxed: 60 * time.Minute},
+		{Label: "email", Completed: 1, Boxed: 30 * time.Minute},
+	}) {
+		t.Fatalf("unexpected labels: %#v", s.Labels)
+	} else if msg := s.Message(); msg != "3 of 5 intervals completed (60%), 1h30m boxed, longest streak 3" {
+		t.Fatalf("unexpected message: %q", msg)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(buf.String(), "# Saturday, January 1, 2000\n\n3 of 5 intervals completed") {
+		t.Fatalf("unexpected markdown: %s", buf.String())
+	} else if !strings.Contains(buf.String(), "| design doc | 2 | 1h0m |\n") {
+		t.Fatalf("unexpected markdown: %s", buf.String())
+	}
+}
+
+// Ensure the summarizer reads the journal & writes a Markdown file.
+func TestSummarizer_Summarize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boxer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	j := boxer.NewFileJournal(filepath.Join(dir, "journal.jsonl"))
+	if err := j.Record(&boxer.IntervalRecord{Command: "wallpaper", Start: start, End: start.Add(1 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got *boxer.DaySummary
+	s := boxer.NewSummarizer(j, 17*time.Hour)
+	s.Handlers = []boxer.SummaryHandler{
+		func(s *boxer.DaySummary) error { got = s; return nil },
+		boxer.NewSummaryFileHandler(filepath.Join(dir, "summaries")),
+	}
+	if err := s.Summarize(start.Add(8 * time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if got == nil || got.Completed != 1 || got.Boxed != 1*time.Hour {
+		t.Fatalf("unexpected summary: %#v", got)
+	}
+
+	if buf, err := ioutil.ReadFile(filepath.Join(dir, "summaries", "2000-01-01.md")); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(buf), "1 of 1 intervals completed (100%), 1h0m boxed.") {
+		t.Fatalf("unexpected file: %s", buf)
+	}
+}
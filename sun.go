@@ -0,0 +1,55 @@
+package boxer
+
+import (
+	"math"
+	"time"
+)
+
+// SunTimes returns the sunrise & sunset on the day of t, in t's location,
+// at the given latitude & longitude in degrees. Longitudes are positive to
+// the east. Returns false if the sun does not rise & set that day, such as
+// during a polar day or night.
+//
+// Times are calculated with the sunrise equation and are accurate to within
+// a few minutes.
+func SunTimes(t time.Time, lat, lon float64) (sunrise, sunset time.Time, ok bool) {
+	const j2000 = 2451545.0
+
+	// Days since the J2000 epoch at noon UTC on the date.
+	y, m, d := t.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	n := math.Floor(julianDay(noon)-j2000) + 0.0008
+
+	// Approximate the solar noon at the longitude & the sun's position.
+	js := n - lon/360
+	anomaly := math.Mod(357.5291+0.98560028*js, 360)
+	center := 1.9148*sinDeg(anomaly) + 0.0200*sinDeg(2*anomaly) + 0.0003*sinDeg(3*anomaly)
+	ecliptic := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := j2000 + js + 0.0053*sinDeg(anomaly) - 0.0069*sinDeg(2*ecliptic)
+	declination := math.Asin(sinDeg(ecliptic) * sinDeg(23.4397))
+
+	// Determine the hour angle where the sun crosses the horizon, allowing
+	// for refraction & the size of the sun's disc.
+	cosHourAngle := (sinDeg(-0.833) - sinDeg(lat)*math.Sin(declination)) / (cosDeg(lat) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+
+	sunrise = fromJulianDay(transit - hourAngle/360).In(t.Location())
+	sunset = fromJulianDay(transit + hourAngle/360).In(t.Location())
+	return sunrise, sunset, true
+}
+
+// julianDay returns the Julian day number of t.
+func julianDay(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+// fromJulianDay returns the time of a Julian day number.
+func fromJulianDay(jd float64) time.Time {
+	return time.Unix(0, int64((jd-2440587.5)*86400*float64(time.Second))).UTC()
+}
+
+func sinDeg(v float64) float64 { return math.Sin(v * math.Pi / 180) }
+func cosDeg(v float64) float64 { return math.Cos(v * math.Pi / 180) }
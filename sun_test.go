@@ -0,0 +1,31 @@
+package boxer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure sunrise & sunset are calculated in the time's location.
+func TestSunTimes(t *testing.T) {
+	// New York City on the summer solstice.
+	edt := time.FixedZone("EDT", -4*60*60)
+	sunrise, sunset, ok := boxer.SunTimes(time.Date(2021, time.June, 21, 12, 0, 0, 0, edt), 40.7128, -74.0060)
+	if !ok {
+		t.Fatal("expected sunrise & sunset")
+	} else if d := sunrise.Sub(time.Date(2021, time.June, 21, 5, 25, 0, 0, edt)); d < -3*time.Minute || d > 3*time.Minute {
+		t.Fatalf("unexpected sunrise: %s", sunrise)
+	} else if d := sunset.Sub(time.Date(2021, time.June, 21, 20, 31, 0, 0, edt)); d < -3*time.Minute || d > 3*time.Minute {
+		t.Fatalf("unexpected sunset: %s", sunset)
+	} else if sunrise.Location() != edt {
+		t.Fatalf("unexpected location: %s", sunrise.Location())
+	}
+}
+
+// Ensure there is no sunrise or sunset during a polar night.
+func TestSunTimes_PolarNight(t *testing.T) {
+	if _, _, ok := boxer.SunTimes(time.Date(2021, time.December, 21, 12, 0, 0, 0, time.UTC), 78.2232, 15.6267); ok {
+		t.Fatal("expected no sunrise")
+	}
+}
@@ -0,0 +1,262 @@
+package boxer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeTracker represents a time tracking service, such as Toggl or Clockify.
+type TimeTracker interface {
+	// Starts a running time entry & returns its ID.
+	StartEntry(description string, start time.Time) (string, error)
+
+	// Stops the running time entry at end.
+	StopEntry(id string, end time.Time) error
+}
+
+// NewTimeTracker returns the time tracker for a service, "toggl" or "clockify".
+func NewTimeTracker(service, token, workspace string) (TimeTracker, error) {
+	if token == "" {
+		return nil, fmt.Errorf("api token required")
+	} else if workspace == "" {
+		return nil, fmt.Errorf("workspace required")
+	}
+
+	switch service {
+	case "", "toggl":
+		return &TogglTracker{Token: token, Workspace: workspace}, nil
+	case "clockify":
+		return &ClockifyTracker{Token: token, Workspace: workspace}, nil
+	default:
+		return nil, fmt.Errorf("invalid time tracking service: %q", service)
+	}
+}
+
+// DefaultTimeEntryDescription is the description of time entries for
+// intervals without a label.
+const DefaultTimeEntryDescription = "Timebox"
+
+// NewTimeTrackingHandler returns a handler that stops the previous interval's
+// time entry & starts a new entry, described by the current label, at the
+// start of every interval. Entries are stopped at the end of their interval
+// so time is not tracked while boxer is inactive between intervals.
+func NewTimeTrackingHandler(tracker TimeTracker, interval time.Duration) IntervalHandler {
+	var mu sync.Mutex
+	var id string
+	var end time.Time
+
+	return func(ctx *Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Stop the previous entry, if any, once it has ended.
+		if id != "" {
+			if ctx.IntervalStart.Before(end) {
+				end = ctx.IntervalStart
+			}
+			if err := tracker.StopEntry(id, end); err != nil {
+				return fmt.Errorf("stop time entry: %s", err)
+			}
+			id = ""
+		}
+
+		description := ctx.Label
+		if description == "" {
+			description = DefaultTimeEntryDescription
+		}
+
+		var err error
+		if id, err = tracker.StartEntry(description, ctx.IntervalStart); err != nil {
+			return fmt.Errorf("start time entry: %s", err)
+		}
+		end = ctx.IntervalStart.Add(interval)
+		return nil
+	}
+}
+
+// TogglURL is the base URL of the Toggl Track API.
+const TogglURL = "https://api.track.toggl.com/api/v9"
+
+// TogglTracker tracks time entries with the Toggl Track API.
+type TogglTracker struct {
+	Token     string
+	Workspace string // workspace ID
+
+	// The base URL & client of the API. Default to TogglURL & the default client.
+	URL    string
+	Client *http.Client
+}
+
+// StartEntry creates a running time entry in the workspace.
+func (t *TogglTracker) StartEntry(description string, start time.Time) (string, error) {
+	wid, err := strconv.ParseInt(t.Workspace, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid toggl workspace: %q", t.Workspace)
+	}
+
+	var entry struct {
+		ID int64 `json:"id"`
+	}
+	if err := t.do("POST", "/workspaces/"+t.Workspace+"/time_entries", map[string]interface{}{
+		"description":  description,
+		"start":        start.UTC().Format(time.RFC3339),
+		"duration":     -1,
+		"workspace_id": wid,
+		"created_with": "boxer",
+	}, &entry); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(entry.ID, 10), nil
+}
+
+// StopEntry sets the stop time of the entry.
+func (t *TogglTracker) StopEntry(id string, end time.Time) error {
+	return t.do("PUT", "/workspaces/"+t.Workspace+"/time_entries/"+id, map[string]interface{}{
+		"stop": end.UTC().Format(time.RFC3339),
+	}, nil)
+}
+
+// do sends a JSON request authenticated with the API token & decodes the
+// response into v, if set.
+func (t *TogglTracker) do(method, path string, body, v interface{}) error {
+	req, err := newJSONRequest(method, urlOrDefault(t.URL, TogglURL)+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.Token, "api_token")
+	return doJSONRequest(t.Client, req, v)
+}
+
+// ClockifyURL is the base URL of the Clockify API.
+const ClockifyURL = "https://api.clockify.me/api/v1"
+
+// ClockifyTracker tracks time entries with the Clockify API.
+type ClockifyTracker struct {
+	Token     string
+	Workspace string // workspace ID
+
+	// The base URL & client of the API. Default to ClockifyURL & the default client.
+	URL    string
+	Client *http.Client
+
+	mu     sync.Mutex
+	userID string
+}
+
+// StartEntry creates a running time entry in the workspace.
+func (t *ClockifyTracker) StartEntry(description string, start time.Time) (string, error) {
+	var entry struct {
+		ID string `json:"id"`
+	}
+	if err := t.do("POST", "/workspaces/"+t.Workspace+"/time-entries", map[string]interface{}{
+		"description": description,
+		"start":       start.UTC().Format(time.RFC3339),
+	}, &entry); err != nil {
+		return "", err
+	}
+	return entry.ID, nil
+}
+
+// StopEntry stops the user's running entry at end. Clockify only allows one
+// running entry per user so the ID is not needed.
+func (t *ClockifyTracker) StopEntry(id string, end time.Time) error {
+	userID, err := t.user()
+	if err != nil {
+		return err
+	}
+	return t.do("PATCH", "/workspaces/"+t.Workspace+"/user/"+userID+"/time-entries", map[string]interface{}{
+		"end": end.UTC().Format(time.RFC3339),
+	}, nil)
+}
+
+// user returns the ID of the token's user. The ID is cached after the first request.
+func (t *ClockifyTracker) user() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.userID == "" {
+		var user struct {
+			ID string `json:"id"`
+		}
+		if err := t.do("GET", "/user", nil, &user); err != nil {
+			return "", fmt.Errorf("clockify user: %s", err)
+		}
+		t.userID = user.ID
+	}
+	return t.userID, nil
+}
+
+// do sends a JSON request authenticated with the API key & decodes the
+// response into v, if set.
+func (t *ClockifyTracker) do(method, path string, body, v interface{}) error {
+	req, err := newJSONRequest(method, urlOrDefault(t.URL, ClockifyURL)+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Api-Key", t.Token)
+	return doJSONRequest(t.Client, req, v)
+}
+
+// urlOrDefault returns u with any trailing slash removed, or def if u is blank.
+func urlOrDefault(u, def string) string {
+	if u == "" {
+		return def
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// newJSONRequest returns a request with body encoded as JSON, if set.
+func newJSONRequest(method, url string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// doJSONRequest sends req with client, or the default client if nil, and
+// decodes the response into v, if set. Returns the response body as the
+// error if the request was not successful.
+func doJSONRequest(client *http.Client, req *http.Request, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	} else if v == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package boxer_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/boxer"
+)
+
+// Ensure the handler stops the previous entry at the end of its interval &
+// starts a new entry described by the label.
+func TestTimeTrackingHandler(t *testing.T) {
+	var calls []string
+	tracker := &TestTimeTracker{
+		StartEntryFn: func(description string, start time.Time) (string, error) {
+			calls = append(calls, "start "+description+" "+start.Format("15:04"))
+			return start.Format("1504"), nil
+		},
+		StopEntryFn: func(id string, end time.Time) error {
+			calls = append(calls, "stop "+id+" "+end.Format("15:04"))
+			return nil
+		},
+	}
+
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	h := boxer.NewTimeTrackingHandler(tracker, 15*time.Minute)
+	for _, ctx := range []*boxer.Context{
+		{IntervalStart: start, Label: "write design doc"},
+		{IntervalStart: start.Add(15 * time.Minute)},
+		{IntervalStart: start.Add(2 * time.Hour)},
+	} {
+		if err := h(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exp := []string{
+		"start write design doc 09:00",
+		"stop 0900 09:15",
+		"start Timebox 09:15",
+		"stop 0915 09:30",
+		"start Timebox 11:00",
+	}; !reflect.DeepEqual(calls, exp) {
+		t.Fatalf("unexpected calls: %#v", calls)
+	}
+}
+
+// Ensure Toggl entries are created in the workspace & stopped by ID.
+func TestTogglTracker(t *testing.T) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "TOKEN" || pass != "api_token" {
+			t.Errorf("unexpected auth: %s:%s", user, pass)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(b))
+		w.Write([]byte(`{"id":1234}`))
+	}))
+	defer s.Close()
+
+	tracker := &boxer.TogglTracker{Token: "TOKEN", Workspace: "42", URL: s.URL}
+	start := time.Date(2000, time.January, 1, 9, 0, 0, 0, time.UTC)
+	if id, err := tracker.StartEntry("write design doc", start); err != nil {
+		t.Fatal(err)
+	} else if id != "1234" {
+		t.Fatalf("unexpected id: %s", id)
+	} else if err := tracker.StopEntry(id, start.Add(15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []string{
+		`POST /workspaces/42/time_entries {"created_with":"boxer","description":"write design doc","duration":-1,"start":"2000-01-01T09:00:00Z","workspace_id":42}`,
+		`PUT /workspaces/42/time_entries/1234 {"stop":"2000-01-01T09:15:00Z"}`,
+	}; !reflect.DeepEqual(requests, exp) {
+		t.Fatalf("unexpected requests: %#v", requests)
+	}
+}
+
+// Ensure Clockify entries are stopped with the token's user.
+func TestClockifyTracker(t *testing.T) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("X-Api-Key"); key != "TOKEN" {
+			t.Errorf("unexpected api key: %s", key)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		end, _ := body["end"].(string)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+end))
+		if r.URL.Path == "/user" {
+			w.Write([]byte(`{"id":"USER"}`))
+		} else {
+			w.Write([]byte(`{"id":"ENTRY"}`))
+		}
+	}))
+	defer s.Close()
+
+	tracker := &boxer.ClockifyTracker{Token: "TOKEN", Workspace: "WS", URL: s.URL}
+	end := time.Date(2000, time.January, 1, 9, 15, 0, 0, time.UTC)
+	if err := tracker.StopEntry("ENTRY", end); err != nil {
+		t.Fatal(err)
+	} else if err := tracker.StopEntry("ENTRY", end); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{
+		`GET /user`,
+		`PATCH /workspaces/WS/user/USER/time-entries 2000-01-01T09:15:00Z`,
+		`PATCH /workspaces/WS/user/USER/time-entries 2000-01-01T09:15:00Z`,
+	}; !reflect.DeepEqual(requests, exp) {
+		t.Fatalf("unexpected requests: %#v", requests)
+	}
+}
+
+// Ensure an unsuccessful response returns its status & body.
+func TestTogglTracker_ErrStatus(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Incorrect username and/or password", http.StatusForbidden)
+	}))
+	defer s.Close()
+
+	tracker := &boxer.TogglTracker{Token: "TOKEN", Workspace: "42", URL: s.URL}
+	if _, err := tracker.StartEntry("", time.Now()); err == nil || err.Error() != `unexpected status: 403 Forbidden: Incorrect username and/or password` {
+		t.Fatal(err)
+	}
+}
+
+// TestTimeTracker is a mock implementation of boxer.TimeTracker.
+type TestTimeTracker struct {
+	StartEntryFn func(description string, start time.Time) (string, error)
+	StopEntryFn  func(id string, end time.Time) error
+}
+
+func (t *TestTimeTracker) StartEntry(description string, start time.Time) (string, error) {
+	return t.StartEntryFn(description, start)
+}
+
+func (t *TestTimeTracker) StopEntry(id string, end time.Time) error {
+	return t.StopEntryFn(id, end)
+}
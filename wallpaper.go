@@ -0,0 +1,377 @@
+package boxer
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// NewWallpaperHandler returns a handler for visualizing steps with the desktop wallpaper.
+// Generated images are stored in an unbounded cache in the path directory.
+func NewWallpaperHandler(desktop Desktop, generator WallpaperGenerator, path string) Handler {
+	return NewCachedWallpaperHandler(desktop, NewWallpaperCache(path, generator))
+}
+
+// NewCachedWallpaperHandler returns a handler for visualizing steps with the
+// desktop wallpaper using images from cache.
+//
+// On the first step, at the start of every interval, after missed steps, and
+// whenever the desktop size or scale changes, the handler regenerates every step's
+// image for the interval so later steps do not wait on image generation.
+// Stale images are then pruned from the cache.
+func NewCachedWallpaperHandler(desktop Desktop, cache *WallpaperCache) Handler {
+	var initialized bool
+	var prevW, prevH, prevI int
+	var prevScale float64
+
+	return func(ctx *Context) error {
+		i, n := ctx.Step, ctx.Total
+
+		// Retrieve desktop size.
+		w, h, err := desktop.Size()
+		if err != nil {
+			return fmt.Errorf("desktop size: %s", err)
+		}
+		scale, err := DesktopScale(desktop)
+		if err != nil {
+			return fmt.Errorf("desktop scale: %s", err)
+		}
+
+		// Steps past the end of an interval held open stay on the last image
+		// or are drawn overdue.
+		if ctx.Overtime > 0 {
+			var imgpath string
+			if cache.Overdue != nil {
+				imgpath, err = cache.GenerateOverdue(w, h, scale, ctx.Overtime, n)
+			} else {
+				imgpath, err = cache.Get(w, h, scale, n-1, n)
+			}
+			if err != nil {
+				return err
+			}
+			return desktop.SetWallpaper(imgpath)
+		}
+
+		// Determine if the images for the interval need to be refreshed.
+		// Images are also refreshed if steps were missed, such as after the
+		// computer wakes from sleep, since the colors may have changed.
+		refresh := !initialized || i == 0 || i != prevI+1 || w != prevW || h != prevH || scale != prevScale
+		initialized, prevW, prevH, prevScale, prevI = true, w, h, scale, i
+
+		// Generate the current wallpaper first so the desktop can be updated
+		// immediately. Otherwise only generate it if it doesn't exist.
+		var imgpath string
+		if refresh {
+			imgpath, err = cache.Generate(w, h, scale, i, n)
+		} else {
+			imgpath, err = cache.Get(w, h, scale, i, n)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Update the current background.
+		if err := desktop.SetWallpaper(imgpath); err != nil {
+			return err
+		}
+
+		// Generate the remaining steps & remove stale images.
+		if refresh {
+			if err := cache.Pregenerate(w, h, scale, n, i); err != nil {
+				return err
+			}
+			if err := cache.Prune(w, h, scale, n); err != nil {
+				return fmt.Errorf("prune wallpaper cache: %s", err)
+			}
+		}
+		return nil
+	}
+}
+
+// WallpaperGenerator generates a wallpaper at the given path. The desktop
+// size, w x h, is in points and the image is generated with scale pixels per
+// point so it is sharp on high resolution displays.
+type WallpaperGenerator func(path string, w, h int, scale, pct float64) error
+
+// OverdueSteps is the number of steps of overtime until an overdue wallpaper
+// reaches its full intensity.
+const OverdueSteps = 4
+
+// OverdueIntensity returns the intensity of an overdue wallpaper after the
+// given steps of overtime, from 1/OverdueSteps on the first step up to 1.
+func OverdueIntensity(overtime int) float64 {
+	if overtime > OverdueSteps {
+		overtime = OverdueSteps
+	}
+	return float64(overtime) / OverdueSteps
+}
+
+// WallpaperCache manages generated wallpaper images within a directory.
+// Images are saved to a common filename format that includes the desktop
+// size, scale, step, and key so images can be reused and stale images can be
+// found.
+type WallpaperCache struct {
+	mu        sync.RWMutex // held for writing while pruning
+	path      string
+	generator WallpaperGenerator
+
+	// The maximum total size, in bytes, of cached images. Images for other
+	// desktop sizes, scales, or step counts are removed oldest first until the cache
+	// fits. If zero, all images for other desktop sizes are removed.
+	MaxSize int64
+
+	// The filename extension of generated images, such as ".jpg". Images
+	// with another extension are stale. Defaults to ".png".
+	Ext string
+
+	// Identifies the settings of the generator, such as its colors & style,
+	// so changing them doesn't reuse old images. Images with another key are
+	// always removed by Prune. See WallpaperKey.
+	Key string
+
+	// Generates the image for steps past the end of an interval held open
+	// by the overtime policy, if set. See NewOverdueWallpaperGenerator.
+	Overdue WallpaperGenerator
+
+	// The number of images generated concurrently by Pregenerate. Defaults
+	// to the number of CPUs.
+	Workers int
+
+	// Called by Pregenerate before the first image & after each image is
+	// generated, if set.
+	Progress func(done, total int)
+}
+
+// NewWallpaperCache returns a new cache that stores images in path. The
+// generator must be safe for concurrent use.
+func NewWallpaperCache(path string, generator WallpaperGenerator) *WallpaperCache {
+	return &WallpaperCache{path: path, generator: generator}
+}
+
+// Path returns the path to the image for a given desktop size, scale, and
+// step. Images with a scale other than 1 have a suffix such as "@2x" and the
+// key, if set, is appended to the step.
+func (c *WallpaperCache) Path(w, h int, scale float64, i, n int) string {
+	var suffix string
+	if c.Key != "" {
+		suffix = "_" + c.Key
+	}
+	if scale != 1 {
+		suffix += "@" + strconv.FormatFloat(scale, 'f', -1, 64) + "x"
+	}
+	return filepath.Join(c.path, fmt.Sprintf("wallpaper_%04d_%04d_%02d_%02d%s%s", w, h, i, n, suffix, c.ext()))
+}
+
+// WallpaperKey returns a short hash of the JSON encoding of v, such as the
+// settings used to create a generator, for use as the key of a cache.
+func WallpaperKey(v interface{}) (string, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New32a()
+	h.Write(buf)
+	return fmt.Sprintf("%08x", h.Sum32()), nil
+}
+
+// ext returns the filename extension of generated images.
+func (c *WallpaperCache) ext() string {
+	if c.Ext == "" {
+		return ".png"
+	}
+	return c.Ext
+}
+
+// Get returns the path to the image for a given desktop size, scale, and
+// step. The image is generated if it doesn't exist.
+func (c *WallpaperCache) Get(w, h int, scale float64, i, n int) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	path := c.Path(w, h, scale, i, n)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := c.generator(path, w, h, scale, float64(i)/float64(n)); err != nil {
+			return "", fmt.Errorf("generate wallpaper: %s", err)
+		}
+	}
+	return path, nil
+}
+
+// Generate generates the image for a given desktop size, scale, and step,
+// replacing any existing image. Returns the path to the image.
+func (c *WallpaperCache) Generate(w, h int, scale float64, i, n int) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	path := c.Path(w, h, scale, i, n)
+	if err := c.generator(path, w, h, scale, float64(i)/float64(n)); err != nil {
+		return "", fmt.Errorf("generate wallpaper: %s", err)
+	}
+	return path, nil
+}
+
+// GenerateOverdue generates the image for a given desktop size, scale, and
+// step of overtime past the end of an interval of n steps with the Overdue
+// generator. Images are stored after the interval's steps. Returns the path
+// to the image.
+func (c *WallpaperCache) GenerateOverdue(w, h int, scale float64, overtime, n int) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if overtime > OverdueSteps {
+		overtime = OverdueSteps
+	}
+	path := c.Path(w, h, scale, n+overtime-1, n)
+	if err := c.Overdue(path, w, h, scale, OverdueIntensity(overtime)); err != nil {
+		return "", fmt.Errorf("generate overdue wallpaper: %s", err)
+	}
+	return path, nil
+}
+
+// Pregenerate generates the images for every step of an interval, except the
+// steps in skip, with a pool of workers. Returns the first error, if any.
+func (c *WallpaperCache) Pregenerate(w, h int, scale float64, n int, skip ...int) error {
+	var steps []int
+	for i := 0; i < n; i++ {
+		if !containsInt(skip, i) {
+			steps = append(steps, i)
+		}
+	}
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(steps) {
+		workers = len(steps)
+	}
+
+	var mu sync.Mutex
+	var done int
+	var firstErr error
+	if c.Progress != nil {
+		c.Progress(0, len(steps))
+	}
+
+	// Distribute the steps to the workers in order.
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for k := 0; k < workers; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				_, err := c.Generate(w, h, scale, i, n)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if err == nil {
+					if done++; c.Progress != nil {
+						c.Progress(done, len(steps))
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, i := range steps {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+
+	return firstErr
+}
+
+// containsInt returns true if a contains v.
+func containsInt(a []int, v int) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune removes cached images that don't match the given desktop size,
+// scale, step count, and extension until the cache is within MaxSize.
+// Images generated with another key & leftover temporary files are always
+// removed.
+func (c *WallpaperCache) Prune(w, h int, scale float64, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fis, err := ioutil.ReadDir(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Find stale images & total the size of all images.
+	var stale []os.FileInfo
+	var total int64
+	for _, fi := range fis {
+		// Remove temporary files left behind by a crash mid-write.
+		if wallpaperTempFilenameRegex.MatchString(fi.Name()) && !fi.IsDir() {
+			if err := os.Remove(filepath.Join(c.path, fi.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+
+		m := wallpaperFilenameRegex.FindStringSubmatch(fi.Name())
+		if m == nil || fi.IsDir() {
+			continue
+		}
+
+		// Remove images generated with other settings.
+		if m[5] != c.Key {
+			if err := os.Remove(filepath.Join(c.path, fi.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		total += fi.Size()
+
+		fw, _ := strconv.Atoi(m[1])
+		fh, _ := strconv.Atoi(m[2])
+		fn, _ := strconv.Atoi(m[4])
+		fscale := 1.0
+		if m[6] != "" {
+			fscale, _ = strconv.ParseFloat(m[6], 64)
+		}
+		if fw != w || fh != h || fn != n || fscale != scale || m[7] != c.ext() {
+			stale = append(stale, fi)
+		}
+	}
+
+	// Remove stale images, oldest first, until the cache fits.
+	sort.Slice(stale, func(i, j int) bool { return stale[i].ModTime().Before(stale[j].ModTime()) })
+	for _, fi := range stale {
+		if c.MaxSize > 0 && total <= c.MaxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.path, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= fi.Size()
+	}
+	return nil
+}
+
+// wallpaperFilenameRegex matches the filenames of cached wallpaper images.
+var wallpaperFilenameRegex = regexp.MustCompile(`^wallpaper_(\d+)_(\d+)_(\d+)_(\d+)(?:_([0-9a-f]+))?(?:@([\d.]+)x)?(\.png|\.jpg)$`)
+
+// wallpaperTempFilenameRegex matches the temporary files images are written
+// to before they are renamed into place.
+var wallpaperTempFilenameRegex = regexp.MustCompile(`^wallpaper_.*\.tmp$`)
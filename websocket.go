@@ -0,0 +1,229 @@
+package boxer
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// websocketGUID is appended to the client key to compute the accept key.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketFrameSize is the largest frame accepted from a client. Clients
+// of the event stream only send control frames so this is generous.
+const maxWebSocketFrameSize = 1 << 16
+
+// WebSocket frame opcodes.
+const (
+	websocketText  = 0x1
+	websocketClose = 0x8
+	websocketPing  = 0x9
+	websocketPong  = 0xA
+)
+
+// websocketConn represents the server side of a WebSocket connection as
+// defined by RFC 6455. It only supports the subset needed to stream events:
+// writing unfragmented text frames and answering pings & closes.
+type websocketConn struct {
+	mu   sync.Mutex // serializes writes
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// upgradeWebSocket performs the opening handshake & hijacks the connection.
+// If the request is not a valid WebSocket handshake then an error response
+// is written and an error is returned.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, errors.New("method not allowed")
+	} else if !sameOrigin(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return nil, errors.New("cross-origin request")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket upgrade required")
+	} else if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, errors.New("unsupported websocket version")
+	} else if key == "" {
+		http.Error(w, "websocket key required", http.StatusBadRequest)
+		return nil, errors.New("websocket key required")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("response cannot be hijacked")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("hijack: %s", err)
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n")
+	fmt.Fprintf(rw, "Upgrade: websocket\r\n")
+	fmt.Fprintf(rw, "Connection: Upgrade\r\n")
+	fmt.Fprintf(rw, "Sec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write handshake: %s", err)
+	}
+
+	return &websocketConn{conn: conn, rw: rw}, nil
+}
+
+// sameOrigin returns true if the request has no Origin header or if the
+// origin has the same host as the request. Browsers always send an Origin
+// with WebSocket handshakes so other web pages cannot read the event stream.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// websocketAccept returns the Sec-WebSocket-Accept value for a client key.
+func websocketAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains returns true if the comma-separated header contains token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Close closes the underlying connection.
+func (c *websocketConn) Close() error {
+	return c.conn.Close()
+}
+
+// WriteText writes p as a single text frame.
+func (c *websocketConn) WriteText(p []byte) error {
+	return c.writeFrame(websocketText, p)
+}
+
+// writeFrame writes an unmasked, unfragmented frame. Server frames are never
+// masked.
+func (c *websocketConn) writeFrame(opcode byte, p []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hdr := []byte{0x80 | opcode}
+	switch n := len(p); {
+	case n <= 125:
+		hdr = append(hdr, byte(n))
+	case n <= 0xFFFF:
+		hdr = append(hdr, 126, 0, 0)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(n))
+	default:
+		hdr = append(hdr, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(hdr[2:], uint64(n))
+	}
+
+	if _, err := c.rw.Write(hdr); err != nil {
+		return err
+	} else if _, err := c.rw.Write(p); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readFrame reads the next frame from the client & unmasks its payload.
+func (c *websocketConn) readFrame() (opcode byte, p []byte, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.rw, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode = hdr[0] & 0x0F
+
+	// Client frames must be masked.
+	if hdr[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var buf [2]byte
+		if _, err := io.ReadFull(c.rw, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(buf[:]))
+	case 127:
+		var buf [8]byte
+		if _, err := io.ReadFull(c.rw, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(buf[:])
+	}
+	if n > maxWebSocketFrameSize {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	p = make([]byte, n)
+	if _, err := io.ReadFull(c.rw, p); err != nil {
+		return 0, nil, err
+	}
+	for i := range p {
+		p[i] ^= mask[i%4]
+	}
+	return opcode, p, nil
+}
+
+// readLoop reads frames from the client until it closes the connection or
+// an error occurs. Pings are answered & all data frames are discarded.
+func (c *websocketConn) readLoop() error {
+	for {
+		opcode, p, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+
+		switch opcode {
+		case websocketPing:
+			if err := c.writeFrame(websocketPong, p); err != nil {
+				return err
+			}
+		case websocketClose:
+			// Echo the status code back to complete the closing handshake.
+			if len(p) > 2 {
+				p = p[:2]
+			}
+			_ = c.writeFrame(websocketClose, p)
+			return nil
+		}
+	}
+}